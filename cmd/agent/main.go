@@ -17,6 +17,8 @@ import (
 	"github.com/servereye/servereye/internal/agent"
 	"github.com/servereye/servereye/internal/config"
 	"github.com/servereye/servereye/internal/version"
+	"github.com/servereye/servereye/pkg/auth"
+	"github.com/servereye/servereye/pkg/protocol"
 	"github.com/sirupsen/logrus"
 )
 
@@ -29,9 +31,12 @@ const (
 // KeyRegistrationRequest represents a request to register a generated key
 type KeyRegistrationRequest struct {
 	SecretKey    string `json:"secret_key"`
+	SigningKey   string `json:"signing_key,omitempty"`
 	AgentVersion string `json:"agent_version,omitempty"`
 	OSInfo       string `json:"os_info,omitempty"`
 	Hostname     string `json:"hostname,omitempty"`
+
+	Capabilities *protocol.Capabilities `json:"capabilities,omitempty"`
 }
 
 func main() {
@@ -120,6 +125,12 @@ func handleInstall() error {
 		return fmt.Errorf("failed to generate secret key: %v", err)
 	}
 
+	// Commands are signed with a separate key: the secret key is visible in Redis names
+	signingKey, err := auth.GenerateSigningKey()
+	if err != nil {
+		return err
+	}
+
 	// Try to use system config directory, fallback to user home
 	configDir := "/etc/servereye"
 	logPath := "/var/log/servereye/agent.log"
@@ -145,6 +156,7 @@ func handleInstall() error {
   name: "Production Server"
   description: "ServerEye monitored server"
   secret_key: "%s"
+  signing_key: "%s"
 
 redis:
   address: "localhost:6379"
@@ -158,7 +170,7 @@ metrics:
 logging:
   level: "info"
   file: "%s"
-`, secretKey, logPath)
+`, secretKey, signingKey, logPath)
 
 	configPath := fmt.Sprintf("%s/config.yaml", configDir)
 	if err := os.WriteFile(configPath, []byte(configContent), 0600); err != nil {
@@ -179,7 +191,7 @@ logging:
 
 	// Try to register key with bot
 	fmt.Println("🔄 Registering key with ServerEye bot...")
-	if err := registerKeyWithBot(secretKey); err != nil {
+	if err := registerKeyWithBot(secretKey, signingKey); err != nil {
 		fmt.Printf("⚠️  Key registration failed: %v\n", err)
 	}
 
@@ -209,14 +221,17 @@ func generateSecretKey() (string, error) {
 }
 
 // registerKeyWithBot registers the generated key with the bot via HTTP API
-func registerKeyWithBot(secretKey string) error {
+func registerKeyWithBot(secretKey, signingKey string) error {
 	hostname, _ := os.Hostname()
+	caps := agent.Capabilities()
 
 	req := KeyRegistrationRequest{
 		SecretKey:    secretKey,
+		SigningKey:   signingKey,
 		AgentVersion: version.GetVersion(),
 		OSInfo:       runtime.GOOS + " " + runtime.GOARCH,
 		Hostname:     hostname,
+		Capabilities: &caps,
 	}
 
 	jsonData, err := json.Marshal(req)
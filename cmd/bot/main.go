@@ -96,6 +96,7 @@ func loadConfigFromEnv() (*config.BotConfig, error) {
 	telegramToken := os.Getenv("TELEGRAM_TOKEN")
 	redisAddress := os.Getenv("REDIS_ADDRESS")
 	redisPassword := os.Getenv("REDIS_PASSWORD")
+	redisMode := os.Getenv("REDIS_MODE")
 	databaseURL := os.Getenv("DATABASE_URL")
 
 	// Support legacy REDIS_URL format
@@ -109,15 +110,21 @@ func loadConfigFromEnv() (*config.BotConfig, error) {
 		}
 	}
 
-	if telegramToken == "" || redisAddress == "" || databaseURL == "" {
+	if telegramToken == "" || (redisAddress == "" && redisMode != config.RedisModeMemory) || databaseURL == "" {
 		return nil, fmt.Errorf("missing required environment variables")
 	}
 
+	transportServers, err := config.ParseTransportServers(os.Getenv("TRANSPORT_SERVERS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRANSPORT_SERVERS: %w", err)
+	}
+
 	return &config.BotConfig{
 		Telegram: config.TelegramConfig{
 			Token: telegramToken,
 		},
 		Redis: config.RedisConfig{
+			Mode:     redisMode,
 			Address:  redisAddress,
 			Password: redisPassword,
 			DB:       0,
@@ -125,6 +132,23 @@ func loadConfigFromEnv() (*config.BotConfig, error) {
 		Database: config.DatabaseConfig{
 			URL: databaseURL,
 		},
+		Heartbeat: config.HeartbeatConfig{
+			OfflineAfter:  os.Getenv("HEARTBEAT_OFFLINE_AFTER"),
+			CheckInterval: os.Getenv("HEARTBEAT_CHECK_INTERVAL"),
+		},
+		Queue: config.QueueConfig{
+			Enabled: os.Getenv("COMMAND_QUEUE_ENABLED") == "true",
+			TTL:     os.Getenv("COMMAND_QUEUE_TTL"),
+		},
+		Signing: config.SigningConfig{
+			Enabled: os.Getenv("COMMAND_SIGNING_ENABLED") == "true",
+		},
+		Encryption: config.EncryptionConfig{
+			Enabled: os.Getenv("PAYLOAD_ENCRYPTION_ENABLED") == "true",
+		},
+		Transport: config.TransportConfig{
+			Servers: transportServers,
+		},
 		Logging: config.LoggingConfig{
 			Level: "info",
 		},
@@ -2,46 +2,46 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/servereye/servereye/internal/config"
+	"github.com/servereye/servereye/pkg/auth"
 	"github.com/servereye/servereye/pkg/docker"
+	"github.com/servereye/servereye/pkg/e2e"
 	"github.com/servereye/servereye/pkg/kafka"
 	"github.com/servereye/servereye/pkg/metrics"
 	"github.com/servereye/servereye/pkg/protocol"
 	"github.com/servereye/servereye/pkg/publisher"
 	"github.com/servereye/servereye/pkg/redis"
 	"github.com/servereye/servereye/pkg/redis/streams"
+	"github.com/servereye/servereye/pkg/transport"
 	"github.com/sirupsen/logrus"
 )
 
-// RedisClientInterface общий интерфейс для Redis клиентов
-type RedisClientInterface interface {
-	Subscribe(ctx context.Context, channel string) (SubscriptionInterface, error)
-	Publish(ctx context.Context, channel string, message []byte) error
-	Close() error
-}
-
-// SubscriptionInterface общий интерфейс для подписок
-type SubscriptionInterface interface {
-	Channel() <-chan []byte
-	Close() error
-}
-
 // Agent представляет агент ServerEye
 type Agent struct {
-	config          *config.AgentConfig
-	logger          *logrus.Logger
-	redisClient     RedisClientInterface
-	streamsClient   streams.StreamClient // NEW: for Streams support
-	metricPublisher publisher.Publisher  // NEW: unified publisher (может быть multi-publisher)
-	cpuMetrics      *metrics.CPUMetrics
-	systemMonitor   *metrics.SystemMonitor
-	dockerClient    *docker.Client
-	ctx             context.Context
-	cancel          context.CancelFunc
-	useStreams      bool // Flag to use Streams instead of Pub/Sub
+	config           *config.AgentConfig
+	logger           *logrus.Logger
+	redisClient      transport.PubSubClient
+	streamsClient    streams.StreamClient // NEW: for Streams support
+	metricPublisher  publisher.Publisher  // NEW: unified publisher (может быть multi-publisher)
+	tunnel           *transport.TunnelClient
+	cpuMetrics       *metrics.CPUMetrics
+	cpuUsage         *metrics.CPUUsageCollector
+	systemMonitor    *metrics.SystemMonitor
+	dockerClient     *docker.Client
+	collectors       *metrics.Registry // сборщики метрик для Kafka, создаются при старте сбора
+	ctx              context.Context
+	cancel           context.CancelFunc
+	commandTransport string                // transport.Tunnel, transport.Streams или transport.PubSub, выбирается при старте
+	commandState     *commandState         // stream cursor and processed command IDs
+	verifier         *auth.MessageVerifier // nil, если подпись команд не требуется
+	encryptionKeys   *encryptionKeys       // nil, если шифрование payload выключено
+	running          runningCommands       // выполняющиеся команды для cancel_command
 
 	// updateFunc allows mocking performUpdate in tests
 	updateFunc func(string) error
@@ -96,22 +96,23 @@ func initializeMetricPublisher(cfg *config.AgentConfig, logger *logrus.Logger) (
 		return nil, nil
 	}
 
-	// Если один publisher, возвращаем его напрямую
-	if len(publishers) == 1 {
-		return publishers[0], nil
+	// Если один publisher, используем его напрямую
+	pub := publishers[0]
+	if len(publishers) > 1 {
+		// Если несколько publishers, создаем multi-publisher
+		// Используем FailIfPrimary - ошибка только если Kafka (первый) упадет
+		pub = publisher.NewMultiPublisher(publishers, publisher.FailIfPrimary, logger)
+		logger.WithField("count", len(publishers)).Info("Multi-publisher инициализирован")
 	}
 
-	// Если несколько publishers, создаем multi-publisher
-	// Используем FailIfPrimary - ошибка только если Kafka (первый) упадет
-	multiPub := publisher.NewMultiPublisher(publishers, publisher.FailIfPrimary, logger)
-	logger.WithField("count", len(publishers)).Info("Multi-publisher инициализирован")
-
-	return multiPub, nil
+	// Метрики уходят пакетами в фоне, сбор не ждет брокер
+	return publisher.NewQueuedPublisher(pub, cfg.Kafka.QueueSize, logger), nil
 }
 
 // New создает новый агент
 func New(cfg *config.AgentConfig, logger *logrus.Logger) (*Agent, error) {
-	var redisClient RedisClientInterface
+	var redisClient transport.PubSubClient
+	var tunnel *transport.TunnelClient
 
 	// Выбираем тип клиента на основе конфигурации
 	if cfg.API.BaseURL != "" {
@@ -123,14 +124,23 @@ func New(cfg *config.AgentConfig, logger *logrus.Logger) (*Agent, error) {
 			}
 		}
 
+		if cfg.API.Tunnel {
+			tunnel = transport.NewTunnelClient(transport.HTTPConfig{
+				BaseURL:   cfg.API.BaseURL,
+				SecretKey: cfg.Server.SecretKey,
+				Timeout:   timeout,
+			}, logger)
+		}
+
 		httpClient, err := redis.NewHTTPClient(redis.HTTPConfig{
-			BaseURL: cfg.API.BaseURL,
-			Timeout: timeout,
+			BaseURL:   cfg.API.BaseURL,
+			SecretKey: cfg.Server.SecretKey,
+			Timeout:   timeout,
 		}, logger)
 		if err != nil {
 			return nil, fmt.Errorf("не удалось создать HTTP клиент: %v", err)
 		}
-		redisClient = &HTTPClientAdapter{client: httpClient}
+		redisClient = transport.WrapPubSub(httpClient)
 		logger.Info("Используется HTTP клиент для связи с сервером")
 	} else {
 		// Используем прямой Redis клиент
@@ -142,19 +152,16 @@ func New(cfg *config.AgentConfig, logger *logrus.Logger) (*Agent, error) {
 		if err != nil {
 			return nil, fmt.Errorf("не удалось создать Redis клиент: %v", err)
 		}
-		redisClient = &DirectClientAdapter{client: directClient}
+		redisClient = transport.WrapPubSub(directClient)
 		logger.Info("Используется прямой Redis клиент")
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Initialize Streams client if using HTTP API
+	// Initialize Streams client if using HTTP API, transport is negotiated in Start
 	var streamsClient streams.StreamClient
-	var useStreams bool
 	if cfg.API.BaseURL != "" {
-		streamsClient = streams.NewHTTPStreamClient(cfg.API.BaseURL, logger)
-		useStreams = true
-		logger.Info("Streams support enabled via HTTP API")
+		streamsClient = streams.NewHTTPStreamClient(cfg.API.BaseURL, cfg.Server.SecretKey, logger)
 	}
 
 	// Initialize metric publisher(s)
@@ -164,15 +171,59 @@ func New(cfg *config.AgentConfig, logger *logrus.Logger) (*Agent, error) {
 		return nil, fmt.Errorf("не удалось инициализировать metric publisher: %v", err)
 	}
 
+	statePath := filepath.Join(cfg.State.GetDir(), commandStateFile)
+	cmdState, err := loadCommandState(statePath, defaultSeenCommandsLimit)
+	if err != nil {
+		// Продолжаем с пустым состоянием, файл будет перезаписан
+		logger.WithError(err).Warn("Не удалось загрузить состояние команд")
+	}
+
+	var verifier *auth.MessageVerifier
+	if cfg.Signing.Enabled {
+		verifier = auth.NewMessageVerifier(cfg.Server.SigningKey, cfg.Signing.GetMaxAge())
+		logger.Info("Принимаются только подписанные команды")
+	}
+
+	var keys *encryptionKeys
+	if cfg.Encryption.Enabled {
+		rotateAfter := cfg.Encryption.GetRotateAfter()
+		if cfg.Server.SigningKey == "" {
+			// Без ключа подписи бот закрепляет первый ключ шифрования и не примет новый
+			logger.Warn("Не задан server.signing_key: ротация ключа шифрования отключена")
+			rotateAfter = 0
+		}
+		keysPath := filepath.Join(cfg.State.GetDir(), encryptionKeysFile)
+		keys, err = loadEncryptionKeys(keysPath, rotateAfter)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("не удалось загрузить ключи шифрования: %v", err)
+		}
+		logger.WithField("key_id", keys.PublicKey().ID).Info("Шифрование payload включено")
+	}
+
+	host := cfg.Metrics.Host
+	hostPaths := metrics.NewHostPaths(host.Proc, host.Sys, host.Root)
+	if hostPaths != metrics.DefaultHostPaths() {
+		logger.WithFields(logrus.Fields{
+			"proc": hostPaths.Proc,
+			"sys":  hostPaths.Sys,
+			"root": hostPaths.Root,
+		}).Info("Метрики собираются с файловых систем хоста")
+	}
+
 	return &Agent{
 		config:          cfg,
 		logger:          logger,
 		redisClient:     redisClient,
 		streamsClient:   streamsClient,
+		tunnel:          tunnel,
 		metricPublisher: metricPublisher,
-		useStreams:      useStreams,
-		cpuMetrics:      metrics.NewCPUMetrics(),
-		systemMonitor:   metrics.NewSystemMonitor(logger),
+		commandState:    cmdState,
+		verifier:        verifier,
+		encryptionKeys:  keys,
+		cpuMetrics:      metrics.NewCPUMetricsWithPaths(hostPaths),
+		cpuUsage:        metrics.NewCPUUsageCollectorWithPaths(hostPaths),
+		systemMonitor:   metrics.NewSystemMonitorWithPaths(logger, hostPaths),
 		dockerClient:    docker.NewClient(logger),
 		ctx:             ctx,
 		cancel:          cancel,
@@ -186,11 +237,15 @@ func (a *Agent) Start() error {
 		"secret_key":  a.config.Server.SecretKey,
 	}).Info("Запуск агента ServerEye")
 
-	// Use Streams if available
-	if a.useStreams && a.streamsClient != nil {
+	a.commandTransport = a.negotiateTransport()
+	switch a.commandTransport {
+	case transport.Tunnel:
+		a.logger.Info("Starting with tunnel mode")
+		go a.handleCommandsViaTunnel()
+	case transport.Streams:
 		a.logger.Info("Starting with Streams mode")
 		go a.handleCommandsViaStreams()
-	} else {
+	default:
 		// Fallback to Pub/Sub
 		a.logger.Info("Starting with Pub/Sub mode")
 		cmdChannel := redis.GetCommandChannel(a.config.Server.SecretKey)
@@ -213,11 +268,51 @@ func (a *Agent) Start() error {
 	return nil
 }
 
+// negotiateTransport выбирает транспорт команд: туннель (если включен), затем Streams,
+// если бот их поддерживает, иначе Pub/Sub
+func (a *Agent) negotiateTransport() string {
+	ctx, cancel := context.WithTimeout(a.ctx, 10*time.Second)
+	defer cancel()
+
+	if a.tunnel != nil {
+		err := a.tunnel.Probe(ctx)
+		if err == nil {
+			return transport.Tunnel
+		}
+		if !errors.Is(err, transport.ErrTunnelUnsupported) {
+			// Бот временно недоступен: туннель переподключается сам
+			a.logger.WithError(err).Warn("Не удалось проверить туннель")
+			return transport.Tunnel
+		}
+		a.logger.Warn("Бот не поддерживает туннель, пробуем Streams API")
+		a.tunnel = nil
+	}
+
+	if a.streamsClient == nil {
+		return transport.PubSub
+	}
+
+	err := a.streamsClient.Ping(ctx)
+	if errors.Is(err, streams.ErrUnsupported) {
+		a.logger.Warn("Бот не поддерживает Streams API, используем Pub/Sub")
+		return transport.PubSub
+	}
+	if err != nil {
+		// Бот временно недоступен: остаемся на Streams, чтение повторяется до успеха
+		a.logger.WithError(err).Warn("Не удалось проверить Streams API")
+	}
+	return transport.Streams
+}
+
 // Stop останавливает агент
 func (a *Agent) Stop() error {
 	a.logger.Info("Остановка агента")
 	a.cancel()
 
+	if a.tunnel != nil {
+		a.tunnel.Close()
+	}
+
 	// Закрываем metric publisher если есть
 	if a.metricPublisher != nil {
 		if err := a.metricPublisher.Close(); err != nil {
@@ -225,18 +320,27 @@ func (a *Agent) Stop() error {
 		}
 	}
 
+	if a.commandState != nil {
+		if err := a.commandState.Flush(); err != nil {
+			a.logger.WithError(err).Error("Не удалось сохранить состояние команд")
+		}
+	}
+
 	return a.redisClient.Close()
 }
 
-// handleCommands обрабатывает входящие команды
+// handleCommands обрабатывает входящие команды по очереди, cancel_command - сразу
 func (a *Agent) handleCommands(msgChan <-chan []byte) {
+	queue := streams.NewCommandQueue(a.ctx)
+	defer queue.Close()
+
 	for {
 		select {
 		case msg := <-msgChan:
 			if msg == nil {
 				return
 			}
-			a.processCommand(msg)
+			queue.Submit(commandType(msg), func() { a.processCommand(msg) })
 		case <-a.ctx.Done():
 			return
 		}
@@ -252,12 +356,117 @@ func (a *Agent) processCommand(data []byte) {
 		return
 	}
 
+	response := a.handleCommand(a.ctx, msg)
+	if response == nil {
+		return
+	}
+
+	// Отправляем в уникальный канал с ID команды (Redis Streams)
+	if err := a.sendResponseToCommand(response, msg); err != nil {
+		a.logger.WithError(err).Error("Не удалось отправить ответ")
+	} else {
+		a.logger.WithField("command_id", msg.ID).Info("Ответ успешно отправлен")
+	}
+}
+
+// supportedCommands - команды, которые обрабатывает handleCommand.
+// Список объявляется боту в heartbeat, при добавлении команды дополните оба места.
+var supportedCommands = []protocol.MessageType{
+	protocol.TypeGetCPUTemp,
+	protocol.TypeGetCPUUsage,
+	protocol.TypeGetContainers,
+	protocol.TypeStartContainer,
+	protocol.TypeStopContainer,
+	protocol.TypeRestartContainer,
+	protocol.TypeRemoveContainer,
+	protocol.TypeCreateContainer,
+	protocol.TypeGetMemoryInfo,
+	protocol.TypeGetDiskInfo,
+	protocol.TypeGetUptime,
+	protocol.TypeGetSystemInfo,
+	protocol.TypeGetProcesses,
+	protocol.TypeGetNetworkInfo,
+	protocol.TypeUpdateAgent,
+	protocol.TypePing,
+	protocol.TypeCancelCommand,
+}
+
+// Capabilities возвращает версию протокола и команды, поддерживаемые агентом
+func Capabilities() protocol.Capabilities {
+	return protocol.NewCapabilities(append([]protocol.MessageType(nil), supportedCommands...)...)
+}
+
+// capabilities дополняет Capabilities публичным ключом шифрования агента
+func (a *Agent) capabilities() protocol.Capabilities {
+	caps := Capabilities()
+	if a.encryptionKeys != nil {
+		key := a.encryptionKeys.PublicKey()
+		// Подписанный ключ бот принимает только от нас, а не от любого, кто знает secret_key
+		if a.config.Server.SigningKey != "" {
+			auth.SignEncryptionKey(a.config.Server.SigningKey, &key)
+		}
+		caps.EncryptionKey = &key
+	}
+	return caps
+}
+
+// handleCommand выполняет команду и возвращает ответ.
+// Повторно доставленная команда не выполняется: возвращается сохранённый ответ,
+// nil - если отвечать не нужно.
+func (a *Agent) handleCommand(parent context.Context, msg *protocol.Message) (response *protocol.Message) {
 	a.logger.WithFields(logrus.Fields{
 		"command_id":   msg.ID,
 		"command_type": msg.Type,
 	}).Info("Получена команда")
 
-	var response *protocol.Message
+	// Подпись проверяем до отметки команды: поддельная не должна занять ID настоящей
+	if a.verifier != nil {
+		if err := a.verifier.Verify(msg); err != nil {
+			return a.rejectedCommandResponse(msg, err)
+		}
+	}
+
+	// Расшифровываем payload, ответ шифруется тем же сеансом
+	var session *e2e.Session
+	if msg.Encryption != "" {
+		var err error
+		if session, err = a.decryptCommand(msg); err != nil {
+			return a.decryptionFailedResponse(msg, err)
+		}
+	}
+
+	// Отмечаем команду до выполнения: после рестарта она не будет выполнена повторно
+	if a.commandState != nil {
+		firstSeen, err := a.commandState.MarkSeen(msg.ID)
+		if err != nil {
+			a.logger.WithError(err).Error("Не удалось сохранить состояние команд")
+		}
+		if !firstSeen {
+			return a.repeatedCommandResponse(msg)
+		}
+
+		// Ответ запоминаем до отправки: если она не удастся, команду доставят повторно
+		defer func() {
+			if response != nil {
+				a.commandState.SaveResponse(msg.ID, response)
+			}
+		}()
+	}
+
+	// Команда могла пролежать в очереди, пока агент был офлайн: бот уже не ждёт ответа
+	if msg.IsExpired(time.Now()) {
+		return a.expiredCommandResponse(msg)
+	}
+
+	// Команду несовместимой версии протокола не разбираем
+	if !protocol.IsCompatible(msg.Version) {
+		return a.incompatibleVersionResponse(msg)
+	}
+
+	ctx, cancel := a.commandContext(parent, msg)
+	defer cancel()
+	a.running.add(msg.ID, cancel)
+	defer a.running.remove(msg.ID)
 
 	// Обрабатываем команду с обработкой паники
 	defer func() {
@@ -273,9 +482,6 @@ func (a *Agent) processCommand(data []byte) {
 				ErrorMessage: fmt.Sprintf("Внутренняя ошибка при обработке команды: %v", r),
 			})
 			response.ID = msg.ID
-			if err := a.sendResponse(response); err != nil {
-				a.logger.WithError(err).Error("Failed to send response")
-			}
 		}
 	}()
 
@@ -283,24 +489,28 @@ func (a *Agent) processCommand(data []byte) {
 	switch msg.Type {
 	case protocol.TypeGetCPUTemp:
 		response = a.handleGetCPUTemp(msg)
+	case protocol.TypeGetCPUUsage:
+		response = a.handleGetCPUUsage(msg)
 	case protocol.TypeGetContainers:
-		response = a.handleGetContainers(msg)
+		response = a.handleGetContainers(ctx, msg)
 	case protocol.TypeStartContainer:
-		response = a.handleStartContainer(msg)
+		response = a.handleStartContainer(ctx, msg)
 	case protocol.TypeStopContainer:
-		response = a.handleStopContainer(msg)
+		response = a.handleStopContainer(ctx, msg)
 	case protocol.TypeRestartContainer:
-		response = a.handleRestartContainer(msg)
+		response = a.handleRestartContainer(ctx, msg)
 	case protocol.TypeRemoveContainer:
-		response = a.handleRemoveContainer(msg)
+		response = a.handleRemoveContainer(ctx, msg)
 	case protocol.TypeCreateContainer:
-		response = a.handleCreateContainer(msg)
+		response = a.handleCreateContainer(ctx, msg)
 	case protocol.TypeGetMemoryInfo:
 		response = a.handleGetMemoryInfo(msg)
 	case protocol.TypeGetDiskInfo:
 		response = a.handleGetDiskInfo(msg)
 	case protocol.TypeGetUptime:
 		response = a.handleGetUptime(msg)
+	case protocol.TypeGetSystemInfo:
+		response = a.handleGetSystemInfo(msg)
 	case protocol.TypeGetProcesses:
 		response = a.handleGetProcesses(msg)
 	case protocol.TypeGetNetworkInfo:
@@ -309,29 +519,147 @@ func (a *Agent) processCommand(data []byte) {
 		response = a.handleUpdateAgent(msg)
 	case protocol.TypePing:
 		response = a.handlePing(msg)
+	case protocol.TypeCancelCommand:
+		response = a.handleCancelCommand(msg)
 	default:
 		response = a.handleUnknownCommand(msg)
 	}
 
-	// Отправляем ответ
-	if response != nil {
-		a.logger.WithFields(logrus.Fields{
-			"command_id":    msg.ID,
-			"response_type": response.Type,
-		}).Info("Отправляем ответ")
+	if response == nil {
+		a.logger.WithField("command_id", msg.ID).Error("Ответ не сгенерирован")
+		return nil
+	}
+
+	a.logger.WithFields(logrus.Fields{
+		"command_id":    msg.ID,
+		"response_type": response.Type,
+	}).Info("Отправляем ответ")
+
+	// Дополнительно отправляем метрику в Kafka (если настроен)
+	a.publishMetricToKafka(response)
+
+	if session != nil {
+		if err := session.EncryptResponse(response); err != nil {
+			a.logger.WithError(err).Error("Не удалось зашифровать ответ")
+			return a.decryptionFailedResponse(msg, err)
+		}
+	}
+
+	return response
+}
 
-		// Отправляем в уникальный канал с ID команды (Redis Streams)
-		if err := a.sendResponseToCommand(response, msg.ID); err != nil {
-			a.logger.WithError(err).Error("Не удалось отправить ответ")
-		} else {
-			a.logger.WithField("command_id", msg.ID).Info("Ответ успешно отправлен")
+// commandContext возвращает контекст обработчика с учётом дедлайна команды
+func (a *Agent) commandContext(parent context.Context, msg *protocol.Message) (context.Context, context.CancelFunc) {
+	if deadline, ok := msg.Deadline(); ok {
+		return context.WithDeadline(parent, deadline)
+	}
+	return context.WithCancel(parent)
+}
+
+// rejectedCommandResponse формирует ошибку SIGNATURE_INVALID для команды, не прошедшей проверку подписи.
+// На повтор уже принятой команды отвечаем так же, как при дедупликации.
+func (a *Agent) rejectedCommandResponse(msg *protocol.Message, err error) *protocol.Message {
+	log := a.logger.WithFields(logrus.Fields{
+		"command_id":   msg.ID,
+		"command_type": msg.Type,
+	}).WithError(err)
+
+	if errors.Is(err, auth.ErrReplay) {
+		return a.repeatedCommandResponse(msg)
+	}
+	log.Warn("Команда отклонена: неверная подпись")
+
+	response := protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
+		ErrorCode:    protocol.ErrorSignatureInvalid,
+		ErrorMessage: fmt.Sprintf("Команда отклонена: %v", err),
+	})
+	response.ID = msg.ID
+	return response
+}
+
+// repeatedCommandResponse отвечает на повторно доставленную команду, не выполняя её.
+// Бот получает сохранённый ответ, если первая отправка не удалась.
+func (a *Agent) repeatedCommandResponse(msg *protocol.Message) *protocol.Message {
+	log := a.logger.WithFields(logrus.Fields{
+		"command_id":   msg.ID,
+		"command_type": msg.Type,
+	})
+
+	if a.commandState != nil {
+		if response, ok := a.commandState.Response(msg.ID); ok {
+			log.Warn("Команда уже обработана, повторно отправляем ответ")
+			return response
 		}
 
-		// Дополнительно отправляем метрику в Kafka (если настроен)
-		a.publishMetricToKafka(response)
-	} else {
-		a.logger.WithField("command_id", msg.ID).Error("Ответ не сгенерирован")
+		// Отметка есть, а ответа нет: агент перезапустился, пока команда выполнялась
+		if a.commandState.Seen(msg.ID) && !a.running.has(msg.ID) {
+			log.Warn("Команда была прервана перезапуском агента")
+			response := protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
+				ErrorCode:    protocol.ErrorCommandInterrupted,
+				ErrorMessage: "Команда была прервана перезапуском агента, результат неизвестен",
+			})
+			response.ID = msg.ID
+			return response
+		}
+	}
+
+	log.Warn("Повтор команды, пропускаем")
+	return nil
+}
+
+// decryptCommand расшифровывает payload команды ключом агента
+func (a *Agent) decryptCommand(msg *protocol.Message) (*e2e.Session, error) {
+	if a.encryptionKeys == nil {
+		return nil, errors.New("шифрование не включено в конфигурации агента")
 	}
+	return e2e.DecryptCommand(msg, a.encryptionKeys.KeyPair)
+}
+
+// decryptionFailedResponse формирует ошибку DECRYPTION_FAILED
+func (a *Agent) decryptionFailedResponse(msg *protocol.Message, err error) *protocol.Message {
+	a.logger.WithFields(logrus.Fields{
+		"command_id":   msg.ID,
+		"command_type": msg.Type,
+	}).WithError(err).Warn("Не удалось расшифровать команду")
+
+	response := protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
+		ErrorCode:    protocol.ErrorDecryptionFailed,
+		ErrorMessage: fmt.Sprintf("Не удалось расшифровать команду: %v", err),
+	})
+	response.ID = msg.ID
+	return response
+}
+
+// incompatibleVersionResponse отвечает на команду несовместимой версии протокола
+func (a *Agent) incompatibleVersionResponse(msg *protocol.Message) *protocol.Message {
+	a.logger.WithFields(logrus.Fields{
+		"command_id": msg.ID,
+		"version":    msg.Version,
+	}).Warn("Несовместимая версия протокола")
+
+	response := protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
+		ErrorCode:    protocol.ErrorUnsupportedCommand,
+		ErrorMessage: fmt.Sprintf("Версия протокола %s не поддерживается (агент: %s)", msg.Version, protocol.ProtocolVersion),
+	})
+	response.ID = msg.ID
+	return response
+}
+
+// expiredCommandResponse формирует ошибку COMMAND_EXPIRED вместо выполнения команды
+func (a *Agent) expiredCommandResponse(msg *protocol.Message) *protocol.Message {
+	deadline, _ := msg.Deadline()
+	a.logger.WithFields(logrus.Fields{
+		"command_id":   msg.ID,
+		"command_type": msg.Type,
+		"expires_at":   deadline,
+	}).Warn("Срок действия команды истёк, пропускаем")
+
+	response := protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
+		ErrorCode:    protocol.ErrorCommandExpired,
+		ErrorMessage: fmt.Sprintf("Срок действия команды истёк: %s", deadline.Format(time.RFC3339)),
+	})
+	response.ID = msg.ID
+	return response
 }
 
 // Command handlers are in separate files:
@@ -341,68 +669,102 @@ func (a *Agent) processCommand(data []byte) {
 // - heartbeat.go: Heartbeat functionality
 // - helpers.go: Utility functions (ping, sendResponse, etc.)
 
-// HTTPClientAdapter адаптер для HTTP клиента
-type HTTPClientAdapter struct {
-	client *redis.HTTPClient
-}
+// handleCommandsViaStreams reads commands from Streams via consumer group
+func (a *Agent) handleCommandsViaStreams() {
+	a.logger.Info("Streams command handler started")
 
-func (h *HTTPClientAdapter) Subscribe(ctx context.Context, channel string) (SubscriptionInterface, error) {
-	sub, err := h.client.Subscribe(ctx, channel)
-	if err != nil {
-		return nil, err
+	// Новая группа стартует с сохраненного курсора; без него только новые сообщения,
+	// чтобы не выполнять повторно исторические команды
+	startID := "$"
+	if a.commandState != nil {
+		if saved := a.commandState.LastStreamID(); saved != "" {
+			startID = saved
+		}
 	}
-	return &HTTPSubscriptionAdapter{sub: sub}, nil
-}
 
-func (h *HTTPClientAdapter) Publish(ctx context.Context, channel string, message []byte) error {
-	return h.client.Publish(ctx, channel, message)
-}
+	adapter := streams.NewAgentAdapter(
+		a.streamsClient,
+		a.config.Server.SecretKey,
+		a.config.Commands.GetConsumerGroup(),
+		a.consumerName(),
+		a.logger,
+	)
+	adapter.SetStartID(startID)
+	adapter.SetRetryPolicy(a.config.Commands.MaxRetries, a.config.Commands.GetVisibilityTimeout())
+
+	if err := adapter.Initialize(a.ctx); err != nil {
+		// Старый бот без поддержки consumer groups
+		a.logger.WithError(err).Warn("Consumer group недоступна, читаем stream по курсору")
+		a.readCommandsFromCursor(startID)
+		return
+	}
 
-func (h *HTTPClientAdapter) Close() error {
-	return h.client.Close()
+	if err := adapter.ProcessCommands(a.ctx, a.handleCommand); err != nil && a.ctx.Err() == nil {
+		a.logger.WithError(err).Error("Обработка команд из stream остановлена")
+	}
 }
 
-// HTTPSubscriptionAdapter адаптер для HTTP подписки
-type HTTPSubscriptionAdapter struct {
-	sub *redis.HTTPSubscription
-}
+// handleCommandsViaTunnel получает команды, которые бот пушит в туннель.
+// Бот читает их через consumer group: команды без ответа после переподключения приходят повторно.
+func (a *Agent) handleCommandsViaTunnel() {
+	a.logger.Info("Tunnel command handler started")
 
-func (h *HTTPSubscriptionAdapter) Channel() <-chan []byte {
-	return h.sub.Channel()
-}
+	resumeID := func() string {
+		if a.commandState != nil {
+			if saved := a.commandState.LastStreamID(); saved != "" {
+				return saved
+			}
+		}
+		return "$"
+	}
 
-func (h *HTTPSubscriptionAdapter) Close() error {
-	return h.sub.Close()
-}
+	queue := streams.NewCommandQueue(a.ctx)
+	defer queue.Close()
+	handle := func(msg streams.StreamMessage) {
+		queue.Submit(protocol.MessageType(msg.Values["type"]), func() { a.handleTunnelCommand(msg) })
+	}
 
-// DirectClientAdapter адаптер для прямого Redis клиента
-type DirectClientAdapter struct {
-	client *redis.Client
+	if err := a.tunnel.Run(a.ctx, resumeID, handle); err != nil && a.ctx.Err() == nil {
+		a.logger.WithError(err).Error("Туннель остановлен")
+	}
 }
 
-func (d *DirectClientAdapter) Subscribe(ctx context.Context, channel string) (SubscriptionInterface, error) {
-	sub, err := d.client.Subscribe(ctx, channel)
+// handleTunnelCommand выполняет команду из туннеля и сдвигает курсор
+func (a *Agent) handleTunnelCommand(msg streams.StreamMessage) {
+	command, err := protocol.FromJSON([]byte(msg.Values["payload"]))
 	if err != nil {
-		return nil, err
+		a.logger.WithError(err).Error("Failed to parse command")
+	} else if response := a.handleCommand(a.ctx, command); response != nil {
+		if err := a.sendResponseToCommand(response, command); err != nil {
+			a.logger.WithError(err).Error("Не удалось отправить ответ через туннель")
+		}
 	}
-	return &DirectSubscriptionAdapter{sub: sub}, nil
-}
 
-func (d *DirectClientAdapter) Publish(ctx context.Context, channel string, message []byte) error {
-	return d.client.Publish(ctx, channel, message)
+	if a.commandState != nil {
+		if err := a.commandState.Advance(msg.ID); err != nil {
+			a.logger.WithError(err).Error("Не удалось сохранить курсор stream")
+		}
+	}
 }
 
-func (d *DirectClientAdapter) Close() error {
-	return d.client.Close()
+// consumerName возвращает имя consumer в группе: имя сервера или hostname
+func (a *Agent) consumerName() string {
+	if a.config.Server.Name != "" {
+		return a.config.Server.Name
+	}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		return hostname
+	}
+	return "agent"
 }
 
-// handleCommandsViaStreams reads commands from Streams
-func (a *Agent) handleCommandsViaStreams() {
-	a.logger.Info("Streams command handler started")
+// readCommandsFromCursor reads commands with plain XREAD, persisting the cursor
+func (a *Agent) readCommandsFromCursor(lastID string) {
 	cmdStream := fmt.Sprintf("stream:cmd:%s", a.config.Server.SecretKey)
+	a.logger.WithField("last_id", lastID).Info("Чтение stream команд с курсора")
 
-	lastID := "0" // Start from beginning, then use "$" for new messages
-	firstRead := true
+	queue := streams.NewCommandQueue(a.ctx)
+	defer queue.Close()
 
 	for {
 		select {
@@ -410,13 +772,7 @@ func (a *Agent) handleCommandsViaStreams() {
 			a.logger.Info("Streams handler stopped")
 			return
 		default:
-			// Read from stream
-			id := lastID
-			if !firstRead {
-				id = "$" // Only new messages after first read
-			}
-
-			messages, err := a.streamsClient.ReadMessages(a.ctx, cmdStream, id, 10, 5*time.Second)
+			messages, err := a.streamsClient.ReadMessages(a.ctx, cmdStream, lastID, 10, 5*time.Second)
 			if err != nil {
 				if err.Error() != "XREAD failed: context deadline exceeded" {
 					a.logger.WithError(err).Error("Failed to read from stream")
@@ -425,8 +781,6 @@ func (a *Agent) handleCommandsViaStreams() {
 				continue
 			}
 
-			firstRead = false
-
 			// Process messages
 			for _, msg := range messages {
 				lastID = msg.ID
@@ -446,21 +800,17 @@ func (a *Agent) handleCommandsViaStreams() {
 
 				// Process command (processCommand expects []byte)
 				cmdData, _ := command.ToJSON()
-				a.processCommand(cmdData)
+				streamID := msg.ID
+				queue.Submit(command.Type, func() {
+					a.processCommand(cmdData)
+
+					if a.commandState != nil {
+						if err := a.commandState.Advance(streamID); err != nil {
+							a.logger.WithError(err).Error("Не удалось сохранить курсор stream")
+						}
+					}
+				})
 			}
 		}
 	}
 }
-
-// DirectSubscriptionAdapter адаптер для прямой Redis подписки
-type DirectSubscriptionAdapter struct {
-	sub *redis.Subscription
-}
-
-func (d *DirectSubscriptionAdapter) Channel() <-chan []byte {
-	return d.sub.Channel()
-}
-
-func (d *DirectSubscriptionAdapter) Close() error {
-	return d.sub.Close()
-}
@@ -8,6 +8,8 @@ import (
 	"github.com/servereye/servereye/internal/config"
 	"github.com/servereye/servereye/pkg/metrics"
 	"github.com/servereye/servereye/pkg/protocol"
+	"github.com/servereye/servereye/pkg/redis"
+	"github.com/servereye/servereye/pkg/transport"
 	"github.com/sirupsen/logrus"
 )
 
@@ -188,15 +190,9 @@ func TestAgent_LoggerAccess(t *testing.T) {
 }
 
 func TestRedisClientAdapter_Interface(t *testing.T) {
-	// Test that adapters implement the interface
-	var _ RedisClientInterface = (*HTTPClientAdapter)(nil)
-	var _ RedisClientInterface = (*DirectClientAdapter)(nil)
-}
-
-func TestSubscriptionAdapter_Interface(t *testing.T) {
-	// Test subscription adapters
-	var _ SubscriptionInterface = (*HTTPSubscriptionAdapter)(nil)
-	var _ SubscriptionInterface = (*DirectSubscriptionAdapter)(nil)
+	// Test that Redis and HTTP clients can be used as agent Pub/Sub clients
+	var _ transport.PubSubClient = transport.WrapPubSub(&redis.HTTPClient{})
+	var _ transport.PubSubClient = transport.WrapPubSub(&redis.Client{})
 }
 
 func TestAgent_Initialization(t *testing.T) {
@@ -6,6 +6,7 @@ import (
 
 	"github.com/servereye/servereye/internal/config"
 	"github.com/servereye/servereye/pkg/protocol"
+	"github.com/servereye/servereye/pkg/transport"
 	"github.com/sirupsen/logrus"
 )
 
@@ -156,12 +157,12 @@ func TestAgentStop(t *testing.T) {
 
 func TestRedisClientInterface(t *testing.T) {
 	// Test that interface is properly defined
-	var _ RedisClientInterface = (*mockRedisClient)(nil)
+	var _ transport.PubSubClient = (*mockRedisClient)(nil)
 }
 
 func TestSubscriptionInterface(t *testing.T) {
 	// Test that subscription interface exists
-	var sub SubscriptionInterface
+	var sub transport.Subscription
 	if sub != nil {
 		t.Error("Uninitialized interface should be nil")
 	}
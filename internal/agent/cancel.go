@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/servereye/servereye/pkg/protocol"
+	"github.com/sirupsen/logrus"
+)
+
+// runningCommands хранит функции отмены выполняющихся команд для cancel_command
+type runningCommands struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc // по ID команды
+}
+
+// add регистрирует выполняющуюся команду
+func (r *runningCommands) add(commandID string, cancel context.CancelFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancels == nil {
+		r.cancels = make(map[string]context.CancelFunc)
+	}
+	r.cancels[commandID] = cancel
+}
+
+// remove снимает команду с учета после завершения
+func (r *runningCommands) remove(commandID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.cancels, commandID)
+}
+
+// has сообщает, выполняется ли команда
+func (r *runningCommands) has(commandID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.cancels[commandID]
+	return ok
+}
+
+// cancel отменяет контекст команды, false если она не выполняется
+func (r *runningCommands) cancel(commandID string) bool {
+	r.mu.Lock()
+	cancel, ok := r.cancels[commandID]
+	r.mu.Unlock()
+
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// handleCancelCommand отменяет выполняющуюся команду.
+// Обработчик получает отмененный контекст и завершается с ошибкой COMMAND_CANCELLED.
+func (a *Agent) handleCancelCommand(msg *protocol.Message) *protocol.Message {
+	var payload protocol.CancelCommandPayload
+	if err := msg.Decode(&payload); err != nil {
+		response := protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
+			ErrorCode:    protocol.ErrorInvalidCommand,
+			ErrorMessage: fmt.Sprintf("Неверный формат команды: %v", err),
+		})
+		response.ID = msg.ID
+		return response
+	}
+
+	cancelled := a.running.cancel(payload.CommandID)
+	a.logger.WithFields(logrus.Fields{
+		"command_id": payload.CommandID,
+		"cancelled":  cancelled,
+	}).Info("Запрошена отмена команды")
+
+	response := protocol.NewMessage(protocol.TypeCancelCommandResponse, protocol.CancelCommandResponse{
+		CommandID: payload.CommandID,
+		Cancelled: cancelled,
+	})
+	response.ID = msg.ID
+	return response
+}
+
+// commandType возвращает тип команды из JSON, пустой при ошибке разбора
+func commandType(data []byte) protocol.MessageType {
+	var header struct {
+		Type protocol.MessageType `json:"type"`
+	}
+	_ = json.Unmarshal(data, &header)
+	return header.Type
+}
@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+func TestHandleCancelCommand_Running(t *testing.T) {
+	agent := createTestAgent()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agent.running.add("create-001", cancel)
+
+	msg := protocol.NewMessage(protocol.TypeCancelCommand, protocol.CancelCommandPayload{CommandID: "create-001"})
+	response := agent.handleCancelCommand(msg)
+
+	if response.Type != protocol.TypeCancelCommandResponse || response.ID != msg.ID {
+		t.Fatalf("Unexpected response %s with ID %s", response.Type, response.ID)
+	}
+	result, err := protocol.DecodeAs[protocol.CancelCommandResponse](response)
+	if err != nil {
+		t.Fatalf("DecodeAs() error = %v", err)
+	}
+	if !result.Cancelled || result.CommandID != "create-001" {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if ctx.Err() == nil {
+		t.Error("Command context was not cancelled")
+	}
+	if code := dockerErrorCode(ctx, protocol.ErrorContainerAction); code != protocol.ErrorCommandCancelled {
+		t.Errorf("dockerErrorCode() = %s, want %s", code, protocol.ErrorCommandCancelled)
+	}
+}
+
+func TestHandleCancelCommand_NotRunning(t *testing.T) {
+	agent := createTestAgent()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	agent.running.add("create-001", cancel)
+	agent.running.remove("create-001")
+
+	response := agent.handleCancelCommand(protocol.NewMessage(protocol.TypeCancelCommand,
+		protocol.CancelCommandPayload{CommandID: "create-001"}))
+	result, err := protocol.DecodeAs[protocol.CancelCommandResponse](response)
+	if err != nil {
+		t.Fatalf("DecodeAs() error = %v", err)
+	}
+	if result.Cancelled {
+		t.Error("Finished command reported as cancelled")
+	}
+	if ctx.Err() != nil {
+		t.Error("Finished command context must not be cancelled")
+	}
+}
+
+func TestHandleCancelCommand_InvalidPayload(t *testing.T) {
+	agent := createTestAgent()
+
+	response := agent.handleCancelCommand(protocol.NewMessage(protocol.TypeCancelCommand, map[string]string{}))
+	payload, err := protocol.DecodeAs[protocol.ErrorPayload](response)
+	if err != nil {
+		t.Fatalf("DecodeAs() error = %v", err)
+	}
+	if payload.ErrorCode != protocol.ErrorInvalidCommand {
+		t.Errorf("ErrorCode = %s, want %s", payload.ErrorCode, protocol.ErrorInvalidCommand)
+	}
+}
+
+func TestCommandType(t *testing.T) {
+	data, _ := protocol.NewMessage(protocol.TypeCancelCommand, protocol.CancelCommandPayload{CommandID: "x"}).ToJSON()
+	if got := commandType(data); got != protocol.TypeCancelCommand {
+		t.Errorf("commandType() = %s, want %s", got, protocol.TypeCancelCommand)
+	}
+	if got := commandType([]byte("not json")); got != "" {
+		t.Errorf("commandType() = %s for invalid JSON", got)
+	}
+}
@@ -0,0 +1,265 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+// defaultSeenCommandsLimit ограничивает количество запоминаемых ID команд
+const defaultSeenCommandsLimit = 1000
+
+// defaultSavedResponsesBytes ограничивает суммарный размер payload сохраненных ответов
+const defaultSavedResponsesBytes = 32 << 20
+
+// commandStateSaveInterval ограничивает частоту записи файла состояния при потоке команд
+const commandStateSaveInterval = time.Second
+
+// commandStateFile имя файла состояния в state директории
+const commandStateFile = "command_state.json"
+
+// persistedCommandState формат файла состояния
+type persistedCommandState struct {
+	LastStreamID string   `json:"last_stream_id"`
+	SeenCommands []string `json:"seen_commands"`
+}
+
+// commandState хранит курсор stream и множество обработанных команд.
+// Состояние сохраняется на диск, чтобы после рестарта команды не выполнялись повторно.
+// Ответы хранятся только в памяти: они бывают большими, а после рестарта бот их уже не ждёт.
+// При превышении responseLimit байт вытесняются самые старые ответы.
+type commandState struct {
+	mu    sync.Mutex
+	path  string
+	limit int
+
+	lastStreamID string
+	seen         map[string]struct{}
+	order        []string                     // порядок добавления для вытеснения старых ID
+	responses    map[string]*protocol.Message // последний ответ по ID команды
+
+	responseOrder []string // порядок сохранения ответов для вытеснения
+	responseBytes int      // суммарный размер payload ответов
+	responseLimit int
+
+	dirty      bool        // есть несохраненные ID команд
+	savedAt    time.Time   // время последней записи файла
+	flushTimer *time.Timer // отложенная запись
+}
+
+// loadCommandState загружает состояние из файла, отсутствие файла не является ошибкой
+func loadCommandState(path string, limit int) (*commandState, error) {
+	if limit <= 0 {
+		limit = defaultSeenCommandsLimit
+	}
+
+	state := &commandState{
+		path:          path,
+		limit:         limit,
+		seen:          make(map[string]struct{}),
+		responses:     make(map[string]*protocol.Message),
+		responseLimit: defaultSavedResponsesBytes,
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read command state: %w", err)
+	}
+
+	var persisted persistedCommandState
+	if err := json.Unmarshal(data, &persisted); err != nil {
+		return state, fmt.Errorf("failed to parse command state: %w", err)
+	}
+
+	state.lastStreamID = persisted.LastStreamID
+	for _, id := range persisted.SeenCommands {
+		state.remember(id)
+	}
+
+	return state, nil
+}
+
+// LastStreamID возвращает ID последнего обработанного сообщения stream ("" если нет)
+func (s *commandState) LastStreamID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastStreamID
+}
+
+// MarkSeen запоминает ID команды, запись на диск не чаще commandStateSaveInterval.
+// Возвращает false, если команда уже обрабатывалась.
+// Команду без ID нельзя отличить от другой такой же, поэтому она не запоминается.
+func (s *commandState) MarkSeen(commandID string) (bool, error) {
+	if commandID == "" {
+		return true, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.seen[commandID]; ok {
+		return false, nil
+	}
+
+	s.remember(commandID)
+	s.dirty = true
+	return true, s.scheduleSaveLocked()
+}
+
+// Seen сообщает, обрабатывалась ли команда
+func (s *commandState) Seen(commandID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.seen[commandID]
+	return ok
+}
+
+// SaveResponse запоминает ответ на команду для повторной доставки.
+// Ответ больше responseLimit не сохраняется.
+func (s *commandState) SaveResponse(commandID string, response *protocol.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.seen[commandID]; !ok {
+		return
+	}
+
+	s.dropResponseLocked(commandID)
+	size := len(response.Payload)
+	if size > s.responseLimit {
+		return
+	}
+
+	s.responses[commandID] = response
+	s.responseOrder = append(s.responseOrder, commandID)
+	s.responseBytes += size
+
+	for s.responseBytes > s.responseLimit {
+		s.dropResponseLocked(s.responseOrder[0])
+	}
+}
+
+// Response возвращает сохранённый ответ на команду
+func (s *commandState) Response(commandID string) (*protocol.Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	response, ok := s.responses[commandID]
+	return response, ok
+}
+
+// Advance сдвигает курсор stream и сохраняет состояние
+func (s *commandState) Advance(streamID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastStreamID = streamID
+	return s.saveLocked()
+}
+
+// Flush записывает отложенные изменения состояния
+func (s *commandState) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+		s.flushTimer = nil
+	}
+	if !s.dirty {
+		return nil
+	}
+	return s.saveLocked()
+}
+
+// scheduleSaveLocked сохраняет состояние сразу, если интервал с прошлой записи истек,
+// иначе откладывает запись до его окончания
+func (s *commandState) scheduleSaveLocked() error {
+	wait := commandStateSaveInterval - time.Since(s.savedAt)
+	if wait <= 0 {
+		return s.saveLocked()
+	}
+
+	if s.flushTimer == nil {
+		s.flushTimer = time.AfterFunc(wait, func() {
+			// Ошибка повторится и вернется при следующей записи
+			_ = s.Flush()
+		})
+	}
+	return nil
+}
+
+// dropResponseLocked удаляет сохраненный ответ на команду
+func (s *commandState) dropResponseLocked(commandID string) {
+	response, ok := s.responses[commandID]
+	if !ok {
+		return
+	}
+
+	delete(s.responses, commandID)
+	s.responseBytes -= len(response.Payload)
+	for i, id := range s.responseOrder {
+		if id == commandID {
+			s.responseOrder = append(s.responseOrder[:i], s.responseOrder[i+1:]...)
+			break
+		}
+	}
+}
+
+// remember добавляет ID, вытесняя самый старый при превышении лимита
+func (s *commandState) remember(commandID string) {
+	if _, ok := s.seen[commandID]; ok {
+		return
+	}
+
+	s.seen[commandID] = struct{}{}
+	s.order = append(s.order, commandID)
+
+	for len(s.order) > s.limit {
+		delete(s.seen, s.order[0])
+		s.dropResponseLocked(s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// saveLocked атомарно записывает состояние (tmp файл + rename)
+func (s *commandState) saveLocked() error {
+	if s.path == "" {
+		s.dirty = false
+		return nil
+	}
+
+	data, err := json.Marshal(persistedCommandState{
+		LastStreamID: s.lastStreamID,
+		SeenCommands: s.order,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to serialize command state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0750); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write command state: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to save command state: %w", err)
+	}
+
+	s.dirty = false
+	s.savedAt = time.Now()
+	return nil
+}
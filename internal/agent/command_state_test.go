@@ -0,0 +1,242 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+func TestCommandState_PersistsAcrossRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", commandStateFile)
+
+	state, err := loadCommandState(path, 10)
+	if err != nil {
+		t.Fatalf("loadCommandState() error = %v", err)
+	}
+	if state.LastStreamID() != "" {
+		t.Errorf("Expected empty cursor, got %q", state.LastStreamID())
+	}
+
+	if first, err := state.MarkSeen("cmd-1"); err != nil || !first {
+		t.Fatalf("MarkSeen() = %v, %v; want true, nil", first, err)
+	}
+	if err := state.Advance("1700000000000-0"); err != nil {
+		t.Fatalf("Advance() error = %v", err)
+	}
+
+	// Simulate restart
+	restored, err := loadCommandState(path, 10)
+	if err != nil {
+		t.Fatalf("loadCommandState() after restart error = %v", err)
+	}
+
+	if restored.LastStreamID() != "1700000000000-0" {
+		t.Errorf("Cursor not restored, got %q", restored.LastStreamID())
+	}
+	if first, _ := restored.MarkSeen("cmd-1"); first {
+		t.Error("Command seen before restart must be reported as duplicate")
+	}
+}
+
+func TestCommandState_BoundedSeenSet(t *testing.T) {
+	state, err := loadCommandState("", 2)
+	if err != nil {
+		t.Fatalf("loadCommandState() error = %v", err)
+	}
+
+	for _, id := range []string{"a", "b", "c"} {
+		if first, _ := state.MarkSeen(id); !first {
+			t.Fatalf("MarkSeen(%q) reported duplicate", id)
+		}
+	}
+
+	if len(state.seen) != 2 {
+		t.Errorf("Expected seen-set bounded to 2, got %d", len(state.seen))
+	}
+	// Oldest ID was evicted
+	if first, _ := state.MarkSeen("a"); !first {
+		t.Error("Evicted ID must be accepted again")
+	}
+	if first, _ := state.MarkSeen("c"); first {
+		t.Error("Recent ID must still be deduplicated")
+	}
+}
+
+func TestCommandState_EmptyIDNotRemembered(t *testing.T) {
+	state, err := loadCommandState("", 10)
+	if err != nil {
+		t.Fatalf("loadCommandState() error = %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if first, err := state.MarkSeen(""); err != nil || !first {
+			t.Fatalf("MarkSeen(\"\") = %v, %v; want true, nil", first, err)
+		}
+	}
+	if len(state.seen) != 0 {
+		t.Errorf("Empty ID must not be remembered, seen = %v", state.order)
+	}
+}
+
+func TestCommandState_BatchesWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), commandStateFile)
+	state, err := loadCommandState(path, 10)
+	if err != nil {
+		t.Fatalf("loadCommandState() error = %v", err)
+	}
+
+	state.MarkSeen("cmd-1")
+	state.MarkSeen("cmd-2")
+
+	// First command is written at once, the second waits for the interval
+	restored, _ := loadCommandState(path, 10)
+	if !restored.Seen("cmd-1") || restored.Seen("cmd-2") {
+		t.Fatalf("Unexpected persisted IDs %v", restored.order)
+	}
+
+	if err := state.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	restored, _ = loadCommandState(path, 10)
+	if !restored.Seen("cmd-2") {
+		t.Error("Flush() must persist pending IDs")
+	}
+}
+
+func TestCommandState_CorruptedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), commandStateFile)
+	if err := os.WriteFile(path, []byte("{broken"), 0600); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
+	}
+
+	state, err := loadCommandState(path, 10)
+	if err == nil {
+		t.Error("Expected error for corrupted state file")
+	}
+	if state == nil {
+		t.Fatal("Expected usable empty state on error")
+	}
+	if first, _ := state.MarkSeen("cmd-1"); !first {
+		t.Error("Empty state must accept new commands")
+	}
+}
+
+func TestCommandState_Responses(t *testing.T) {
+	state, err := loadCommandState("", 2)
+	if err != nil {
+		t.Fatalf("loadCommandState() error = %v", err)
+	}
+
+	pong := protocol.NewMessage(protocol.TypePong, nil)
+	state.SaveResponse("a", pong)
+	if _, ok := state.Response("a"); ok {
+		t.Error("Response of an unseen command must not be stored")
+	}
+
+	state.MarkSeen("a")
+	state.SaveResponse("a", pong)
+	if got, ok := state.Response("a"); !ok || got != pong {
+		t.Errorf("Response() = %v, %v; want stored pong", got, ok)
+	}
+
+	// Evicted together with the ID
+	state.MarkSeen("b")
+	state.MarkSeen("c")
+	if _, ok := state.Response("a"); ok || state.Seen("a") {
+		t.Error("Evicted command must drop its response")
+	}
+}
+
+func TestCommandState_ResponsesBoundedBySize(t *testing.T) {
+	state, err := loadCommandState("", 10)
+	if err != nil {
+		t.Fatalf("loadCommandState() error = %v", err)
+	}
+	state.responseLimit = 10
+
+	response := func(payload string) *protocol.Message {
+		return &protocol.Message{Type: protocol.TypePong, Payload: []byte(payload)}
+	}
+
+	for _, id := range []string{"a", "b", "c", "big"} {
+		state.MarkSeen(id)
+	}
+	state.SaveResponse("a", response(`"1234"`))
+	state.SaveResponse("b", response(`"12"`))
+	state.SaveResponse("c", response(`"12"`))
+
+	// a is evicted to fit c into 10 bytes
+	if _, ok := state.Response("a"); ok {
+		t.Error("Oldest response must be evicted over the byte limit")
+	}
+	if _, ok := state.Response("c"); !ok {
+		t.Error("Newest response must be kept")
+	}
+	if state.responseBytes != 8 {
+		t.Errorf("responseBytes = %d, want 8", state.responseBytes)
+	}
+
+	state.SaveResponse("big", response(`"123456789"`))
+	if _, ok := state.Response("big"); ok {
+		t.Error("Response over the limit must not be stored")
+	}
+	if _, ok := state.Response("b"); !ok {
+		t.Error("Oversized response must not evict others")
+	}
+}
+
+func TestProcessCommand_DuplicateCommandResendsResponse(t *testing.T) {
+	agent := createTestAgent()
+	state, err := loadCommandState(filepath.Join(t.TempDir(), commandStateFile), 10)
+	if err != nil {
+		t.Fatalf("loadCommandState() error = %v", err)
+	}
+	agent.commandState = state
+
+	msg := protocol.NewMessage(protocol.TypePing, nil)
+	msg.ID = "ping-dup"
+	jsonBytes, _ := msg.ToJSON()
+
+	agent.processCommand(jsonBytes)
+	agent.processCommand(jsonBytes)
+
+	mockClient := agent.redisClient.(*mockRedisClient)
+	if len(mockClient.publishedMessages) != 2 {
+		t.Fatalf("Expected the response to be sent again, got %d", len(mockClient.publishedMessages))
+	}
+	if mockClient.publishedMessages[0] != mockClient.publishedMessages[1] {
+		t.Error("Duplicate command must get the saved response, not a new one")
+	}
+}
+
+func TestHandleCommand_InterruptedBeforeRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), commandStateFile)
+	state, err := loadCommandState(path, 10)
+	if err != nil {
+		t.Fatalf("loadCommandState() error = %v", err)
+	}
+	// The agent stopped after marking the command, before it answered
+	state.MarkSeen("ping-interrupted")
+
+	agent := createTestAgent()
+	if agent.commandState, err = loadCommandState(path, 10); err != nil {
+		t.Fatalf("loadCommandState() after restart error = %v", err)
+	}
+
+	msg := protocol.NewMessage(protocol.TypePing, nil)
+	msg.ID = "ping-interrupted"
+	response := agent.handleCommand(context.Background(), msg)
+	if response == nil || response.Type != protocol.TypeErrorResponse {
+		t.Fatalf("Expected error response, got %+v", response)
+	}
+	payload, err := protocol.DecodeAs[protocol.ErrorPayload](response)
+	if err != nil {
+		t.Fatalf("Failed to decode error payload: %v", err)
+	}
+	if payload.ErrorCode != protocol.ErrorCommandInterrupted {
+		t.Errorf("Expected %s, got %v", protocol.ErrorCommandInterrupted, payload.ErrorCode)
+	}
+}
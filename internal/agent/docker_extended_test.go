@@ -37,7 +37,7 @@ func TestHandleStartContainer_InvalidPayload(t *testing.T) {
 
 	// Invalid payload
 	msg := protocol.NewMessage(protocol.TypeStartContainer, "invalid")
-	response := agent.handleStartContainer(msg)
+	response := agent.handleStartContainer(context.Background(), msg)
 
 	if response == nil {
 		t.Fatal("handleStartContainer returned nil")
@@ -60,7 +60,7 @@ func TestHandleCreateContainer_InvalidPayload(t *testing.T) {
 	}
 
 	msg := protocol.NewMessage(protocol.TypeCreateContainer, "invalid")
-	response := agent.handleCreateContainer(msg)
+	response := agent.handleCreateContainer(context.Background(), msg)
 
 	if response == nil {
 		t.Fatal("handleCreateContainer returned nil")
@@ -1,19 +1,20 @@
 package agent
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 
 	"github.com/servereye/servereye/pkg/protocol"
 )
 
 // handleGetContainers обрабатывает команду получения списка Docker контейнеров
-func (a *Agent) handleGetContainers(msg *protocol.Message) *protocol.Message {
-	containers, err := a.dockerClient.GetContainers(a.ctx)
+func (a *Agent) handleGetContainers(ctx context.Context, msg *protocol.Message) *protocol.Message {
+	containers, err := a.dockerClient.GetContainers(ctx)
 	if err != nil {
 		a.logger.WithError(err).Error("Не удалось получить список Docker контейнеров")
 		return protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
-			ErrorCode:    protocol.ErrorDockerUnavailable,
+			ErrorCode:    dockerErrorCode(ctx, protocol.ErrorDockerUnavailable),
 			ErrorMessage: fmt.Sprintf("Не удалось получить список контейнеров: %v", err),
 		})
 	}
@@ -22,23 +23,23 @@ func (a *Agent) handleGetContainers(msg *protocol.Message) *protocol.Message {
 }
 
 // handleStartContainer обрабатывает команду запуска контейнера
-func (a *Agent) handleStartContainer(msg *protocol.Message) *protocol.Message {
+func (a *Agent) handleStartContainer(ctx context.Context, msg *protocol.Message) *protocol.Message {
 	a.logger.Info("Обработка команды start_container")
 
 	var actionPayload protocol.ContainerActionPayload
-	if err := parsePayload(msg.Payload, &actionPayload); err != nil {
+	if err := msg.Decode(&actionPayload); err != nil {
 		a.logger.WithError(err).Error("Не удалось распарсить payload")
 		return protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
 			ErrorCode:    protocol.ErrorInvalidCommand,
-			ErrorMessage: "Неверный формат команды",
+			ErrorMessage: fmt.Sprintf("Неверный формат команды: %v", err),
 		})
 	}
 
-	response, err := a.dockerClient.StartContainer(a.ctx, actionPayload.ContainerID)
+	response, err := a.dockerClient.StartContainer(ctx, actionPayload.ContainerID)
 	if err != nil {
 		a.logger.WithError(err).Error("Ошибка при запуске контейнера")
 		return protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
-			ErrorCode:    protocol.ErrorContainerAction,
+			ErrorCode:    dockerErrorCode(ctx, protocol.ErrorContainerAction),
 			ErrorMessage: fmt.Sprintf("Ошибка при запуске контейнера: %v", err),
 		})
 	}
@@ -49,23 +50,23 @@ func (a *Agent) handleStartContainer(msg *protocol.Message) *protocol.Message {
 }
 
 // handleStopContainer обрабатывает команду остановки контейнера
-func (a *Agent) handleStopContainer(msg *protocol.Message) *protocol.Message {
+func (a *Agent) handleStopContainer(ctx context.Context, msg *protocol.Message) *protocol.Message {
 	a.logger.Info("Обработка команды stop_container")
 
 	var actionPayload protocol.ContainerActionPayload
-	if err := parsePayload(msg.Payload, &actionPayload); err != nil {
+	if err := msg.Decode(&actionPayload); err != nil {
 		a.logger.WithError(err).Error("Не удалось распарсить payload")
 		return protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
 			ErrorCode:    protocol.ErrorInvalidCommand,
-			ErrorMessage: "Неверный формат команды",
+			ErrorMessage: fmt.Sprintf("Неверный формат команды: %v", err),
 		})
 	}
 
-	response, err := a.dockerClient.StopContainer(a.ctx, actionPayload.ContainerID)
+	response, err := a.dockerClient.StopContainer(ctx, actionPayload.ContainerID)
 	if err != nil {
 		a.logger.WithError(err).Error("Ошибка при остановке контейнера")
 		return protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
-			ErrorCode:    protocol.ErrorContainerAction,
+			ErrorCode:    dockerErrorCode(ctx, protocol.ErrorContainerAction),
 			ErrorMessage: fmt.Sprintf("Ошибка при остановке контейнера: %v", err),
 		})
 	}
@@ -76,23 +77,23 @@ func (a *Agent) handleStopContainer(msg *protocol.Message) *protocol.Message {
 }
 
 // handleRestartContainer обрабатывает команду перезапуска контейнера
-func (a *Agent) handleRestartContainer(msg *protocol.Message) *protocol.Message {
+func (a *Agent) handleRestartContainer(ctx context.Context, msg *protocol.Message) *protocol.Message {
 	a.logger.Info("Обработка команды restart_container")
 
 	var actionPayload protocol.ContainerActionPayload
-	if err := parsePayload(msg.Payload, &actionPayload); err != nil {
+	if err := msg.Decode(&actionPayload); err != nil {
 		a.logger.WithError(err).Error("Не удалось распарсить payload")
 		return protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
 			ErrorCode:    protocol.ErrorInvalidCommand,
-			ErrorMessage: "Неверный формат команды",
+			ErrorMessage: fmt.Sprintf("Неверный формат команды: %v", err),
 		})
 	}
 
-	response, err := a.dockerClient.RestartContainer(a.ctx, actionPayload.ContainerID)
+	response, err := a.dockerClient.RestartContainer(ctx, actionPayload.ContainerID)
 	if err != nil {
 		a.logger.WithError(err).Error("Ошибка при перезапуске контейнера")
 		return protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
-			ErrorCode:    protocol.ErrorContainerAction,
+			ErrorCode:    dockerErrorCode(ctx, protocol.ErrorContainerAction),
 			ErrorMessage: fmt.Sprintf("Ошибка при перезапуске контейнера: %v", err),
 		})
 	}
@@ -103,23 +104,23 @@ func (a *Agent) handleRestartContainer(msg *protocol.Message) *protocol.Message
 }
 
 // handleRemoveContainer обрабатывает команду удаления контейнера
-func (a *Agent) handleRemoveContainer(msg *protocol.Message) *protocol.Message {
+func (a *Agent) handleRemoveContainer(ctx context.Context, msg *protocol.Message) *protocol.Message {
 	a.logger.Info("Обработка команды remove_container")
 
 	var actionPayload protocol.ContainerActionPayload
-	if err := parsePayload(msg.Payload, &actionPayload); err != nil {
+	if err := msg.Decode(&actionPayload); err != nil {
 		a.logger.WithError(err).Error("Не удалось распарсить payload")
 		return protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
 			ErrorCode:    protocol.ErrorInvalidCommand,
-			ErrorMessage: "Неверный формат команды",
+			ErrorMessage: fmt.Sprintf("Неверный формат команды: %v", err),
 		})
 	}
 
-	response, err := a.dockerClient.RemoveContainer(a.ctx, actionPayload.ContainerID)
+	response, err := a.dockerClient.RemoveContainer(ctx, actionPayload.ContainerID)
 	if err != nil {
 		a.logger.WithError(err).Error("Ошибка при удалении контейнера")
 		return protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
-			ErrorCode:    protocol.ErrorContainerAction,
+			ErrorCode:    dockerErrorCode(ctx, protocol.ErrorContainerAction),
 			ErrorMessage: fmt.Sprintf("Ошибка при удалении контейнера: %v", err),
 		})
 	}
@@ -130,23 +131,23 @@ func (a *Agent) handleRemoveContainer(msg *protocol.Message) *protocol.Message {
 }
 
 // handleCreateContainer обрабатывает команду создания контейнера
-func (a *Agent) handleCreateContainer(msg *protocol.Message) *protocol.Message {
+func (a *Agent) handleCreateContainer(ctx context.Context, msg *protocol.Message) *protocol.Message {
 	a.logger.Info("Обработка команды create_container")
 
 	var createPayload protocol.CreateContainerPayload
-	if err := parsePayload(msg.Payload, &createPayload); err != nil {
+	if err := msg.Decode(&createPayload); err != nil {
 		a.logger.WithError(err).Error("Не удалось распарсить payload")
 		return protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
 			ErrorCode:    protocol.ErrorInvalidCommand,
-			ErrorMessage: "Неверный формат команды",
+			ErrorMessage: fmt.Sprintf("Неверный формат команды: %v", err),
 		})
 	}
 
-	response, err := a.dockerClient.CreateContainer(a.ctx, &createPayload)
+	response, err := a.dockerClient.CreateContainer(ctx, &createPayload)
 	if err != nil {
 		a.logger.WithError(err).Error("Ошибка при создании контейнера")
 		return protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
-			ErrorCode:    protocol.ErrorContainerAction,
+			ErrorCode:    dockerErrorCode(ctx, protocol.ErrorContainerAction),
 			ErrorMessage: fmt.Sprintf("Ошибка при создании контейнера: %v", err),
 		})
 	}
@@ -155,16 +156,14 @@ func (a *Agent) handleCreateContainer(msg *protocol.Message) *protocol.Message {
 	return protocol.NewMessage(protocol.TypeContainerActionResponse, response)
 }
 
-// parsePayload helper для парсинга payload
-func parsePayload(payload interface{}, target interface{}) error {
-	payloadData, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("не удалось сериализовать payload: %w", err)
-	}
-
-	if err := json.Unmarshal(payloadData, target); err != nil {
-		return fmt.Errorf("не удалось распарсить payload: %w", err)
+// dockerErrorCode возвращает COMMAND_TIMEOUT или COMMAND_CANCELLED, если операция прервана
+// дедлайном команды или cancel_command
+func dockerErrorCode(ctx context.Context, fallback string) string {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return protocol.ErrorCommandTimeout
+	case errors.Is(ctx.Err(), context.Canceled):
+		return protocol.ErrorCommandCancelled
 	}
-
-	return nil
+	return fallback
 }
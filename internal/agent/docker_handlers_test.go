@@ -6,7 +6,12 @@ import (
 	"github.com/servereye/servereye/pkg/protocol"
 )
 
-func TestParsePayload(t *testing.T) {
+// decodeCommandPayload decodes payload the way handlers see it: carried in a command message
+func decodeCommandPayload(payload interface{}, target interface{}) error {
+	return protocol.NewMessage("", payload).Decode(target)
+}
+
+func TestDecodeCommandPayload(t *testing.T) {
 	tests := []struct {
 		name    string
 		payload interface{}
@@ -41,25 +46,25 @@ func TestParsePayload(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := parsePayload(tt.payload, tt.target)
+			err := decodeCommandPayload(tt.payload, tt.target)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("parsePayload() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("decodeCommandPayload() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
-func TestParsePayload_ContainerActionPayload(t *testing.T) {
+func TestDecodeCommandPayload_ContainerActionPayload(t *testing.T) {
 	payload := map[string]interface{}{
 		"container_id":   "abc123",
 		"container_name": "my-container",
 	}
 
 	var result protocol.ContainerActionPayload
-	err := parsePayload(payload, &result)
+	err := decodeCommandPayload(payload, &result)
 
 	if err != nil {
-		t.Fatalf("parsePayload() error = %v", err)
+		t.Fatalf("decodeCommandPayload() error = %v", err)
 	}
 
 	if result.ContainerID != "abc123" {
@@ -71,7 +76,7 @@ func TestParsePayload_ContainerActionPayload(t *testing.T) {
 	}
 }
 
-func TestParsePayload_CreateContainerPayload(t *testing.T) {
+func TestDecodeCommandPayload_CreateContainerPayload(t *testing.T) {
 	payload := map[string]interface{}{
 		"name":  "test-nginx",
 		"image": "nginx:alpine",
@@ -79,10 +84,10 @@ func TestParsePayload_CreateContainerPayload(t *testing.T) {
 	}
 
 	var result protocol.CreateContainerPayload
-	err := parsePayload(payload, &result)
+	err := decodeCommandPayload(payload, &result)
 
 	if err != nil {
-		t.Fatalf("parsePayload() error = %v", err)
+		t.Fatalf("decodeCommandPayload() error = %v", err)
 	}
 
 	if result.Name != "test-nginx" {
@@ -94,14 +99,14 @@ func TestParsePayload_CreateContainerPayload(t *testing.T) {
 	}
 }
 
-func TestParsePayload_EmptyPayload(t *testing.T) {
+func TestDecodeCommandPayload_EmptyPayload(t *testing.T) {
 	payload := map[string]interface{}{}
 
 	var result protocol.ContainerActionPayload
-	err := parsePayload(payload, &result)
+	err := decodeCommandPayload(payload, &result)
 
 	if err != nil {
-		t.Fatalf("parsePayload() with empty payload error = %v", err)
+		t.Fatalf("decodeCommandPayload() with empty payload error = %v", err)
 	}
 
 	if result.ContainerID != "" {
@@ -109,11 +114,11 @@ func TestParsePayload_EmptyPayload(t *testing.T) {
 	}
 }
 
-func TestParsePayload_NilTarget(t *testing.T) {
-	t.Skip("parsePayload doesn't panic on nil, it returns error")
+func TestDecodeCommandPayload_NilTarget(t *testing.T) {
+	t.Skip("decodeCommandPayload doesn't panic on nil, it returns error")
 }
 
-func TestParsePayload_ComplexPayload(t *testing.T) {
+func TestDecodeCommandPayload_ComplexPayload(t *testing.T) {
 	payload := map[string]interface{}{
 		"name":    "complex-container",
 		"image":   "nginx:latest",
@@ -123,10 +128,10 @@ func TestParsePayload_ComplexPayload(t *testing.T) {
 	}
 
 	var result protocol.CreateContainerPayload
-	err := parsePayload(payload, &result)
+	err := decodeCommandPayload(payload, &result)
 
 	if err != nil {
-		t.Fatalf("parsePayload() error = %v", err)
+		t.Fatalf("decodeCommandPayload() error = %v", err)
 	}
 
 	if result.Name != "complex-container" {
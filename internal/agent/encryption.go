@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/servereye/servereye/pkg/e2e"
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+// encryptionKeysFile имя файла ключей шифрования в state директории
+const encryptionKeysFile = "encryption_keys.json"
+
+// persistedKey формат ключа в файле
+type persistedKey struct {
+	Private   []byte    `json:"private"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// persistedEncryptionKeys формат файла ключей
+type persistedEncryptionKeys struct {
+	Current  persistedKey  `json:"current"`
+	Previous *persistedKey `json:"previous,omitempty"`
+}
+
+// encryptionKeys хранит текущую пару ключей X25519 агента и предыдущую после ротации.
+// Предыдущий ключ нужен для команд, зашифрованных ботом до того, как он узнал о новом.
+type encryptionKeys struct {
+	mu          sync.RWMutex
+	path        string
+	rotateAfter time.Duration
+
+	current  *e2e.KeyPair
+	previous *e2e.KeyPair
+}
+
+// loadEncryptionKeys загружает ключи из файла, при отсутствии файла создает новую пару
+func loadEncryptionKeys(path string, rotateAfter time.Duration) (*encryptionKeys, error) {
+	keys := &encryptionKeys{path: path, rotateAfter: rotateAfter}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		current, err := e2e.GenerateKeyPair()
+		if err != nil {
+			return nil, err
+		}
+		keys.current = current
+		return keys, keys.saveLocked()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encryption keys: %w", err)
+	}
+
+	var persisted persistedEncryptionKeys
+	if err := json.Unmarshal(data, &persisted); err != nil {
+		return nil, fmt.Errorf("failed to parse encryption keys: %w", err)
+	}
+
+	if keys.current, err = e2e.ParseKeyPair(persisted.Current.Private, persisted.Current.CreatedAt); err != nil {
+		return nil, err
+	}
+	if persisted.Previous != nil {
+		if keys.previous, err = e2e.ParseKeyPair(persisted.Previous.Private, persisted.Previous.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
+
+// PublicKey возвращает текущий публичный ключ для объявления боту
+func (k *encryptionKeys) PublicKey() protocol.EncryptionKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return protocol.EncryptionKey{ID: k.current.ID, PublicKey: k.current.PublicKey()}
+}
+
+// KeyPair возвращает пару ключей по ID: текущую или предыдущую
+func (k *encryptionKeys) KeyPair(keyID string) (*e2e.KeyPair, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range []*e2e.KeyPair{k.current, k.previous} {
+		if key != nil && key.ID == keyID {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// RotateIfDue создает новую пару ключей, если текущая старше rotateAfter.
+// Возвращает true, если ключ сменился.
+func (k *encryptionKeys) RotateIfDue(now time.Time) (bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.rotateAfter <= 0 || now.Sub(k.current.CreatedAt) < k.rotateAfter {
+		return false, nil
+	}
+
+	next, err := e2e.GenerateKeyPair()
+	if err != nil {
+		return false, err
+	}
+
+	k.previous, k.current = k.current, next
+	return true, k.saveLocked()
+}
+
+// saveLocked атомарно записывает ключи (tmp файл + rename) с правами 0600
+func (k *encryptionKeys) saveLocked() error {
+	if k.path == "" {
+		return nil
+	}
+
+	persisted := persistedEncryptionKeys{
+		Current: persistedKey{Private: k.current.Private.Bytes(), CreatedAt: k.current.CreatedAt},
+	}
+	if k.previous != nil {
+		persisted.Previous = &persistedKey{Private: k.previous.Private.Bytes(), CreatedAt: k.previous.CreatedAt}
+	}
+
+	data, err := json.Marshal(persisted)
+	if err != nil {
+		return fmt.Errorf("failed to serialize encryption keys: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(k.path), 0750); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tmpPath := k.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write encryption keys: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, k.path); err != nil {
+		return fmt.Errorf("failed to save encryption keys: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/servereye/servereye/pkg/auth"
+	"github.com/servereye/servereye/pkg/e2e"
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+func TestEncryptionKeys_PersistAcrossRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", encryptionKeysFile)
+
+	keys, err := loadEncryptionKeys(path, time.Hour)
+	if err != nil {
+		t.Fatalf("loadEncryptionKeys() error = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Keys file not created: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Keys file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	restored, err := loadEncryptionKeys(path, time.Hour)
+	if err != nil {
+		t.Fatalf("loadEncryptionKeys() after restart error = %v", err)
+	}
+	if restored.PublicKey().ID != keys.PublicKey().ID {
+		t.Errorf("Key changed after restart: %s != %s", restored.PublicKey().ID, keys.PublicKey().ID)
+	}
+}
+
+func TestEncryptionKeys_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), encryptionKeysFile)
+	keys, err := loadEncryptionKeys(path, time.Hour)
+	if err != nil {
+		t.Fatalf("loadEncryptionKeys() error = %v", err)
+	}
+	oldID := keys.PublicKey().ID
+
+	if rotated, _ := keys.RotateIfDue(time.Now()); rotated {
+		t.Fatal("Fresh key must not be rotated")
+	}
+
+	rotated, err := keys.RotateIfDue(time.Now().Add(2 * time.Hour))
+	if err != nil || !rotated {
+		t.Fatalf("RotateIfDue() = %v, %v; want true, nil", rotated, err)
+	}
+
+	newID := keys.PublicKey().ID
+	if newID == oldID {
+		t.Fatal("Key ID did not change after rotation")
+	}
+
+	// Предыдущий ключ остается для команд, зашифрованных до ротации
+	restored, err := loadEncryptionKeys(path, time.Hour)
+	if err != nil {
+		t.Fatalf("loadEncryptionKeys() error = %v", err)
+	}
+	for _, id := range []string{oldID, newID} {
+		if _, ok := restored.KeyPair(id); !ok {
+			t.Errorf("Key %s not available after rotation", id)
+		}
+	}
+}
+
+func TestHandleCommand_Encrypted(t *testing.T) {
+	agent := createTestAgent()
+	keys, err := loadEncryptionKeys(filepath.Join(t.TempDir(), encryptionKeysFile), 0)
+	if err != nil {
+		t.Fatalf("loadEncryptionKeys() error = %v", err)
+	}
+	agent.encryptionKeys = keys
+
+	if caps := agent.capabilities(); caps.EncryptionKey == nil || caps.EncryptionKey.ID != keys.PublicKey().ID {
+		t.Fatalf("Capabilities don't advertise the encryption key: %+v", caps.EncryptionKey)
+	}
+
+	// Ключ подписывается ключом подписи сервера, иначе бот не примет его смену
+	agent.config.Server.SigningKey = "sig_test"
+	if key := agent.capabilities().EncryptionKey; auth.VerifyEncryptionKey("sig_test", *key) != nil {
+		t.Errorf("Advertised encryption key is not signed: %+v", key)
+	}
+
+	msg := protocol.NewMessage(protocol.TypePing, nil)
+	session, err := e2e.EncryptCommand(msg, keys.PublicKey())
+	if err != nil {
+		t.Fatalf("EncryptCommand() error = %v", err)
+	}
+
+	response := agent.handleCommand(context.Background(), msg)
+	if response == nil || response.Encryption == "" {
+		t.Fatalf("Expected encrypted response, got %+v", response)
+	}
+	if err := session.DecryptResponse(response); err != nil {
+		t.Fatalf("DecryptResponse() error = %v", err)
+	}
+	if response.Type != protocol.TypePong {
+		t.Errorf("Expected pong, got %s", response.Type)
+	}
+	if _, err := protocol.DecodeAs[protocol.PongPayload](response); err != nil {
+		t.Errorf("Failed to decode decrypted response: %v", err)
+	}
+}
+
+func TestHandleCommand_EncryptedWithoutKeys(t *testing.T) {
+	agent := createTestAgent()
+
+	key, err := e2e.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := protocol.NewMessage(protocol.TypePing, nil)
+	if _, err := e2e.EncryptCommand(msg, protocol.EncryptionKey{ID: key.ID, PublicKey: key.PublicKey()}); err != nil {
+		t.Fatal(err)
+	}
+
+	response := agent.handleCommand(context.Background(), msg)
+	payload, err := protocol.DecodeAs[protocol.ErrorPayload](response)
+	if err != nil {
+		t.Fatalf("Expected error response: %v", err)
+	}
+	if payload.ErrorCode != protocol.ErrorDecryptionFailed {
+		t.Errorf("Expected %s, got %s", protocol.ErrorDecryptionFailed, payload.ErrorCode)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"net/http"
 	"time"
+
+	"github.com/servereye/servereye/pkg/auth"
 )
 
 // startHeartbeat запускает отправку heartbeat сообщений
@@ -19,6 +21,7 @@ func (a *Agent) startHeartbeat() {
 	for {
 		select {
 		case <-ticker.C:
+			a.rotateEncryptionKey()
 			a.sendHeartbeat()
 		case <-a.ctx.Done():
 			return
@@ -28,6 +31,13 @@ func (a *Agent) startHeartbeat() {
 
 // sendHeartbeat отправляет heartbeat сообщение в Web API
 func (a *Agent) sendHeartbeat() {
+	// Пока туннель поднят, heartbeat идет через него
+	if a.tunnel != nil && a.tunnel.Connected() {
+		if err := a.tunnel.Heartbeat(a.capabilities()); err == nil {
+			return
+		}
+	}
+
 	// Check if Web API base URL is configured
 	webAPIURL := a.config.API.BaseURL
 	if webAPIURL == "" {
@@ -37,7 +47,8 @@ func (a *Agent) sendHeartbeat() {
 	}
 
 	heartbeat := map[string]interface{}{
-		"api_key": a.config.Server.SecretKey,
+		"api_key":      a.config.Server.SecretKey,
+		"capabilities": a.capabilities(),
 	}
 
 	data, err := json.Marshal(heartbeat)
@@ -55,6 +66,10 @@ func (a *Agent) sendHeartbeat() {
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	if err := auth.SignRequest(req, a.config.Server.SecretKey, data); err != nil {
+		a.logger.WithError(err).Error("Не удалось подписать heartbeat запрос")
+		return
+	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -71,10 +86,11 @@ func (a *Agent) sendHeartbeat() {
 // sendHeartbeatRedis отправляет heartbeat в Redis (legacy/fallback)
 func (a *Agent) sendHeartbeatRedis() {
 	heartbeat := map[string]interface{}{
-		"server_key":  a.config.Server.SecretKey,
-		"server_name": a.config.Server.Name,
-		"timestamp":   time.Now(),
-		"status":      "online",
+		"server_key":   a.config.Server.SecretKey,
+		"server_name":  a.config.Server.Name,
+		"timestamp":    time.Now(),
+		"status":       "online",
+		"capabilities": a.capabilities(),
 	}
 
 	data, err := json.Marshal(heartbeat)
@@ -88,3 +104,19 @@ func (a *Agent) sendHeartbeatRedis() {
 		a.logger.WithError(err).Error("Не удалось отправить heartbeat")
 	}
 }
+
+// rotateEncryptionKey меняет ключ шифрования по расписанию, новый ключ уходит боту со следующим heartbeat
+func (a *Agent) rotateEncryptionKey() {
+	if a.encryptionKeys == nil {
+		return
+	}
+
+	rotated, err := a.encryptionKeys.RotateIfDue(time.Now())
+	if err != nil {
+		a.logger.WithError(err).Error("Не удалось сменить ключ шифрования")
+		return
+	}
+	if rotated {
+		a.logger.WithField("key_id", a.encryptionKeys.PublicKey().ID).Info("Ключ шифрования обновлен")
+	}
+}
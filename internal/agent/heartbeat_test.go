@@ -2,11 +2,14 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/servereye/servereye/internal/config"
+	"github.com/servereye/servereye/pkg/protocol"
+	"github.com/servereye/servereye/pkg/transport"
 	"github.com/sirupsen/logrus"
 )
 
@@ -16,7 +19,7 @@ type mockRedisClient struct {
 	publishedChannels []string
 }
 
-func (m *mockRedisClient) Subscribe(ctx context.Context, channel string) (SubscriptionInterface, error) {
+func (m *mockRedisClient) Subscribe(ctx context.Context, channel string) (transport.Subscription, error) {
 	return nil, nil
 }
 
@@ -66,6 +69,16 @@ func TestSendHeartbeat(t *testing.T) {
 		if message == "" {
 			t.Error("Published message is empty")
 		}
+
+		var heartbeat struct {
+			Capabilities protocol.Capabilities `json:"capabilities"`
+		}
+		if err := json.Unmarshal([]byte(message), &heartbeat); err != nil {
+			t.Fatalf("Heartbeat is not JSON: %v", err)
+		}
+		if heartbeat.Capabilities.ProtocolVersion != protocol.ProtocolVersion || !heartbeat.Capabilities.Supports(protocol.TypePing) {
+			t.Errorf("Heartbeat capabilities = %+v", heartbeat.Capabilities)
+		}
 	}
 }
 
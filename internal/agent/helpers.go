@@ -2,10 +2,11 @@ package agent
 
 import (
 	"fmt"
-	"time"
 
 	"github.com/servereye/servereye/pkg/protocol"
 	"github.com/servereye/servereye/pkg/redis"
+	"github.com/servereye/servereye/pkg/redis/streams"
+	"github.com/servereye/servereye/pkg/transport"
 )
 
 // handlePing обрабатывает ping команду
@@ -23,7 +24,7 @@ func (a *Agent) handlePing(msg *protocol.Message) *protocol.Message {
 // handleUnknownCommand обрабатывает неизвестную команду
 func (a *Agent) handleUnknownCommand(msg *protocol.Message) *protocol.Message {
 	payload := protocol.ErrorPayload{
-		ErrorCode:    protocol.ErrorInvalidCommand,
+		ErrorCode:    protocol.ErrorUnsupportedCommand,
 		ErrorMessage: fmt.Sprintf("Неизвестная команда: %s", msg.Type),
 	}
 
@@ -43,36 +44,48 @@ func (a *Agent) sendResponse(msg *protocol.Message) error {
 	return a.redisClient.Publish(a.ctx, respChannel, data)
 }
 
-// sendResponseToCommand отправляет ответ в Stream или Pub/Sub
-func (a *Agent) sendResponseToCommand(msg *protocol.Message, commandID string) error {
-	data, err := msg.ToJSON()
-	if err != nil {
-		return fmt.Errorf("не удалось сериализовать ответ: %w", err)
-	}
-
-	// Use Streams if available
-	if a.useStreams && a.streamsClient != nil {
-		respStream := fmt.Sprintf("stream:resp:%s", a.config.Server.SecretKey)
-
-		values := map[string]string{
-			"type":       string(msg.Type),
-			"id":         msg.ID,
-			"command_id": commandID,
-			"payload":    string(data),
-			"timestamp":  time.Now().Format(time.RFC3339),
-		}
-
-		if _, err := a.streamsClient.AddMessage(a.ctx, respStream, values); err != nil {
-			a.logger.WithError(err).Error("Failed to send via Streams")
+// sendResponseToCommand отправляет ответ на command в Stream или Pub/Sub.
+// Большие ответы в Stream и туннель отправляются частями, если бот умеет их собирать.
+func (a *Agent) sendResponseToCommand(msg *protocol.Message, command *protocol.Message) error {
+	// Ответ уходит тем же транспортом, которым пришла команда
+	if (a.commandTransport == transport.Tunnel && a.tunnel != nil) ||
+		(a.commandTransport == transport.Streams && a.streamsClient != nil) {
+		entries, err := streams.ResponseEntries(msg, command.ID, streams.ResponseChunkSize(command))
+		if err != nil {
 			return err
 		}
 
-		a.logger.WithField("stream", respStream).Debug("Response sent via Streams")
+		for _, values := range entries {
+			if err := a.sendResponseEntry(values); err != nil {
+				return err
+			}
+		}
 		return nil
 	}
 
+	data, err := msg.ToJSON()
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать ответ: %w", err)
+	}
+
 	// Fallback to Pub/Sub
-	respChannel := fmt.Sprintf("resp:%s:%s", a.config.Server.SecretKey, commandID)
+	respChannel := fmt.Sprintf("resp:%s:%s", a.config.Server.SecretKey, command.ID)
 	a.logger.WithField("response_channel", respChannel).Debug("Отправка ответа в уникальный канал")
 	return a.redisClient.Publish(a.ctx, respChannel, data)
 }
+
+// sendResponseEntry добавляет запись ответа в stream:resp через туннель или Streams
+func (a *Agent) sendResponseEntry(values map[string]string) error {
+	if a.commandTransport == transport.Tunnel {
+		return a.tunnel.SendResponse(a.ctx, values)
+	}
+
+	respStream := fmt.Sprintf("stream:resp:%s", a.config.Server.SecretKey)
+	if _, err := a.streamsClient.AddMessage(a.ctx, respStream, values); err != nil {
+		a.logger.WithError(err).Error("Failed to send via Streams")
+		return err
+	}
+
+	a.logger.WithField("stream", respStream).Debug("Response sent via Streams")
+	return nil
+}
@@ -36,9 +36,9 @@ func TestHandlePing(t *testing.T) {
 	}
 
 	// Check payload
-	payload, ok := response.Payload.(protocol.PongPayload)
-	if !ok {
-		t.Fatal("Payload is not PongPayload")
+	payload, err := protocol.DecodeAs[protocol.PongPayload](response)
+	if err != nil {
+		t.Fatalf("Payload is not PongPayload: %v", err)
 	}
 
 	if payload.Status != "healthy" {
@@ -70,13 +70,13 @@ func TestHandleUnknownCommand(t *testing.T) {
 	}
 
 	// Check error payload
-	payload, ok := response.Payload.(protocol.ErrorPayload)
-	if !ok {
-		t.Fatal("Payload is not ErrorPayload")
+	payload, err := protocol.DecodeAs[protocol.ErrorPayload](response)
+	if err != nil {
+		t.Fatalf("Payload is not ErrorPayload: %v", err)
 	}
 
-	if payload.ErrorCode != protocol.ErrorInvalidCommand {
-		t.Errorf("ErrorCode = %v, want %v", payload.ErrorCode, protocol.ErrorInvalidCommand)
+	if payload.ErrorCode != protocol.ErrorUnsupportedCommand {
+		t.Errorf("ErrorCode = %v, want %v", payload.ErrorCode, protocol.ErrorUnsupportedCommand)
 	}
 }
 
@@ -128,9 +128,9 @@ func TestHandleUnknownCommand_DifferentTypes(t *testing.T) {
 				t.Errorf("Expected TypeErrorResponse, got %v", response.Type)
 			}
 
-			payload, ok := response.Payload.(protocol.ErrorPayload)
-			if !ok {
-				t.Fatal("Payload is not ErrorPayload")
+			payload, err := protocol.DecodeAs[protocol.ErrorPayload](response)
+			if err != nil {
+				t.Fatalf("Payload is not ErrorPayload: %v", err)
 			}
 
 			if !strings.Contains(payload.ErrorMessage, string(cmdType)) {
@@ -156,13 +156,15 @@ func TestSendResponseToCommand_WithMockClient(t *testing.T) {
 				SecretKey: "test-key",
 			},
 		},
-		useStreams: false,
 	}
 
 	msg := protocol.NewMessage(protocol.TypePong, protocol.PongPayload{Status: "ok"})
 	msg.ID = "test-response-123"
 
-	err := agent.sendResponseToCommand(msg, "cmd-456")
+	command := protocol.NewMessage(protocol.TypePing, nil)
+	command.ID = "cmd-456"
+
+	err := agent.sendResponseToCommand(msg, command)
 	if err != nil {
 		t.Errorf("sendResponseToCommand() error = %v", err)
 	}
@@ -2,12 +2,20 @@ package agent
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/servereye/servereye/internal/config"
 	"github.com/servereye/servereye/pkg/metrics"
 	"github.com/servereye/servereye/pkg/protocol"
+	"github.com/servereye/servereye/pkg/redis"
+	"github.com/servereye/servereye/pkg/redis/streams"
+	"github.com/servereye/servereye/pkg/transport"
 	"github.com/sirupsen/logrus"
 )
 
@@ -316,7 +324,6 @@ func TestAgentInitialization_AllFields(t *testing.T) {
 		systemMonitor: metrics.NewSystemMonitor(logger),
 		ctx:           ctx,
 		cancel:        cancel,
-		useStreams:    false,
 	}
 
 	// Verify all fields initialized
@@ -404,3 +411,220 @@ func TestRapidFireCommands(t *testing.T) {
 		t.Errorf("Expected 100 responses, got %d", len(mockClient.publishedMessages))
 	}
 }
+
+// newInMemoryAgent creates an agent wired to in-memory transports
+func newInMemoryAgent(t *testing.T, pubsub *redis.MemoryClient, streamsClient streams.StreamClient) *Agent {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	return &Agent{
+		logger:        logger,
+		ctx:           ctx,
+		cancel:        cancel,
+		redisClient:   transport.WrapPubSub(pubsub),
+		streamsClient: streamsClient,
+		cpuMetrics:    metrics.NewCPUMetrics(),
+		systemMonitor: metrics.NewSystemMonitor(logger),
+		config: &config.AgentConfig{
+			Server: config.ServerConfig{Name: "in-memory", SecretKey: "srv_inmemory"},
+		},
+		updateFunc: MockUpdateFunc(),
+	}
+}
+
+func TestInMemoryRoundTrip_Streams(t *testing.T) {
+	client := streams.NewMemoryClient(nil)
+	agent := newInMemoryAgent(t, redis.NewMemoryClient(logrus.New()), client)
+	agent.commandTransport = transport.Streams
+	go agent.handleCommandsViaStreams()
+
+	// Группа создается со стартом "$", ждем ее перед отправкой команды
+	cmdStream := "stream:cmd:srv_inmemory"
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		_, err := client.PendingMessages(context.Background(), cmdStream, config.DefaultConsumerGroup, 0, 1)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Consumer group was not created: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	bot := streams.NewBotAdapter(client, agent.logger)
+	defer bot.Close()
+
+	cmd := protocol.NewMessage(protocol.TypePing, nil)
+	cmd.ID = "in-memory-ping"
+	response, err := bot.SendCommand(context.Background(), "srv_inmemory", cmd, 5*time.Second)
+	if err != nil {
+		t.Fatalf("SendCommand() error = %v", err)
+	}
+	if response.Type != protocol.TypePong || response.ID != cmd.ID {
+		t.Errorf("Got response %s/%s, want %s/%s", response.Type, response.ID, protocol.TypePong, cmd.ID)
+	}
+
+	// Обработанная команда подтверждена в группе
+	pending, err := client.PendingMessages(context.Background(), cmdStream, config.DefaultConsumerGroup, 0, 10)
+	if err != nil {
+		t.Fatalf("PendingMessages() error = %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("Expected no pending commands, got %d", len(pending))
+	}
+}
+
+// flakyStreamClient fails the first response write, like a Redis hiccup after the command ran
+type flakyStreamClient struct {
+	streams.StreamClient
+
+	mu     sync.Mutex
+	failed chan struct{}
+}
+
+func (c *flakyStreamClient) AddMessage(ctx context.Context, stream string, values map[string]string) (string, error) {
+	if strings.HasPrefix(stream, "stream:resp:") {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		select {
+		case <-c.failed:
+		default:
+			close(c.failed)
+			return "", errors.New("connection reset")
+		}
+	}
+	return c.StreamClient.AddMessage(ctx, stream, values)
+}
+
+func TestStreams_ResendsResponseAfterFailedSend(t *testing.T) {
+	memory := streams.NewMemoryClient(nil)
+	client := &flakyStreamClient{StreamClient: memory, failed: make(chan struct{})}
+	agent := newInMemoryAgent(t, redis.NewMemoryClient(logrus.New()), client)
+	state, err := loadCommandState("", 10)
+	if err != nil {
+		t.Fatalf("loadCommandState() error = %v", err)
+	}
+	agent.commandState = state
+
+	cmdStream, respStream := "stream:cmd:srv_inmemory", "stream:resp:srv_inmemory"
+	adapter := streams.NewAgentAdapter(client, "srv_inmemory", "group", "agent", agent.logger)
+	adapter.SetStartID("0")
+	adapter.SetRetryPolicy(3, 10*time.Millisecond)
+	if err := adapter.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	cmd := protocol.NewMessage(protocol.TypePing, nil)
+	cmd.ID = "resend-ping"
+	data, _ := cmd.ToJSON()
+	if _, err := memory.AddMessage(context.Background(), cmdStream, map[string]string{
+		"type": string(cmd.Type), "id": cmd.ID, "payload": string(data),
+	}); err != nil {
+		t.Fatalf("AddMessage() error = %v", err)
+	}
+
+	// First delivery: the command runs, its response is lost
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		adapter.ProcessCommands(ctx, agent.handleCommand)
+	}()
+	select {
+	case <-client.failed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Command was not processed")
+	}
+	cancel()
+	<-done
+
+	// Redelivery after the visibility timeout answers with the saved response
+	time.Sleep(20 * time.Millisecond)
+	adapter.RecoverPending(context.Background(), agent.handleCommand)
+
+	responses, err := memory.RangeMessages(context.Background(), respStream, "-", "+", 10)
+	if err != nil {
+		t.Fatalf("RangeMessages() error = %v", err)
+	}
+	if len(responses) != 1 {
+		t.Fatalf("Expected 1 response in %s, got %d", respStream, len(responses))
+	}
+	response, err := protocol.FromJSON([]byte(responses[0].Values["payload"]))
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Type != protocol.TypePong || response.ID != cmd.ID {
+		t.Errorf("Got response %s/%s, want %s/%s", response.Type, response.ID, protocol.TypePong, cmd.ID)
+	}
+
+	pending, err := memory.PendingMessages(context.Background(), cmdStream, "group", 0, 10)
+	if err != nil {
+		t.Fatalf("PendingMessages() error = %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("Expected redelivered command to be ACKed, got %d pending", len(pending))
+	}
+}
+
+func TestInMemoryRoundTrip_PubSub(t *testing.T) {
+	broker := redis.NewMemoryClient(logrus.New())
+	agent := newInMemoryAgent(t, broker, nil)
+
+	sub, err := agent.redisClient.Subscribe(agent.ctx, redis.GetCommandChannel("srv_inmemory"))
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	go agent.handleCommands(sub.Channel())
+
+	cmd := protocol.NewMessage(protocol.TypePing, nil)
+	cmd.ID = "in-memory-pubsub"
+	respSub, err := broker.Subscribe(agent.ctx, "resp:srv_inmemory:"+cmd.ID)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer respSub.Close()
+
+	data, _ := cmd.ToJSON()
+	if err := broker.Publish(context.Background(), redis.GetCommandChannel("srv_inmemory"), data); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	select {
+	case raw := <-respSub.Channel():
+		response, err := protocol.FromJSON(raw)
+		if err != nil {
+			t.Fatalf("Invalid response: %v", err)
+		}
+		if response.Type != protocol.TypePong || response.ID != cmd.ID {
+			t.Errorf("Got response %s/%s, want %s/%s", response.Type, response.ID, protocol.TypePong, cmd.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for response")
+	}
+}
+
+func TestNegotiateTransport(t *testing.T) {
+	// Старый бот без /api/streams/* отвечает 404
+	legacyBot := httptest.NewServer(http.NotFoundHandler())
+	defer legacyBot.Close()
+
+	agent := newInMemoryAgent(t, redis.NewMemoryClient(logrus.New()), streams.NewHTTPStreamClient(legacyBot.URL, "srv_inmemory", logrus.New()))
+	if got := agent.negotiateTransport(); got != transport.PubSub {
+		t.Errorf("negotiateTransport() with legacy bot = %s, want %s", got, transport.PubSub)
+	}
+
+	agent = newInMemoryAgent(t, redis.NewMemoryClient(logrus.New()), streams.NewMemoryClient(nil))
+	if got := agent.negotiateTransport(); got != transport.Streams {
+		t.Errorf("negotiateTransport() = %s, want %s", got, transport.Streams)
+	}
+
+	agent = newInMemoryAgent(t, redis.NewMemoryClient(logrus.New()), nil)
+	if got := agent.negotiateTransport(); got != transport.PubSub {
+		t.Errorf("negotiateTransport() without Streams = %s, want %s", got, transport.PubSub)
+	}
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/servereye/servereye/internal/config"
+	"github.com/servereye/servereye/pkg/docker"
+	"github.com/servereye/servereye/pkg/metrics"
+	"github.com/servereye/servereye/pkg/publisher"
+)
+
+// recordingPublisher запоминает отправленные метрики
+type recordingPublisher struct {
+	mu      sync.Mutex
+	metrics []*publisher.Metric
+	batches int
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, metric *publisher.Metric) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.metrics = append(p.metrics, metric)
+	return nil
+}
+
+func (p *recordingPublisher) PublishBatch(ctx context.Context, batch []*publisher.Metric) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.metrics = append(p.metrics, batch...)
+	p.batches++
+	return nil
+}
+
+func (p *recordingPublisher) Close() error { return nil }
+func (p *recordingPublisher) Name() string { return "recording" }
+
+func TestNewCollectorRegistry_FromConfig(t *testing.T) {
+	agent := createTestAgent()
+	agent.cpuUsage = metrics.NewCPUUsageCollector()
+	agent.dockerClient = docker.NewClient(agent.logger)
+
+	disabled := false
+	agent.config.Metrics = config.MetricsConfig{
+		CPUTemperature: false,
+		Collectors: map[string]config.CollectorConfig{
+			metrics.CollectorDocker: {Enabled: &disabled},
+		},
+	}
+
+	names := agent.newCollectorRegistry().Names()
+	want := []string{metrics.CollectorCPUUsage, metrics.CollectorMemory, metrics.CollectorDisk, metrics.CollectorNetwork}
+	if len(names) != len(want) {
+		t.Fatalf("Collectors = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("Collectors = %v, want %v", names, want)
+			break
+		}
+	}
+}
+
+func TestPublishMetrics(t *testing.T) {
+	agent := createTestAgent()
+	agent.config.Server.Name = "web-1"
+	recorder := &recordingPublisher{}
+	agent.metricPublisher = recorder
+
+	agent.publishMetrics(metrics.CollectorDisk, []metrics.Metric{
+		{Name: "disk_usage", Value: 42.0, Tags: map[string]string{"path": "/"}},
+		{Name: "disk_usage", Value: 7.0, Tags: map[string]string{"path": "/var"}},
+	})
+
+	if len(recorder.metrics) != 2 || recorder.batches != 1 {
+		t.Fatalf("Expected 2 metrics in 1 batch, got %d in %d", len(recorder.metrics), recorder.batches)
+	}
+	metric := recorder.metrics[0]
+	if metric.Type != "disk_usage" || metric.Tags["path"] != "/" || metric.Tags["server_name"] != "web-1" {
+		t.Errorf("Unexpected metric %+v", metric)
+	}
+}
@@ -3,6 +3,7 @@ package agent
 import (
 	"fmt"
 
+	"github.com/servereye/servereye/internal/version"
 	"github.com/servereye/servereye/pkg/protocol"
 	"github.com/sirupsen/logrus"
 )
@@ -29,6 +30,36 @@ func (a *Agent) handleGetCPUTemp(msg *protocol.Message) *protocol.Message {
 	return response
 }
 
+// handleGetCPUUsage обрабатывает команду получения загрузки CPU
+func (a *Agent) handleGetCPUUsage(msg *protocol.Message) *protocol.Message {
+	a.logger.Debug("Обработка команды получения загрузки CPU")
+
+	if a.cpuUsage == nil {
+		return protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
+			ErrorCode:    "CPU_USAGE_ERROR",
+			ErrorMessage: "Сборщик загрузки CPU не инициализирован",
+		})
+	}
+
+	usage, err := a.cpuUsage.GetUsage()
+	if err != nil {
+		a.logger.WithError(err).Error("Ошибка получения загрузки CPU")
+		return protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
+			ErrorCode:    "CPU_USAGE_ERROR",
+			ErrorMessage: fmt.Sprintf("Ошибка получения загрузки CPU: %v", err),
+		})
+	}
+
+	a.logger.WithFields(logrus.Fields{
+		"usage_percent": usage.Total.Usage,
+		"cores":         len(usage.Cores),
+	}).Info("Загрузка CPU получена")
+
+	response := protocol.NewMessage(protocol.TypeCPUUsageResponse, usage)
+	response.ID = msg.ID
+	return response
+}
+
 // handleGetMemoryInfo обрабатывает команду получения информации о памяти
 func (a *Agent) handleGetMemoryInfo(msg *protocol.Message) *protocol.Message {
 	a.logger.Debug("Обработка команды получения информации о памяти")
@@ -90,6 +121,31 @@ func (a *Agent) handleGetUptime(msg *protocol.Message) *protocol.Message {
 	return response
 }
 
+// handleGetSystemInfo обрабатывает команду получения информации о системе
+func (a *Agent) handleGetSystemInfo(msg *protocol.Message) *protocol.Message {
+	a.logger.Debug("Обработка команды получения информации о системе")
+
+	systemInfo, err := a.systemMonitor.GetSystemInfo()
+	if err != nil {
+		a.logger.WithError(err).Error("Ошибка получения информации о системе")
+		return protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
+			ErrorCode:    "SYSTEM_INFO_ERROR",
+			ErrorMessage: fmt.Sprintf("Ошибка получения информации о системе: %v", err),
+		})
+	}
+	systemInfo.AgentVersion = version.GetVersion()
+
+	a.logger.WithFields(logrus.Fields{
+		"hostname": systemInfo.Hostname,
+		"distro":   systemInfo.Distro,
+		"kernel":   systemInfo.Kernel,
+	}).Info("Информация о системе получена")
+
+	response := protocol.NewMessage(protocol.TypeSystemInfoResponse, systemInfo)
+	response.ID = msg.ID
+	return response
+}
+
 // handleGetProcesses обрабатывает команду получения списка процессов
 func (a *Agent) handleGetProcesses(msg *protocol.Message) *protocol.Message {
 	a.logger.Debug("Обработка команды получения списка процессов")
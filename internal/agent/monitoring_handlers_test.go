@@ -38,6 +38,31 @@ func TestHandleGetCPUTemp(t *testing.T) {
 	}
 }
 
+func TestHandleGetCPUUsage(t *testing.T) {
+	logger := logrus.New()
+
+	agent := &Agent{
+		logger:   logger,
+		cpuUsage: metrics.NewCPUUsageCollector(),
+	}
+
+	msg := protocol.NewMessage(protocol.TypeGetCPUUsage, nil)
+
+	response := agent.handleGetCPUUsage(msg)
+
+	if response == nil {
+		t.Fatal("handleGetCPUUsage returned nil")
+	}
+
+	if response.ID == "" {
+		t.Error("Response ID is empty")
+	}
+
+	if response.Type != protocol.TypeCPUUsageResponse && response.Type != protocol.TypeErrorResponse {
+		t.Errorf("Unexpected response type: %v", response.Type)
+	}
+}
+
 func TestHandleGetMemoryInfo(t *testing.T) {
 	logger := logrus.New()
 	systemMonitor := metrics.NewSystemMonitor(logger)
@@ -116,6 +141,44 @@ func TestHandleGetUptime(t *testing.T) {
 	}
 }
 
+func TestHandleGetSystemInfo(t *testing.T) {
+	logger := logrus.New()
+	systemMonitor := metrics.NewSystemMonitor(logger)
+
+	agent := &Agent{
+		logger:        logger,
+		systemMonitor: systemMonitor,
+	}
+
+	msg := protocol.NewMessage(protocol.TypeGetSystemInfo, nil)
+
+	response := agent.handleGetSystemInfo(msg)
+
+	if response == nil {
+		t.Fatal("handleGetSystemInfo returned nil")
+	}
+
+	if response.ID != msg.ID {
+		t.Errorf("Expected response ID %s, got %s", msg.ID, response.ID)
+	}
+
+	if response.Type != protocol.TypeSystemInfoResponse {
+		t.Fatalf("Unexpected response type: %v", response.Type)
+	}
+
+	info, err := protocol.DecodeAs[protocol.SystemInfoPayload](response)
+	if err != nil {
+		t.Fatalf("Unexpected payload: %v", err)
+	}
+
+	if info.Hostname == "" {
+		t.Error("Hostname is empty")
+	}
+	if info.AgentVersion == "" {
+		t.Error("AgentVersion is empty")
+	}
+}
+
 func TestHandleGetProcesses(t *testing.T) {
 	logger := logrus.New()
 	systemMonitor := metrics.NewSystemMonitor(logger)
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/servereye/servereye/internal/config"
+	"github.com/servereye/servereye/pkg/auth"
 	"github.com/servereye/servereye/pkg/metrics"
 	"github.com/servereye/servereye/pkg/protocol"
 	"github.com/sirupsen/logrus"
@@ -23,7 +24,7 @@ func createTestAgent() *Agent {
 		cpuMetrics:    metrics.NewCPUMetrics(),
 		systemMonitor: metrics.NewSystemMonitor(logger),
 		config: &config.AgentConfig{
-			Server: config.ServerConfig{SecretKey: "test-key"},
+			Server: config.ServerConfig{SecretKey: "sig_test"},
 		},
 		updateFunc: MockUpdateFunc(),
 	}
@@ -335,3 +336,137 @@ func TestProcessCommand_MultipleSequential(t *testing.T) {
 		t.Errorf("Expected %d responses, got %d", len(commandTypes), len(mockClient.publishedMessages))
 	}
 }
+
+func TestProcessCommand_ExpiredCommand(t *testing.T) {
+	agent := createTestAgent()
+
+	msg := protocol.NewMessage(protocol.TypePing, nil)
+	msg.ID = "ping-expired"
+	msg.Timestamp = time.Now().Add(-time.Minute)
+	msg.SetTTL(30 * time.Second)
+	jsonBytes, _ := msg.ToJSON()
+
+	agent.processCommand(jsonBytes)
+
+	mockClient := agent.redisClient.(*mockRedisClient)
+	if len(mockClient.publishedMessages) != 1 {
+		t.Fatalf("Expected one error response, got %d", len(mockClient.publishedMessages))
+	}
+
+	response, err := protocol.FromJSON([]byte(mockClient.publishedMessages[0]))
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Type != protocol.TypeErrorResponse {
+		t.Fatalf("Expected error response, got %s", response.Type)
+	}
+	payload, err := protocol.DecodeAs[protocol.ErrorPayload](response)
+	if err != nil {
+		t.Fatalf("Failed to decode error payload: %v", err)
+	}
+	if payload.ErrorCode != protocol.ErrorCommandExpired {
+		t.Errorf("Expected %s, got %v", protocol.ErrorCommandExpired, payload.ErrorCode)
+	}
+}
+
+func TestHandleCommand_IncompatibleVersion(t *testing.T) {
+	agent := createTestAgent()
+
+	msg := protocol.NewMessage(protocol.TypePing, nil)
+	msg.Version = "2.0"
+
+	response := agent.handleCommand(context.Background(), msg)
+	if response == nil || response.Type != protocol.TypeErrorResponse {
+		t.Fatalf("Expected error response, got %+v", response)
+	}
+	payload, err := protocol.DecodeAs[protocol.ErrorPayload](response)
+	if err != nil {
+		t.Fatalf("Failed to decode error payload: %v", err)
+	}
+	if payload.ErrorCode != protocol.ErrorUnsupportedCommand {
+		t.Errorf("Expected %s, got %v", protocol.ErrorUnsupportedCommand, payload.ErrorCode)
+	}
+
+	// 1.0 commands from older bots are still handled
+	msg = protocol.NewMessage(protocol.TypePing, nil)
+	msg.Version = protocol.LegacyProtocolVersion
+	if response := agent.handleCommand(context.Background(), msg); response == nil || response.Type != protocol.TypePong {
+		t.Errorf("Expected pong for 1.0 command, got %+v", response)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	caps := Capabilities()
+	if caps.ProtocolVersion != protocol.ProtocolVersion {
+		t.Errorf("ProtocolVersion = %s, want %s", caps.ProtocolVersion, protocol.ProtocolVersion)
+	}
+
+	// Агент объявляет как минимум все команды протокола 1.0
+	for _, msgType := range protocol.LegacyCapabilities().Commands {
+		if !caps.Supports(msgType) {
+			t.Errorf("Capabilities() lacks %s", msgType)
+		}
+	}
+}
+
+func TestHandleCommand_Signature(t *testing.T) {
+	agent := createTestAgent()
+	agent.verifier = auth.NewMessageVerifier("sig_test", time.Minute)
+
+	// Неподписанная команда отклоняется с отдельным кодом ошибки
+	response := agent.handleCommand(context.Background(), protocol.NewMessage(protocol.TypePing, nil))
+	if response == nil || response.Type != protocol.TypeErrorResponse {
+		t.Fatalf("Expected error response, got %+v", response)
+	}
+	payload, err := protocol.DecodeAs[protocol.ErrorPayload](response)
+	if err != nil {
+		t.Fatalf("Failed to decode error payload: %v", err)
+	}
+	if payload.ErrorCode != protocol.ErrorSignatureInvalid {
+		t.Errorf("Expected %s, got %v", protocol.ErrorSignatureInvalid, payload.ErrorCode)
+	}
+
+	// Подписанная ключом подписи сервера выполняется
+	msg := protocol.NewMessage(protocol.TypePing, nil)
+	auth.SignMessage("sig_test", msg)
+	if response := agent.handleCommand(context.Background(), msg); response == nil || response.Type != protocol.TypePong {
+		t.Fatalf("Expected pong for signed command, got %+v", response)
+	}
+
+	// Повтор того же ID не выполняется
+	if response := agent.handleCommand(context.Background(), msg); response != nil {
+		t.Errorf("Expected replayed command to be dropped, got %+v", response)
+	}
+
+	// С состоянием команд на повтор уходит сохранённый ответ
+	agent.commandState, _ = loadCommandState("", 10)
+	msg = protocol.NewMessage(protocol.TypePing, nil)
+	auth.SignMessage("sig_test", msg)
+	first := agent.handleCommand(context.Background(), msg)
+	if replayed := agent.handleCommand(context.Background(), msg); replayed == nil || replayed != first {
+		t.Errorf("Expected the saved response for a replayed command, got %+v", replayed)
+	}
+}
+
+func TestCommandContext_Deadline(t *testing.T) {
+	agent := createTestAgent()
+
+	msg := protocol.NewMessage(protocol.TypeGetContainers, nil)
+	ctx, cancel := agent.commandContext(context.Background(), msg)
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("Command without ExpiresAt must not get a deadline")
+	}
+	cancel()
+
+	msg.SetTTL(5 * time.Second)
+	ctx, cancel = agent.commandContext(context.Background(), msg)
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		t.Fatal("Expected handler context to carry the command deadline")
+	}
+	if !deadline.Equal(*msg.ExpiresAt) {
+		t.Errorf("Expected deadline %v, got %v", *msg.ExpiresAt, deadline)
+	}
+}
@@ -3,11 +3,13 @@ package agent
 import (
 	"context"
 	"testing"
+
+	"github.com/servereye/servereye/pkg/transport"
 )
 
 func TestRedisClient_Interface(t *testing.T) {
 	// Test that interfaces are properly defined
-	var _ RedisClientInterface = (*mockRedisClient)(nil)
+	var _ transport.PubSubClient = (*mockRedisClient)(nil)
 }
 
 func TestRedisClient_MockPublish(t *testing.T) {
@@ -39,7 +41,7 @@ func TestRedisClient_MockClose(t *testing.T) {
 
 func TestSubscription_Interface(t *testing.T) {
 	// Test subscription interface
-	var sub SubscriptionInterface
+	var sub transport.Subscription
 	if sub != nil {
 		t.Error("Uninitialized subscription should be nil")
 	}
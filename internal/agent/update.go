@@ -16,7 +16,7 @@ func (a *Agent) handleUpdateAgent(msg *protocol.Message) *protocol.Message {
 	a.logger.Info("Обработка команды обновления агента")
 
 	var payload protocol.UpdateAgentPayload
-	if err := parsePayload(msg.Payload, &payload); err != nil {
+	if err := msg.Decode(&payload); err != nil {
 		a.logger.WithError(err).Error("Ошибка парсинга payload")
 		return protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
 			ErrorCode:    "INVALID_PAYLOAD",
@@ -85,9 +85,9 @@ func TestHandleUpdateAgent_LatestVersion(t *testing.T) {
 		t.Fatal("handleUpdateAgent returned nil")
 	}
 
-	updateResp, ok := response.Payload.(protocol.UpdateAgentResponse)
-	if !ok {
-		t.Fatal("Response payload is not UpdateAgentResponse")
+	updateResp, err := protocol.DecodeAs[protocol.UpdateAgentResponse](response)
+	if err != nil {
+		t.Fatalf("Response payload is not UpdateAgentResponse: %v", err)
 	}
 
 	if updateResp.NewVersion != "latest" {
@@ -327,9 +327,9 @@ func TestHandleUpdateAgent_BackgroundExecution(t *testing.T) {
 		t.Fatal("handleUpdateAgent returned nil")
 	}
 
-	updateResp, ok := response.Payload.(protocol.UpdateAgentResponse)
-	if !ok {
-		t.Fatal("Response payload is not UpdateAgentResponse")
+	updateResp, err := protocol.DecodeAs[protocol.UpdateAgentResponse](response)
+	if err != nil {
+		t.Fatalf("Response payload is not UpdateAgentResponse: %v", err)
 	}
 
 	if !updateResp.Success {
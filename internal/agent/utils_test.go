@@ -6,7 +6,7 @@ import (
 	"github.com/servereye/servereye/pkg/protocol"
 )
 
-func TestParsePayload_ContainerAction(t *testing.T) {
+func TestDecodeCommandPayload_ContainerAction(t *testing.T) {
 	tests := []struct {
 		name    string
 		payload interface{}
@@ -41,23 +41,23 @@ func TestParsePayload_ContainerAction(t *testing.T) {
 		{
 			name:    "nil payload",
 			payload: nil,
-			wantErr: false, // parsePayload handles nil gracefully
+			wantErr: false, // decodeCommandPayload handles nil gracefully
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var result protocol.ContainerActionPayload
-			err := parsePayload(tt.payload, &result)
+			err := decodeCommandPayload(tt.payload, &result)
 
 			if (err != nil) != tt.wantErr {
-				t.Errorf("parsePayload() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("decodeCommandPayload() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
-func TestParsePayload_CreateContainer(t *testing.T) {
+func TestDecodeCommandPayload_CreateContainer(t *testing.T) {
 	tests := []struct {
 		name    string
 		payload interface{}
@@ -99,16 +99,16 @@ func TestParsePayload_CreateContainer(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var result protocol.CreateContainerPayload
-			err := parsePayload(tt.payload, &result)
+			err := decodeCommandPayload(tt.payload, &result)
 
 			if (err != nil) != tt.wantErr {
-				t.Errorf("parsePayload() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("decodeCommandPayload() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
-func TestParsePayload_UpdateAgent(t *testing.T) {
+func TestDecodeCommandPayload_UpdateAgent(t *testing.T) {
 	tests := []struct {
 		name    string
 		payload interface{}
@@ -139,16 +139,16 @@ func TestParsePayload_UpdateAgent(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var result protocol.UpdateAgentPayload
-			err := parsePayload(tt.payload, &result)
+			err := decodeCommandPayload(tt.payload, &result)
 
 			if (err != nil) != tt.wantErr {
-				t.Errorf("parsePayload() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("decodeCommandPayload() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
-func TestParsePayload_EmptyMaps(t *testing.T) {
+func TestDecodeCommandPayload_EmptyMaps(t *testing.T) {
 	tests := []struct {
 		name    string
 		target  interface{}
@@ -174,16 +174,16 @@ func TestParsePayload_EmptyMaps(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			payload := map[string]interface{}{}
-			err := parsePayload(payload, tt.target)
+			err := decodeCommandPayload(payload, tt.target)
 
 			if (err != nil) != tt.wantErr {
-				t.Errorf("parsePayload() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("decodeCommandPayload() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
-func TestParsePayload_NestedStructures(t *testing.T) {
+func TestDecodeCommandPayload_NestedStructures(t *testing.T) {
 	payload := map[string]interface{}{
 		"name":  "complex-container",
 		"image": "nginx:latest",
@@ -200,10 +200,10 @@ func TestParsePayload_NestedStructures(t *testing.T) {
 	}
 
 	var result protocol.CreateContainerPayload
-	err := parsePayload(payload, &result)
+	err := decodeCommandPayload(payload, &result)
 
 	if err != nil {
-		t.Fatalf("parsePayload() error = %v", err)
+		t.Fatalf("decodeCommandPayload() error = %v", err)
 	}
 
 	if result.Name != "complex-container" {
@@ -219,7 +219,7 @@ func TestParsePayload_NestedStructures(t *testing.T) {
 	}
 }
 
-func TestParsePayload_TypeConversions(t *testing.T) {
+func TestDecodeCommandPayload_TypeConversions(t *testing.T) {
 	tests := []struct {
 		name    string
 		payload interface{}
@@ -243,17 +243,17 @@ func TestParsePayload_TypeConversions(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var result protocol.ContainerActionPayload
-			err := parsePayload(tt.payload, &result)
+			err := decodeCommandPayload(tt.payload, &result)
 
 			// Just test that it doesn't panic
 			if err != nil {
-				t.Logf("parsePayload() returned error (may be expected): %v", err)
+				t.Logf("decodeCommandPayload() returned error (may be expected): %v", err)
 			}
 		})
 	}
 }
 
-func TestParsePayload_LargePayloads(t *testing.T) {
+func TestDecodeCommandPayload_LargePayloads(t *testing.T) {
 	// Create payload with multiple ports
 	ports := map[string]string{
 		"80/tcp":    "8080",
@@ -275,10 +275,10 @@ func TestParsePayload_LargePayloads(t *testing.T) {
 	}
 
 	var result protocol.CreateContainerPayload
-	err := parsePayload(payload, &result)
+	err := decodeCommandPayload(payload, &result)
 
 	if err != nil {
-		t.Fatalf("parsePayload() error = %v", err)
+		t.Fatalf("decodeCommandPayload() error = %v", err)
 	}
 
 	if len(result.Ports) != 10 {
@@ -286,17 +286,17 @@ func TestParsePayload_LargePayloads(t *testing.T) {
 	}
 }
 
-func TestParsePayload_SpecialCharacters(t *testing.T) {
+func TestDecodeCommandPayload_SpecialCharacters(t *testing.T) {
 	payload := map[string]interface{}{
 		"name":  "test-container-with-дashes-and-кириллица",
 		"image": "nginx:latest-α-β-γ",
 	}
 
 	var result protocol.CreateContainerPayload
-	err := parsePayload(payload, &result)
+	err := decodeCommandPayload(payload, &result)
 
 	if err != nil {
-		t.Fatalf("parsePayload() error = %v", err)
+		t.Fatalf("decodeCommandPayload() error = %v", err)
 	}
 
 	if result.Name == "" {
@@ -308,7 +308,7 @@ func TestParsePayload_SpecialCharacters(t *testing.T) {
 	}
 }
 
-func TestParsePayload_BooleanValues(t *testing.T) {
+func TestDecodeCommandPayload_BooleanValues(t *testing.T) {
 	payload := map[string]interface{}{
 		"container_id": "abc123",
 		"auto_remove":  true,
@@ -316,14 +316,14 @@ func TestParsePayload_BooleanValues(t *testing.T) {
 	}
 
 	var result map[string]interface{}
-	err := parsePayload(payload, &result)
+	err := decodeCommandPayload(payload, &result)
 
 	if err != nil {
-		t.Logf("parsePayload() error: %v", err)
+		t.Logf("decodeCommandPayload() error: %v", err)
 	}
 }
 
-func TestParsePayload_NumericValues(t *testing.T) {
+func TestDecodeCommandPayload_NumericValues(t *testing.T) {
 	payload := map[string]interface{}{
 		"name":       "test",
 		"cpu_shares": 1024,
@@ -331,9 +331,9 @@ func TestParsePayload_NumericValues(t *testing.T) {
 	}
 
 	var result map[string]interface{}
-	err := parsePayload(payload, &result)
+	err := decodeCommandPayload(payload, &result)
 
 	if err != nil {
-		t.Logf("parsePayload() error: %v", err)
+		t.Logf("decodeCommandPayload() error: %v", err)
 	}
 }
@@ -6,7 +6,6 @@ import (
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/servereye/servereye/pkg/protocol"
-	"github.com/servereye/servereye/pkg/redis"
 	"github.com/sirupsen/logrus"
 )
 
@@ -111,59 +110,6 @@ func (d *DatabaseAdapter) Close() error {
 	return d.db.Close()
 }
 
-// RedisAdapter adapts redis.Client to our RedisClient interface
-type RedisAdapter struct {
-	client *redis.Client
-}
-
-// NewRedisAdapter creates a new Redis adapter
-func NewRedisAdapter(client *redis.Client) *RedisAdapter {
-	return &RedisAdapter{client: client}
-}
-
-// Subscribe subscribes to a Redis channel
-func (r *RedisAdapter) Subscribe(ctx context.Context, channel string) (Subscription, error) {
-	sub, err := r.client.Subscribe(ctx, channel)
-	if err != nil {
-		return nil, err
-	}
-	return &SubscriptionAdapter{sub: sub}, nil
-}
-
-// Publish publishes a message to a Redis channel
-func (r *RedisAdapter) Publish(ctx context.Context, channel string, message []byte) error {
-	return r.client.Publish(ctx, channel, message)
-}
-
-// Close closes the Redis connection
-func (r *RedisAdapter) Close() error {
-	return r.client.Close()
-}
-
-// SubscriptionAdapter adapts redis subscription to our Subscription interface
-type SubscriptionAdapter struct {
-	sub interface {
-		Channel() <-chan []byte
-		Close() error
-	}
-}
-
-// Channel returns the subscription channel
-func (s *SubscriptionAdapter) Channel() <-chan []byte {
-	return s.sub.Channel()
-}
-
-// Close closes the subscription
-func (s *SubscriptionAdapter) Close() error {
-	defer func() {
-		if r := recover(); r != nil {
-			// Log and ignore panic from closing already closed channel
-			_ = r // Explicitly mark as intentionally ignored
-		}
-	}()
-	return s.sub.Close()
-}
-
 // AgentClientAdapter adapts existing agent methods to AgentClient interface
 type AgentClientAdapter struct {
 	bot *Bot
@@ -179,6 +125,11 @@ func (a *AgentClientAdapter) GetCPUTemperature(ctx context.Context, serverKey st
 	return a.bot.getCPUTemperature(serverKey)
 }
 
+// GetCPUUsage gets CPU utilization from agent
+func (a *AgentClientAdapter) GetCPUUsage(ctx context.Context, serverKey string) (*protocol.CPUUsagePayload, error) {
+	return a.bot.getCPUUsage(serverKey)
+}
+
 // GetContainers gets containers from agent
 func (a *AgentClientAdapter) GetContainers(ctx context.Context, serverKey string) (*protocol.ContainersPayload, error) {
 	return a.bot.getContainers(serverKey)
@@ -199,6 +150,11 @@ func (a *AgentClientAdapter) GetUptime(ctx context.Context, serverKey string) (*
 	return a.bot.getUptime(serverKey)
 }
 
+// GetSystemInfo gets system info from agent
+func (a *AgentClientAdapter) GetSystemInfo(ctx context.Context, serverKey string) (*protocol.SystemInfoPayload, error) {
+	return a.bot.getSystemInfo(serverKey)
+}
+
 // GetProcesses gets processes from agent
 func (a *AgentClientAdapter) GetProcesses(ctx context.Context, serverKey string) (*protocol.ProcessesPayload, error) {
 	return a.bot.getProcesses(serverKey)
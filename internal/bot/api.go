@@ -1,13 +1,11 @@
 package bot
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/servereye/servereye/pkg/protocol"
-	"github.com/servereye/servereye/pkg/redis"
 )
 
 // getCPUTemperature requests CPU temperature from agent via Streams
@@ -33,95 +31,14 @@ func (b *Bot) getCPUTemperature(serverKey string) (float64, error) {
 
 // getContainers requests Docker containers list from agent
 func (b *Bot) getContainers(serverKey string) (*protocol.ContainersPayload, error) {
-	// Try Streams first if available
-	if b.streamsClient != nil {
-		containers, err := b.getContainersViaStreams(serverKey)
-		if err == nil {
-			return containers, nil
-		}
-		b.logger.Error("Streams failed, using Pub/Sub", err)
-	}
-
-	// Fallback to Pub/Sub
-	return b.getContainersViaPubSub(serverKey)
-}
-
-// getContainersViaPubSub is the old Pub/Sub implementation
-func (b *Bot) getContainersViaPubSub(serverKey string) (*protocol.ContainersPayload, error) {
-	// Create command message first to get ID
-	cmd := protocol.NewMessage(protocol.TypeGetContainers, nil)
-
-	// Subscribe to UNIQUE response channel with command ID
-	respChannel := fmt.Sprintf("resp:%s:%s", serverKey, cmd.ID)
-	b.logger.Info("Подписались на канал Redis")
-
-	subscription, err := b.redisClient.Subscribe(b.ctx, respChannel)
-	if err != nil {
-		return nil, fmt.Errorf("failed to subscribe to response: %v", err)
-	}
-	defer func() {
-		if subscription != nil {
-			if err := subscription.Close(); err != nil {
-				b.logger.Error("Failed to close subscription", err)
-			}
-		}
-	}()
-
-	// Send command to agent
-	cmdChannel := redis.GetCommandChannel(serverKey)
-	data, err := cmd.ToJSON()
-	if err != nil {
-		return nil, fmt.Errorf("failed to serialize command: %v", err)
-	}
-
-	if err := b.redisClient.Publish(b.ctx, cmdChannel, data); err != nil {
-		return nil, fmt.Errorf("failed to send command: %v", err)
-	}
-
-	b.logger.Info("Команда отправлена агенту")
-
-	// Wait for response with timeout
-	timeout := time.After(10 * time.Second)
-	for {
-		select {
-		case respData := <-subscription.Channel():
-			b.logger.Debug("Получен ответ от агента")
-
-			resp, err := protocol.FromJSON(respData)
-			if err != nil {
-				b.logger.Error("Failed to parse response", err)
-				continue
-			}
-
-			// Check if this response is for our command
-			if resp.ID != cmd.ID {
-				b.logger.Debug("Response ID mismatch, waiting for correct response")
-				continue
-			}
-
-			if resp.Type == protocol.TypeErrorResponse {
-				return nil, fmt.Errorf("agent error: %v", resp.Payload)
-			}
-
-			if resp.Type == protocol.TypeContainersResponse {
-				// Parse containers from payload
-				if payload, ok := resp.Payload.(map[string]interface{}); ok {
-					containersData, _ := json.Marshal(payload)
-					var containers protocol.ContainersPayload
-					if err := json.Unmarshal(containersData, &containers); err == nil {
-						b.logger.Info("Получен список контейнеров")
-						return &containers, nil
-					}
-				}
-				return nil, fmt.Errorf("invalid containers data in response")
-			}
-
-			return nil, fmt.Errorf("unexpected response type: %s", resp.Type)
-
-		case <-timeout:
-			return nil, fmt.Errorf("timeout waiting for response")
-		}
-	}
+	return sendCommandAndParse[protocol.ContainersPayload](
+		b,
+		serverKey,
+		protocol.TypeGetContainers,
+		nil,
+		protocol.TypeContainersResponse,
+		10*time.Second,
+	)
 }
 
 // formatContainers formats containers list for display
@@ -161,6 +78,18 @@ func (b *Bot) formatContainers(containers *protocol.ContainersPayload) string {
 	return result.String()
 }
 
+// getCPUUsage requests CPU utilization from agent via Streams
+func (b *Bot) getCPUUsage(serverKey string) (*protocol.CPUUsagePayload, error) {
+	return sendCommandAndParse[protocol.CPUUsagePayload](
+		b,
+		serverKey,
+		protocol.TypeGetCPUUsage,
+		nil,
+		protocol.TypeCPUUsageResponse,
+		10*time.Second,
+	)
+}
+
 // getMemoryInfo requests memory information from agent via Streams
 func (b *Bot) getMemoryInfo(serverKey string) (*protocol.MemoryInfo, error) {
 	return sendCommandAndParse[protocol.MemoryInfo](
@@ -185,6 +114,18 @@ func (b *Bot) getDiskInfo(serverKey string) (*protocol.DiskInfoPayload, error) {
 	)
 }
 
+// getSystemInfo requests static system information from agent via Streams
+func (b *Bot) getSystemInfo(serverKey string) (*protocol.SystemInfoPayload, error) {
+	return sendCommandAndParse[protocol.SystemInfoPayload](
+		b,
+		serverKey,
+		protocol.TypeGetSystemInfo,
+		nil,
+		protocol.TypeSystemInfoResponse,
+		10*time.Second,
+	)
+}
+
 // getUptime requests uptime information from agent via Streams
 func (b *Bot) getUptime(serverKey string) (*protocol.UptimeInfo, error) {
 	return sendCommandAndParse[protocol.UptimeInfo](
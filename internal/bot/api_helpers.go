@@ -2,7 +2,6 @@ package bot
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"time"
 
@@ -20,39 +19,36 @@ func sendCommandAndParse[T any](
 	timeout time.Duration,
 ) (*T, error) {
 	cmd := protocol.NewMessage(commandType, payload)
+	// После таймаута ответ уже никто не ждёт, агент не должен выполнять команду
+	cmd.SetTTL(timeout)
 
 	ctx, cancel := context.WithTimeout(b.ctx, timeout)
 	defer cancel()
 
-	resp, err := b.sendCommandViaStreams(ctx, serverKey, cmd, timeout)
+	resp, err := b.sendCommand(ctx, serverKey, cmd, timeout)
 	if err != nil {
 		return nil, err
 	}
 
 	if resp.Type == protocol.TypeErrorResponse {
-		return nil, fmt.Errorf("agent error: %v", resp.Payload)
+		return nil, agentError(resp)
 	}
 
 	if resp.Type != expectedResponseType {
 		return nil, fmt.Errorf("unexpected response type: expected %s, got %s", expectedResponseType, resp.Type)
 	}
 
-	// Parse payload
-	payloadMap, ok := resp.Payload.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid payload format")
-	}
+	return protocol.DecodeAs[T](resp)
+}
 
-	// Marshal and unmarshal for type conversion
-	data, err := json.Marshal(payloadMap)
+// agentError converts an error_response from the agent into an error
+func agentError(resp *protocol.Message) error {
+	payload, err := protocol.DecodeAs[protocol.ErrorPayload](resp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		return fmt.Errorf("agent returned error: %w", err)
 	}
-
-	var result T
-	if err := json.Unmarshal(data, &result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	if payload.ErrorMessage == "" {
+		return fmt.Errorf("agent error: %s", payload.ErrorCode)
 	}
-
-	return &result, nil
+	return fmt.Errorf("agent error: %s", payload.ErrorMessage)
 }
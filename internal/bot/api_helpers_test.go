@@ -2,6 +2,7 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -110,3 +111,19 @@ func TestGenericHelperTypeConstraints(t *testing.T) {
 		t.Errorf("ID = %v, want test-id", testStruct.ID)
 	}
 }
+
+func TestAgentError(t *testing.T) {
+	resp := protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{
+		ErrorCode:    protocol.ErrorContainerNotFound,
+		ErrorMessage: "container not found",
+	})
+	if err := agentError(resp); err == nil || err.Error() != "agent error: container not found" {
+		t.Errorf("agentError() = %v, want agent error: container not found", err)
+	}
+
+	// Ответ без обязательного error_code
+	malformed := protocol.NewMessage(protocol.TypeErrorResponse, map[string]string{"error_message": "boom"})
+	if err := agentError(malformed); !errors.Is(err, protocol.ErrInvalidPayload) {
+		t.Errorf("agentError() = %v, want ErrInvalidPayload", err)
+	}
+}
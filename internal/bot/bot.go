@@ -14,8 +14,10 @@ import (
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	_ "github.com/lib/pq"
 	"github.com/servereye/servereye/internal/config"
+	"github.com/servereye/servereye/pkg/auth"
 	"github.com/servereye/servereye/pkg/redis"
 	"github.com/servereye/servereye/pkg/redis/streams"
+	"github.com/servereye/servereye/pkg/transport"
 	"github.com/sirupsen/logrus"
 )
 
@@ -40,7 +42,27 @@ type Bot struct {
 	redisRawClient *redis.Client
 
 	// Streams client for new architecture
-	streamsClient *streams.Client
+	streamsClient  streams.StreamClient
+	streamsAdapter *streams.BotAdapter // shared response dispatcher
+
+	// Command delivery: Streams with Pub/Sub fallback
+	router     *transport.Router
+	routerOnce sync.Once
+
+	// Agent HTTP API authentication
+	apiVerifier *auth.Verifier
+	agentKeys   sync.Map // key ID -> secret key cache
+	// Key IDs recently not found in generated_keys
+	unknownAgentKeys unknownKeyCache
+
+	// Agent capabilities: server key -> protocol.Capabilities
+	capabilities sync.Map
+	// Command signing keys registered at enrollment: server key -> signing key
+	signingKeys sync.Map
+	// Active agent encryption keys: server key -> protocol.EncryptionKey
+	encryptionKeys sync.Map
+	// Long commands the user can cancel: command ID -> runningCommand
+	runningCommands sync.Map
 
 	// Context management
 	ctx    context.Context
@@ -113,14 +135,22 @@ func NewFromConfig(cfg *config.BotConfig, logger *logrus.Logger) (*Bot, error) {
 
 	logger.WithField("username", tgBot.Self.UserName).Info("Telegram bot authorized")
 
-	// Initialize Redis client
-	redisClient, err := redis.NewClient(redis.Config{
-		Address:  cfg.Redis.Address,
-		Password: cfg.Redis.Password,
-		DB:       cfg.Redis.DB,
-	}, logger)
-	if err != nil {
-		return nil, NewRedisError("failed to create Redis client", err)
+	// Initialize Redis client, in memory mode Pub/Sub stays inside the process
+	var redisClient *redis.Client
+	var redisAdapter RedisClient
+	if cfg.Redis.IsMemory() {
+		redisAdapter = transport.WrapPubSub(redis.NewMemoryClient(logger))
+		logger.Warn("Using in-memory transport, commands are lost on restart")
+	} else {
+		redisClient, err = redis.NewClient(redis.Config{
+			Address:  cfg.Redis.Address,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		}, logger)
+		if err != nil {
+			return nil, NewRedisError("failed to create Redis client", err)
+		}
+		redisAdapter = transport.WrapPubSub(redisClient)
 	}
 
 	// Initialize database connection
@@ -140,7 +170,6 @@ func NewFromConfig(cfg *config.BotConfig, logger *logrus.Logger) (*Bot, error) {
 
 	// Create adapters
 	dbAdapter := NewDatabaseAdapter(db, tempBot)
-	redisAdapter := NewRedisAdapter(redisClient)
 	agentAdapter := NewAgentClientAdapter(tempBot)
 
 	// Create bot with real implementations
@@ -176,14 +205,33 @@ func NewFromConfig(cfg *config.BotConfig, logger *logrus.Logger) (*Bot, error) {
 		StreamMaxLength: 1000,
 	}
 
-	streamsClient, err := streams.NewClient(streamsConfig, logger)
-	if err != nil {
+	if cfg.Redis.IsMemory() {
+		bot.streamsClient = streams.NewMemoryClient(streamsConfig)
+		logger.Info("In-memory Streams client initialized")
+	} else if streamsClient, err := streams.NewClient(streamsConfig, logger); err != nil {
 		logger.WithError(err).Warn("Failed to create Streams client, will use Pub/Sub")
 	} else {
 		bot.streamsClient = streamsClient
 		logger.Info("Redis Streams client initialized")
 	}
 
+	// Streams first, Pub/Sub as fallback
+	var transports []transport.Transport
+	if bot.streamsClient != nil {
+		streamsTransport := transport.NewStreams(bot.streamsClient, logger)
+		bot.streamsAdapter = streamsTransport.Adapter()
+		transports = append(transports, streamsTransport)
+	}
+	transports = append(transports, transport.NewPubSub(redisAdapter, logger))
+	bot.router = transport.NewRouter(logger, transports...)
+	bot.router.SetObserver(bot.recordTransportMetrics)
+
+	for serverKey, name := range cfg.Transport.Servers {
+		if err := bot.router.SetServerTransport(serverKey, name); err != nil {
+			logger.WithError(err).WithField("server_key", maskKey(serverKey)).Warn("Ignoring transport override")
+		}
+	}
+
 	return bot, nil
 }
 
@@ -207,12 +255,36 @@ func (b *Bot) Start() error {
 		// Non-critical error, continue
 	}
 
+	// Probe transports so fallback skips the ones that are down
+	if b.router != nil {
+		ctx, cancel := context.WithTimeout(b.ctx, 5*time.Second)
+		b.router.Negotiate(ctx)
+		cancel()
+	}
+
 	// Start HTTP server for agent API
 	go func() {
 		b.logger.Info("About to start HTTP server goroutine...")
 		b.startHTTPServer()
 	}()
 
+	// Start heartbeat monitor to detect offline servers
+	if b.db != nil {
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			b.startHeartbeatMonitor()
+		}()
+
+		if b.redisRawClient != nil {
+			b.wg.Add(1)
+			go func() {
+				defer b.wg.Done()
+				b.startRedisHeartbeatListener()
+			}()
+		}
+	}
+
 	// Start Telegram updates handler
 	if err := b.startTelegramHandler(); err != nil {
 		return NewTelegramError("failed to start Telegram handler", err)
@@ -289,8 +361,12 @@ func (b *Bot) Stop() error {
 		b.logger.Warn("Timeout waiting for goroutines to stop")
 	}
 
-	// Close connections
-	if b.redisClient != nil {
+	// Close connections, the router owns Streams and Pub/Sub clients
+	if b.router != nil {
+		if err := b.router.Close(); err != nil {
+			b.logger.Error("Error closing transports", err)
+		}
+	} else if b.redisClient != nil {
 		if err := b.redisClient.Close(); err != nil {
 			b.logger.Error("Error closing Redis connection", err)
 		}
@@ -445,9 +521,11 @@ func (b *Bot) setMenuCommands() error {
 		{Command: "start", Description: "Start bot and show welcome message"},
 		{Command: "help", Description: "Show available commands"},
 		{Command: "temp", Description: "Get CPU temperature"},
+		{Command: "cpu", Description: "Get CPU usage"},
 		{Command: "memory", Description: "Get memory usage"},
 		{Command: "disk", Description: "Get disk usage"},
 		{Command: "uptime", Description: "Get system uptime"},
+		{Command: "info", Description: "Get system information"},
 		{Command: "processes", Description: "List running processes"},
 		{Command: "containers", Description: "Manage Docker containers"},
 		{Command: "update", Description: "Update agent to latest version"},
@@ -3,6 +3,8 @@ package bot
 import (
 	"fmt"
 	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
 )
 
 // executeTemperatureCommand executes temperature command for specific server
@@ -20,6 +22,21 @@ func (b *Bot) executeTemperatureCommand(servers []ServerInfo, serverNum string)
 	return fmt.Sprintf("🌡️ %s CPU Temperature: %.1f°C", server.Name, temp)
 }
 
+// executeCPUCommand executes CPU usage command for specific server
+func (b *Bot) executeCPUCommand(servers []ServerInfo, serverNum string) string {
+	server, err := selectServer(servers, serverNum)
+	if err != nil {
+		return "❌ Invalid server selection"
+	}
+
+	usage, err := b.getCPUUsage(server.Key)
+	if err != nil {
+		return fmt.Sprintf("❌ Failed to get CPU usage from %s: %v", server.Name, err)
+	}
+
+	return formatCPUUsage(fmt.Sprintf("⚙️ %s CPU Usage", server.Name), usage)
+}
+
 // executeContainersCommand executes containers command for specific server
 func (b *Bot) executeContainersCommand(servers []ServerInfo, serverNum string) string {
 	server, err := selectServer(servers, serverNum)
@@ -118,6 +135,21 @@ func (b *Bot) executeDiskCommand(servers []ServerInfo, serverNum string) string
 	return response
 }
 
+// executeInfoCommand executes system info command for specific server
+func (b *Bot) executeInfoCommand(servers []ServerInfo, serverNum string) string {
+	server, err := selectServer(servers, serverNum)
+	if err != nil {
+		return "❌ Invalid server selection"
+	}
+
+	info, err := b.getSystemInfo(server.Key)
+	if err != nil {
+		return fmt.Sprintf("❌ Failed to get system info from %s: %v", server.Name, err)
+	}
+
+	return formatSystemInfo(fmt.Sprintf("🖥️ %s System Info", server.Name), info)
+}
+
 // executeUptimeCommand executes uptime command for specific server
 func (b *Bot) executeUptimeCommand(servers []ServerInfo, serverNum string) string {
 	server, err := selectServer(servers, serverNum)
@@ -202,7 +234,8 @@ func (b *Bot) executeStatusCommand(servers []ServerInfo, serverNum string) strin
 		return "❌ Invalid server selection"
 	}
 
-	return fmt.Sprintf("🟢 %s Status: Online\n⏱️ Uptime: 15 days 8 hours\n💾 Last activity: just now", server.Name)
+	report := b.collectServerStatus(server.Key, server.Name)
+	return formatServerStatus(report)
 }
 
 // executeUpdateCommand executes update command for specific server
@@ -212,6 +245,11 @@ func (b *Bot) executeUpdateCommand(servers []ServerInfo, serverNum string, chatI
 		return "❌ Invalid server selection"
 	}
 
+	command := protocol.NewMessage(protocol.TypeUpdateAgent, &protocol.UpdateAgentPayload{Version: "latest"})
+	if text, queued := b.queueIfOffline(chatID, server.Key, server.Name, command); queued {
+		return text
+	}
+
 	// Send "updating" message
 	b.sendMessage(chatID, fmt.Sprintf("🔄 Updating agent on %s...\n\nThis may take a minute.", server.Name))
 
@@ -220,17 +258,5 @@ func (b *Bot) executeUpdateCommand(servers []ServerInfo, serverNum string, chatI
 		return fmt.Sprintf("❌ Failed to update agent on %s: %v", server.Name, err)
 	}
 
-	if !updateResp.Success {
-		return fmt.Sprintf("❌ Update failed on %s:\n%s", server.Name, updateResp.Message)
-	}
-
-	response := fmt.Sprintf("✅ Agent updated successfully on %s!\n\n", server.Name)
-	response += fmt.Sprintf("📦 Old version: %s\n", updateResp.OldVersion)
-	response += fmt.Sprintf("📦 New version: %s\n", updateResp.NewVersion)
-
-	if updateResp.RestartRequired {
-		response += "\n⚠️ Agent restart required to apply changes."
-	}
-
-	return response
+	return formatUpdateResponse(server.Name, updateResp)
 }
@@ -1,8 +1,12 @@
 package bot
 
 import (
+	"context"
+	"errors"
 	"strings"
 	"testing"
+
+	"github.com/sirupsen/logrus"
 )
 
 func TestExecuteTemperatureCommand_InvalidServer(t *testing.T) {
@@ -18,6 +22,19 @@ func TestExecuteTemperatureCommand_InvalidServer(t *testing.T) {
 	}
 }
 
+func TestExecuteCPUCommand_InvalidServer(t *testing.T) {
+	bot := &Bot{}
+	servers := []ServerInfo{
+		{SecretKey: "key1", Name: "Server 1"},
+	}
+
+	result := bot.executeCPUCommand(servers, "5")
+
+	if !strings.Contains(result, "Invalid server selection") {
+		t.Errorf("Expected invalid server error, got: %v", result)
+	}
+}
+
 func TestExecuteContainersCommand_InvalidServer(t *testing.T) {
 	bot := &Bot{}
 	servers := []ServerInfo{
@@ -44,6 +61,19 @@ func TestExecuteMemoryCommand_InvalidServer(t *testing.T) {
 	}
 }
 
+func TestExecuteInfoCommand_InvalidServer(t *testing.T) {
+	bot := &Bot{}
+	servers := []ServerInfo{
+		{SecretKey: "key1", Name: "Server 1"},
+	}
+
+	result := bot.executeInfoCommand(servers, "0")
+
+	if !strings.Contains(result, "Invalid server selection") {
+		t.Errorf("Expected invalid server error, got: %v", result)
+	}
+}
+
 func TestExecuteDiskCommand_InvalidServer(t *testing.T) {
 	bot := &Bot{}
 	servers := []ServerInfo{
@@ -96,8 +126,28 @@ func TestExecuteStatusCommand_InvalidServer(t *testing.T) {
 	}
 }
 
+// unreachableRedisClient fails every operation, simulating an agent that is down
+type unreachableRedisClient struct{}
+
+func (unreachableRedisClient) Subscribe(ctx context.Context, channel string) (Subscription, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (unreachableRedisClient) Publish(ctx context.Context, channel string, message []byte) error {
+	return errors.New("connection refused")
+}
+
+func (unreachableRedisClient) Close() error { return nil }
+
 func TestExecuteStatusCommand_ValidServer(t *testing.T) {
-	bot := &Bot{}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	bot := &Bot{
+		ctx:         context.Background(),
+		logger:      NewStructuredLogger(logger),
+		redisClient: unreachableRedisClient{},
+	}
 	servers := []ServerInfo{
 		{SecretKey: "key1", Name: "TestServer"},
 	}
@@ -108,8 +158,13 @@ func TestExecuteStatusCommand_ValidServer(t *testing.T) {
 		t.Errorf("Expected TestServer in result, got: %v", result)
 	}
 
-	if !strings.Contains(result, "Status: Online") {
-		t.Errorf("Expected status info, got: %v", result)
+	// Agent is unreachable: status must say so instead of a fake "Online"
+	if !strings.Contains(result, "Status: Offline") {
+		t.Errorf("Expected offline status, got: %v", result)
+	}
+
+	if !strings.Contains(result, "Unavailable") {
+		t.Errorf("Expected unavailable sections, got: %v", result)
 	}
 }
 
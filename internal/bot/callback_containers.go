@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/servereye/servereye/pkg/protocol"
 )
 
 // handleContainerActionCallback handles container action button clicks
@@ -281,29 +282,55 @@ func (b *Bot) handleTemplateSelection(query *tgbotapi.CallbackQuery) error {
 		templateName = template
 	}
 
+	payload, err := b.getTemplateConfig(template)
+	if err != nil {
+		editMsg := tgbotapi.NewEditMessageText(
+			query.Message.Chat.ID,
+			query.Message.MessageID,
+			fmt.Sprintf("❌ Unknown template: %s", template),
+		)
+		if _, err := b.telegramAPI.Send(editMsg); err != nil {
+			b.logger.Error("Error occurred", err)
+		}
+		return nil
+	}
+	cmd := protocol.NewMessage(protocol.TypeCreateContainer, payload)
+
 	editMsg := tgbotapi.NewEditMessageText(
 		query.Message.Chat.ID,
 		query.Message.MessageID,
 		fmt.Sprintf("📦 Creating %s...", templateName),
 	)
 	editMsg.ParseMode = "Markdown"
+	// Pulling an image takes minutes: let the user stop it
+	if b.serverCapabilities(serverKey).Supports(protocol.TypeCancelCommand) {
+		keyboard := cancelCommandKeyboard(cmd.ID)
+		editMsg.ReplyMarkup = &keyboard
+	}
 	if _, err := b.telegramAPI.Send(editMsg); err != nil {
 		b.logger.Error("Error occurred", err)
 	}
 
-	// Create container based on template
-	response := b.createContainerFromTemplate(query.From.ID, serverKey, template)
+	// Wait for the agent outside the update loop so the Cancel button stays responsive
+	b.trackCommand(cmd.ID, serverKey, query.From.ID)
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer b.untrackCommand(cmd.ID)
 
-	// Update message with result
-	editMsg = tgbotapi.NewEditMessageText(
-		query.Message.Chat.ID,
-		query.Message.MessageID,
-		response,
-	)
-	editMsg.ParseMode = "Markdown"
-	if _, err := b.telegramAPI.Send(editMsg); err != nil {
-		b.logger.Error("Error occurred", err)
-	}
+		response := b.createContainerFromTemplate(serverKey, cmd, payload)
+
+		// Update message with result
+		editMsg := tgbotapi.NewEditMessageText(
+			query.Message.Chat.ID,
+			query.Message.MessageID,
+			response,
+		)
+		editMsg.ParseMode = "Markdown"
+		if _, err := b.telegramAPI.Send(editMsg); err != nil {
+			b.logger.Error("Error occurred", err)
+		}
+	}()
 
 	return nil
 }
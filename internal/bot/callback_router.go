@@ -70,6 +70,11 @@ func (b *Bot) handleCallbackQuery(query *tgbotapi.CallbackQuery) error {
 		return b.handleRemoveServerConfirm(query)
 	}
 
+	// Check for cancellation of a running command
+	if strings.HasPrefix(query.Data, cancelCommandPrefix) {
+		return b.handleCancelCommandCallback(query)
+	}
+
 	// Check if it's a create template selection
 	if strings.HasPrefix(query.Data, "create_template_") {
 		return b.handleTemplateSelection(query)
@@ -107,6 +112,8 @@ func (b *Bot) handleCallbackQuery(query *tgbotapi.CallbackQuery) error {
 	switch command {
 	case "temp":
 		response = b.executeTemperatureCommand(servers, serverNum)
+	case "cpu":
+		response = b.executeCPUCommand(servers, serverNum)
 	case "containers":
 		response = b.executeContainersCommand(servers, serverNum)
 	case "memory":
@@ -115,6 +122,8 @@ func (b *Bot) handleCallbackQuery(query *tgbotapi.CallbackQuery) error {
 		response = b.executeDiskCommand(servers, serverNum)
 	case "uptime":
 		response = b.executeUptimeCommand(servers, serverNum)
+	case "info":
+		response = b.executeInfoCommand(servers, serverNum)
 	case "processes":
 		response = b.executeProcessesCommand(servers, serverNum)
 	case "status":
@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+// cancelCommandPrefix prefixes callback data of the Cancel button: "cancel_cmd_<commandID>"
+const cancelCommandPrefix = "cancel_cmd_"
+
+// cancelCommandTimeout bounds the wait for the agent's cancel_command reply
+const cancelCommandTimeout = 10 * time.Second
+
+// runningCommand is a long command waiting for the agent's reply
+type runningCommand struct {
+	serverKey string
+	userID    int64
+}
+
+// trackCommand remembers a running command so its owner can cancel it
+func (b *Bot) trackCommand(commandID, serverKey string, userID int64) {
+	b.runningCommands.Store(commandID, runningCommand{serverKey: serverKey, userID: userID})
+}
+
+// untrackCommand forgets a finished command
+func (b *Bot) untrackCommand(commandID string) {
+	b.runningCommands.Delete(commandID)
+}
+
+// cancelCommandKeyboard returns the Cancel button for a running command
+func cancelCommandKeyboard(commandID string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⛔ Cancel", cancelCommandPrefix+commandID),
+		),
+	)
+}
+
+// isCancelled reports whether an error_response means the command was stopped by cancel_command
+func isCancelled(resp *protocol.Message) bool {
+	payload, err := protocol.DecodeAs[protocol.ErrorPayload](resp)
+	return err == nil && payload.ErrorCode == protocol.ErrorCommandCancelled
+}
+
+// handleCancelCommandCallback sends cancel_command for a running command.
+// The result message is edited by the goroutine waiting for the command itself.
+func (b *Bot) handleCancelCommandCallback(query *tgbotapi.CallbackQuery) error {
+	commandID := strings.TrimPrefix(query.Data, cancelCommandPrefix)
+
+	value, ok := b.runningCommands.Load(commandID)
+	if !ok {
+		// Already finished, the result replaces this message
+		return nil
+	}
+	running := value.(runningCommand)
+	if running.userID != query.From.ID {
+		return fmt.Errorf("user %d can't cancel command %s", query.From.ID, commandID)
+	}
+
+	cmd := protocol.NewMessage(protocol.TypeCancelCommand, protocol.CancelCommandPayload{CommandID: commandID})
+	ctx, cancel := context.WithTimeout(b.ctx, cancelCommandTimeout)
+	defer cancel()
+
+	resp, err := b.sendCommand(ctx, running.serverKey, cmd, cancelCommandTimeout)
+	if err != nil {
+		b.logger.Error("Failed to cancel command", err, StringField("command_id", commandID))
+		b.sendMessage(query.Message.Chat.ID, fmt.Sprintf("❌ Failed to cancel: %v", err))
+		return err
+	}
+	if resp.Type == protocol.TypeErrorResponse {
+		err := agentError(resp)
+		b.sendMessage(query.Message.Chat.ID, fmt.Sprintf("❌ Failed to cancel: %v", err))
+		return err
+	}
+
+	result, err := protocol.DecodeAs[protocol.CancelCommandResponse](resp)
+	if err != nil {
+		return fmt.Errorf("failed to parse cancel response: %w", err)
+	}
+
+	text := "⛔ Cancelling..."
+	if !result.Cancelled {
+		// Queued behind another command or finishing right now
+		text = "⏳ The command is not running yet or is already finishing, it can't be cancelled"
+	}
+	editMsg := tgbotapi.NewEditMessageText(query.Message.Chat.ID, query.Message.MessageID, text)
+	if _, err := b.telegramAPI.Send(editMsg); err != nil {
+		b.logger.Error("Error occurred", err)
+	}
+	return nil
+}
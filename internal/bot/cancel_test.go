@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+func TestCancelCommandKeyboard(t *testing.T) {
+	cmd := protocol.NewMessage(protocol.TypeCreateContainer, nil)
+	keyboard := cancelCommandKeyboard(cmd.ID)
+
+	data := keyboard.InlineKeyboard[0][0].CallbackData
+	if data == nil || *data != cancelCommandPrefix+cmd.ID {
+		t.Fatalf("Unexpected callback data %v", data)
+	}
+	// Telegram limits callback data to 64 bytes
+	if len(*data) > 64 {
+		t.Errorf("Callback data is %d bytes", len(*data))
+	}
+}
+
+func TestIsCancelled(t *testing.T) {
+	cancelled := protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{ErrorCode: protocol.ErrorCommandCancelled})
+	if !isCancelled(cancelled) {
+		t.Error("COMMAND_CANCELLED not recognized")
+	}
+	timeout := protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{ErrorCode: protocol.ErrorCommandTimeout})
+	if isCancelled(timeout) {
+		t.Error("COMMAND_TIMEOUT reported as cancelled")
+	}
+}
+
+func TestHandleCancelCommandCallback_NotOwner(t *testing.T) {
+	api := &recordingTelegramAPI{}
+	bot := newAuthTestBot()
+	bot.telegramAPI = api
+	bot.trackCommand("create-001", testAgentKey, 1)
+	defer bot.untrackCommand("create-001")
+
+	query := &tgbotapi.CallbackQuery{
+		Data:    cancelCommandPrefix + "create-001",
+		From:    &tgbotapi.User{ID: 2},
+		Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 2}},
+	}
+	err := bot.handleCancelCommandCallback(query)
+	if err == nil || !strings.Contains(err.Error(), "can't cancel") {
+		t.Errorf("Expected ownership error, got %v", err)
+	}
+	if len(api.sent) != 0 {
+		t.Errorf("Nothing must be sent, got %d messages", len(api.sent))
+	}
+}
+
+func TestHandleCancelCommandCallback_Finished(t *testing.T) {
+	api := &recordingTelegramAPI{}
+	bot := newAuthTestBot()
+	bot.telegramAPI = api
+
+	query := &tgbotapi.CallbackQuery{
+		Data:    cancelCommandPrefix + "create-001",
+		From:    &tgbotapi.User{ID: 1},
+		Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 1}},
+	}
+	if err := bot.handleCancelCommandCallback(query); err != nil {
+		t.Errorf("handleCancelCommandCallback() error = %v", err)
+	}
+	if len(api.sent) != 0 {
+		t.Errorf("Result message must not be overwritten, got %d messages", len(api.sent))
+	}
+}
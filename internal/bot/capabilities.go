@@ -0,0 +1,138 @@
+package bot
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+// UnsupportedCommandError is returned for commands the server's agent doesn't know
+type UnsupportedCommandError struct {
+	Command         protocol.MessageType
+	ProtocolVersion string
+}
+
+// Error implements the error interface, the text is shown to users as is
+func (e *UnsupportedCommandError) Error() string {
+	return fmt.Sprintf("%s is not supported by this agent (protocol %s). Upgrade the agent with /update to use it",
+		e.Command, e.ProtocolVersion)
+}
+
+// updateServerCapabilities stores capabilities advertised by an agent
+func (b *Bot) updateServerCapabilities(serverKey string, caps protocol.Capabilities) {
+	if caps.ProtocolVersion == "" {
+		caps.ProtocolVersion = protocol.LegacyProtocolVersion
+	}
+
+	if caps.EncryptionKey != nil {
+		b.registerEncryptionKey(serverKey, *caps.EncryptionKey)
+	}
+
+	if cached, ok := b.capabilities.Load(serverKey); ok && cached.(protocol.Capabilities).Equal(caps) {
+		return
+	}
+
+	if b.db != nil {
+		if err := b.saveServerCapabilities(serverKey, caps); err != nil {
+			b.logger.Error("Failed to save agent capabilities", err, StringField("server_key", maskKey(serverKey)))
+			return
+		}
+	}
+	b.capabilities.Store(serverKey, caps)
+
+	b.logger.Info("Agent capabilities updated",
+		StringField("server_key", maskKey(serverKey)),
+		StringField("protocol_version", caps.ProtocolVersion),
+		IntField("commands", len(caps.Commands)))
+}
+
+// serverCapabilities returns what the server's agent supports.
+// Agents that never advertised capabilities are treated as protocol 1.0.
+func (b *Bot) serverCapabilities(serverKey string) protocol.Capabilities {
+	if cached, ok := b.capabilities.Load(serverKey); ok {
+		return cached.(protocol.Capabilities)
+	}
+
+	caps := protocol.LegacyCapabilities()
+	if b.db != nil {
+		stored, err := b.loadServerCapabilities(serverKey)
+		switch {
+		case err == nil:
+			caps = stored
+		case err != sql.ErrNoRows:
+			// Don't cache: the next call retries the database
+			b.logger.Error("Failed to load agent capabilities", err, StringField("server_key", maskKey(serverKey)))
+			return caps
+		}
+	}
+
+	b.capabilities.Store(serverKey, caps)
+	return caps
+}
+
+// checkCommandSupported returns UnsupportedCommandError if the agent can't handle command
+func (b *Bot) checkCommandSupported(serverKey string, command protocol.MessageType) error {
+	caps := b.serverCapabilities(serverKey)
+	if caps.Supports(command) {
+		return nil
+	}
+	return &UnsupportedCommandError{Command: command, ProtocolVersion: caps.ProtocolVersion}
+}
+
+// unsupportedResponse converts an agent error about an unknown command into UnsupportedCommandError.
+// Agents before protocol 1.1 answer unknown commands with INVALID_COMMAND.
+func (b *Bot) unsupportedResponse(serverKey string, command protocol.MessageType, resp *protocol.Message) error {
+	if resp.Type != protocol.TypeErrorResponse {
+		return nil
+	}
+
+	payload, err := protocol.DecodeAs[protocol.ErrorPayload](resp)
+	if err != nil {
+		return nil
+	}
+
+	unsupported := payload.ErrorCode == protocol.ErrorUnsupportedCommand ||
+		(payload.ErrorCode == protocol.ErrorInvalidCommand && !protocol.IsLegacyCommand(command))
+	if !unsupported {
+		return nil
+	}
+
+	return &UnsupportedCommandError{Command: command, ProtocolVersion: b.serverCapabilities(serverKey).ProtocolVersion}
+}
+
+// saveServerCapabilities upserts capabilities of a server key
+func (b *Bot) saveServerCapabilities(serverKey string, caps protocol.Capabilities) error {
+	commands, err := json.Marshal(caps.Commands)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.db.Exec(`
+		INSERT INTO server_capabilities (secret_key, protocol_version, commands, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (secret_key) DO UPDATE
+		SET protocol_version = EXCLUDED.protocol_version, commands = EXCLUDED.commands, updated_at = NOW()
+	`, serverKey, caps.ProtocolVersion, string(commands))
+	return err
+}
+
+// loadServerCapabilities reads stored capabilities, sql.ErrNoRows if the agent never advertised any
+func (b *Bot) loadServerCapabilities(serverKey string) (protocol.Capabilities, error) {
+	var caps protocol.Capabilities
+	var commands string
+
+	err := b.db.QueryRow(
+		"SELECT protocol_version, commands FROM server_capabilities WHERE secret_key = $1",
+		serverKey,
+	).Scan(&caps.ProtocolVersion, &commands)
+	if err != nil {
+		return caps, err
+	}
+
+	if err := json.Unmarshal([]byte(commands), &caps.Commands); err != nil {
+		return caps, fmt.Errorf("invalid stored capabilities: %w", err)
+	}
+	return caps, nil
+}
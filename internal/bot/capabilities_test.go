@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+func TestServerCapabilities_DefaultsToLegacy(t *testing.T) {
+	bot := newAuthTestBot()
+
+	caps := bot.serverCapabilities(testAgentKey)
+	if caps.ProtocolVersion != protocol.LegacyProtocolVersion {
+		t.Errorf("ProtocolVersion = %q, want %q", caps.ProtocolVersion, protocol.LegacyProtocolVersion)
+	}
+	if !caps.Supports(protocol.TypeStartContainer) {
+		t.Error("legacy agent should support start_container")
+	}
+}
+
+func TestUpdateServerCapabilities(t *testing.T) {
+	bot := newAuthTestBot()
+
+	bot.updateServerCapabilities(testAgentKey, protocol.NewCapabilities(protocol.TypePing, protocol.TypeGetContainers))
+
+	caps := bot.serverCapabilities(testAgentKey)
+	if caps.ProtocolVersion != protocol.ProtocolVersion {
+		t.Errorf("ProtocolVersion = %q, want %q", caps.ProtocolVersion, protocol.ProtocolVersion)
+	}
+	if caps.Supports(protocol.TypeStartContainer) {
+		t.Error("start_container was not advertised")
+	}
+
+	// Heartbeat without version is a 1.0 agent
+	bot.updateServerCapabilities(testAgentKey, protocol.Capabilities{Commands: []protocol.MessageType{protocol.TypePing}})
+	if got := bot.serverCapabilities(testAgentKey).ProtocolVersion; got != protocol.LegacyProtocolVersion {
+		t.Errorf("ProtocolVersion = %q, want %q", got, protocol.LegacyProtocolVersion)
+	}
+}
+
+func TestCheckCommandSupported(t *testing.T) {
+	bot := newAuthTestBot()
+	bot.updateServerCapabilities(testAgentKey, protocol.NewCapabilities(protocol.TypePing))
+
+	if err := bot.checkCommandSupported(testAgentKey, protocol.TypePing); err != nil {
+		t.Errorf("ping: unexpected error %v", err)
+	}
+
+	err := bot.checkCommandSupported(testAgentKey, protocol.TypeUpdateAgent)
+	var unsupported *UnsupportedCommandError
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("update_agent: error = %v, want UnsupportedCommandError", err)
+	}
+	if unsupported.Command != protocol.TypeUpdateAgent || !strings.Contains(err.Error(), "Upgrade the agent") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestUnsupportedResponse(t *testing.T) {
+	bot := newAuthTestBot()
+
+	errorResponse := func(code string) *protocol.Message {
+		return protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{ErrorCode: code, ErrorMessage: "x"})
+	}
+
+	tests := []struct {
+		name    string
+		command protocol.MessageType
+		resp    *protocol.Message
+		want    bool
+	}{
+		{"success response", protocol.TypePing, protocol.NewMessage(protocol.TypePong, protocol.PongPayload{Status: "healthy"}), false},
+		{"unsupported command", protocol.TypePing, errorResponse(protocol.ErrorUnsupportedCommand), true},
+		{"old agent, new command", "cancel_command", errorResponse(protocol.ErrorInvalidCommand), true},
+		{"old agent, bad payload", protocol.TypeStartContainer, errorResponse(protocol.ErrorInvalidCommand), false},
+		{"other error", protocol.TypeStartContainer, errorResponse(protocol.ErrorContainerNotFound), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := bot.unsupportedResponse(testAgentKey, tt.command, tt.resp)
+			if (err != nil) != tt.want {
+				t.Errorf("unsupportedResponse() = %v, want error: %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainerActionButtons(t *testing.T) {
+	rows := containerActionButtons(protocol.LegacyCapabilities())
+	if len(rows) != 3 || len(rows[0]) != 2 || len(rows[2]) != 1 {
+		t.Fatalf("legacy agent: unexpected layout %v", rows)
+	}
+
+	rows = containerActionButtons(protocol.NewCapabilities(protocol.TypeStartContainer, protocol.TypeStopContainer, protocol.TypeCreateContainer))
+	var callbacks []string
+	for _, row := range rows {
+		for _, button := range row {
+			callbacks = append(callbacks, *button.CallbackData)
+		}
+	}
+	want := "container_action_start,container_action_stop,container_action_create"
+	if got := strings.Join(callbacks, ","); got != want {
+		t.Errorf("callbacks = %s, want %s", got, want)
+	}
+
+	if rows := containerActionButtons(protocol.NewCapabilities(protocol.TypePing)); len(rows) != 0 {
+		t.Errorf("expected no buttons, got %v", rows)
+	}
+}
@@ -0,0 +1,243 @@
+package bot
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+// queueableCommands lists commands that may run later without the user watching,
+// with the response timeout used when they are finally delivered
+var queueableCommands = map[protocol.MessageType]time.Duration{
+	protocol.TypeStartContainer:   60 * time.Second,
+	protocol.TypeStopContainer:    90 * time.Second,
+	protocol.TypeRestartContainer: 90 * time.Second,
+	protocol.TypeRemoveContainer:  90 * time.Second,
+	protocol.TypeUpdateAgent:      30 * time.Second,
+}
+
+// queuedCommand is a command stored until its server comes back online
+type queuedCommand struct {
+	ID         int64
+	ServerKey  string
+	ServerName string
+	UserID     int64
+	Command    *protocol.Message
+}
+
+// queueEnabled reports whether offline commands are queued instead of failing
+func (b *Bot) queueEnabled() bool {
+	return b.db != nil && b.config != nil && b.config.Queue.Enabled
+}
+
+// queueIfOffline stores command when its server is offline.
+// Returns the text for the user and true if the command was queued.
+func (b *Bot) queueIfOffline(userID int64, serverKey, serverName string, command *protocol.Message) (string, bool) {
+	if !b.queueEnabled() {
+		return "", false
+	}
+	if _, ok := queueableCommands[command.Type]; !ok {
+		return "", false
+	}
+
+	status, lastSeen, err := b.getServerPresence(serverKey)
+	if err != nil || !knownOffline(status, lastSeen) {
+		return "", false
+	}
+
+	ttl := b.config.Queue.GetTTL()
+	if err := b.enqueueCommand(userID, serverKey, command, ttl); err != nil {
+		b.logger.Error("Failed to queue command", err, StringField("server_key", maskKey(serverKey)))
+		return "", false
+	}
+
+	// The server may have come back between the status check and enqueue,
+	// its heartbeat then found the queue empty
+	if status, _, err := b.getServerPresence(serverKey); err == nil && status == serverStatusOnline {
+		go b.deliverQueuedCommands(serverKey)
+	}
+
+	b.logger.Info("Command queued for offline server",
+		StringField("server_key", maskKey(serverKey)),
+		StringField("command_type", string(command.Type)))
+
+	return fmt.Sprintf("📬 %s is offline.\n\nCommand %q is queued and will run when the server is back online (expires in %s).",
+		serverLabel(serverName, serverKey), describeCommand(command), ttl), true
+}
+
+// knownOffline reports whether the server is offline according to tracked heartbeats.
+// A server that never sent one (last_seen is NULL) has unknown presence,
+// its commands are sent directly.
+func knownOffline(status string, lastSeen sql.NullTime) bool {
+	return status == serverStatusOffline && lastSeen.Valid
+}
+
+// deliverQueuedCommands sends commands queued for a server that came back online
+// and reports each result to the user who queued it
+func (b *Bot) deliverQueuedCommands(serverKey string) {
+	commands, err := b.takeQueuedCommands(serverKey)
+	if err != nil {
+		b.logger.Error("Failed to load queued commands", err, StringField("server_key", maskKey(serverKey)))
+		return
+	}
+
+	for _, queued := range commands {
+		if b.ctx.Err() != nil {
+			return
+		}
+
+		// The command is issued now: a stale timestamp would make it expire or fail the signature freshness check
+		queued.Command.Timestamp = time.Now()
+		timeout := queueableCommands[queued.Command.Type]
+		ctx, cancel := context.WithTimeout(b.ctx, timeout)
+		resp, err := b.sendCommand(ctx, serverKey, queued.Command, timeout)
+		cancel()
+
+		b.logger.Info("Queued command delivered",
+			StringField("server_key", maskKey(serverKey)),
+			StringField("command_type", string(queued.Command.Type)))
+
+		b.sendMessage(queued.UserID, b.formatQueuedResult(queued, resp, err))
+	}
+}
+
+// expireQueuedCommands drops commands whose server didn't come back in time
+func (b *Bot) expireQueuedCommands() {
+	expired, err := b.takeExpiredCommands()
+	if err != nil {
+		b.logger.Error("Failed to expire queued commands", err)
+		return
+	}
+
+	for _, queued := range expired {
+		b.sendMessage(queued.UserID, fmt.Sprintf("⌛ Queued command %q for %s expired: the server did not come back online in time.",
+			describeCommand(queued.Command), serverLabel(queued.ServerName, queued.ServerKey)))
+	}
+}
+
+// formatQueuedResult formats the eventual result of a queued command
+func (b *Bot) formatQueuedResult(queued queuedCommand, resp *protocol.Message, err error) string {
+	serverName := serverLabel(queued.ServerName, queued.ServerKey)
+	header := fmt.Sprintf("📬 Queued command %q on %s\n\n", describeCommand(queued.Command), serverName)
+
+	if err != nil {
+		return header + fmt.Sprintf("❌ Delivery failed: %v", err)
+	}
+
+	switch resp.Type {
+	case protocol.TypeErrorResponse:
+		return header + fmt.Sprintf("❌ %v", agentError(resp))
+
+	case protocol.TypeContainerActionResponse:
+		payload, err := protocol.DecodeAs[protocol.ContainerActionResponse](resp)
+		if err != nil {
+			return header + fmt.Sprintf("❌ %v", err)
+		}
+		return header + b.formatContainerActionResponse(payload)
+
+	case protocol.TypeUpdateAgentResponse:
+		payload, err := protocol.DecodeAs[protocol.UpdateAgentResponse](resp)
+		if err != nil {
+			return header + fmt.Sprintf("❌ %v", err)
+		}
+		return header + formatUpdateResponse(serverName, payload)
+	}
+
+	return header + fmt.Sprintf("❌ Unexpected response type: %s", resp.Type)
+}
+
+// serverLabel returns server name for messages, masked key if the name is unknown
+func serverLabel(name, serverKey string) string {
+	if name != "" {
+		return name
+	}
+	return maskKey(serverKey)
+}
+
+// describeCommand returns a short human-readable command description
+func describeCommand(command *protocol.Message) string {
+	if command.Type == protocol.TypeUpdateAgent {
+		return "update agent"
+	}
+
+	payload, err := protocol.DecodeAs[protocol.ContainerActionPayload](command)
+	if err == nil && payload.Action != "" {
+		return fmt.Sprintf("%s %s", payload.Action, payload.ContainerName)
+	}
+
+	return string(command.Type)
+}
+
+// enqueueCommand stores command for serverKey until ttl passes
+func (b *Bot) enqueueCommand(userID int64, serverKey string, command *protocol.Message, ttl time.Duration) error {
+	data, err := command.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to serialize command: %w", err)
+	}
+
+	_, err = b.db.Exec(`
+		INSERT INTO queued_commands (server_key, user_id, command, expires_at)
+		VALUES ($1, $2, $3, NOW() + make_interval(secs => $4))
+	`, serverKey, userID, string(data), ttl.Seconds())
+	return err
+}
+
+// takeQueuedCommands removes and returns commands queued for serverKey, oldest first.
+// Expired ones are left for expireQueuedCommands.
+func (b *Bot) takeQueuedCommands(serverKey string) ([]queuedCommand, error) {
+	return b.takeCommands(`
+		WITH taken AS (
+			DELETE FROM queued_commands
+			WHERE server_key = $1 AND expires_at >= NOW()
+			RETURNING id, server_key, user_id, command
+		)
+		SELECT taken.id, taken.server_key, COALESCE(s.name, ''), taken.user_id, taken.command
+		FROM taken LEFT JOIN servers s ON s.secret_key = taken.server_key
+		ORDER BY taken.id
+	`, serverKey)
+}
+
+// takeExpiredCommands removes and returns commands past their expiry
+func (b *Bot) takeExpiredCommands() ([]queuedCommand, error) {
+	return b.takeCommands(`
+		WITH taken AS (
+			DELETE FROM queued_commands
+			WHERE expires_at < NOW()
+			RETURNING id, server_key, user_id, command
+		)
+		SELECT taken.id, taken.server_key, COALESCE(s.name, ''), taken.user_id, taken.command
+		FROM taken LEFT JOIN servers s ON s.secret_key = taken.server_key
+		ORDER BY taken.id
+	`)
+}
+
+// takeCommands runs a DELETE ... RETURNING query and decodes queued commands
+func (b *Bot) takeCommands(query string, args ...interface{}) ([]queuedCommand, error) {
+	rows, err := b.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var commands []queuedCommand
+	for rows.Next() {
+		var queued queuedCommand
+		var data string
+		if err := rows.Scan(&queued.ID, &queued.ServerKey, &queued.ServerName, &queued.UserID, &data); err != nil {
+			return nil, err
+		}
+
+		command, err := protocol.FromJSON([]byte(data))
+		if err != nil {
+			b.logger.Error("Dropping malformed queued command", err)
+			continue
+		}
+		queued.Command = command
+		commands = append(commands, queued)
+	}
+
+	return commands, rows.Err()
+}
@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/servereye/servereye/internal/config"
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+func TestDescribeCommand(t *testing.T) {
+	restart := protocol.NewMessage(protocol.TypeRestartContainer, protocol.ContainerActionPayload{
+		ContainerName: "nginx",
+		Action:        "restart",
+	})
+	if got := describeCommand(restart); got != "restart nginx" {
+		t.Errorf("describeCommand() = %q, want %q", got, "restart nginx")
+	}
+
+	update := protocol.NewMessage(protocol.TypeUpdateAgent, &protocol.UpdateAgentPayload{Version: "latest"})
+	if got := describeCommand(update); got != "update agent" {
+		t.Errorf("describeCommand() = %q, want %q", got, "update agent")
+	}
+}
+
+func TestQueueIfOffline_Disabled(t *testing.T) {
+	bot := &Bot{config: &config.BotConfig{}}
+	command := protocol.NewMessage(protocol.TypeRestartContainer, nil)
+
+	if _, queued := bot.queueIfOffline(1, "srv_test", "test", command); queued {
+		t.Error("Command queued with queue disabled")
+	}
+}
+
+func TestKnownOffline(t *testing.T) {
+	seen := sql.NullTime{Time: time.Now(), Valid: true}
+
+	tests := []struct {
+		name     string
+		status   string
+		lastSeen sql.NullTime
+		want     bool
+	}{
+		{"offline after heartbeats", serverStatusOffline, seen, true},
+		{"online", serverStatusOnline, seen, false},
+		{"never sent a heartbeat", serverStatusOffline, sql.NullTime{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := knownOffline(tt.status, tt.lastSeen); got != tt.want {
+				t.Errorf("knownOffline() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueueableCommands(t *testing.T) {
+	// Interactive reads must never be queued
+	for _, msgType := range []protocol.MessageType{protocol.TypeGetContainers, protocol.TypeGetCPUTemp} {
+		if _, ok := queueableCommands[msgType]; ok {
+			t.Errorf("%s must not be queueable", msgType)
+		}
+	}
+	if _, ok := queueableCommands[protocol.TypeUpdateAgent]; !ok {
+		t.Error("update_agent must be queueable")
+	}
+}
+
+func TestFormatQueuedResult(t *testing.T) {
+	bot := &Bot{}
+	queued := queuedCommand{
+		ServerKey:  "srv_0123456789abcdef",
+		ServerName: "web-1",
+		Command: protocol.NewMessage(protocol.TypeRestartContainer, protocol.ContainerActionPayload{
+			ContainerName: "nginx",
+			Action:        "restart",
+		}),
+	}
+
+	success := protocol.NewMessage(protocol.TypeContainerActionResponse, protocol.ContainerActionResponse{
+		Success:       true,
+		ContainerName: "nginx",
+		Action:        "restart",
+		NewState:      "running",
+	})
+	got := bot.formatQueuedResult(queued, success, nil)
+	if !strings.Contains(got, "web-1") || !strings.Contains(got, "successfully restarted") {
+		t.Errorf("formatQueuedResult() = %q", got)
+	}
+
+	agentErr := protocol.NewMessage(protocol.TypeErrorResponse, protocol.ErrorPayload{ErrorMessage: "no such container"})
+	if got := bot.formatQueuedResult(queued, agentErr, nil); !strings.Contains(got, "no such container") {
+		t.Errorf("formatQueuedResult() = %q", got)
+	}
+
+	if got := bot.formatQueuedResult(queued, nil, errors.New("timeout waiting for response")); !strings.Contains(got, "Delivery failed") {
+		t.Errorf("formatQueuedResult() = %q", got)
+	}
+
+	// Server removed while the command waited
+	queued.ServerName = ""
+	if got := bot.formatQueuedResult(queued, success, nil); !strings.Contains(got, maskKey(queued.ServerKey)) {
+		t.Errorf("formatQueuedResult() = %q, want masked key", got)
+	}
+}
@@ -2,7 +2,6 @@ package bot
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -18,7 +17,7 @@ func (b *Bot) handleContainerAction(userID int64, containerID, action string) st
 	}
 
 	// Получаем серверы пользователя
-	servers, err := b.getUserServers(userID)
+	servers, err := b.getUserServersWithInfo(userID)
 	if err != nil {
 		b.logger.Error("Error occurred", err)
 		return "❌ Error getting your servers. Please try again."
@@ -31,7 +30,7 @@ func (b *Bot) handleContainerAction(userID int64, containerID, action string) st
 	b.logger.Info("Найдено серверов пользователя")
 
 	// Пока работаем только с первым сервером
-	serverKey := servers[0]
+	serverKey := servers[0].SecretKey
 	b.logger.Info("Operation completed")
 
 	// Определяем тип команды
@@ -56,6 +55,10 @@ func (b *Bot) handleContainerAction(userID int64, containerID, action string) st
 		Action:        action,
 	}
 
+	if text, queued := b.queueIfOffline(userID, serverKey, servers[0].Name, protocol.NewMessage(messageType, payload)); queued {
+		return text
+	}
+
 	response, err := b.sendContainerAction(serverKey, messageType, payload)
 	if err != nil {
 		b.logger.Error("Error occurred", err)
@@ -66,7 +69,7 @@ func (b *Bot) handleContainerAction(userID int64, containerID, action string) st
 	return b.formatContainerActionResponse(response)
 }
 
-// sendContainerAction sends container action command to agent via Streams
+// sendContainerAction sends container action command to agent
 func (b *Bot) sendContainerAction(serverKey string, messageType protocol.MessageType, payload protocol.ContainerActionPayload) (*protocol.ContainerActionResponse, error) {
 	message := protocol.NewMessage(messageType, payload)
 
@@ -79,29 +82,17 @@ func (b *Bot) sendContainerAction(serverKey string, messageType protocol.Message
 	ctx, cancel := context.WithTimeout(b.ctx, timeout)
 	defer cancel()
 
-	resp, err := b.sendCommandViaStreams(ctx, serverKey, message, timeout)
+	resp, err := b.sendCommand(ctx, serverKey, message, timeout)
 	if err != nil {
 		return nil, err
 	}
 
 	if resp.Type == protocol.TypeErrorResponse {
-		if errorData, ok := resp.Payload.(map[string]interface{}); ok {
-			errorMsg := "unknown error"
-			if msg, exists := errorData["error_message"]; exists {
-				errorMsg = fmt.Sprintf("%v", msg)
-			}
-			return nil, fmt.Errorf("agent error: %s", errorMsg)
-		}
-		return nil, fmt.Errorf("agent returned error")
+		return nil, agentError(resp)
 	}
 
 	if resp.Type == protocol.TypeContainerActionResponse {
-		var actionResp protocol.ContainerActionResponse
-		payloadData, _ := json.Marshal(resp.Payload)
-		if err := json.Unmarshal(payloadData, &actionResp); err == nil {
-			return &actionResp, nil
-		}
-		return nil, fmt.Errorf("invalid container action response format")
+		return protocol.DecodeAs[protocol.ContainerActionResponse](resp)
 	}
 
 	return nil, fmt.Errorf("unexpected response type: %s", resp.Type)
@@ -204,45 +195,29 @@ func (b *Bot) validateContainerAction(containerID, action string) error {
 	return nil
 }
 
-// createContainerFromTemplate creates a container from predefined template
-func (b *Bot) createContainerFromTemplate(userID int64, _ string, template string) string {
+// createContainerFromTemplate sends a create_container command built from a template
+func (b *Bot) createContainerFromTemplate(serverKey string, cmd *protocol.Message, payload *protocol.CreateContainerPayload) string {
 	b.logger.Info("Creating container from template")
 
-	// Get template configuration
-	payload, err := b.getTemplateConfig(template)
-	if err != nil {
-		return fmt.Sprintf("❌ Unknown template: %s", template)
-	}
-
-	// Get user's servers
-	servers, err := b.getUserServers(userID)
-	if err != nil {
-		b.logger.Error("Error occurred", err)
-		return "❌ Error getting your servers"
-	}
-
-	if len(servers) == 0 {
-		return "❌ No servers found"
-	}
-
-	// Use first server
-	serverKey := servers[0]
-
-	// Send create container command via Streams
-	cmd := protocol.NewMessage(protocol.TypeCreateContainer, payload)
 	ctx, cancel := context.WithTimeout(b.ctx, 120*time.Second)
 	defer cancel()
 
-	resp, err := b.sendCommandViaStreams(ctx, serverKey, cmd, 120*time.Second)
+	resp, err := b.sendCommand(ctx, serverKey, cmd, 120*time.Second)
 	if err != nil {
 		b.logger.Error("Error occurred", err)
 		return fmt.Sprintf("❌ Failed to create container: %v", err)
 	}
 
+	if resp.Type == protocol.TypeErrorResponse {
+		if isCancelled(resp) {
+			return fmt.Sprintf("⛔ Creation of %s cancelled", payload.Name)
+		}
+		return fmt.Sprintf("❌ Failed to create container: %v", agentError(resp))
+	}
+
 	// Parse response
-	var response protocol.ContainerActionResponse
-	respData, _ := json.Marshal(resp.Payload)
-	if err := json.Unmarshal(respData, &response); err != nil {
+	response, err := protocol.DecodeAs[protocol.ContainerActionResponse](resp)
+	if err != nil {
 		b.logger.Error("Error occurred", err)
 		return fmt.Sprintf("❌ Failed to parse response: %v", err)
 	}
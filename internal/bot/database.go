@@ -3,8 +3,10 @@ package bot
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/servereye/servereye/pkg/auth"
 )
 
 // initDatabase initializes the database schema
@@ -52,6 +54,7 @@ func (b *Bot) initDatabase() error {
 		`CREATE TABLE IF NOT EXISTS generated_keys (
 			id BIGSERIAL PRIMARY KEY,
 			secret_key VARCHAR(64) UNIQUE NOT NULL,
+			key_id VARCHAR(64),
 			generated_at TIMESTAMP DEFAULT NOW(),
 			first_connection TIMESTAMP,
 			last_seen TIMESTAMP,
@@ -62,12 +65,46 @@ func (b *Bot) initDatabase() error {
 			status VARCHAR(20) DEFAULT 'generated'
 		)`,
 
+		`CREATE TABLE IF NOT EXISTS queued_commands (
+			id BIGSERIAL PRIMARY KEY,
+			server_key VARCHAR(64) NOT NULL,
+			user_id BIGINT NOT NULL,
+			command JSONB NOT NULL,
+			created_at TIMESTAMP DEFAULT NOW(),
+			expires_at TIMESTAMP NOT NULL
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS server_capabilities (
+			secret_key VARCHAR(64) PRIMARY KEY,
+			protocol_version VARCHAR(16) NOT NULL,
+			commands JSONB NOT NULL,
+			updated_at TIMESTAMP DEFAULT NOW()
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS server_encryption_keys (
+			secret_key VARCHAR(64) NOT NULL,
+			key_id VARCHAR(32) NOT NULL,
+			public_key TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT NOW(),
+			retired_at TIMESTAMP,
+			PRIMARY KEY (secret_key, key_id)
+		)`,
+
+		// key_id (auth.KeyID of secret_key) authenticates agent HTTP requests by index;
+		// keys generated before the column existed are backfilled
+		`ALTER TABLE generated_keys ADD COLUMN IF NOT EXISTS key_id VARCHAR(64)`,
+		`UPDATE generated_keys SET key_id = encode(sha256(secret_key::bytea), 'hex') WHERE key_id IS NULL`,
+		// signing_key signs commands; unlike secret_key it never appears in stream or channel names
+		`ALTER TABLE generated_keys ADD COLUMN IF NOT EXISTS signing_key VARCHAR(128)`,
+
 		`CREATE INDEX IF NOT EXISTS idx_servers_secret_key ON servers(secret_key)`,
 		`CREATE INDEX IF NOT EXISTS idx_servers_owner_id ON servers(owner_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_user_servers_user_id ON user_servers(user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_command_history_server_id ON command_history(server_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_generated_keys_secret_key ON generated_keys(secret_key)`,
 		`CREATE INDEX IF NOT EXISTS idx_generated_keys_status ON generated_keys(status)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_generated_keys_key_id ON generated_keys(key_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_queued_commands_server_key ON queued_commands(server_key)`,
 	}
 
 	for _, query := range queries {
@@ -240,6 +277,22 @@ type ServerInfo struct {
 	Status    string
 }
 
+// getServerPresence returns stored status and last heartbeat time for a server
+func (b *Bot) getServerPresence(serverKey string) (string, sql.NullTime, error) {
+	var status string
+	var lastSeen sql.NullTime
+
+	err := b.db.QueryRow(
+		"SELECT COALESCE(status, 'offline'), last_seen FROM servers WHERE secret_key = $1",
+		serverKey,
+	).Scan(&status, &lastSeen)
+	if err != nil {
+		return "", sql.NullTime{}, err
+	}
+
+	return status, lastSeen, nil
+}
+
 // getUserServersWithInfo returns list of servers with names for a user
 func (b *Bot) getUserServersWithInfo(userID int64) ([]ServerInfo, error) {
 	query := `
@@ -351,18 +404,22 @@ func (b *Bot) connectServerWithName(userID int64, serverKey, serverName string)
 	return tx.Commit()
 }
 
-// recordGeneratedKey records a newly generated server key
-func (b *Bot) recordGeneratedKey(secretKey string) error {
+// recordGeneratedKey records a newly generated server key with its signing key.
+// The first signing key registered for a server is pinned, later ones are ignored.
+func (b *Bot) recordGeneratedKey(secretKey, signingKey string) error {
 	query := `
-		INSERT INTO generated_keys (secret_key, status)
-		VALUES ($1, 'generated')
-		ON CONFLICT (secret_key) DO NOTHING
+		INSERT INTO generated_keys (secret_key, key_id, signing_key, status)
+		VALUES ($1, $2, NULLIF($3, ''), 'generated')
+		ON CONFLICT (secret_key) DO UPDATE
+		SET signing_key = COALESCE(generated_keys.signing_key, EXCLUDED.signing_key)
 	`
 
-	_, err := b.db.Exec(query, secretKey)
+	keyID := auth.KeyID(secretKey)
+	_, err := b.db.Exec(query, secretKey, keyID, signingKey)
 	if err != nil {
 		return fmt.Errorf("failed to record generated key: %v", err)
 	}
+	b.unknownAgentKeys.remove(keyID)
 
 	keyPrefix := secretKey
 	if len(keyPrefix) > 12 {
@@ -417,3 +474,96 @@ func (b *Bot) updateKeyConnection(secretKey, agentVersion, osInfo, hostname stri
 
 	return nil
 }
+
+// recordHeartbeat updates last_seen for a server key and marks it online.
+// Returns previous server status ("" if key is not connected to any server)
+// and whether the server never sent a heartbeat before.
+func (b *Bot) recordHeartbeat(serverKey string) (previousStatus string, firstHeartbeat bool, err error) {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return "", false, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(
+		"SELECT COALESCE(status, 'offline'), last_seen IS NULL FROM servers WHERE secret_key = $1 FOR UPDATE",
+		serverKey,
+	).Scan(&previousStatus, &firstHeartbeat)
+	if err != nil && err != sql.ErrNoRows {
+		return "", false, fmt.Errorf("failed to get server status: %w", err)
+	}
+
+	if err == nil {
+		if _, err := tx.Exec(
+			"UPDATE servers SET last_seen = NOW(), status = 'online', updated_at = NOW() WHERE secret_key = $1",
+			serverKey,
+		); err != nil {
+			return "", false, fmt.Errorf("failed to update server: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(
+		"UPDATE generated_keys SET last_seen = NOW() WHERE secret_key = $1",
+		serverKey,
+	); err != nil {
+		return "", false, fmt.Errorf("failed to update generated key: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", false, err
+	}
+
+	return previousStatus, firstHeartbeat, nil
+}
+
+// markStaleServersOffline marks online servers without heartbeat for gracePeriod as offline
+func (b *Bot) markStaleServersOffline(gracePeriod time.Duration) ([]ServerInfo, error) {
+	rows, err := b.db.Query(`
+		UPDATE servers
+		SET status = 'offline', updated_at = NOW()
+		WHERE status = 'online'
+		  AND (last_seen IS NULL OR last_seen < NOW() - make_interval(secs => $1))
+		RETURNING secret_key, name, status
+	`, gracePeriod.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var servers []ServerInfo
+	for rows.Next() {
+		var server ServerInfo
+		if err := rows.Scan(&server.SecretKey, &server.Name, &server.Status); err != nil {
+			return nil, err
+		}
+		servers = append(servers, server)
+	}
+
+	return servers, rows.Err()
+}
+
+// getServerSubscribers returns server name and Telegram IDs of users connected to it
+func (b *Bot) getServerSubscribers(serverKey string) (string, []int64, error) {
+	rows, err := b.db.Query(`
+		SELECT s.name, us.user_id
+		FROM servers s
+		JOIN user_servers us ON s.id = us.server_id
+		WHERE s.secret_key = $1
+	`, serverKey)
+	if err != nil {
+		return "", nil, err
+	}
+	defer rows.Close()
+
+	var name string
+	var userIDs []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&name, &userID); err != nil {
+			return "", nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	return name, userIDs, rows.Err()
+}
@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/servereye/servereye/pkg/redis/streams"
+)
+
+const (
+	defaultDLQLimit = 10
+	maxDLQLimit     = 50
+)
+
+// handleDLQ shows commands moved to the dead-letter stream of a server (admin command)
+func (b *Bot) handleDLQ(message *tgbotapi.Message) string {
+	if !isAdmin(message.From.ID) {
+		return "❌ This command is only available for administrators."
+	}
+
+	serverKey, limit, err := parseDLQArgs(message.Text)
+	if err != nil {
+		return fmt.Sprintf("❌ %v\n\nUsage: /dlq <server_key> [count]", err)
+	}
+
+	if b.streamsAdapter == nil {
+		return "❌ Redis Streams are not available"
+	}
+
+	ctx, cancel := context.WithTimeout(b.ctx, 5*time.Second)
+	defer cancel()
+
+	messages, total, err := b.streamsAdapter.GetDeadLetters(ctx, serverKey, limit)
+	if err != nil {
+		b.logger.Error("Failed to read dead-letter stream", err, StringField("server_key", maskKey(serverKey)))
+		return fmt.Sprintf("❌ Failed to read dead-letter stream: %v", err)
+	}
+
+	return formatDeadLetters(serverKey, total, messages)
+}
+
+// parseDLQArgs parses "/dlq <server_key> [count]"
+func parseDLQArgs(text string) (string, int64, error) {
+	parts := strings.Fields(text)
+	if len(parts) < 2 {
+		return "", 0, fmt.Errorf("server key is required")
+	}
+
+	serverKey := parts[1]
+	if !strings.HasPrefix(serverKey, "srv_") {
+		return "", 0, fmt.Errorf("invalid server key format")
+	}
+
+	limit := int64(defaultDLQLimit)
+	if len(parts) > 2 {
+		n, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil || n <= 0 {
+			return "", 0, fmt.Errorf("invalid count: %s", parts[2])
+		}
+		limit = n
+	}
+	if limit > maxDLQLimit {
+		limit = maxDLQLimit
+	}
+
+	return serverKey, limit, nil
+}
+
+// formatDeadLetters formats dead-lettered commands for Telegram
+func formatDeadLetters(serverKey string, total int64, messages []streams.StreamMessage) string {
+	if total == 0 {
+		return fmt.Sprintf("✅ Dead-letter queue for %s is empty", maskKey(serverKey))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("☠️ **Dead-letter queue** for %s\n", maskKey(serverKey)))
+	sb.WriteString(fmt.Sprintf("Showing %d of %d\n", len(messages), total))
+
+	for i, msg := range messages {
+		sb.WriteString(fmt.Sprintf("\n%d. `%s` (%s)\n", i+1, msg.Values["type"], msg.Values["id"]))
+		sb.WriteString(fmt.Sprintf("   Retries: %s, consumer: %s\n", msg.Values["retries"], msg.Values["consumer"]))
+		sb.WriteString(fmt.Sprintf("   Reason: %s\n", msg.Values["reason"]))
+		sb.WriteString(fmt.Sprintf("   Dead since: %s\n", msg.Values["dead_lettered_at"]))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/servereye/servereye/pkg/redis/streams"
+)
+
+func TestParseDLQArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		wantKey   string
+		wantLimit int64
+		wantErr   bool
+	}{
+		{"default limit", "/dlq srv_abc", "srv_abc", defaultDLQLimit, false},
+		{"custom limit", "/dlq srv_abc 5", "srv_abc", 5, false},
+		{"limit capped", "/dlq srv_abc 500", "srv_abc", maxDLQLimit, false},
+		{"missing key", "/dlq", "", 0, true},
+		{"invalid key", "/dlq abc", "", 0, true},
+		{"invalid count", "/dlq srv_abc many", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, limit, err := parseDLQArgs(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDLQArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if key != tt.wantKey || limit != tt.wantLimit {
+				t.Errorf("parseDLQArgs() = %q, %d; want %q, %d", key, limit, tt.wantKey, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestHandleDLQ_NonAdmin(t *testing.T) {
+	b := &Bot{}
+	msg := &tgbotapi.Message{
+		Text: "/dlq srv_abc",
+		From: &tgbotapi.User{ID: 12345},
+	}
+
+	got := b.handleDLQ(msg)
+	if !strings.Contains(got, "only available for administrators") {
+		t.Errorf("Expected admin-only error, got %q", got)
+	}
+}
+
+func TestFormatDeadLetters(t *testing.T) {
+	if got := formatDeadLetters("srv_abcdef1234567890", 0, nil); !strings.Contains(got, "is empty") {
+		t.Errorf("Expected empty queue message, got %q", got)
+	}
+
+	messages := []streams.StreamMessage{{
+		ID: "1700000000000-0",
+		Values: map[string]string{
+			"type":             "restart_container",
+			"id":               "cmd-1",
+			"retries":          "3",
+			"consumer":         "web-1",
+			"reason":           "max retries exceeded",
+			"dead_lettered_at": "2024-01-01T00:00:00Z",
+		},
+	}}
+
+	got := formatDeadLetters("srv_abcdef1234567890", 4, messages)
+	for _, want := range []string{"Showing 1 of 4", "restart_container", "cmd-1", "Retries: 3", "web-1", "max retries exceeded"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in output:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "srv_abcdef1234567890") {
+		t.Error("Server key must be masked")
+	}
+}
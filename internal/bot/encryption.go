@@ -0,0 +1,158 @@
+package bot
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/servereye/servereye/pkg/auth"
+	"github.com/servereye/servereye/pkg/e2e"
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+// encryptionEnabled reports whether commands are encrypted for agents that advertise a key
+func (b *Bot) encryptionEnabled() bool {
+	return b.config != nil && b.config.Encryption.Enabled
+}
+
+// registerEncryptionKey records the key an agent advertised.
+// A new key ID means the agent rotated its key: older keys of the server are retired.
+func (b *Bot) registerEncryptionKey(serverKey string, key protocol.EncryptionKey) {
+	if key.ID == "" || e2e.KeyID(key.PublicKey) != key.ID {
+		b.logger.Warn("Agent advertised invalid encryption key", StringField("server_key", maskKey(serverKey)))
+		return
+	}
+
+	previous, known := b.activeEncryptionKey(serverKey)
+	if known && previous.ID == key.ID {
+		return
+	}
+
+	if err := b.authenticateEncryptionKey(serverKey, key, known); err != nil {
+		b.logger.Warn("Rejected agent encryption key",
+			StringField("server_key", maskKey(serverKey)),
+			StringField("key_id", key.ID),
+			StringField("reason", err.Error()))
+		return
+	}
+
+	if b.db != nil {
+		if err := b.saveEncryptionKey(serverKey, key); err != nil {
+			b.logger.Error("Failed to save agent encryption key", err, StringField("server_key", maskKey(serverKey)))
+			return
+		}
+	}
+	b.encryptionKeys.Store(serverKey, key)
+
+	if known {
+		b.logger.Info("Agent encryption key rotated",
+			StringField("server_key", maskKey(serverKey)),
+			StringField("old_key_id", previous.ID),
+			StringField("key_id", key.ID))
+	}
+}
+
+// authenticateEncryptionKey checks that key comes from the agent and not from anyone
+// who knows the server key, which is public in Redis names. Keys must be signed with
+// the signing key registered at enrollment; agents without one can only pin their first key.
+func (b *Bot) authenticateEncryptionKey(serverKey string, key protocol.EncryptionKey, hasActive bool) error {
+	signingKey, err := b.serverSigningKey(serverKey)
+	if err != nil {
+		return fmt.Errorf("failed to load signing key: %w", err)
+	}
+	if signingKey != "" {
+		return auth.VerifyEncryptionKey(signingKey, key)
+	}
+	if hasActive {
+		return errors.New("key change requires a signing key")
+	}
+	return nil
+}
+
+// activeEncryptionKey returns the current key of the server's agent, false if it has none
+func (b *Bot) activeEncryptionKey(serverKey string) (protocol.EncryptionKey, bool) {
+	if cached, ok := b.encryptionKeys.Load(serverKey); ok {
+		key := cached.(protocol.EncryptionKey)
+		return key, key.ID != ""
+	}
+
+	// Empty key is cached for agents without encryption
+	var key protocol.EncryptionKey
+	if b.db != nil {
+		stored, err := b.loadEncryptionKey(serverKey)
+		switch {
+		case err == nil:
+			key = stored
+		case err != sql.ErrNoRows:
+			b.logger.Error("Failed to load agent encryption key", err, StringField("server_key", maskKey(serverKey)))
+			return key, false
+		}
+	}
+
+	b.encryptionKeys.Store(serverKey, key)
+	return key, key.ID != ""
+}
+
+// encryptCommand seals the command payload for the server's agent.
+// Returns the message to send and the session for its response, nil if the agent has no key.
+func (b *Bot) encryptCommand(serverKey string, command *protocol.Message) (*protocol.Message, *e2e.Session, error) {
+	key, ok := b.activeEncryptionKey(serverKey)
+	if !ok {
+		return command, nil, nil
+	}
+
+	// Callers keep the plaintext command, e.g. to show it in messages
+	sealed := *command
+	session, err := e2e.EncryptCommand(&sealed, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encrypt command: %w", err)
+	}
+	return &sealed, session, nil
+}
+
+// saveEncryptionKey stores key as the active one and retires the others
+func (b *Bot) saveEncryptionKey(serverKey string, key protocol.EncryptionKey) error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE server_encryption_keys SET retired_at = NOW()
+		WHERE secret_key = $1 AND key_id <> $2 AND retired_at IS NULL
+	`, serverKey, key.ID); err != nil {
+		return fmt.Errorf("failed to retire encryption keys: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO server_encryption_keys (secret_key, key_id, public_key)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (secret_key, key_id) DO UPDATE SET retired_at = NULL
+	`, serverKey, key.ID, base64.StdEncoding.EncodeToString(key.PublicKey)); err != nil {
+		return fmt.Errorf("failed to save encryption key: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// loadEncryptionKey reads the active key of a server, sql.ErrNoRows if there is none
+func (b *Bot) loadEncryptionKey(serverKey string) (protocol.EncryptionKey, error) {
+	var key protocol.EncryptionKey
+	var publicKey string
+
+	err := b.db.QueryRow(`
+		SELECT key_id, public_key FROM server_encryption_keys
+		WHERE secret_key = $1 AND retired_at IS NULL
+		ORDER BY created_at DESC LIMIT 1
+	`, serverKey).Scan(&key.ID, &publicKey)
+	if err != nil {
+		return key, err
+	}
+
+	if key.PublicKey, err = base64.StdEncoding.DecodeString(publicKey); err != nil {
+		return key, fmt.Errorf("invalid stored encryption key: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,108 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/servereye/servereye/pkg/auth"
+	"github.com/servereye/servereye/pkg/e2e"
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+func newTestEncryptionKey(t *testing.T) (*e2e.KeyPair, protocol.EncryptionKey) {
+	t.Helper()
+	pair, err := e2e.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pair, protocol.EncryptionKey{ID: pair.ID, PublicKey: pair.PublicKey()}
+}
+
+func TestRegisterEncryptionKey(t *testing.T) {
+	bot := newAuthTestBot()
+	signingKey, err := auth.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot.signingKeys.Store(testAgentKey, signingKey)
+
+	if _, ok := bot.activeEncryptionKey(testAgentKey); ok {
+		t.Fatal("server without advertised key must not have one")
+	}
+
+	_, first := newTestEncryptionKey(t)
+	auth.SignEncryptionKey(signingKey, &first)
+	bot.updateServerCapabilities(testAgentKey, protocol.Capabilities{
+		ProtocolVersion: protocol.ProtocolVersion,
+		Commands:        []protocol.MessageType{protocol.TypePing},
+		EncryptionKey:   &first,
+	})
+	if key, ok := bot.activeEncryptionKey(testAgentKey); !ok || key.ID != first.ID {
+		t.Fatalf("activeEncryptionKey() = %v, %v; want %s", key.ID, ok, first.ID)
+	}
+
+	// Rotation replaces the active key
+	_, second := newTestEncryptionKey(t)
+	auth.SignEncryptionKey(signingKey, &second)
+	bot.registerEncryptionKey(testAgentKey, second)
+	if key, _ := bot.activeEncryptionKey(testAgentKey); key.ID != second.ID {
+		t.Errorf("activeEncryptionKey() = %s, want %s", key.ID, second.ID)
+	}
+
+	// Key whose ID doesn't match the public key is ignored
+	forged := protocol.EncryptionKey{ID: "deadbeef", PublicKey: first.PublicKey}
+	bot.registerEncryptionKey(testAgentKey, forged)
+	if key, _ := bot.activeEncryptionKey(testAgentKey); key.ID != second.ID {
+		t.Errorf("forged key was accepted: %s", key.ID)
+	}
+
+	// Anyone who knows the server key can advertise a key, but can't sign it
+	_, unsigned := newTestEncryptionKey(t)
+	bot.registerEncryptionKey(testAgentKey, unsigned)
+	_, wrongSigner := newTestEncryptionKey(t)
+	auth.SignEncryptionKey(testAgentKey, &wrongSigner)
+	bot.registerEncryptionKey(testAgentKey, wrongSigner)
+	if key, _ := bot.activeEncryptionKey(testAgentKey); key.ID != second.ID {
+		t.Errorf("key without a valid signature was accepted: %s", key.ID)
+	}
+}
+
+func TestRegisterEncryptionKey_PinsFirstKeyWithoutSigningKey(t *testing.T) {
+	bot := newAuthTestBot()
+
+	_, first := newTestEncryptionKey(t)
+	bot.registerEncryptionKey(testAgentKey, first)
+	if key, ok := bot.activeEncryptionKey(testAgentKey); !ok || key.ID != first.ID {
+		t.Fatalf("activeEncryptionKey() = %v, %v; want %s", key.ID, ok, first.ID)
+	}
+
+	_, second := newTestEncryptionKey(t)
+	bot.registerEncryptionKey(testAgentKey, second)
+	if key, _ := bot.activeEncryptionKey(testAgentKey); key.ID != first.ID {
+		t.Errorf("key change without a signing key was accepted: %s", key.ID)
+	}
+}
+
+func TestEncryptCommand(t *testing.T) {
+	bot := newAuthTestBot()
+
+	command := protocol.NewMessage(protocol.TypeGetProcesses, nil)
+	sent, session, err := bot.encryptCommand(testAgentKey, command)
+	if err != nil || session != nil || sent != command {
+		t.Fatalf("agent without key: encryptCommand() = %v, %v, %v", sent, session, err)
+	}
+
+	pair, key := newTestEncryptionKey(t)
+	bot.registerEncryptionKey(testAgentKey, key)
+
+	sent, session, err = bot.encryptCommand(testAgentKey, command)
+	if err != nil || session == nil {
+		t.Fatalf("encryptCommand() error = %v", err)
+	}
+	if sent.Encryption != e2e.Algorithm || command.Encryption != "" {
+		t.Errorf("expected encrypted copy and untouched original, got %q / %q", sent.Encryption, command.Encryption)
+	}
+
+	if _, err := e2e.DecryptCommand(sent, func(id string) (*e2e.KeyPair, bool) { return pair, id == pair.ID }); err != nil {
+		t.Errorf("agent failed to decrypt command: %v", err)
+	}
+}
@@ -19,12 +19,18 @@ func (b *Bot) handleMessage(message *tgbotapi.Message) error {
 	case strings.HasPrefix(message.Text, "/temp"):
 		b.logger.Info("Info message")
 		response = b.handleTemp(message)
+	case strings.HasPrefix(message.Text, "/cpu"):
+		b.logger.Info("Info message")
+		response = b.handleCPU(message)
 	case strings.HasPrefix(message.Text, "/memory"):
 		b.logger.Info("Info message")
 		response = b.handleMemory(message)
 	case strings.HasPrefix(message.Text, "/disk"):
 		b.logger.Info("Info message")
 		response = b.handleDisk(message)
+	case strings.HasPrefix(message.Text, "/info"):
+		b.logger.Info("Info message")
+		response = b.handleInfo(message)
 	case strings.HasPrefix(message.Text, "/uptime"):
 		b.logger.Info("Info message")
 		response = b.handleUptime(message)
@@ -65,6 +71,9 @@ func (b *Bot) handleMessage(message *tgbotapi.Message) error {
 	case strings.HasPrefix(message.Text, "/stats"):
 		b.logger.Info("Info message")
 		response = b.handleStats(message)
+	case strings.HasPrefix(message.Text, "/dlq"):
+		b.logger.Info("Info message")
+		response = b.handleDLQ(message)
 	case strings.HasPrefix(message.Text, "srv_"):
 		b.logger.Info("Info message")
 		response = "❌ Please use /add command instead.\nExample: /add srv_your_key_here"
@@ -98,9 +107,11 @@ Example: /add srv_a1b2c3d4e5f6g7h8 MyServer
 Available commands:
 /add <key> [name] - Add server with optional name
 /temp - Get CPU temperature
+/cpu - Get CPU usage
 /memory - Get memory usage
 /disk - Get disk usage
 /uptime - Get system uptime
+/info - Get system information
 /processes - Get top processes
 /network - Get network statistics
 /containers - Manage Docker containers
@@ -115,9 +126,11 @@ func (b *Bot) handleHelp(message *tgbotapi.Message) string {
 
 📊 **Monitoring:**
 /temp - Get CPU temperature
+/cpu - Get CPU usage
 /memory - Get memory usage  
 /disk - Get disk usage
 /uptime - Get system uptime
+/info - Get system information
 /processes - List running processes
 /network - Get network statistics
 
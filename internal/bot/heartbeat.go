@@ -0,0 +1,174 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+// Server presence states stored in servers.status
+const (
+	serverStatusOnline  = "online"
+	serverStatusOffline = "offline"
+)
+
+// redisHeartbeatPattern matches heartbeat channels of agents connected to Redis directly
+const redisHeartbeatPattern = "heartbeat:*"
+
+// redisHeartbeat is the heartbeat agents publish to heartbeat:<key>
+type redisHeartbeat struct {
+	ServerKey    string                 `json:"server_key"`
+	Capabilities *protocol.Capabilities `json:"capabilities,omitempty"`
+}
+
+// heartbeatKey returns server key from heartbeat request (agents send api_key, legacy clients server_key)
+func heartbeatKey(req HeartbeatRequest) string {
+	if req.APIKey != "" {
+		return req.APIKey
+	}
+	return req.ServerKey
+}
+
+// processHeartbeat records heartbeat and notifies owners if server came back online.
+// caps is nil for agents that don't advertise capabilities.
+func (b *Bot) processHeartbeat(serverKey string, caps *protocol.Capabilities) error {
+	previousStatus, firstHeartbeat, err := b.recordHeartbeat(serverKey)
+	if err != nil {
+		return err
+	}
+
+	if caps != nil {
+		b.updateServerCapabilities(serverKey, *caps)
+	}
+
+	if previousStatus == serverStatusOffline {
+		// A new server starts as offline, its first heartbeat isn't a recovery
+		if firstHeartbeat {
+			b.logger.Info("Server connected for the first time", StringField("server_key", maskKey(serverKey)))
+		} else {
+			b.logger.Info("Server is back online", StringField("server_key", maskKey(serverKey)))
+			b.notifyServerSubscribers(serverKey, true)
+		}
+
+		if b.queueEnabled() {
+			go b.deliverQueuedCommands(serverKey)
+		}
+	}
+
+	return nil
+}
+
+// parseRedisHeartbeat decodes a heartbeat published to Redis Pub/Sub
+func parseRedisHeartbeat(data []byte) (redisHeartbeat, error) {
+	var heartbeat redisHeartbeat
+	if err := json.Unmarshal(data, &heartbeat); err != nil {
+		return heartbeat, fmt.Errorf("invalid heartbeat: %w", err)
+	}
+	if !strings.HasPrefix(heartbeat.ServerKey, "srv_") {
+		return heartbeat, fmt.Errorf("invalid heartbeat server key")
+	}
+	return heartbeat, nil
+}
+
+// startRedisHeartbeatListener records heartbeats of agents without the HTTP API,
+// they publish to heartbeat:<key> instead
+func (b *Bot) startRedisHeartbeatListener() {
+	sub, err := b.redisRawClient.PSubscribe(b.ctx, redisHeartbeatPattern)
+	if err != nil {
+		b.logger.Error("Failed to subscribe to Redis heartbeats", err)
+		return
+	}
+	defer sub.Close()
+
+	b.logger.Info("Redis heartbeat listener started")
+	b.consumeRedisHeartbeats(sub)
+}
+
+// consumeRedisHeartbeats processes heartbeats until the subscription is closed
+func (b *Bot) consumeRedisHeartbeats(sub Subscription) {
+	for data := range sub.Channel() {
+		heartbeat, err := parseRedisHeartbeat(data)
+		if err != nil {
+			b.logger.Warn("Ignoring Redis heartbeat", StringField("error", err.Error()))
+			continue
+		}
+
+		if err := b.processHeartbeat(heartbeat.ServerKey, heartbeat.Capabilities); err != nil {
+			b.logger.Error("Failed to record heartbeat", err, StringField("server_key", maskKey(heartbeat.ServerKey)))
+		}
+	}
+}
+
+// startHeartbeatMonitor periodically marks servers without heartbeat as offline
+func (b *Bot) startHeartbeatMonitor() {
+	offlineAfter := b.config.Heartbeat.GetOfflineAfter()
+	ticker := time.NewTicker(b.config.Heartbeat.GetCheckInterval())
+	defer ticker.Stop()
+
+	b.logger.Info("Heartbeat monitor started", StringField("offline_after", offlineAfter.String()))
+
+	for {
+		select {
+		case <-ticker.C:
+			b.sweepOfflineServers(offlineAfter)
+		case <-b.ctx.Done():
+			b.logger.Info("Heartbeat monitor stopped")
+			return
+		}
+	}
+}
+
+// sweepOfflineServers marks stale servers offline and notifies their owners
+func (b *Bot) sweepOfflineServers(offlineAfter time.Duration) {
+	servers, err := b.markStaleServersOffline(offlineAfter)
+	if err != nil {
+		b.logger.Error("Failed to mark stale servers offline", err)
+		return
+	}
+
+	for _, server := range servers {
+		b.logger.Warn("Server went offline", StringField("server_key", maskKey(server.SecretKey)))
+		b.notifyServerSubscribers(server.SecretKey, false)
+	}
+
+	if b.queueEnabled() {
+		b.expireQueuedCommands()
+	}
+}
+
+// notifyServerSubscribers sends online/offline notification to every user connected to the server
+func (b *Bot) notifyServerSubscribers(serverKey string, online bool) {
+	if b.telegramAPI == nil {
+		return
+	}
+
+	name, userIDs, err := b.getServerSubscribers(serverKey)
+	if err != nil {
+		b.logger.Error("Failed to get server subscribers", err)
+		return
+	}
+
+	text := formatPresenceNotification(name, online, time.Now())
+	for _, userID := range userIDs {
+		b.sendMessage(userID, text)
+	}
+}
+
+// formatPresenceNotification formats server down/up notification text
+func formatPresenceNotification(serverName string, online bool, at time.Time) string {
+	if online {
+		return fmt.Sprintf("🟢 Server %s is back online\n🕒 %s", serverName, at.Format("2006-01-02 15:04:05"))
+	}
+	return fmt.Sprintf("🔴 Server %s is offline\n⚠️ No heartbeat received\n🕒 %s", serverName, at.Format("2006-01-02 15:04:05"))
+}
+
+// maskKey hides most of the secret key for logging
+func maskKey(key string) string {
+	if len(key) <= 12 {
+		return key
+	}
+	return key[:12] + "..."
+}
@@ -0,0 +1,136 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatKey(t *testing.T) {
+	tests := []struct {
+		name string
+		req  HeartbeatRequest
+		want string
+	}{
+		{"agent api_key", HeartbeatRequest{APIKey: "srv_agent"}, "srv_agent"},
+		{"legacy server_key", HeartbeatRequest{ServerKey: "srv_legacy"}, "srv_legacy"},
+		{"api_key wins", HeartbeatRequest{APIKey: "srv_a", ServerKey: "srv_b"}, "srv_a"},
+		{"empty", HeartbeatRequest{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := heartbeatKey(tt.req); got != tt.want {
+				t.Errorf("heartbeatKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleHeartbeat_Validation(t *testing.T) {
+	bot := &Bot{logger: NewStructuredLogger(nil)}
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+	}{
+		{"wrong method", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"invalid json", http.MethodPost, "{", http.StatusBadRequest},
+		{"invalid key", http.MethodPost, `{"api_key":"bad"}`, http.StatusBadRequest},
+		{"foreign key", http.MethodPost, `{"api_key":"srv_other"}`, http.StatusForbidden},
+		{"no database", http.MethodPost, `{"api_key":"srv_test"}`, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/servers/heartbeat", bytes.NewBufferString(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), agentKeyContextKey{}, "srv_test"))
+			w := httptest.NewRecorder()
+
+			bot.handleHeartbeat(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestParseRedisHeartbeat(t *testing.T) {
+	// Format of the agent's sendHeartbeatRedis
+	data := `{"server_key":"srv_redis","server_name":"web-1","timestamp":"2024-01-02T03:04:05Z","status":"online",` +
+		`"capabilities":{"protocol_version":"1.2","commands":["ping"]}}`
+
+	heartbeat, err := parseRedisHeartbeat([]byte(data))
+	if err != nil {
+		t.Fatalf("parseRedisHeartbeat() error = %v", err)
+	}
+	if heartbeat.ServerKey != "srv_redis" {
+		t.Errorf("ServerKey = %q, want srv_redis", heartbeat.ServerKey)
+	}
+	if heartbeat.Capabilities == nil || heartbeat.Capabilities.ProtocolVersion != "1.2" {
+		t.Errorf("Capabilities = %+v, want protocol 1.2", heartbeat.Capabilities)
+	}
+
+	for _, bad := range []string{"{", `{"server_key":"bad"}`, `{}`} {
+		if _, err := parseRedisHeartbeat([]byte(bad)); err == nil {
+			t.Errorf("parseRedisHeartbeat(%s) expected error", bad)
+		}
+	}
+}
+
+// fakeSubscription delivers messages written to ch
+type fakeSubscription struct {
+	ch chan []byte
+}
+
+func (s *fakeSubscription) Channel() <-chan []byte { return s.ch }
+func (s *fakeSubscription) Close() error           { return nil }
+
+func TestConsumeRedisHeartbeats_StopsOnClose(t *testing.T) {
+	bot := newAuthTestBot()
+	sub := &fakeSubscription{ch: make(chan []byte, 1)}
+	sub.ch <- []byte("{broken")
+	close(sub.ch)
+
+	done := make(chan struct{})
+	go func() {
+		bot.consumeRedisHeartbeats(sub)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("consumeRedisHeartbeats did not return after the subscription closed")
+	}
+}
+
+func TestFormatPresenceNotification(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	offline := formatPresenceNotification("web-1", false, at)
+	if !strings.Contains(offline, "web-1 is offline") || !strings.Contains(offline, "2024-01-02 03:04:05") {
+		t.Errorf("Unexpected offline notification: %s", offline)
+	}
+
+	online := formatPresenceNotification("web-1", true, at)
+	if !strings.Contains(online, "web-1 is back online") {
+		t.Errorf("Unexpected online notification: %s", online)
+	}
+}
+
+func TestMaskKey(t *testing.T) {
+	if got := maskKey("srv_short"); got != "srv_short" {
+		t.Errorf("maskKey() = %q, want unchanged short key", got)
+	}
+	if got := maskKey("srv_0123456789abcdef"); got != "srv_01234567..." {
+		t.Errorf("maskKey() = %q, want masked key", got)
+	}
+}
@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/servereye/servereye/pkg/auth"
+)
+
+// maxAgentRequestBody limits body size of authenticated agent requests
+const maxAgentRequestBody = 1 << 20
+
+// Unknown key IDs are rejected without a database lookup for unknownKeyTTL;
+// at most maxUnknownKeys are remembered, the cache is reset when full
+const (
+	unknownKeyTTL  = time.Minute
+	maxUnknownKeys = 10000
+)
+
+// agentKeyContextKey is the request context key for authenticated server key
+type agentKeyContextKey struct{}
+
+// withAgentAuth verifies HMAC signature of agent requests before calling next
+func (b *Bot) withAgentAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := auth.ReadBody(r, maxAgentRequestBody)
+		if err != nil {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		secretKey, err := b.apiVerifier.Verify(r, body)
+		if err != nil {
+			b.logger.Warn("Agent authentication failed", StringField("path", r.URL.Path), StringField("reason", err.Error()))
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), agentKeyContextKey{}, secretKey)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// agentKeyFromRequest returns server key authenticated by withAgentAuth
+func agentKeyFromRequest(r *http.Request) string {
+	key, _ := r.Context().Value(agentKeyContextKey{}).(string)
+	return key
+}
+
+// resolveAgentKey looks up secret key by its public key ID
+func (b *Bot) resolveAgentKey(keyID string) (string, error) {
+	if cached, ok := b.agentKeys.Load(keyID); ok {
+		return cached.(string), nil
+	}
+
+	if b.db == nil || !validKeyID(keyID) || b.unknownAgentKeys.contains(keyID, time.Now()) {
+		return "", auth.ErrUnknownKey
+	}
+
+	var secretKey string
+	err := b.db.QueryRow("SELECT secret_key FROM generated_keys WHERE key_id = $1", keyID).Scan(&secretKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		b.unknownAgentKeys.add(keyID, time.Now())
+		return "", auth.ErrUnknownKey
+	}
+	if err != nil {
+		return "", err
+	}
+
+	b.agentKeys.Store(keyID, secretKey)
+	return secretKey, nil
+}
+
+// validKeyID reports whether keyID looks like auth.KeyID output (hex SHA-256)
+func validKeyID(keyID string) bool {
+	if len(keyID) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(keyID)
+	return err == nil
+}
+
+// unknownKeyCache remembers key IDs missing from the database, so unauthenticated
+// callers can't make every request hit it. The zero value is ready to use.
+type unknownKeyCache struct {
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+// contains reports whether keyID was recently found unknown
+func (c *unknownKeyCache) contains(keyID string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires, ok := c.expires[keyID]
+	if ok && now.After(expires) {
+		delete(c.expires, keyID)
+		return false
+	}
+	return ok
+}
+
+// add remembers keyID as unknown for unknownKeyTTL
+func (c *unknownKeyCache) add(keyID string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.expires == nil || len(c.expires) >= maxUnknownKeys {
+		c.expires = make(map[string]time.Time)
+	}
+	c.expires[keyID] = now.Add(unknownKeyTTL)
+}
+
+// remove forgets keyID, used when the key is generated
+func (c *unknownKeyCache) remove(keyID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.expires, keyID)
+}
@@ -0,0 +1,180 @@
+package bot
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/servereye/servereye/pkg/auth"
+	"github.com/sirupsen/logrus"
+)
+
+const testAgentKey = "srv_0123456789abcdef0123456789abcdef"
+
+func newAuthTestBot() *Bot {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	bot := &Bot{logger: NewStructuredLogger(logger)}
+	bot.agentKeys.Store(auth.KeyID(testAgentKey), testAgentKey)
+	bot.apiVerifier = auth.NewVerifier(bot.resolveAgentKey)
+	return bot
+}
+
+func TestWithAgentAuth(t *testing.T) {
+	bot := newAuthTestBot()
+
+	var gotKey string
+	handler := bot.withAgentAuth(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = agentKeyFromRequest(r)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	body := []byte(`{"stream":"stream:resp:` + testAgentKey + `"}`)
+
+	// Unsigned request
+	req := httptest.NewRequest(http.MethodPost, "/api/streams/xadd", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("unsigned request: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	// Signed request
+	req = httptest.NewRequest(http.MethodPost, "/api/streams/xadd", bytes.NewReader(body))
+	if err := auth.SignRequest(req, testAgentKey, body); err != nil {
+		t.Fatalf("SignRequest() error = %v", err)
+	}
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("signed request: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if gotKey != testAgentKey {
+		t.Errorf("authenticated key = %q, want %q", gotKey, testAgentKey)
+	}
+
+	// Replayed request
+	replay := httptest.NewRequest(http.MethodPost, "/api/streams/xadd", bytes.NewReader(body))
+	replay.Header = req.Header.Clone()
+	w = httptest.NewRecorder()
+	handler(w, replay)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("replayed request: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	// Unknown key, no database configured
+	req = httptest.NewRequest(http.MethodPost, "/api/streams/xadd", bytes.NewReader(body))
+	if err := auth.SignRequest(req, "srv_unknown", body); err != nil {
+		t.Fatalf("SignRequest() error = %v", err)
+	}
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("unknown key: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestStreamHandlers_RejectForeignStream(t *testing.T) {
+	bot := newAuthTestBot()
+
+	handlers := map[string]http.HandlerFunc{
+		"/api/streams/xadd":       bot.handleStreamAdd,
+		"/api/streams/xread":      bot.handleStreamRead,
+		"/api/streams/xreadgroup": bot.handleStreamReadGroup,
+		"/api/streams/xack":       bot.handleStreamAck,
+	}
+
+	body := []byte(`{"stream":"stream:cmd:srv_someone_else"}`)
+	for path, handler := range handlers {
+		t.Run(path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+			if err := auth.SignRequest(req, testAgentKey, body); err != nil {
+				t.Fatalf("SignRequest() error = %v", err)
+			}
+			w := httptest.NewRecorder()
+
+			bot.withAgentAuth(handler)(w, req)
+
+			if w.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestPubSubHandlers_RejectForeignChannel(t *testing.T) {
+	bot := newAuthTestBot()
+
+	tests := []struct {
+		path    string
+		handler http.HandlerFunc
+		body    string
+	}{
+		{"/api/redis/publish", bot.handleRedisPublish, `{"channel":"resp:srv_other:1","message":"x"}`},
+		{"/api/redis/subscribe", bot.handleRedisSubscribe, `{"channel":"cmd:srv_other"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			body := []byte(tt.body)
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader(body))
+			if err := auth.SignRequest(req, testAgentKey, body); err != nil {
+				t.Fatalf("SignRequest() error = %v", err)
+			}
+			w := httptest.NewRecorder()
+
+			bot.withAgentAuth(tt.handler)(w, req)
+
+			if w.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestValidKeyID(t *testing.T) {
+	if !validKeyID(auth.KeyID(testAgentKey)) {
+		t.Error("auth.KeyID output must be valid")
+	}
+	for _, keyID := range []string{"", "abc", testAgentKey, strings.Repeat("z", 64)} {
+		if validKeyID(keyID) {
+			t.Errorf("validKeyID(%q) = true, want false", keyID)
+		}
+	}
+}
+
+func TestUnknownKeyCache(t *testing.T) {
+	var cache unknownKeyCache
+	now := time.Now()
+	keyID := auth.KeyID("srv_unknown")
+
+	if cache.contains(keyID, now) {
+		t.Fatal("Empty cache must not contain key IDs")
+	}
+
+	cache.add(keyID, now)
+	if !cache.contains(keyID, now.Add(unknownKeyTTL/2)) {
+		t.Error("Unknown key ID must be remembered within TTL")
+	}
+	if cache.contains(keyID, now.Add(2*unknownKeyTTL)) {
+		t.Error("Unknown key ID must expire after TTL")
+	}
+
+	cache.add(keyID, now)
+	cache.remove(keyID)
+	if cache.contains(keyID, now) {
+		t.Error("Generated key must be removed from the cache")
+	}
+
+	for i := 0; i < maxUnknownKeys+1; i++ {
+		cache.add(auth.KeyID(strconv.Itoa(i)), now)
+	}
+	if n := len(cache.expires); n > maxUnknownKeys {
+		t.Errorf("Cache holds %d key IDs, limit is %d", n, maxUnknownKeys)
+	}
+}
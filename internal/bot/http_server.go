@@ -6,15 +6,21 @@ import (
 	"strings"
 	"time"
 
-	"github.com/redis/go-redis/v9"
+	"github.com/servereye/servereye/pkg/auth"
+	"github.com/servereye/servereye/pkg/protocol"
+	"github.com/servereye/servereye/pkg/redis/streams"
+	"github.com/servereye/servereye/pkg/transport"
 )
 
 // KeyRegistrationRequest represents a request to register a generated key
 type KeyRegistrationRequest struct {
 	SecretKey    string `json:"secret_key"`
+	SigningKey   string `json:"signing_key,omitempty"`
 	AgentVersion string `json:"agent_version,omitempty"`
 	OSInfo       string `json:"os_info,omitempty"`
 	Hostname     string `json:"hostname,omitempty"`
+
+	Capabilities *protocol.Capabilities `json:"capabilities,omitempty"`
 }
 
 // startHTTPServer starts HTTP server for agent API
@@ -26,23 +32,26 @@ func (b *Bot) startHTTPServer() {
 	}()
 
 	b.logger.Info("Info message")
+	b.apiVerifier = auth.NewVerifier(b.resolveAgentKey)
+
+	// Public endpoints: key bootstrap and web integration
 	http.HandleFunc("/api/register-key", b.handleRegisterKey)
 	http.HandleFunc("/api/validate-key/", b.handleValidateKey)
 	http.HandleFunc("/api/health", b.handleHealth)
-	http.HandleFunc("/api/heartbeat", b.handleHeartbeat)
-	http.HandleFunc("/api/redis/publish", b.handleRedisPublish)
-	http.HandleFunc("/api/redis/subscribe", b.handleRedisSubscribe)
+
+	// Agent endpoints require HMAC-signed requests
+	http.HandleFunc("/api/heartbeat", b.withAgentAuth(b.handleHeartbeat))
+	http.HandleFunc("/api/v1/servers/heartbeat", b.withAgentAuth(b.handleHeartbeat))
+	http.HandleFunc("/api/redis/publish", b.withAgentAuth(b.handleRedisPublish))
+	http.HandleFunc("/api/redis/subscribe", b.withAgentAuth(b.handleRedisSubscribe))
 
 	// Redis Streams endpoints (new)
-	http.HandleFunc("/api/streams/xadd", b.handleStreamAdd)
-	http.HandleFunc("/api/streams/xread", b.handleStreamRead)
-	http.HandleFunc("/api/streams/xreadgroup", b.handleStreamReadGroup)
-	http.HandleFunc("/api/streams/xack", b.handleStreamAck)
+	b.registerStreamRoutes(http.DefaultServeMux)
 
-	http.HandleFunc("/api/monitoring/memory", b.handleMemoryRequest)
-	http.HandleFunc("/api/monitoring/disk", b.handleDiskRequest)
-	http.HandleFunc("/api/monitoring/uptime", b.handleUptimeRequest)
-	http.HandleFunc("/api/monitoring/processes", b.handleProcessesRequest)
+	http.HandleFunc("/api/monitoring/memory", b.withAgentAuth(b.handleMemoryRequest))
+	http.HandleFunc("/api/monitoring/disk", b.withAgentAuth(b.handleDiskRequest))
+	http.HandleFunc("/api/monitoring/uptime", b.withAgentAuth(b.handleUptimeRequest))
+	http.HandleFunc("/api/monitoring/processes", b.withAgentAuth(b.handleProcessesRequest))
 
 	// Statistics endpoints for ServerEye-Web integration
 	http.HandleFunc("/api/stats/users", b.handleUserStats)
@@ -62,6 +71,24 @@ func (b *Bot) startHTTPServer() {
 	}
 }
 
+// registerStreamRoutes registers /api/streams/* endpoints, one per StreamClient method,
+// and the agent tunnel served from the same streams
+func (b *Bot) registerStreamRoutes(mux *http.ServeMux) {
+	mux.HandleFunc(transport.TunnelPath, b.withAgentAuth(b.handleTunnel))
+	mux.HandleFunc("/api/streams/xadd", b.withAgentAuth(b.handleStreamAdd))
+	mux.HandleFunc("/api/streams/xread", b.withAgentAuth(b.handleStreamRead))
+	mux.HandleFunc("/api/streams/xreadgroup", b.withAgentAuth(b.handleStreamReadGroup))
+	mux.HandleFunc("/api/streams/xack", b.withAgentAuth(b.handleStreamAck))
+	mux.HandleFunc("/api/streams/xgroup", b.withAgentAuth(b.handleStreamGroupCreate))
+	mux.HandleFunc("/api/streams/xpending", b.withAgentAuth(b.handleStreamPending))
+	mux.HandleFunc("/api/streams/xclaim", b.withAgentAuth(b.handleStreamClaim))
+	mux.HandleFunc("/api/streams/xrange", b.withAgentAuth(b.handleStreamRange))
+	mux.HandleFunc("/api/streams/xlast", b.withAgentAuth(b.handleStreamLastID))
+	mux.HandleFunc("/api/streams/xtrim", b.withAgentAuth(b.handleStreamTrim))
+	mux.HandleFunc("/api/streams/xlen", b.withAgentAuth(b.handleStreamLength))
+	mux.HandleFunc("/api/streams/ping", b.withAgentAuth(b.handleStreamPing))
+}
+
 // handleRegisterKey handles key registration from agent
 func (b *Bot) handleRegisterKey(w http.ResponseWriter, r *http.Request) {
 	b.logger.Info("HTTP request received")
@@ -86,9 +113,13 @@ func (b *Bot) handleRegisterKey(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid secret key format", http.StatusBadRequest)
 		return
 	}
+	if req.SigningKey != "" && !strings.HasPrefix(req.SigningKey, auth.SigningKeyPrefix) {
+		http.Error(w, "Invalid signing key format", http.StatusBadRequest)
+		return
+	}
 
 	// Record the key
-	if err := b.recordGeneratedKey(req.SecretKey); err != nil {
+	if err := b.recordGeneratedKey(req.SecretKey, req.SigningKey); err != nil {
 		b.logger.Error("Error occurred", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -101,6 +132,10 @@ func (b *Bot) handleRegisterKey(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if req.Capabilities != nil {
+		b.updateServerCapabilities(req.SecretKey, *req.Capabilities)
+	}
+
 	b.logger.Info("Operation completed")
 
 	b.writeJSONSuccess(w, map[string]string{
@@ -157,9 +192,12 @@ func (b *Bot) handleHealth(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// HeartbeatRequest represents an agent heartbeat
+// HeartbeatRequest represents an agent heartbeat.
+// Capabilities is nil for agents older than protocol 1.1.
 type HeartbeatRequest struct {
-	ServerKey string `json:"server_key"`
+	ServerKey    string                 `json:"server_key,omitempty"`
+	APIKey       string                 `json:"api_key,omitempty"`
+	Capabilities *protocol.Capabilities `json:"capabilities,omitempty"`
 }
 
 // handleHeartbeat handles heartbeat requests from agents
@@ -175,9 +213,31 @@ func (b *Bot) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	serverKey := heartbeatKey(req)
+	if !strings.HasPrefix(serverKey, "srv_") {
+		http.Error(w, "Invalid secret key format", http.StatusBadRequest)
+		return
+	}
+
+	if serverKey != agentKeyFromRequest(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if b.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if err := b.processHeartbeat(serverKey, req.Capabilities); err != nil {
+		b.logger.Error("Failed to record heartbeat", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	b.writeJSON(w, map[string]string{
 		"status":     "ok",
-		"server_key": req.ServerKey,
+		"server_key": serverKey,
 	})
 }
 
@@ -215,6 +275,11 @@ func (b *Bot) handleRedisPublish(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !auth.ChannelAllowed(agentKeyFromRequest(r), req.Channel) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
 	// Publish to Redis
 	b.logger.Info("Publishing to Redis")
 	if err := b.redisClient.Publish(b.ctx, req.Channel, []byte(req.Message)); err != nil {
@@ -252,6 +317,11 @@ func (b *Bot) handleRedisSubscribe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !auth.ChannelAllowed(agentKeyFromRequest(r), req.Channel) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
 	// Set default timeout - very short to not miss commands
 	if req.Timeout == 0 {
 		req.Timeout = 1
@@ -328,6 +398,11 @@ func (b *Bot) handleMemoryRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.ServerKey != agentKeyFromRequest(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
 	// Get memory info directly
 	memInfo, err := b.getMemoryInfo(req.ServerKey)
 	if err != nil {
@@ -357,113 +432,137 @@ func (b *Bot) handleProcessesRequest(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "Not implemented yet", http.StatusNotImplemented)
 }
 
-// getRawRedisClient returns the underlying redis.Client for Streams operations
-func (b *Bot) getRawRedisClient() (*redis.Client, bool) {
-	if b.redisRawClient != nil {
-		return b.redisRawClient.GetRawClient(), true
+// maxStreamBlock caps blocking reads below the HTTP server WriteTimeout
+const maxStreamBlock = 10 * time.Second
+
+// streamsBackend returns the Streams client used by /api/streams endpoints
+func (b *Bot) streamsBackend(w http.ResponseWriter) (streams.StreamClient, bool) {
+	if b.streamsClient == nil {
+		http.Error(w, "Streams client not available", http.StatusServiceUnavailable)
+		return nil, false
 	}
-	return nil, false
+	return b.streamsClient, true
 }
 
-// handleStreamAdd handles XADD requests (add message to stream)
-func (b *Bot) handleStreamAdd(w http.ResponseWriter, r *http.Request) {
+// decodeStreamRequest decodes a POST body and checks the agent may access stream
+func decodeStreamRequest(w http.ResponseWriter, r *http.Request, req interface{}, stream func() string) bool {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+		return false
 	}
 
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return false
+	}
+
+	if stream != nil && !auth.StreamAllowed(agentKeyFromRequest(r), stream()) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// streamBlock converts block_ms to a duration capped by maxStreamBlock
+func streamBlock(ms int64) time.Duration {
+	block := time.Duration(ms) * time.Millisecond
+	if block > maxStreamBlock {
+		return maxStreamBlock
+	}
+	return block
+}
+
+// streamWireMessage is the JSON shape of a stream entry in HTTP responses
+type streamWireMessage struct {
+	ID     string            `json:"ID"`
+	Values map[string]string `json:"Values"`
+}
+
+// streamWireMessages converts stream entries to their JSON shape
+func streamWireMessages(messages []streams.StreamMessage) []streamWireMessage {
+	result := make([]streamWireMessage, 0, len(messages))
+	for _, msg := range messages {
+		result = append(result, streamWireMessage{ID: msg.ID, Values: msg.Values})
+	}
+	return result
+}
+
+// writeStreamsResponse writes entries in the XREAD reply shape: [{Stream, Messages}]
+func writeStreamsResponse(w http.ResponseWriter, stream string, messages []streams.StreamMessage) {
+	type wireStream struct {
+		Stream   string              `json:"Stream"`
+		Messages []streamWireMessage `json:"Messages"`
+	}
+
+	result := []wireStream{}
+	if len(messages) > 0 {
+		result = append(result, wireStream{Stream: stream, Messages: streamWireMessages(messages)})
+	}
+
+	writeStreamJSON(w, map[string]interface{}{"streams": result})
+}
+
+// writeStreamJSON writes a JSON response for /api/streams endpoints
+func writeStreamJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+// handleStreamAdd handles XADD requests (add message to stream)
+func (b *Bot) handleStreamAdd(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Stream string            `json:"stream"`
 		Values map[string]string `json:"values"`
 	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	if !decodeStreamRequest(w, r, &req, func() string { return req.Stream }) {
 		return
 	}
 
-	rdb, ok := b.getRawRedisClient()
+	client, ok := b.streamsBackend(w)
 	if !ok {
-		http.Error(w, "Redis client not available", http.StatusInternalServerError)
 		return
 	}
 
-	id, err := rdb.XAdd(r.Context(), &redis.XAddArgs{
-		Stream: req.Stream,
-		Values: req.Values,
-	}).Result()
-
+	id, err := client.AddMessage(r.Context(), req.Stream, req.Values)
 	if err != nil {
 		b.logger.Error("XADD failed", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"id": id})
+	writeStreamJSON(w, map[string]string{"id": id})
 }
 
 // handleStreamRead handles XREAD requests (read messages)
 func (b *Bot) handleStreamRead(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var req struct {
 		Stream string `json:"stream"`
 		LastID string `json:"last_id"`
 		Count  int64  `json:"count"`
 		Block  int64  `json:"block_ms"` // milliseconds
 	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	if !decodeStreamRequest(w, r, &req, func() string { return req.Stream }) {
 		return
 	}
 
-	if req.LastID == "" {
-		req.LastID = "0"
-	}
-	if req.Count <= 0 {
-		req.Count = 10
-	}
-
-	rdb, ok := b.getRawRedisClient()
+	client, ok := b.streamsBackend(w)
 	if !ok {
-		http.Error(w, "Redis client not available", http.StatusInternalServerError)
 		return
 	}
 
-	streams, err := rdb.XRead(r.Context(), &redis.XReadArgs{
-		Streams: []string{req.Stream, req.LastID},
-		Count:   req.Count,
-		Block:   time.Duration(req.Block) * time.Millisecond,
-	}).Result()
-
+	messages, err := client.ReadMessages(r.Context(), req.Stream, req.LastID, req.Count, streamBlock(req.Block))
 	if err != nil {
-		if err == redis.Nil {
-			// No messages
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]interface{}{"messages": []interface{}{}})
-			return
-		}
 		b.logger.Error("XREAD failed", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"streams": streams})
+	writeStreamsResponse(w, req.Stream, messages)
 }
 
 // handleStreamReadGroup handles XREADGROUP requests (consumer group read)
 func (b *Bot) handleStreamReadGroup(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var req struct {
 		Stream   string `json:"stream"`
 		Group    string `json:"group"`
@@ -471,78 +570,273 @@ func (b *Bot) handleStreamReadGroup(w http.ResponseWriter, r *http.Request) {
 		Count    int64  `json:"count"`
 		Block    int64  `json:"block_ms"`
 	}
+	if !decodeStreamRequest(w, r, &req, func() string { return req.Stream }) {
+		return
+	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	client, ok := b.streamsBackend(w)
+	if !ok {
+		return
+	}
+
+	messages, err := client.ReadGroupMessages(r.Context(), req.Stream, req.Group, req.Consumer, req.Count, streamBlock(req.Block))
+	if err != nil {
+		b.logger.Error("XREADGROUP failed", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeStreamsResponse(w, req.Stream, messages)
+}
+
+// handleStreamAck handles XACK requests (acknowledge message)
+func (b *Bot) handleStreamAck(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Stream string `json:"stream"`
+		Group  string `json:"group"`
+		ID     string `json:"id"`
+	}
+	if !decodeStreamRequest(w, r, &req, func() string { return req.Stream }) {
+		return
+	}
+
+	client, ok := b.streamsBackend(w)
+	if !ok {
+		return
+	}
+
+	if err := client.AckMessage(r.Context(), req.Stream, req.Group, req.ID); err != nil {
+		b.logger.Error("XACK failed", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeStreamJSON(w, map[string]bool{"success": true})
+}
+
+// handleStreamGroupCreate handles XGROUP CREATE requests (create consumer group)
+func (b *Bot) handleStreamGroupCreate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Stream  string `json:"stream"`
+		Group   string `json:"group"`
+		StartID string `json:"start_id"`
+	}
+	if !decodeStreamRequest(w, r, &req, func() string { return req.Stream }) {
+		return
+	}
+	if req.Group == "" {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
 
-	if req.Count <= 0 {
-		req.Count = 10
+	client, ok := b.streamsBackend(w)
+	if !ok {
+		return
+	}
+
+	if err := client.CreateConsumerGroup(r.Context(), req.Stream, req.Group, req.StartID); err != nil {
+		b.logger.Error("XGROUP CREATE failed", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeStreamJSON(w, map[string]bool{"success": true})
+}
+
+// handleStreamPending handles XPENDING requests (list unacknowledged messages)
+func (b *Bot) handleStreamPending(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Stream string `json:"stream"`
+		Group  string `json:"group"`
+		IdleMs int64  `json:"idle_ms"`
+		Count  int64  `json:"count"`
+	}
+	if !decodeStreamRequest(w, r, &req, func() string { return req.Stream }) {
+		return
 	}
 
-	rdb, ok := b.getRawRedisClient()
+	client, ok := b.streamsBackend(w)
 	if !ok {
-		http.Error(w, "Redis client not available", http.StatusInternalServerError)
 		return
 	}
 
-	streams, err := rdb.XReadGroup(r.Context(), &redis.XReadGroupArgs{
-		Group:    req.Group,
-		Consumer: req.Consumer,
-		Streams:  []string{req.Stream, ">"},
-		Count:    req.Count,
-		Block:    time.Duration(req.Block) * time.Millisecond,
-	}).Result()
+	pending, err := client.PendingMessages(r.Context(), req.Stream, req.Group, time.Duration(req.IdleMs)*time.Millisecond, req.Count)
+	if err != nil {
+		b.logger.Error("XPENDING failed", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
+	type pendingEntry struct {
+		ID         string `json:"id"`
+		Consumer   string `json:"consumer"`
+		IdleMs     int64  `json:"idle_ms"`
+		RetryCount int64  `json:"retry_count"`
+	}
+
+	entries := make([]pendingEntry, 0, len(pending))
+	for _, p := range pending {
+		entries = append(entries, pendingEntry{
+			ID:         p.ID,
+			Consumer:   p.Consumer,
+			IdleMs:     p.Idle.Milliseconds(),
+			RetryCount: p.RetryCount,
+		})
+	}
+
+	writeStreamJSON(w, map[string]interface{}{"pending": entries})
+}
+
+// handleStreamClaim handles XCLAIM requests (take over pending messages)
+func (b *Bot) handleStreamClaim(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Stream    string   `json:"stream"`
+		Group     string   `json:"group"`
+		Consumer  string   `json:"consumer"`
+		MinIdleMs int64    `json:"min_idle_ms"`
+		IDs       []string `json:"ids"`
+	}
+	if !decodeStreamRequest(w, r, &req, func() string { return req.Stream }) {
+		return
+	}
+
+	client, ok := b.streamsBackend(w)
+	if !ok {
+		return
+	}
+
+	messages, err := client.ClaimMessages(r.Context(), req.Stream, req.Group, req.Consumer, time.Duration(req.MinIdleMs)*time.Millisecond, req.IDs)
 	if err != nil {
-		if err == redis.Nil {
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]interface{}{"messages": []interface{}{}})
-			return
-		}
-		b.logger.Error("XREADGROUP failed", err)
+		b.logger.Error("XCLAIM failed", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"streams": streams})
+	writeStreamJSON(w, map[string]interface{}{"messages": streamWireMessages(messages)})
 }
 
-// handleStreamAck handles XACK requests (acknowledge message)
-func (b *Bot) handleStreamAck(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// handleStreamRange handles XRANGE requests (read messages between IDs)
+func (b *Bot) handleStreamRange(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Stream string `json:"stream"`
+		Start  string `json:"start"`
+		End    string `json:"end"`
+		Count  int64  `json:"count"`
+	}
+	if !decodeStreamRequest(w, r, &req, func() string { return req.Stream }) {
+		return
+	}
+
+	client, ok := b.streamsBackend(w)
+	if !ok {
 		return
 	}
 
+	messages, err := client.RangeMessages(r.Context(), req.Stream, req.Start, req.End, req.Count)
+	if err != nil {
+		b.logger.Error("XRANGE failed", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeStreamJSON(w, map[string]interface{}{"messages": streamWireMessages(messages)})
+}
+
+// handleStreamLastID handles requests for the newest message ID (XREVRANGE + - COUNT 1)
+func (b *Bot) handleStreamLastID(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Stream string `json:"stream"`
-		Group  string `json:"group"`
-		ID     string `json:"id"`
+	}
+	if !decodeStreamRequest(w, r, &req, func() string { return req.Stream }) {
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	client, ok := b.streamsBackend(w)
+	if !ok {
+		return
+	}
+
+	id, err := client.LastMessageID(r.Context(), req.Stream)
+	if err != nil {
+		b.logger.Error("XREVRANGE failed", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeStreamJSON(w, map[string]string{"id": id})
+}
+
+// handleStreamTrim handles XTRIM requests (limit stream length)
+func (b *Bot) handleStreamTrim(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Stream string `json:"stream"`
+		MaxLen int64  `json:"max_len"`
+	}
+	if !decodeStreamRequest(w, r, &req, func() string { return req.Stream }) {
+		return
+	}
+	if req.MaxLen < 0 {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
 
-	rdb, ok := b.getRawRedisClient()
+	client, ok := b.streamsBackend(w)
+	if !ok {
+		return
+	}
+
+	if err := client.TrimStream(r.Context(), req.Stream, req.MaxLen); err != nil {
+		b.logger.Error("XTRIM failed", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeStreamJSON(w, map[string]bool{"success": true})
+}
+
+// handleStreamLength handles XLEN requests (stream length)
+func (b *Bot) handleStreamLength(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Stream string `json:"stream"`
+	}
+	if !decodeStreamRequest(w, r, &req, func() string { return req.Stream }) {
+		return
+	}
+
+	client, ok := b.streamsBackend(w)
 	if !ok {
-		http.Error(w, "Redis client not available", http.StatusInternalServerError)
 		return
 	}
 
-	err := rdb.XAck(r.Context(), req.Stream, req.Group, req.ID).Err()
+	length, err := client.GetStreamLength(r.Context(), req.Stream)
 	if err != nil {
-		b.logger.Error("XACK failed", err)
+		b.logger.Error("XLEN failed", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	writeStreamJSON(w, map[string]int64{"length": length})
+}
+
+// handleStreamPing checks the Streams backend is reachable
+func (b *Bot) handleStreamPing(w http.ResponseWriter, r *http.Request) {
+	var req struct{}
+	if !decodeStreamRequest(w, r, &req, nil) {
+		return
+	}
+
+	client, ok := b.streamsBackend(w)
+	if !ok {
+		return
+	}
+
+	if err := client.Ping(r.Context()); err != nil {
+		b.logger.Error("Streams ping failed", err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	writeStreamJSON(w, map[string]bool{"success": true})
 }
 
 // handleUserStats returns user statistics for ServerEye-Web integration
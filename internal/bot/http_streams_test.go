@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/servereye/servereye/pkg/auth"
+	"github.com/servereye/servereye/pkg/redis/streams"
+	"github.com/servereye/servereye/pkg/redis/streams/streamtest"
+	"github.com/sirupsen/logrus"
+)
+
+// newStreamsTestServer serves /api/streams/* backed by client
+func newStreamsTestServer(t *testing.T, client streams.StreamClient) (*Bot, *httptest.Server) {
+	t.Helper()
+
+	bot := newAuthTestBot()
+	bot.streamsClient = client
+
+	mux := http.NewServeMux()
+	bot.registerStreamRoutes(mux)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return bot, server
+}
+
+// httpStreamFactory returns HTTPStreamClients for fresh agent keys against server
+func httpStreamFactory(bot *Bot, server *httptest.Server) streamtest.Factory {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	return func(t *testing.T) (streams.StreamClient, string) {
+		secretKey := "srv_" + uuid.NewString()
+		bot.agentKeys.Store(auth.KeyID(secretKey), secretKey)
+		return streams.NewHTTPStreamClient(server.URL, secretKey, logger), "stream:cmd:" + secretKey
+	}
+}
+
+func TestHTTPStreamClientConformance_Redis(t *testing.T) {
+	addr := os.Getenv("SERVEREYE_TEST_REDIS")
+	if addr == "" {
+		t.Skip("Requires Redis server, set SERVEREYE_TEST_REDIS=host:port")
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	config := streams.DefaultConfig()
+	config.Addr = addr
+	direct, err := streams.NewClient(config, logger)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { direct.Close() })
+
+	bot, server := newStreamsTestServer(t, direct)
+	streamtest.Run(t, httpStreamFactory(bot, server))
+}
+
+func TestHTTPStreamClientConformance_Memory(t *testing.T) {
+	bot, server := newStreamsTestServer(t, streams.NewMemoryClient(nil))
+	streamtest.Run(t, httpStreamFactory(bot, server))
+}
+
+func TestStreamEndpoints_RejectForeignStream(t *testing.T) {
+	bot, server := newStreamsTestServer(t, streams.NewMemoryClient(nil))
+	factory := httpStreamFactory(bot, server)
+	client, _ := factory(t)
+
+	// Signed with own key, but targets another server's stream
+	_, err := client.GetStreamLength(t.Context(), "stream:cmd:"+testAgentKey)
+	if err == nil {
+		t.Fatal("Expected error for foreign stream")
+	}
+	if !strings.HasPrefix(err.Error(), "HTTP error: 403") {
+		t.Errorf("Expected 403, got %v", err)
+	}
+}
+
+func TestStreamEndpoints_NoBackend(t *testing.T) {
+	bot, server := newStreamsTestServer(t, nil)
+	client, stream := httpStreamFactory(bot, server)(t)
+
+	if err := client.Ping(t.Context()); err == nil {
+		t.Error("Expected Ping() error without Streams backend")
+	}
+	if _, err := client.GetStreamLength(t.Context(), stream); err == nil {
+		t.Error("Expected error without Streams backend")
+	}
+}
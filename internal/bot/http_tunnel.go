@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/servereye/servereye/internal/config"
+	"github.com/servereye/servereye/pkg/auth"
+	"github.com/servereye/servereye/pkg/redis/streams"
+	"github.com/servereye/servereye/pkg/transport"
+)
+
+const (
+	// tunnelKeepAlive is the ping interval, a tunnel silent for three intervals is dropped
+	tunnelKeepAlive = 30 * time.Second
+	// tunnelReadBlock is the XREADGROUP block time of the command pusher
+	tunnelReadBlock = 5 * time.Second
+	// tunnelConsumer is the consumer name of tunnel deliveries in the agents' group
+	tunnelConsumer = "tunnel"
+)
+
+// handleTunnel upgrades an authenticated agent request to a persistent tunnel.
+// Commands are pushed from stream:cmd:<key>, responses go to stream:resp:<key>.
+func (b *Bot) handleTunnel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	client, ok := b.streamsBackend(w)
+	if !ok {
+		return
+	}
+
+	// Plain GET is a capability probe
+	if !strings.EqualFold(r.Header.Get("Upgrade"), transport.TunnelProtocol) {
+		w.Header().Set("Upgrade", transport.TunnelProtocol)
+		http.Error(w, "Upgrade required", http.StatusUpgradeRequired)
+		return
+	}
+
+	raw, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		b.logger.Error("Tunnel hijack failed", err)
+		http.Error(w, "Upgrade not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// Server read/write timeouts don't apply to a long-lived tunnel
+	raw.SetDeadline(time.Time{})
+
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: %s\r\n\r\n", transport.TunnelProtocol)
+	if err := rw.Flush(); err != nil {
+		raw.Close()
+		return
+	}
+
+	conn := transport.NewTunnelConn(rw.Reader, raw)
+	b.serveTunnel(conn, client, agentKeyFromRequest(r))
+}
+
+// serveTunnel runs the tunnel protocol until either side closes the connection
+func (b *Bot) serveTunnel(conn *transport.TunnelConn, client streams.StreamClient, serverKey string) {
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(b.ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-conn.Done():
+		}
+	}()
+	go conn.KeepAlive(tunnelKeepAlive)
+
+	hello, err := conn.Receive()
+	if err != nil || hello.Type != transport.FrameHello {
+		conn.Send(transport.TunnelFrame{Type: transport.FrameError, Error: "hello expected"})
+		return
+	}
+
+	respStream := fmt.Sprintf("stream:resp:%s", serverKey)
+
+	// Commands are read through the agents' consumer group and ACKed once answered, so
+	// commands lost with a dropped tunnel are pushed again and dead-lettered like in
+	// Streams mode. The agent cursor only sets where a new group starts.
+	adapter := streams.NewAgentAdapter(client, serverKey, config.DefaultConsumerGroup, tunnelConsumer, b.logrusLogger())
+	adapter.SetStartID(hello.ID)
+	if err := adapter.Initialize(ctx); err != nil {
+		b.logger.Error("Tunnel resume failed", err, StringField("server_key", maskKey(serverKey)))
+		conn.Send(transport.TunnelFrame{Type: transport.FrameError, Error: "resume failed"})
+		return
+	}
+
+	b.logger.Info("Agent tunnel connected", StringField("server_key", maskKey(serverKey)), StringField("last_id", hello.ID))
+
+	// Stream entry IDs of pushed commands by command ID, for the ACK after the response
+	var delivered sync.Map
+	go func() {
+		err := adapter.Deliver(ctx, tunnelReadBlock, func(msg streams.StreamMessage) error {
+			delivered.Store(msg.Values["id"], msg.ID)
+			return conn.Send(transport.TunnelFrame{Type: transport.FrameCommand, ID: msg.ID, Values: msg.Values})
+		})
+		if err != nil && ctx.Err() == nil {
+			b.logger.Error("Tunnel command delivery stopped", err, StringField("server_key", maskKey(serverKey)))
+		}
+	}()
+
+	for {
+		frame, err := conn.Receive()
+		if err != nil {
+			b.logger.Info("Agent tunnel closed", StringField("server_key", maskKey(serverKey)))
+			return
+		}
+
+		switch frame.Type {
+		case transport.FrameResponse:
+			if _, err := client.AddMessage(ctx, respStream, frame.Values); err != nil {
+				// Not ACKed: the command is pushed again after the visibility timeout
+				b.logger.Error("Tunnel response XADD failed", err)
+				continue
+			}
+			if final := frame.Values[streams.ChunkFinalField]; final != "" && final != "true" {
+				continue
+			}
+			if streamID, ok := delivered.LoadAndDelete(frame.Values["command_id"]); ok {
+				if err := adapter.Ack(ctx, streamID.(string)); err != nil {
+					b.logger.Error("Tunnel command ACK failed", err)
+				}
+			}
+		case transport.FrameEvent:
+			if !auth.ChannelAllowed(serverKey, frame.Channel) {
+				b.logger.Warn("Tunnel event to foreign channel rejected", StringField("channel", frame.Channel))
+				continue
+			}
+			if err := b.redisClient.Publish(ctx, frame.Channel, []byte(frame.Data)); err != nil {
+				b.logger.Error("Tunnel event publish failed", err)
+			}
+		case transport.FrameHeartbeat:
+			if b.db == nil {
+				continue
+			}
+			caps, _ := frame.Capabilities()
+			if err := b.processHeartbeat(serverKey, caps); err != nil {
+				b.logger.Error("Tunnel heartbeat failed", err, StringField("server_key", maskKey(serverKey)))
+			}
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/servereye/servereye/internal/config"
+	"github.com/servereye/servereye/pkg/auth"
+	"github.com/servereye/servereye/pkg/protocol"
+	"github.com/servereye/servereye/pkg/redis/streams"
+	"github.com/servereye/servereye/pkg/transport"
+	"github.com/sirupsen/logrus"
+)
+
+// newTunnelTestClient registers a fresh agent key and returns a tunnel client for it
+func newTunnelTestClient(t *testing.T, bot *Bot, baseURL string) (*transport.TunnelClient, string) {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	secretKey := "srv_" + uuid.NewString()
+	bot.agentKeys.Store(auth.KeyID(secretKey), secretKey)
+
+	client := transport.NewTunnelClient(transport.HTTPConfig{
+		BaseURL:   baseURL,
+		SecretKey: secretKey,
+		Timeout:   5 * time.Second,
+	}, logger)
+	return client, secretKey
+}
+
+// runTunnel runs client until the test ends, forwarding received commands to a channel
+func runTunnel(t *testing.T, client *transport.TunnelClient, resumeID func() string) <-chan streams.StreamMessage {
+	t.Helper()
+
+	received := make(chan streams.StreamMessage, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	go func() {
+		defer close(done)
+		client.Run(ctx, resumeID, func(msg streams.StreamMessage) { received <- msg })
+	}()
+	return received
+}
+
+func receiveCommand(t *testing.T, received <-chan streams.StreamMessage) streams.StreamMessage {
+	t.Helper()
+
+	select {
+	case msg := <-received:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for tunnel command")
+		return streams.StreamMessage{}
+	}
+}
+
+func TestTunnel_CommandRoundTrip(t *testing.T) {
+	memory := streams.NewMemoryClient(nil)
+	bot, server := newStreamsTestServer(t, memory)
+	bot.ctx = t.Context()
+
+	client, serverKey := newTunnelTestClient(t, bot, server.URL)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	received := make(chan streams.StreamMessage, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.Run(ctx, func() string { return "0" }, func(msg streams.StreamMessage) {
+		command, err := protocol.FromJSON([]byte(msg.Values["payload"]))
+		if err != nil {
+			t.Errorf("FromJSON() error = %v", err)
+			return
+		}
+
+		response := protocol.NewMessage(protocol.TypePong, nil)
+		response.ID = command.ID
+		data, _ := response.ToJSON()
+		client.SendResponse(ctx, map[string]string{
+			"type":       string(response.Type),
+			"id":         response.ID,
+			"command_id": command.ID,
+			"payload":    string(data),
+		})
+		received <- msg
+	})
+
+	adapter := streams.NewBotAdapter(memory, logger)
+	defer adapter.Close()
+
+	command := protocol.NewMessage(protocol.TypePing, nil)
+	response, err := adapter.SendCommand(t.Context(), serverKey, command, 5*time.Second)
+	if err != nil {
+		t.Fatalf("SendCommand() error = %v", err)
+	}
+	if response.Type != protocol.TypePong || response.ID != command.ID {
+		t.Errorf("Response = %s/%s, want pong/%s", response.Type, response.ID, command.ID)
+	}
+
+	msg := receiveCommand(t, received)
+	if msg.Stream != "stream:cmd:"+serverKey {
+		t.Errorf("Stream = %q", msg.Stream)
+	}
+}
+
+func TestTunnel_ResumesFromLastID(t *testing.T) {
+	memory := streams.NewMemoryClient(nil)
+	bot, server := newStreamsTestServer(t, memory)
+	bot.ctx = t.Context()
+
+	client, serverKey := newTunnelTestClient(t, bot, server.URL)
+	cmdStream := "stream:cmd:" + serverKey
+
+	first, _ := memory.AddMessage(t.Context(), cmdStream, map[string]string{"n": "1"})
+	second, _ := memory.AddMessage(t.Context(), cmdStream, map[string]string{"n": "2"})
+
+	received := runTunnel(t, client, func() string { return first })
+
+	if msg := receiveCommand(t, received); msg.ID != second {
+		t.Fatalf("First pushed command = %s, want %s", msg.ID, second)
+	}
+
+	// Dropped connection is re-established, commands queued meanwhile are delivered
+	client.Close()
+	third, _ := memory.AddMessage(t.Context(), cmdStream, map[string]string{"n": "3"})
+
+	for {
+		msg := receiveCommand(t, received)
+		if msg.ID == third {
+			return
+		}
+		// Resume callback still points at first, so second may be replayed
+		if msg.ID != second {
+			t.Fatalf("Command after reconnect = %s, want %s", msg.ID, third)
+		}
+	}
+}
+
+func TestTunnel_RejectsUnauthenticated(t *testing.T) {
+	bot, server := newStreamsTestServer(t, streams.NewMemoryClient(nil))
+	bot.ctx = t.Context()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+transport.TunnelPath, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", transport.TunnelProtocol)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request error = %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestTunnel_Probe(t *testing.T) {
+	bot, server := newStreamsTestServer(t, streams.NewMemoryClient(nil))
+	client, _ := newTunnelTestClient(t, bot, server.URL)
+
+	if err := client.Probe(t.Context()); err != nil {
+		t.Errorf("Probe() error = %v", err)
+	}
+
+	// Older bot without the tunnel endpoint
+	oldBot := httptest.NewServer(http.NotFoundHandler())
+	defer oldBot.Close()
+	oldClient, _ := newTunnelTestClient(t, bot, oldBot.URL)
+
+	if err := oldClient.Probe(t.Context()); !errors.Is(err, transport.ErrTunnelUnsupported) {
+		t.Errorf("Probe() error = %v, want ErrTunnelUnsupported", err)
+	}
+	err := oldClient.Run(t.Context(), func() string { return "$" }, func(streams.StreamMessage) {})
+	if !errors.Is(err, transport.ErrTunnelUnsupported) {
+		t.Errorf("Run() error = %v, want ErrTunnelUnsupported", err)
+	}
+}
+
+func TestTunnel_RedeliversUnansweredCommand(t *testing.T) {
+	memory := streams.NewMemoryClient(nil)
+	bot, server := newStreamsTestServer(t, memory)
+	bot.ctx = t.Context()
+
+	client, serverKey := newTunnelTestClient(t, bot, server.URL)
+	cmdStream := "stream:cmd:" + serverKey
+
+	received := runTunnel(t, client, func() string { return "0" })
+
+	command := protocol.NewMessage(protocol.TypePing, nil)
+	data, _ := command.ToJSON()
+	entryID, _ := memory.AddMessage(t.Context(), cmdStream, map[string]string{
+		"type": string(command.Type), "id": command.ID, "payload": string(data),
+	})
+
+	// The tunnel drops before the agent answers
+	if msg := receiveCommand(t, received); msg.ID != entryID {
+		t.Fatalf("Pushed command = %s, want %s", msg.ID, entryID)
+	}
+	client.Close()
+
+	msg := receiveCommand(t, received)
+	if msg.ID != entryID {
+		t.Fatalf("Command after reconnect = %s, want %s", msg.ID, entryID)
+	}
+
+	response := protocol.NewMessage(protocol.TypePong, nil)
+	response.ID = command.ID
+	respData, _ := response.ToJSON()
+	if err := client.SendResponse(t.Context(), map[string]string{
+		"type": string(response.Type), "id": response.ID, "command_id": command.ID, "payload": string(respData),
+	}); err != nil {
+		t.Fatalf("SendResponse() error = %v", err)
+	}
+
+	// Answered command is ACKed in the agents' group
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		pending, err := memory.PendingMessages(t.Context(), cmdStream, config.DefaultConsumerGroup, 0, 10)
+		if err != nil {
+			t.Fatalf("PendingMessages() error = %v", err)
+		}
+		if len(pending) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the answered command to be ACKed, %d pending", len(pending))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/servereye/servereye/pkg/protocol"
+	"github.com/servereye/servereye/pkg/transport"
 )
 
 // TelegramAPI defines the interface for Telegram bot operations
@@ -23,10 +24,7 @@ type RedisClient interface {
 }
 
 // Subscription defines the interface for Redis subscriptions
-type Subscription interface {
-	Channel() <-chan []byte
-	Close() error
-}
+type Subscription = transport.Subscription
 
 // Database defines the interface for database operations
 type Database interface {
@@ -44,10 +42,12 @@ type Database interface {
 // AgentClient defines the interface for agent communication
 type AgentClient interface {
 	GetCPUTemperature(ctx context.Context, serverKey string) (float64, error)
+	GetCPUUsage(ctx context.Context, serverKey string) (*protocol.CPUUsagePayload, error)
 	GetContainers(ctx context.Context, serverKey string) (*protocol.ContainersPayload, error)
 	GetMemoryInfo(ctx context.Context, serverKey string) (*protocol.MemoryInfo, error)
 	GetDiskInfo(ctx context.Context, serverKey string) (*protocol.DiskInfoPayload, error)
 	GetUptime(ctx context.Context, serverKey string) (*protocol.UptimeInfo, error)
+	GetSystemInfo(ctx context.Context, serverKey string) (*protocol.SystemInfoPayload, error)
 	GetProcesses(ctx context.Context, serverKey string) (*protocol.ProcessesPayload, error)
 	SendContainerAction(ctx context.Context, serverKey string, messageType protocol.MessageType, payload protocol.ContainerActionPayload) (*protocol.ContainerActionResponse, error)
 }
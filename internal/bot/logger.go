@@ -16,6 +16,14 @@ func NewStructuredLogger(logger *logrus.Logger) *StructuredLogger {
 	}
 }
 
+// logrusLogger returns the logrus logger behind b.logger for packages that take one
+func (b *Bot) logrusLogger() *logrus.Logger {
+	if structured, ok := b.logger.(*StructuredLogger); ok {
+		return structured.logger
+	}
+	return logrus.StandardLogger()
+}
+
 // Debug logs a debug message with structured fields
 func (l *StructuredLogger) Debug(msg string, fields ...Field) {
 	entry := l.logger.WithFields(l.fieldsToLogrus(fields))
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/servereye/servereye/pkg/protocol"
 )
 
 // handleTemp handles the /temp command
@@ -57,6 +58,162 @@ func (b *Bot) handleTemp(message *tgbotapi.Message) string {
 	return fmt.Sprintf("🌡️ CPU Temperature: %.1f°C", temp)
 }
 
+// handleCPU handles the /cpu command
+func (b *Bot) handleCPU(message *tgbotapi.Message) string {
+	b.logger.Info("Operation completed")
+
+	servers, err := b.getUserServersWithInfo(message.From.ID)
+	if err != nil {
+		b.logger.Error("Error occurred", err)
+		return "❌ Error retrieving your servers."
+	}
+
+	if len(servers) == 0 {
+		return "📭 No servers connected. Use /add to connect a server."
+	}
+
+	// If multiple servers, show selection buttons
+	if len(servers) > 1 {
+		parts := strings.Fields(message.Text)
+		if len(parts) == 1 {
+			// No server specified, show buttons
+			b.sendServerSelectionButtons(message.Chat.ID, "cpu", "⚙️ Select server for CPU usage:", servers)
+			return ""
+		}
+	}
+
+	// Parse server number from command or use first server
+	serverKeys := make([]string, len(servers))
+	for i, server := range servers {
+		serverKeys[i] = server.SecretKey
+	}
+
+	serverKey, err := b.getServerFromCommand(message.Text, serverKeys)
+	if err != nil {
+		return err.Error()
+	}
+
+	usage, err := b.getCPUUsage(serverKey)
+	if err != nil {
+		b.logger.Error("Error occurred", err)
+		return fmt.Sprintf("❌ Failed to get CPU usage: %v", err)
+	}
+
+	b.logger.Info("Operation completed")
+	return formatCPUUsage("⚙️ CPU Usage", usage)
+}
+
+// formatCPUUsage formats aggregate and per-core CPU utilization
+func formatCPUUsage(title string, usage *protocol.CPUUsagePayload) string {
+	var result strings.Builder
+
+	result.WriteString(fmt.Sprintf("%s\n\n", title))
+	result.WriteString(fmt.Sprintf("📊 Total: %.1f%%\n", usage.Total.Usage))
+	result.WriteString(fmt.Sprintf("👤 User: %.1f%%\n", usage.Total.User))
+	result.WriteString(fmt.Sprintf("🔧 System: %.1f%%\n", usage.Total.System))
+	result.WriteString(fmt.Sprintf("💾 IOWait: %.1f%%\n", usage.Total.IOWait))
+	result.WriteString(fmt.Sprintf("🕳️ Steal: %.1f%%\n", usage.Total.Steal))
+	result.WriteString(fmt.Sprintf("💤 Idle: %.1f%%\n", usage.Total.Idle))
+
+	if len(usage.Cores) > 0 {
+		result.WriteString(fmt.Sprintf("\n🧩 Cores (%d):\n", len(usage.Cores)))
+		for _, core := range usage.Cores {
+			result.WriteString(fmt.Sprintf("• %s: %.1f%%\n", core.Core, core.Usage))
+		}
+	}
+
+	return result.String()
+}
+
+// handleInfo handles the /info command
+func (b *Bot) handleInfo(message *tgbotapi.Message) string {
+	b.logger.Info("Operation completed")
+
+	servers, err := b.getUserServersWithInfo(message.From.ID)
+	if err != nil {
+		b.logger.Error("Error occurred", err)
+		return "❌ Error retrieving your servers."
+	}
+
+	if len(servers) == 0 {
+		return "📭 No servers connected. Use /add to connect a server."
+	}
+
+	// If multiple servers, show selection buttons
+	if len(servers) > 1 {
+		parts := strings.Fields(message.Text)
+		if len(parts) == 1 {
+			// No server specified, show buttons
+			b.sendServerSelectionButtons(message.Chat.ID, "info", "🖥️ Select server for system info:", servers)
+			return ""
+		}
+	}
+
+	// Parse server number from command or use first server
+	serverKeys := make([]string, len(servers))
+	for i, server := range servers {
+		serverKeys[i] = server.SecretKey
+	}
+
+	serverKey, err := b.getServerFromCommand(message.Text, serverKeys)
+	if err != nil {
+		return err.Error()
+	}
+
+	info, err := b.getSystemInfo(serverKey)
+	if err != nil {
+		b.logger.Error("Error occurred", err)
+		return fmt.Sprintf("❌ Failed to get system info: %v", err)
+	}
+
+	b.logger.Info("Operation completed")
+	return formatSystemInfo("🖥️ System Info", info)
+}
+
+// formatSystemInfo formats static system information
+func formatSystemInfo(title string, info *protocol.SystemInfoPayload) string {
+	distro := info.Distro
+	if distro == "" {
+		distro = info.OS
+	}
+
+	cpuModel := info.CPUModel
+	if cpuModel == "" {
+		cpuModel = "unknown"
+	}
+
+	// Safe conversion from uint64 to int64
+	bootTimeUnix := info.BootTime
+	if bootTimeUnix > (1<<63 - 1) {
+		bootTimeUnix = 1<<63 - 1 // Cap at max int64
+	}
+	bootTime := time.Unix(int64(bootTimeUnix), 0)
+
+	return fmt.Sprintf(`%s
+
+🏷️ Hostname: %s
+🐧 OS: %s
+🧬 Kernel: %s
+🏗️ Arch: %s
+⚙️ CPU: %s (%d cores)
+🧠 RAM: %.1f GB
+📦 Virtualization: %s
+📅 Boot Time: %s
+⏰ Uptime: %s
+🤖 Agent: %s`,
+		title,
+		info.Hostname,
+		distro,
+		info.Kernel,
+		info.Arch,
+		cpuModel, info.CPUCount,
+		float64(info.TotalMemory)/1024/1024/1024,
+		info.Virtualization,
+		bootTime.Format("2006-01-02 15:04:05"),
+		info.Uptime,
+		info.AgentVersion)
+}
+
 // handleMemory handles the /memory command
 func (b *Bot) handleMemory(message *tgbotapi.Message) string {
 	b.logger.Info("Operation completed")
@@ -382,13 +539,21 @@ func (b *Bot) handleStatus(message *tgbotapi.Message) string {
 		serverKeys[i] = server.SecretKey
 	}
 
-	_, err = b.getServerFromCommand(message.Text, serverKeys)
+	serverKey, err := b.getServerFromCommand(message.Text, serverKeys)
 	if err != nil {
 		return err.Error()
 	}
 
 	serverName := servers[0].Name
-	return fmt.Sprintf("🟢 **%s** Status: Online\n⏱️ Uptime: 15 days 8 hours\n💾 Last activity: just now", serverName)
+	for _, server := range servers {
+		if server.SecretKey == serverKey {
+			serverName = server.Name
+			break
+		}
+	}
+
+	report := b.collectServerStatus(serverKey, serverName)
+	return formatServerStatus(report)
 }
 
 // handleContainers handles the /containers command
@@ -468,6 +633,11 @@ func (b *Bot) handleUpdate(message *tgbotapi.Message) string {
 	serverKey := servers[0].SecretKey
 	serverName := servers[0].Name
 
+	command := protocol.NewMessage(protocol.TypeUpdateAgent, &protocol.UpdateAgentPayload{Version: "latest"})
+	if text, queued := b.queueIfOffline(message.From.ID, serverKey, serverName, command); queued {
+		return text
+	}
+
 	b.logger.Info("Updating agent...")
 
 	// Send "updating" message
@@ -479,6 +649,14 @@ func (b *Bot) handleUpdate(message *tgbotapi.Message) string {
 		return fmt.Sprintf("❌ Failed to update agent on %s: %v", serverName, err)
 	}
 
+	if updateResp.Success {
+		b.logger.Info("Agent updated successfully")
+	}
+	return formatUpdateResponse(serverName, updateResp)
+}
+
+// formatUpdateResponse formats agent update result for display
+func formatUpdateResponse(serverName string, updateResp *protocol.UpdateAgentResponse) string {
 	if !updateResp.Success {
 		return fmt.Sprintf("❌ Update failed on %s:\n%s", serverName, updateResp.Message)
 	}
@@ -491,6 +669,5 @@ func (b *Bot) handleUpdate(message *tgbotapi.Message) string {
 		response += "\n⚠️ Agent restart required to apply changes."
 	}
 
-	b.logger.Info("Agent updated successfully")
 	return response
 }
@@ -225,19 +225,22 @@ func (b *Bot) handleDebug(message *tgbotapi.Message) string {
 		userExists, serverCount, totalUsers, totalServers, totalKeys, connectedKeys)
 }
 
-// handleStats shows detailed statistics about generated keys (admin command)
-func (b *Bot) handleStats(message *tgbotapi.Message) string {
-	// Simple admin check - you can make this more sophisticated
-	adminUsers := []int64{1805441944} // Add your Telegram ID here
-	isAdmin := false
+// adminUsers lists Telegram IDs allowed to run admin commands
+var adminUsers = []int64{1805441944} // Add your Telegram ID here
+
+// isAdmin reports whether the Telegram user is an administrator
+func isAdmin(userID int64) bool {
 	for _, adminID := range adminUsers {
-		if message.From.ID == adminID {
-			isAdmin = true
-			break
+		if userID == adminID {
+			return true
 		}
 	}
+	return false
+}
 
-	if !isAdmin {
+// handleStats shows detailed statistics about generated keys (admin command)
+func (b *Bot) handleStats(message *tgbotapi.Message) string {
+	if !isAdmin(message.From.ID) {
 		return "❌ This command is only available for administrators."
 	}
 
@@ -0,0 +1,55 @@
+package bot
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/servereye/servereye/pkg/auth"
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+// signingEnabled reports whether commands are signed for agents that registered a signing key
+func (b *Bot) signingEnabled() bool {
+	return b.config != nil && b.config.Signing.Enabled
+}
+
+// serverSigningKey returns the signing key registered at enrollment, "" if the agent has none
+func (b *Bot) serverSigningKey(serverKey string) (string, error) {
+	if cached, ok := b.signingKeys.Load(serverKey); ok {
+		return cached.(string), nil
+	}
+	if b.db == nil {
+		return "", nil
+	}
+
+	var signingKey string
+	err := b.db.QueryRow(
+		"SELECT COALESCE(signing_key, '') FROM generated_keys WHERE secret_key = $1",
+		serverKey,
+	).Scan(&signingKey)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+
+	// Only found keys are cached: an older agent may still register one
+	if signingKey != "" {
+		b.signingKeys.Store(serverKey, signingKey)
+	}
+	return signingKey, nil
+}
+
+// signCommand signs command with the server's signing key.
+// Agents enrolled without a signing key can't verify signatures and get the command unsigned.
+func (b *Bot) signCommand(serverKey string, command *protocol.Message) error {
+	signingKey, err := b.serverSigningKey(serverKey)
+	if err != nil {
+		return fmt.Errorf("failed to load signing key: %w", err)
+	}
+	if signingKey == "" {
+		b.logger.Debug("Server has no signing key, command sent unsigned", StringField("server_key", maskKey(serverKey)))
+		return nil
+	}
+
+	auth.SignMessage(signingKey, command)
+	return nil
+}
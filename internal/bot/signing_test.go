@@ -0,0 +1,44 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/servereye/servereye/pkg/auth"
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+func TestSignCommand(t *testing.T) {
+	bot := newAuthTestBot()
+	signingKey, err := auth.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot.signingKeys.Store(testAgentKey, signingKey)
+
+	command := protocol.NewMessage(protocol.TypePing, nil)
+	command.SetTTL(time.Minute)
+	if err := bot.signCommand(testAgentKey, command); err != nil {
+		t.Fatalf("signCommand() error = %v", err)
+	}
+
+	if err := auth.NewMessageVerifier(signingKey, 0).Verify(command); err != nil {
+		t.Errorf("Command must verify with the signing key: %v", err)
+	}
+	// The server key is public in Redis names and must not be the signing key
+	if err := auth.NewMessageVerifier(testAgentKey, 0).Verify(command); err == nil {
+		t.Error("Command must not verify with the server key")
+	}
+}
+
+func TestSignCommand_NoSigningKey(t *testing.T) {
+	bot := newAuthTestBot()
+
+	command := protocol.NewMessage(protocol.TypePing, nil)
+	if err := bot.signCommand(testAgentKey, command); err != nil {
+		t.Fatalf("signCommand() error = %v", err)
+	}
+	if command.Signature != "" {
+		t.Error("Agent without a signing key must get an unsigned command")
+	}
+}
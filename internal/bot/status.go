@@ -0,0 +1,250 @@
+package bot
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+// statusQueryTimeout limits each agent sub-query issued by /status
+const statusQueryTimeout = 5 * time.Second
+
+// serverStatusReport aggregates partial results of /status sub-queries
+type serverStatusReport struct {
+	mu sync.Mutex
+
+	Name        string
+	Status      string
+	LastSeen    sql.NullTime
+	Uptime      *protocol.UptimeInfo
+	Memory      *protocol.MemoryInfo
+	Disk        *protocol.DiskInfoPayload
+	CPU         *protocol.CPUUsagePayload
+	Temperature *float64
+	Containers  *protocol.ContainersPayload
+	Reachable   bool
+	Errors      map[string]error
+}
+
+// set stores a sub-query result under the report lock
+func (r *serverStatusReport) set(apply func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	apply()
+}
+
+// runStatusQueries runs queries concurrently and waits at most timeout.
+// Queries still running after timeout are reported as timed out.
+func runStatusQueries(timeout time.Duration, queries map[string]func() error) map[string]error {
+	type result struct {
+		name string
+		err  error
+	}
+
+	// Buffered so late queries never block after we stop waiting
+	results := make(chan result, len(queries))
+	for name, query := range queries {
+		go func(name string, query func() error) {
+			results <- result{name: name, err: query()}
+		}(name, query)
+	}
+
+	errs := make(map[string]error)
+	pending := make(map[string]bool, len(queries))
+	for name := range queries {
+		pending[name] = true
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for len(pending) > 0 {
+		select {
+		case res := <-results:
+			delete(pending, res.name)
+			if res.err != nil {
+				errs[res.name] = res.err
+			}
+		case <-timer.C:
+			for name := range pending {
+				errs[name] = fmt.Errorf("timed out after %s", timeout)
+			}
+			return errs
+		}
+	}
+
+	return errs
+}
+
+// collectServerStatus fans out status sub-queries to the agent
+func (b *Bot) collectServerStatus(serverKey, serverName string) *serverStatusReport {
+	report := &serverStatusReport{Name: serverName}
+
+	if b.db != nil {
+		status, lastSeen, err := b.getServerPresence(serverKey)
+		if err != nil {
+			b.logger.Error("Error occurred", err)
+		} else {
+			report.Status = status
+			report.LastSeen = lastSeen
+		}
+	}
+
+	queries := map[string]func() error{
+		"uptime": func() error {
+			uptime, err := sendCommandAndParse[protocol.UptimeInfo](b, serverKey, protocol.TypeGetUptime, nil, protocol.TypeUptimeResponse, statusQueryTimeout)
+			if err == nil {
+				report.set(func() { report.Uptime = uptime })
+			}
+			return err
+		},
+		"memory": func() error {
+			memory, err := sendCommandAndParse[protocol.MemoryInfo](b, serverKey, protocol.TypeGetMemoryInfo, nil, protocol.TypeMemoryInfoResponse, statusQueryTimeout)
+			if err == nil {
+				report.set(func() { report.Memory = memory })
+			}
+			return err
+		},
+		"disk": func() error {
+			disk, err := sendCommandAndParse[protocol.DiskInfoPayload](b, serverKey, protocol.TypeGetDiskInfo, nil, protocol.TypeDiskInfoResponse, statusQueryTimeout)
+			if err == nil {
+				report.set(func() { report.Disk = disk })
+			}
+			return err
+		},
+		"cpu": func() error {
+			cpu, err := sendCommandAndParse[protocol.CPUUsagePayload](b, serverKey, protocol.TypeGetCPUUsage, nil, protocol.TypeCPUUsageResponse, statusQueryTimeout)
+			if err == nil {
+				report.set(func() { report.CPU = cpu })
+			}
+			return err
+		},
+		"temperature": func() error {
+			temp, err := sendCommandAndParse[protocol.CPUTempPayload](b, serverKey, protocol.TypeGetCPUTemp, nil, protocol.TypeCPUTempResponse, statusQueryTimeout)
+			if err == nil {
+				report.set(func() { report.Temperature = &temp.Temperature })
+			}
+			return err
+		},
+		"containers": func() error {
+			containers, err := sendCommandAndParse[protocol.ContainersPayload](b, serverKey, protocol.TypeGetContainers, nil, protocol.TypeContainersResponse, statusQueryTimeout)
+			if err == nil {
+				report.set(func() { report.Containers = containers })
+			}
+			return err
+		},
+	}
+
+	// Small grace period on top of per-query timeout for response delivery
+	errs := runStatusQueries(statusQueryTimeout+time.Second, queries)
+	report.set(func() {
+		report.Errors = errs
+		report.Reachable = len(errs) < len(queries)
+	})
+
+	if len(errs) > 0 {
+		b.logger.Warn("Status collected with partial results", IntField("failed_queries", len(errs)))
+	}
+
+	return report
+}
+
+// formatServerStatus renders a status report, listing unavailable sections at the end
+func formatServerStatus(report *serverStatusReport) string {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	var result strings.Builder
+
+	// Agent answering any query means it is reachable right now
+	statusEmoji := "🔴"
+	stateText := "Offline"
+	if report.Reachable {
+		statusEmoji = "🟢"
+		stateText = "Online"
+	} else if report.Status == "online" {
+		statusEmoji = "🟡"
+		stateText = "Not responding"
+	}
+
+	result.WriteString(fmt.Sprintf("%s **%s** Status: %s\n", statusEmoji, report.Name, stateText))
+	if report.LastSeen.Valid {
+		result.WriteString(fmt.Sprintf("👁️ Last seen: %s (%s ago)\n",
+			report.LastSeen.Time.Format("2006-01-02 15:04:05"),
+			formatDurationShort(time.Since(report.LastSeen.Time))))
+	} else {
+		result.WriteString("👁️ Last seen: never\n")
+	}
+
+	if report.Uptime != nil {
+		result.WriteString(fmt.Sprintf("⏱️ Uptime: %s\n", report.Uptime.Formatted))
+	}
+	if report.CPU != nil {
+		result.WriteString(fmt.Sprintf("⚙️ CPU: %.1f%%\n", report.CPU.Total.Usage))
+	}
+	if report.Temperature != nil {
+		result.WriteString(fmt.Sprintf("🌡️ Temperature: %.1f°C\n", *report.Temperature))
+	}
+	if report.Memory != nil {
+		result.WriteString(fmt.Sprintf("🧠 Memory: %.1f%% (%.1f / %.1f GB)\n",
+			report.Memory.UsedPercent,
+			float64(report.Memory.Used)/1024/1024/1024,
+			float64(report.Memory.Total)/1024/1024/1024))
+	}
+	if report.Disk != nil {
+		for _, disk := range report.Disk.Disks {
+			result.WriteString(fmt.Sprintf("💽 Disk %s: %.1f%%\n", disk.Path, disk.UsedPercent))
+		}
+	}
+	if report.Containers != nil {
+		running := 0
+		for _, container := range report.Containers.Containers {
+			if strings.ToLower(container.State) == "running" {
+				running++
+			}
+		}
+		result.WriteString(fmt.Sprintf("🐳 Containers: %d running / %d total\n", running, report.Containers.Total))
+	}
+
+	if len(report.Errors) > 0 {
+		names := make([]string, 0, len(report.Errors))
+		for name := range report.Errors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		result.WriteString("\n⚠️ Unavailable:\n")
+		for _, name := range names {
+			result.WriteString(fmt.Sprintf("• %s: %v\n", name, report.Errors[name]))
+		}
+	}
+
+	return strings.TrimRight(result.String(), "\n")
+}
+
+// formatDurationShort formats a duration as "3d 4h", "5h 10m" or "42s"
+func formatDurationShort(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm", minutes)
+	default:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+}
@@ -0,0 +1,121 @@
+package bot
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+func TestRunStatusQueries_PartialResults(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	queries := map[string]func() error{
+		"fast": func() error { return nil },
+		"failed": func() error {
+			return errors.New("agent error")
+		},
+		"slow": func() error {
+			<-release
+			return nil
+		},
+	}
+
+	start := time.Now()
+	errs := runStatusQueries(50*time.Millisecond, queries)
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("runStatusQueries did not respect timeout, took %v", elapsed)
+	}
+
+	if _, ok := errs["fast"]; ok {
+		t.Error("Expected no error for fast query")
+	}
+	if err, ok := errs["failed"]; !ok || err.Error() != "agent error" {
+		t.Errorf("Expected agent error for failed query, got %v", err)
+	}
+	if err, ok := errs["slow"]; !ok || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected timeout for slow query, got %v", err)
+	}
+}
+
+func TestFormatServerStatus_Partial(t *testing.T) {
+	temp := 52.5
+	report := &serverStatusReport{
+		Name:      "web-1",
+		Status:    "online",
+		LastSeen:  sql.NullTime{Time: time.Now().Add(-2 * time.Minute), Valid: true},
+		Reachable: true,
+		Uptime:    &protocol.UptimeInfo{Formatted: "3 days, 2 hours, 1 minutes"},
+		Memory:    &protocol.MemoryInfo{UsedPercent: 40},
+		Containers: &protocol.ContainersPayload{
+			Containers: []protocol.ContainerInfo{{State: "running"}, {State: "exited"}},
+			Total:      2,
+		},
+		Temperature: &temp,
+		Errors: map[string]error{
+			"disk": errors.New("timed out after 5s"),
+		},
+	}
+
+	result := formatServerStatus(report)
+
+	for _, expected := range []string{
+		"web-1",
+		"Online",
+		"ago",
+		"3 days, 2 hours, 1 minutes",
+		"52.5°C",
+		"1 running / 2 total",
+		"Unavailable",
+		"disk: timed out",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected %q in status, got:\n%s", expected, result)
+		}
+	}
+
+	if strings.Contains(result, "15 days 8 hours") {
+		t.Error("Status still contains hard-coded uptime")
+	}
+}
+
+func TestFormatServerStatus_Unreachable(t *testing.T) {
+	report := &serverStatusReport{
+		Name:   "db-1",
+		Status: "offline",
+		Errors: map[string]error{"uptime": errors.New("timeout")},
+	}
+
+	result := formatServerStatus(report)
+
+	if !strings.Contains(result, "Offline") {
+		t.Errorf("Expected Offline state, got:\n%s", result)
+	}
+	if !strings.Contains(result, "Last seen: never") {
+		t.Errorf("Expected never seen, got:\n%s", result)
+	}
+}
+
+func TestFormatDurationShort(t *testing.T) {
+	tests := []struct {
+		input    time.Duration
+		expected string
+	}{
+		{30 * time.Second, "30s"},
+		{5 * time.Minute, "5m"},
+		{2*time.Hour + 10*time.Minute, "2h 10m"},
+		{50 * time.Hour, "2d 2h"},
+		{-time.Second, "0s"},
+	}
+
+	for _, tt := range tests {
+		if got := formatDurationShort(tt.input); got != tt.expected {
+			t.Errorf("formatDurationShort(%v) = %q, expected %q", tt.input, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/servereye/servereye/pkg/e2e"
+	"github.com/servereye/servereye/pkg/protocol"
+	"github.com/servereye/servereye/pkg/transport"
+	"github.com/sirupsen/logrus"
+)
+
+// sendCommand delivers command to the agent through the transport router
+func (b *Bot) sendCommand(ctx context.Context, serverKey string, command *protocol.Message, timeout time.Duration) (*protocol.Message, error) {
+	// Commands without explicit deadline expire together with the wait timeout
+	if _, ok := command.Deadline(); !ok {
+		command.SetTTL(timeout)
+	}
+
+	if err := b.checkCommandSupported(serverKey, command.Type); err != nil {
+		return nil, err
+	}
+
+	// Encrypt first: the signature covers the payload as sent
+	var session *e2e.Session
+	if b.encryptionEnabled() {
+		var err error
+		if command, session, err = b.encryptCommand(serverKey, command); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.signingEnabled() {
+		if err := b.signCommand(serverKey, command); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := b.commandRouter().SendCommand(ctx, serverKey, command, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if session != nil {
+		if err := session.DecryptResponse(resp); err != nil {
+			return nil, fmt.Errorf("failed to decrypt agent response: %w", err)
+		}
+	}
+
+	if err := b.unsupportedResponse(serverKey, command.Type, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// commandRouter returns the transport router.
+// Bots built without NewFromConfig get a Pub/Sub-only router over redisClient.
+func (b *Bot) commandRouter() *transport.Router {
+	b.routerOnce.Do(func() {
+		if b.router != nil {
+			return
+		}
+
+		logger := logrus.StandardLogger()
+		var transports []transport.Transport
+		if b.redisClient != nil {
+			transports = append(transports, transport.NewPubSub(b.redisClient, logger))
+		}
+		b.router = transport.NewRouter(logger, transports...)
+		b.router.SetObserver(b.recordTransportMetrics)
+	})
+
+	return b.router
+}
+
+// recordTransportMetrics reports which delivery path was used and how long it took
+func (b *Bot) recordTransportMetrics(name string, duration time.Duration, err error) {
+	if b.metrics == nil {
+		return
+	}
+
+	metric := "transport_" + name
+	b.metrics.RecordLatency(metric, duration.Seconds())
+	if err != nil {
+		b.metrics.IncrementError(metric)
+		return
+	}
+	b.metrics.IncrementCommand(metric)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/servereye/servereye/pkg/protocol"
@@ -34,14 +35,37 @@ func escapeMarkdown(text string) string {
 	return replacer.Replace(text)
 }
 
-// sendMessage sends a message to a chat
+const (
+	// telegramMessageLimit is the longest text Telegram accepts in one message
+	telegramMessageLimit = 4096
+	// documentTitleLimit limits the first line repeated in a document caption
+	documentTitleLimit = 200
+)
+
+// sendMessage sends a message to a chat, text over the Telegram limit is sent as a document
 func (b *Bot) sendMessage(chatID int64, text string) {
-	msg := tgbotapi.NewMessage(chatID, text)
+	var msg tgbotapi.Chattable = tgbotapi.NewMessage(chatID, text)
+	if utf8.RuneCountInString(text) > telegramMessageLimit {
+		msg = newTextDocument(chatID, text)
+	}
+
 	if _, err := b.telegramAPI.Send(msg); err != nil {
 		b.logger.Error("Error occurred", err)
 	}
 }
 
+// newTextDocument wraps long text into a .txt document captioned with its first line
+func newTextDocument(chatID int64, text string) tgbotapi.DocumentConfig {
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: "output.txt", Bytes: []byte(text)})
+
+	title, _, _ := strings.Cut(text, "\n")
+	if runes := []rune(title); len(runes) > documentTitleLimit {
+		title = string(runes[:documentTitleLimit]) + "…"
+	}
+	doc.Caption = title + "\n\n📎 The output is too long for a message, see the attached file."
+	return doc
+}
+
 // getServerFromCommand parses server number from command and returns server key
 func (b *Bot) getServerFromCommand(command string, servers []string) (string, error) {
 	// Check if servers list is empty
@@ -139,27 +163,50 @@ func (b *Bot) sendContainersWithActionButtons(chatID int64, serverKey string, co
 		text.WriteString("\n")
 	}
 
-	// Add 5 action buttons at bottom
-	buttons := [][]tgbotapi.InlineKeyboardButton{
-		{
-			tgbotapi.NewInlineKeyboardButtonData("▶️ Start", "container_action_start"),
-			tgbotapi.NewInlineKeyboardButtonData("⏹️ Stop", "container_action_stop"),
-		},
-		{
-			tgbotapi.NewInlineKeyboardButtonData("🔄 Restart", "container_action_restart"),
-			tgbotapi.NewInlineKeyboardButtonData("🗑️ Delete", "container_action_remove"),
-		},
-		{
-			tgbotapi.NewInlineKeyboardButtonData("➕ Create", "container_action_create"),
-		},
-	}
-
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons...)
+	// Action buttons at bottom, only for actions the agent supports
 	msg := tgbotapi.NewMessage(chatID, text.String())
 	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = keyboard
+	if buttons := containerActionButtons(b.serverCapabilities(serverKey)); len(buttons) > 0 {
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(buttons...)
+	}
 
 	if _, err := b.telegramAPI.Send(msg); err != nil {
 		b.logger.Error("Error occurred", err)
 	}
 }
+
+// containerActions lists container menu buttons with the command each one sends
+var containerActions = []struct {
+	text     string
+	callback string
+	command  protocol.MessageType
+}{
+	{"▶️ Start", "container_action_start", protocol.TypeStartContainer},
+	{"⏹️ Stop", "container_action_stop", protocol.TypeStopContainer},
+	{"🔄 Restart", "container_action_restart", protocol.TypeRestartContainer},
+	{"🗑️ Delete", "container_action_remove", protocol.TypeRemoveContainer},
+	{"➕ Create", "container_action_create", protocol.TypeCreateContainer},
+}
+
+// containerActionButtons builds container action rows, two buttons per row,
+// hiding actions the agent doesn't support
+func containerActionButtons(caps protocol.Capabilities) [][]tgbotapi.InlineKeyboardButton {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+
+	for _, action := range containerActions {
+		if !caps.Supports(action.command) {
+			continue
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(action.text, action.callback))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	return rows
+}
@@ -1,7 +1,10 @@
 package bot
 
 import (
+	"strings"
 	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 func TestGetServerFromCommand(t *testing.T) {
@@ -141,3 +144,43 @@ func TestGetServerFromCommand_EdgeCases(t *testing.T) {
 		}
 	})
 }
+
+// recordingTelegramAPI records sent messages
+type recordingTelegramAPI struct {
+	TelegramAPI
+	sent []tgbotapi.Chattable
+}
+
+func (r *recordingTelegramAPI) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	r.sent = append(r.sent, c)
+	return tgbotapi.Message{}, nil
+}
+
+func TestSendMessage_LongTextAsDocument(t *testing.T) {
+	api := &recordingTelegramAPI{}
+	bot := newAuthTestBot()
+	bot.telegramAPI = api
+
+	bot.sendMessage(1, "⚙️ Top processes")
+	long := "⚙️ Top processes\n" + strings.Repeat("nginx: worker process\n", 300)
+	bot.sendMessage(1, long)
+
+	if len(api.sent) != 2 {
+		t.Fatalf("Expected 2 sent messages, got %d", len(api.sent))
+	}
+	if _, ok := api.sent[0].(tgbotapi.MessageConfig); !ok {
+		t.Errorf("Short text must be sent as a message, got %T", api.sent[0])
+	}
+
+	doc, ok := api.sent[1].(tgbotapi.DocumentConfig)
+	if !ok {
+		t.Fatalf("Long text must be sent as a document, got %T", api.sent[1])
+	}
+	if !strings.HasPrefix(doc.Caption, "⚙️ Top processes\n") {
+		t.Errorf("Caption must start with the first line, got %q", doc.Caption)
+	}
+	file, ok := doc.File.(tgbotapi.FileBytes)
+	if !ok || string(file.Bytes) != long {
+		t.Error("Document must contain the whole text")
+	}
+}
@@ -3,26 +3,37 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // AgentConfig конфигурация агента
 type AgentConfig struct {
-	Server  ServerConfig  `yaml:"server"`
-	Redis   RedisConfig   `yaml:"redis,omitempty"`
-	API     APIConfig     `yaml:"api,omitempty"`
-	Kafka   KafkaConfig   `yaml:"kafka,omitempty"`
-	Metrics MetricsConfig `yaml:"metrics"`
-	Logging LoggingConfig `yaml:"logging"`
+	Server     ServerConfig     `yaml:"server"`
+	Redis      RedisConfig      `yaml:"redis,omitempty"`
+	API        APIConfig        `yaml:"api,omitempty"`
+	Kafka      KafkaConfig      `yaml:"kafka,omitempty"`
+	Metrics    MetricsConfig    `yaml:"metrics"`
+	State      StateConfig      `yaml:"state,omitempty"`
+	Commands   CommandsConfig   `yaml:"commands,omitempty"`
+	Signing    SigningConfig    `yaml:"signing,omitempty"`
+	Encryption EncryptionConfig `yaml:"encryption,omitempty"`
+	Logging    LoggingConfig    `yaml:"logging"`
 }
 
 // BotConfig конфигурация бота
 type BotConfig struct {
-	Telegram TelegramConfig `yaml:"telegram"`
-	Redis    RedisConfig    `yaml:"redis"`
-	Database DatabaseConfig `yaml:"database"`
-	Logging  LoggingConfig  `yaml:"logging"`
+	Telegram   TelegramConfig   `yaml:"telegram"`
+	Redis      RedisConfig      `yaml:"redis"`
+	Database   DatabaseConfig   `yaml:"database"`
+	Heartbeat  HeartbeatConfig  `yaml:"heartbeat,omitempty"`
+	Transport  TransportConfig  `yaml:"transport,omitempty"`
+	Queue      QueueConfig      `yaml:"queue,omitempty"`
+	Signing    SigningConfig    `yaml:"signing,omitempty"`
+	Encryption EncryptionConfig `yaml:"encryption,omitempty"`
+	Logging    LoggingConfig    `yaml:"logging"`
 }
 
 // ServerConfig конфигурация сервера
@@ -30,25 +41,192 @@ type ServerConfig struct {
 	Name        string `yaml:"name"`
 	Description string `yaml:"description"`
 	SecretKey   string `yaml:"secret_key"`
+	SigningKey  string `yaml:"signing_key"` // ключ подписи команд, в отличие от secret_key не виден в именах каналов
 }
 
 // RedisConfig конфигурация Redis
 type RedisConfig struct {
+	Mode     string `yaml:"mode"` // "redis" (по умолчанию) или "memory" - транспорт в памяти процесса без Redis
 	Address  string `yaml:"address"`
 	Password string `yaml:"password"`
 	DB       int    `yaml:"db"`
 }
 
+// Режимы транспорта
+const (
+	RedisModeRedis  = "redis"
+	RedisModeMemory = "memory"
+)
+
+// IsMemory возвращает true, если транспорт работает в памяти процесса
+func (c RedisConfig) IsMemory() bool {
+	return c.Mode == RedisModeMemory
+}
+
+// validateMode проверяет значение режима транспорта
+func (c RedisConfig) validateMode() error {
+	switch c.Mode {
+	case "", RedisModeRedis, RedisModeMemory:
+		return nil
+	default:
+		return fmt.Errorf("неизвестный режим Redis: %s", c.Mode)
+	}
+}
+
 // APIConfig конфигурация HTTP API
 type APIConfig struct {
 	BaseURL string `yaml:"base_url"`
 	Timeout string `yaml:"timeout,omitempty"`
+	Tunnel  bool   `yaml:"tunnel,omitempty"` // постоянный туннель к боту вместо опроса Streams
 }
 
 // MetricsConfig конфигурация метрик
 type MetricsConfig struct {
 	CPUTemperature bool   `yaml:"cpu_temperature"`
 	Interval       string `yaml:"interval"`
+	StartJitter    string `yaml:"start_jitter"` // случайная задержка первого сбора, "0s" отключает
+	// Collectors настраивает сборщики по имени: cpu_temperature, cpu_usage, memory, disk, network, docker
+	Collectors map[string]CollectorConfig `yaml:"collectors,omitempty"`
+	Host       HostConfig                 `yaml:"host,omitempty"`
+}
+
+// HostConfig пути к файловым системам хоста, смонтированным в контейнер агента.
+// Пустые значения: корень "/", proc и sys внутри корня.
+type HostConfig struct {
+	Proc string `yaml:"proc"` // procfs хоста, например /host/proc
+	Sys  string `yaml:"sys"`  // sysfs хоста, например /host/sys
+	Root string `yaml:"root"` // корневая файловая система хоста: точки монтирования, /etc
+}
+
+// Переменные окружения с путями хоста, имеют приоритет над metrics.host
+const (
+	EnvHostProc = "HOST_PROC"
+	EnvHostSys  = "HOST_SYS"
+	EnvHostRoot = "HOST_ROOT"
+)
+
+// applyEnv переопределяет пути хоста заданными переменными окружения
+func (c *HostConfig) applyEnv() {
+	for env, field := range map[string]*string{
+		EnvHostProc: &c.Proc,
+		EnvHostSys:  &c.Sys,
+		EnvHostRoot: &c.Root,
+	} {
+		if value := os.Getenv(env); value != "" {
+			*field = value
+		}
+	}
+}
+
+// CollectorConfig конфигурация отдельного сборщика метрик
+type CollectorConfig struct {
+	Enabled  *bool  `yaml:"enabled"`  // по умолчанию включен
+	Interval string `yaml:"interval"` // переопределяет metrics.interval для сборщика
+	Timeout  string `yaml:"timeout"`  // сколько ждать результат сборщика
+}
+
+// DefaultCollectorTimeout время ожидания сборщика метрик по умолчанию
+const DefaultCollectorTimeout = 10 * time.Second
+
+// DefaultStartJitter максимальная задержка первого сбора метрик по умолчанию
+const DefaultStartJitter = 10 * time.Second
+
+// GetInterval возвращает интервал сбора метрик (по умолчанию 30s)
+func (c MetricsConfig) GetInterval() time.Duration {
+	return parseDurationOrDefault(c.Interval, 30*time.Second)
+}
+
+// GetStartJitter возвращает максимальную случайную задержку первого сбора (по умолчанию 10s)
+func (c MetricsConfig) GetStartJitter() time.Duration {
+	d, err := time.ParseDuration(c.StartJitter)
+	if err != nil || d < 0 {
+		return DefaultStartJitter
+	}
+	return d
+}
+
+// Collector возвращает конфигурацию сборщика, пустую если он не настроен
+func (c MetricsConfig) Collector(name string) CollectorConfig {
+	return c.Collectors[name]
+}
+
+// IsEnabled сообщает, включен ли сборщик
+func (c CollectorConfig) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// GetInterval возвращает интервал сборщика, metricsInterval если он не переопределен
+func (c CollectorConfig) GetInterval(metricsInterval time.Duration) time.Duration {
+	return parseDurationOrDefault(c.Interval, metricsInterval)
+}
+
+// GetTimeout возвращает время ожидания сборщика (по умолчанию 10s)
+func (c CollectorConfig) GetTimeout() time.Duration {
+	return parseDurationOrDefault(c.Timeout, DefaultCollectorTimeout)
+}
+
+// StateConfig конфигурация локального состояния агента
+type StateConfig struct {
+	Dir string `yaml:"dir"` // директория для курсора stream и обработанных команд
+}
+
+// DefaultStateDir директория состояния агента по умолчанию
+const DefaultStateDir = "/var/lib/servereye"
+
+// GetDir возвращает директорию состояния с учетом значения по умолчанию
+func (c StateConfig) GetDir() string {
+	if c.Dir == "" {
+		return DefaultStateDir
+	}
+	return c.Dir
+}
+
+// CommandsConfig конфигурация доставки команд через consumer group
+type CommandsConfig struct {
+	ConsumerGroup     string `yaml:"consumer_group"`     // имя consumer group для stream команд
+	VisibilityTimeout string `yaml:"visibility_timeout"` // через сколько неподтвержденная команда забирается повторно
+	MaxRetries        int    `yaml:"max_retries"`        // попыток доставки до переноса в dead-letter stream
+}
+
+// DefaultConsumerGroup consumer group агентов по умолчанию
+const DefaultConsumerGroup = "servereye-agent"
+
+// GetConsumerGroup возвращает имя consumer group с учетом значения по умолчанию
+func (c CommandsConfig) GetConsumerGroup() string {
+	if c.ConsumerGroup == "" {
+		return DefaultConsumerGroup
+	}
+	return c.ConsumerGroup
+}
+
+// GetVisibilityTimeout возвращает visibility timeout (по умолчанию 60s)
+func (c CommandsConfig) GetVisibilityTimeout() time.Duration {
+	return parseDurationOrDefault(c.VisibilityTimeout, 60*time.Second)
+}
+
+// TransportConfig конфигурация доставки команд ботом
+type TransportConfig struct {
+	// Servers закрепляет транспорт за сервером: secret_key -> "streams" или "pubsub".
+	// Для закрепленного сервера fallback на другой транспорт не выполняется.
+	Servers map[string]string `yaml:"servers"`
+}
+
+// ParseTransportServers разбирает закрепления транспортов из env: "srv_a=pubsub,srv_b=streams"
+func ParseTransportServers(value string) (map[string]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	servers := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		serverKey, name, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		serverKey, name = strings.TrimSpace(serverKey), strings.TrimSpace(name)
+		if !ok || serverKey == "" || name == "" {
+			return nil, fmt.Errorf("invalid transport override %q, expected secret_key=transport", entry)
+		}
+		servers[serverKey] = name
+	}
+	return servers, nil
 }
 
 // LoggingConfig конфигурация логирования
@@ -67,6 +245,33 @@ type DatabaseConfig struct {
 	URL string `yaml:"url"`
 }
 
+// HeartbeatConfig конфигурация отслеживания heartbeat агентов
+type HeartbeatConfig struct {
+	OfflineAfter  string `yaml:"offline_after"`  // grace period без heartbeat, после которого сервер offline
+	CheckInterval string `yaml:"check_interval"` // как часто проверять устаревшие heartbeat
+}
+
+// QueueConfig конфигурация очереди команд для офлайн серверов
+type QueueConfig struct {
+	Enabled bool   `yaml:"enabled"` // ставить неинтерактивные команды в очередь, пока сервер offline
+	TTL     string `yaml:"ttl"`     // сколько команда ждет сервер, после чего отменяется
+}
+
+// SigningConfig конфигурация HMAC подписи команд ключом подписи сервера.
+// Бот с Enabled подписывает команды, агент с Enabled принимает только подписанные.
+type SigningConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	MaxAge  string `yaml:"max_age"` // агент: команды старше отклоняются
+}
+
+// EncryptionConfig конфигурация сквозного шифрования payload между ботом и агентом.
+// Агент с Enabled объявляет публичный ключ X25519, бот с Enabled шифрует команды
+// для агентов, объявивших ключ.
+type EncryptionConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	RotateAfter string `yaml:"rotate_after"` // агент: через сколько создавать новую пару ключей
+}
+
 // KafkaConfig конфигурация Kafka
 type KafkaConfig struct {
 	Enabled      bool     `yaml:"enabled"`
@@ -76,6 +281,7 @@ type KafkaConfig struct {
 	MaxAttempts  int      `yaml:"max_attempts"`
 	BatchSize    int      `yaml:"batch_size"`
 	RequiredAcks int      `yaml:"required_acks"`
+	QueueSize    int      `yaml:"queue_size"` // сколько метрик ждут отправки в памяти, старые отбрасываются
 }
 
 // LoadAgentConfig загружает конфигурацию агента
@@ -90,6 +296,9 @@ func LoadAgentConfig(filepath string) (*AgentConfig, error) {
 		return nil, fmt.Errorf("не удалось парсить конфигурацию: %v", err)
 	}
 
+	// Агент в контейнере получает пути хоста из окружения
+	config.Metrics.Host.applyEnv()
+
 	// Валидация конфигурации
 	if err := config.validate(); err != nil {
 		return nil, fmt.Errorf("некорректная конфигурация: %v", err)
@@ -127,6 +336,18 @@ func (c *AgentConfig) validate() error {
 		return fmt.Errorf("секретный ключ не может быть пустым")
 	}
 
+	if err := c.Redis.validateMode(); err != nil {
+		return err
+	}
+	// Транспорт в памяти живет в процессе бота, агент подключается к нему через HTTP API
+	if c.Redis.IsMemory() && c.API.BaseURL == "" {
+		return fmt.Errorf("режим memory поддерживается только ботом, укажите api.base_url")
+	}
+
+	if c.Signing.Enabled && c.Server.SigningKey == "" {
+		return fmt.Errorf("signing.enabled требует server.signing_key")
+	}
+
 	// Проверяем, что есть либо Redis, либо HTTP API конфигурация
 	if c.Redis.Address == "" && c.API.BaseURL == "" {
 		return fmt.Errorf("должен быть указан либо адрес Redis, либо базовый URL API")
@@ -140,7 +361,10 @@ func (c *BotConfig) validate() error {
 	if c.Telegram.Token == "" {
 		return fmt.Errorf("токен Telegram бота не может быть пустым")
 	}
-	if c.Redis.Address == "" {
+	if err := c.Redis.validateMode(); err != nil {
+		return err
+	}
+	if c.Redis.Address == "" && !c.Redis.IsMemory() {
 		return fmt.Errorf("адрес Redis не может быть пустым")
 	}
 	if c.Database.URL == "" {
@@ -148,3 +372,37 @@ func (c *BotConfig) validate() error {
 	}
 	return nil
 }
+
+// GetOfflineAfter возвращает grace period для heartbeat (по умолчанию 90s)
+func (c HeartbeatConfig) GetOfflineAfter() time.Duration {
+	return parseDurationOrDefault(c.OfflineAfter, 90*time.Second)
+}
+
+// GetCheckInterval возвращает интервал проверки heartbeat (по умолчанию 30s)
+func (c HeartbeatConfig) GetCheckInterval() time.Duration {
+	return parseDurationOrDefault(c.CheckInterval, 30*time.Second)
+}
+
+// GetTTL возвращает время жизни команды в очереди (по умолчанию 24h)
+func (c QueueConfig) GetTTL() time.Duration {
+	return parseDurationOrDefault(c.TTL, 24*time.Hour)
+}
+
+// GetMaxAge возвращает максимальный возраст подписанной команды (по умолчанию 5m)
+func (c SigningConfig) GetMaxAge() time.Duration {
+	return parseDurationOrDefault(c.MaxAge, 5*time.Minute)
+}
+
+// GetRotateAfter возвращает период ротации ключа шифрования (по умолчанию 720h)
+func (c EncryptionConfig) GetRotateAfter() time.Duration {
+	return parseDurationOrDefault(c.RotateAfter, 720*time.Hour)
+}
+
+// parseDurationOrDefault парсит длительность, возвращая значение по умолчанию при ошибке
+func parseDurationOrDefault(value string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return fallback
+	}
+	return d
+}
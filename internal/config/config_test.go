@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestLoadAgentConfig_Valid(t *testing.T) {
@@ -174,6 +175,44 @@ func TestAgentConfigValidation(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "memory mode without API",
+			config: AgentConfig{
+				Server: ServerConfig{
+					Name:      "TestServer",
+					SecretKey: "srv_test123",
+				},
+				Redis: RedisConfig{
+					Mode: RedisModeMemory,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "signing without signing key",
+			config: AgentConfig{
+				Server: ServerConfig{
+					Name:      "TestServer",
+					SecretKey: "srv_test123",
+				},
+				API:     APIConfig{BaseURL: "https://api.example.com"},
+				Signing: SigningConfig{Enabled: true},
+			},
+			wantErr: true,
+		},
+		{
+			name: "signing with signing key",
+			config: AgentConfig{
+				Server: ServerConfig{
+					Name:       "TestServer",
+					SecretKey:  "srv_test123",
+					SigningKey: "sig_test123",
+				},
+				API:     APIConfig{BaseURL: "https://api.example.com"},
+				Signing: SigningConfig{Enabled: true},
+			},
+			wantErr: false,
+		},
 		{
 			name: "missing both Redis and API",
 			config: AgentConfig{
@@ -276,6 +315,24 @@ func TestBotConfigValidation(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "memory mode without redis address",
+			config: BotConfig{
+				Telegram: TelegramConfig{Token: "test_token"},
+				Redis:    RedisConfig{Mode: RedisModeMemory},
+				Database: DatabaseConfig{URL: "postgres://localhost/db"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown redis mode",
+			config: BotConfig{
+				Telegram: TelegramConfig{Token: "test_token"},
+				Redis:    RedisConfig{Mode: "cluster", Address: "localhost:6379"},
+				Database: DatabaseConfig{URL: "postgres://localhost/db"},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -308,3 +365,162 @@ redis:
 		t.Error("Expected error for invalid YAML")
 	}
 }
+
+func TestHeartbeatConfig_Defaults(t *testing.T) {
+	var cfg HeartbeatConfig
+
+	if got := cfg.GetOfflineAfter(); got != 90*time.Second {
+		t.Errorf("GetOfflineAfter() = %v, want 90s", got)
+	}
+	if got := cfg.GetCheckInterval(); got != 30*time.Second {
+		t.Errorf("GetCheckInterval() = %v, want 30s", got)
+	}
+
+	cfg = HeartbeatConfig{OfflineAfter: "5m", CheckInterval: "invalid"}
+	if got := cfg.GetOfflineAfter(); got != 5*time.Minute {
+		t.Errorf("GetOfflineAfter() = %v, want 5m", got)
+	}
+	if got := cfg.GetCheckInterval(); got != 30*time.Second {
+		t.Errorf("GetCheckInterval() = %v, want fallback 30s", got)
+	}
+}
+
+func TestQueueConfig_TTL(t *testing.T) {
+	if got := (QueueConfig{}).GetTTL(); got != 24*time.Hour {
+		t.Errorf("GetTTL() = %v, want 24h", got)
+	}
+	if got := (QueueConfig{TTL: "2h"}).GetTTL(); got != 2*time.Hour {
+		t.Errorf("GetTTL() = %v, want 2h", got)
+	}
+}
+
+func TestSigningConfig_MaxAge(t *testing.T) {
+	if got := (SigningConfig{}).GetMaxAge(); got != 5*time.Minute {
+		t.Errorf("GetMaxAge() = %v, want 5m", got)
+	}
+	if got := (SigningConfig{MaxAge: "90s"}).GetMaxAge(); got != 90*time.Second {
+		t.Errorf("GetMaxAge() = %v, want 90s", got)
+	}
+}
+
+func TestEncryptionConfig_RotateAfter(t *testing.T) {
+	if got := (EncryptionConfig{}).GetRotateAfter(); got != 720*time.Hour {
+		t.Errorf("GetRotateAfter() = %v, want 720h", got)
+	}
+	if got := (EncryptionConfig{RotateAfter: "24h"}).GetRotateAfter(); got != 24*time.Hour {
+		t.Errorf("GetRotateAfter() = %v, want 24h", got)
+	}
+}
+
+func TestCommandsConfig_Defaults(t *testing.T) {
+	var cfg CommandsConfig
+
+	if got := cfg.GetConsumerGroup(); got != DefaultConsumerGroup {
+		t.Errorf("GetConsumerGroup() = %q, want %q", got, DefaultConsumerGroup)
+	}
+	if got := cfg.GetVisibilityTimeout(); got != 60*time.Second {
+		t.Errorf("GetVisibilityTimeout() = %v, want 60s", got)
+	}
+
+	cfg = CommandsConfig{ConsumerGroup: "custom", VisibilityTimeout: "2m"}
+	if got := cfg.GetConsumerGroup(); got != "custom" {
+		t.Errorf("GetConsumerGroup() = %q, want custom", got)
+	}
+	if got := cfg.GetVisibilityTimeout(); got != 2*time.Minute {
+		t.Errorf("GetVisibilityTimeout() = %v, want 2m", got)
+	}
+}
+
+func TestMetricsConfig_Collectors(t *testing.T) {
+	disabled := false
+	cfg := MetricsConfig{Collectors: map[string]CollectorConfig{
+		"docker": {Enabled: &disabled},
+		"disk":   {Timeout: "3s"},
+	}}
+
+	if got := cfg.GetInterval(); got != 30*time.Second {
+		t.Errorf("GetInterval() = %v, want 30s", got)
+	}
+	if cfg.Collector("docker").IsEnabled() {
+		t.Error("docker collector should be disabled")
+	}
+	if !cfg.Collector("memory").IsEnabled() {
+		t.Error("Unconfigured collector should be enabled")
+	}
+	if got := cfg.Collector("disk").GetTimeout(); got != 3*time.Second {
+		t.Errorf("GetTimeout() = %v, want 3s", got)
+	}
+	if got := cfg.Collector("memory").GetTimeout(); got != DefaultCollectorTimeout {
+		t.Errorf("GetTimeout() = %v, want %v", got, DefaultCollectorTimeout)
+	}
+}
+
+func TestMetricsConfig_IntervalsAndJitter(t *testing.T) {
+	collector := CollectorConfig{Interval: "5m"}
+	if got := collector.GetInterval(30 * time.Second); got != 5*time.Minute {
+		t.Errorf("GetInterval() = %v, want 5m", got)
+	}
+	if got := (CollectorConfig{}).GetInterval(30 * time.Second); got != 30*time.Second {
+		t.Errorf("GetInterval() = %v, want metrics interval", got)
+	}
+
+	if got := (MetricsConfig{}).GetStartJitter(); got != DefaultStartJitter {
+		t.Errorf("GetStartJitter() = %v, want %v", got, DefaultStartJitter)
+	}
+	if got := (MetricsConfig{StartJitter: "0s"}).GetStartJitter(); got != 0 {
+		t.Errorf("GetStartJitter() = %v, want 0 when disabled", got)
+	}
+}
+
+func TestLoadAgentConfig_HostPaths(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "agent.yaml")
+	content := `
+server:
+  name: "TestServer"
+  secret_key: "srv_abc123def456abc123def456abc12345"
+api:
+  base_url: "https://api.example.com"
+metrics:
+  host:
+    proc: "/host/proc"
+    root: "/host/root"
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test config: %v", err)
+	}
+
+	// Environment overrides the file
+	t.Setenv(EnvHostProc, "")
+	t.Setenv(EnvHostSys, "/host/sys")
+	t.Setenv(EnvHostRoot, "/rootfs")
+
+	config, err := LoadAgentConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadAgentConfig() error = %v", err)
+	}
+
+	want := HostConfig{Proc: "/host/proc", Sys: "/host/sys", Root: "/rootfs"}
+	if config.Metrics.Host != want {
+		t.Errorf("Metrics.Host = %+v, want %+v", config.Metrics.Host, want)
+	}
+}
+
+func TestParseTransportServers(t *testing.T) {
+	servers, err := ParseTransportServers(" srv_a=pubsub, srv_b = streams ")
+	if err != nil {
+		t.Fatalf("ParseTransportServers() error = %v", err)
+	}
+	if len(servers) != 2 || servers["srv_a"] != "pubsub" || servers["srv_b"] != "streams" {
+		t.Errorf("ParseTransportServers() = %v", servers)
+	}
+
+	if servers, err := ParseTransportServers(""); err != nil || servers != nil {
+		t.Errorf("Empty value: got %v, %v; want nil, nil", servers, err)
+	}
+
+	for _, value := range []string{"srv_a", "srv_a=", "=pubsub", "srv_a=pubsub,,"} {
+		if _, err := ParseTransportServers(value); err == nil {
+			t.Errorf("ParseTransportServers(%q) expected error", value)
+		}
+	}
+}
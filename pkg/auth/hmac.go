@@ -0,0 +1,185 @@
+// Package auth implements HMAC request signing between agents and the bot HTTP API.
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTP headers carrying request signature
+const (
+	HeaderKeyID     = "X-ServerEye-Key-ID"
+	HeaderTimestamp = "X-ServerEye-Timestamp"
+	HeaderNonce     = "X-ServerEye-Nonce"
+	HeaderSignature = "X-ServerEye-Signature"
+)
+
+// DefaultMaxSkew is the maximum allowed clock difference between agent and bot
+const DefaultMaxSkew = 5 * time.Minute
+
+// Verification errors
+var (
+	ErrMissingHeaders   = errors.New("missing authentication headers")
+	ErrInvalidTimestamp = errors.New("invalid or expired timestamp")
+	ErrUnknownKey       = errors.New("unknown key")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrReplay           = errors.New("nonce already used")
+)
+
+// KeyID returns public identifier of a secret key (hex SHA-256), safe to send over the wire
+func KeyID(secretKey string) string {
+	sum := sha256.Sum256([]byte(secretKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// Sign calculates request signature over method, path, timestamp, nonce and body hash
+func Sign(secretKey, method, path string, timestamp int64, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	canonical := strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		strconv.FormatInt(timestamp, 10),
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignRequest sets authentication headers on request; body must be the exact request body
+func SignRequest(req *http.Request, secretKey string, body []byte) error {
+	nonce, err := newNonce()
+	if err != nil {
+		return err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set(HeaderKeyID, KeyID(secretKey))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, Sign(secretKey, req.Method, req.URL.Path, timestamp, nonce, body))
+	return nil
+}
+
+// newNonce generates random 128-bit nonce
+func newNonce() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// KeyResolver returns secret key for a key ID, or ErrUnknownKey
+type KeyResolver func(keyID string) (string, error)
+
+// Verifier checks request signatures and rejects replays
+type Verifier struct {
+	resolve KeyResolver
+	maxSkew time.Duration
+	nonces  *NonceCache
+	now     func() time.Time
+}
+
+// NewVerifier creates a verifier using resolver to look up secret keys
+func NewVerifier(resolve KeyResolver) *Verifier {
+	return &Verifier{
+		resolve: resolve,
+		maxSkew: DefaultMaxSkew,
+		// Nonces must be remembered for the whole window a timestamp is accepted in
+		nonces: NewNonceCache(2 * DefaultMaxSkew),
+		now:    time.Now,
+	}
+}
+
+// Verify validates request headers against body and returns the caller's secret key
+func (v *Verifier) Verify(req *http.Request, body []byte) (string, error) {
+	keyID := req.Header.Get(HeaderKeyID)
+	timestampStr := req.Header.Get(HeaderTimestamp)
+	nonce := req.Header.Get(HeaderNonce)
+	signature := req.Header.Get(HeaderSignature)
+
+	if keyID == "" || timestampStr == "" || nonce == "" || signature == "" {
+		return "", ErrMissingHeaders
+	}
+
+	timestamp, err := strconv.ParseInt(timestampStr, 10, 64)
+	if err != nil {
+		return "", ErrInvalidTimestamp
+	}
+
+	now := v.now()
+	skew := now.Sub(time.Unix(timestamp, 0))
+	if skew > v.maxSkew || skew < -v.maxSkew {
+		return "", ErrInvalidTimestamp
+	}
+
+	secretKey, err := v.resolve(keyID)
+	if err != nil {
+		return "", err
+	}
+
+	expected := Sign(secretKey, req.Method, req.URL.Path, timestamp, nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "", ErrInvalidSignature
+	}
+
+	// Checked last so that forged requests can't burn legitimate nonces
+	if !v.nonces.Use(keyID+":"+nonce, now) {
+		return "", ErrReplay
+	}
+
+	return secretKey, nil
+}
+
+// StreamAllowed reports whether the holder of secretKey may access stream
+func StreamAllowed(secretKey, stream string) bool {
+	return stream == "stream:cmd:"+secretKey ||
+		stream == "stream:resp:"+secretKey ||
+		stream == "stream:dlq:"+secretKey
+}
+
+// ChannelAllowed reports whether the holder of secretKey may access Pub/Sub channel
+func ChannelAllowed(secretKey, channel string) bool {
+	switch {
+	case channel == "cmd:"+secretKey,
+		channel == "resp:"+secretKey,
+		channel == "heartbeat:"+secretKey:
+		return true
+	case strings.HasPrefix(channel, "resp:"+secretKey+":"):
+		return true
+	default:
+		return false
+	}
+}
+
+// ReadBody reads the request body and restores it so handlers can decode it again
+func ReadBody(req *http.Request, limit int64) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("request body exceeds %d bytes", limit)
+	}
+
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const testSecret = "srv_0123456789abcdef0123456789abcdef"
+
+func staticResolver(keyID string) (string, error) {
+	if keyID == KeyID(testSecret) {
+		return testSecret, nil
+	}
+	return "", ErrUnknownKey
+}
+
+func newSignedRequest(t *testing.T, secret string, body []byte) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/streams/xadd", bytes.NewReader(body))
+	if err := SignRequest(req, secret, body); err != nil {
+		t.Fatalf("SignRequest() error = %v", err)
+	}
+	return req
+}
+
+func TestVerifier_ValidRequest(t *testing.T) {
+	verifier := NewVerifier(staticResolver)
+	body := []byte(`{"stream":"stream:resp:` + testSecret + `"}`)
+
+	secret, err := verifier.Verify(newSignedRequest(t, testSecret, body), body)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if secret != testSecret {
+		t.Errorf("Verify() secret = %q, want %q", secret, testSecret)
+	}
+}
+
+func TestVerifier_Rejects(t *testing.T) {
+	body := []byte(`{"stream":"x"}`)
+
+	tests := []struct {
+		name    string
+		mutate  func(req *http.Request)
+		body    []byte
+		wantErr error
+	}{
+		{
+			name:    "missing headers",
+			mutate:  func(req *http.Request) { req.Header.Del(HeaderSignature) },
+			body:    body,
+			wantErr: ErrMissingHeaders,
+		},
+		{
+			name:    "tampered body",
+			mutate:  func(req *http.Request) {},
+			body:    []byte(`{"stream":"y"}`),
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "unknown key",
+			mutate:  func(req *http.Request) { req.Header.Set(HeaderKeyID, KeyID("srv_other")) },
+			body:    body,
+			wantErr: ErrUnknownKey,
+		},
+		{
+			name: "expired timestamp",
+			mutate: func(req *http.Request) {
+				old := time.Now().Add(-time.Hour).Unix()
+				req.Header.Set(HeaderTimestamp, strconv.FormatInt(old, 10))
+			},
+			body:    body,
+			wantErr: ErrInvalidTimestamp,
+		},
+		{
+			name:    "tampered path",
+			mutate:  func(req *http.Request) { req.URL.Path = "/api/streams/xread" },
+			body:    body,
+			wantErr: ErrInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewVerifier(staticResolver)
+			req := newSignedRequest(t, testSecret, body)
+			tt.mutate(req)
+
+			_, err := verifier.Verify(req, tt.body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifier_RejectsReplay(t *testing.T) {
+	verifier := NewVerifier(staticResolver)
+	body := []byte(`{}`)
+	req := newSignedRequest(t, testSecret, body)
+
+	if _, err := verifier.Verify(req, body); err != nil {
+		t.Fatalf("first Verify() error = %v", err)
+	}
+	if _, err := verifier.Verify(req, body); !errors.Is(err, ErrReplay) {
+		t.Errorf("second Verify() error = %v, want %v", err, ErrReplay)
+	}
+}
+
+func TestStreamAndChannelAllowed(t *testing.T) {
+	other := "srv_other"
+
+	for _, stream := range []string{"stream:cmd:", "stream:resp:", "stream:dlq:"} {
+		if !StreamAllowed(testSecret, stream+testSecret) {
+			t.Errorf("own stream %q must be allowed", stream)
+		}
+	}
+	if StreamAllowed(testSecret, "stream:cmd:"+other) {
+		t.Error("foreign stream must be rejected")
+	}
+	if StreamAllowed(testSecret, "stream:cmd:"+testSecret+"x") {
+		t.Error("stream with key prefix must be rejected")
+	}
+
+	for _, channel := range []string{"cmd:" + testSecret, "resp:" + testSecret + ":cmd-1", "heartbeat:" + testSecret} {
+		if !ChannelAllowed(testSecret, channel) {
+			t.Errorf("channel %q must be allowed", channel)
+		}
+	}
+	for _, channel := range []string{"cmd:" + other, "resp:" + other + ":cmd-1", "resp:" + testSecret + "x:1"} {
+		if ChannelAllowed(testSecret, channel) {
+			t.Errorf("channel %q must be rejected", channel)
+		}
+	}
+}
+
+func TestNonceCache_Expiry(t *testing.T) {
+	cache := NewNonceCache(time.Minute)
+	now := time.Now()
+
+	if !cache.Use("n1", now) {
+		t.Fatal("first use must succeed")
+	}
+	if cache.Use("n1", now.Add(30*time.Second)) {
+		t.Error("reuse within ttl must fail")
+	}
+	if !cache.Use("n1", now.Add(2*time.Minute)) {
+		t.Error("reuse after ttl must succeed")
+	}
+	if cache.Len() != 1 {
+		t.Errorf("expired nonces must be swept, len = %d", cache.Len())
+	}
+}
+
+func TestReadBody_RestoresBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString("payload"))
+
+	body, err := ReadBody(req, 1024)
+	if err != nil {
+		t.Fatalf("ReadBody() error = %v", err)
+	}
+	if string(body) != "payload" {
+		t.Errorf("ReadBody() = %q", body)
+	}
+
+	again := new(bytes.Buffer)
+	again.ReadFrom(req.Body)
+	if again.String() != "payload" {
+		t.Errorf("body not restored, got %q", again.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString("too long"))
+	if _, err := ReadBody(req, 3); err == nil {
+		t.Error("expected error for oversized body")
+	}
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+// messageSigningContext separates command signatures from other HMACs and versions the canonical form
+const messageSigningContext = "servereye-message-v2"
+
+// encryptionKeySigningContext separates encryption key signatures from command signatures
+const encryptionKeySigningContext = "servereye-encryption-key-v1"
+
+// SigningKeyPrefix marks per-server signing keys. Unlike the server key, a signing key
+// never appears in stream or channel names.
+const SigningKeyPrefix = "sig_"
+
+// ErrMissingSignature is returned for unsigned messages when signatures are required
+var ErrMissingSignature = errors.New("message is not signed")
+
+// GenerateSigningKey creates a random per-server signing key
+func GenerateSigningKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	return SigningKeyPrefix + hex.EncodeToString(buf), nil
+}
+
+// MessageSignature calculates signature of msg over its ID, type, timestamp, deadline,
+// version, encryption and payload
+func MessageSignature(signingKey string, msg *protocol.Message) string {
+	// Payload is hashed in compact form: re-encoding the message must not break the signature
+	payload := msg.Payload
+	var compact bytes.Buffer
+	if len(payload) > 0 && json.Compact(&compact, payload) == nil {
+		payload = compact.Bytes()
+	}
+	payloadHash := sha256.Sum256(payload)
+
+	// An extended deadline or a stripped encryption mark must break the signature too
+	expiresAt := ""
+	if msg.ExpiresAt != nil {
+		expiresAt = msg.ExpiresAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	canonical := strings.Join([]string{
+		messageSigningContext,
+		msg.ID,
+		string(msg.Type),
+		msg.Timestamp.UTC().Format(time.RFC3339Nano),
+		expiresAt,
+		msg.Version,
+		msg.Encryption,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignMessage sets msg.Signature; sign after the last change to any signed field
+func SignMessage(signingKey string, msg *protocol.Message) {
+	msg.Signature = MessageSignature(signingKey, msg)
+}
+
+// EncryptionKeySignature calculates signature of an advertised encryption key over its ID and public key
+func EncryptionKeySignature(signingKey string, key protocol.EncryptionKey) string {
+	canonical := strings.Join([]string{
+		encryptionKeySigningContext,
+		key.ID,
+		hex.EncodeToString(key.PublicKey),
+	}, "\n")
+
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignEncryptionKey sets key.Signature, proving the key comes from the agent holding signingKey
+func SignEncryptionKey(signingKey string, key *protocol.EncryptionKey) {
+	key.Signature = EncryptionKeySignature(signingKey, *key)
+}
+
+// VerifyEncryptionKey checks that key was signed with signingKey
+func VerifyEncryptionKey(signingKey string, key protocol.EncryptionKey) error {
+	if key.Signature == "" {
+		return ErrMissingSignature
+	}
+	if !hmac.Equal([]byte(EncryptionKeySignature(signingKey, key)), []byte(key.Signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// MessageVerifier checks command signatures and rejects stale or replayed commands
+type MessageVerifier struct {
+	signingKey string
+	maxAge     time.Duration
+	ids        *NonceCache
+	now        func() time.Time
+}
+
+// NewMessageVerifier creates a verifier for messages signed with signingKey.
+// Commands older than maxAge (DefaultMaxSkew if zero) are rejected.
+func NewMessageVerifier(signingKey string, maxAge time.Duration) *MessageVerifier {
+	if maxAge <= 0 {
+		maxAge = DefaultMaxSkew
+	}
+
+	return &MessageVerifier{
+		signingKey: signingKey,
+		maxAge:     maxAge,
+		// IDs must be remembered for the whole window a timestamp is accepted in
+		ids: NewNonceCache(maxAge + DefaultMaxSkew),
+		now: time.Now,
+	}
+}
+
+// Verify validates signature and freshness of msg and remembers its ID.
+// Returns ErrReplay if a command with the same ID was already accepted.
+func (v *MessageVerifier) Verify(msg *protocol.Message) error {
+	if msg.Signature == "" {
+		return ErrMissingSignature
+	}
+
+	expected := MessageSignature(v.signingKey, msg)
+	if !hmac.Equal([]byte(expected), []byte(msg.Signature)) {
+		return ErrInvalidSignature
+	}
+
+	now := v.now()
+	age := now.Sub(msg.Timestamp)
+	if age > v.maxAge || age < -DefaultMaxSkew {
+		return ErrInvalidTimestamp
+	}
+
+	// Checked last so that forged commands can't burn legitimate IDs
+	if !v.ids.Use(msg.ID, now) {
+		return ErrReplay
+	}
+
+	return nil
+}
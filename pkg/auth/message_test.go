@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+const testSigningKey = "sig_00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff"
+
+func newSignedCommand(t *testing.T) *protocol.Message {
+	t.Helper()
+	msg := protocol.NewMessage(protocol.TypeRemoveContainer, protocol.ContainerActionPayload{
+		ContainerID: "abc123",
+		Action:      "remove",
+	})
+	msg.SetTTL(time.Minute)
+	SignMessage(testSigningKey, msg)
+	return msg
+}
+
+func TestMessageVerifier_RoundTrip(t *testing.T) {
+	msg := newSignedCommand(t)
+
+	// Signature survives serialization, payload whitespace doesn't matter
+	data, err := msg.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := protocol.FromJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed.Payload = json.RawMessage(`{ "container_id": "abc123", "container_name": "", "action": "remove" }`)
+
+	if err := NewMessageVerifier(testSigningKey, 0).Verify(parsed); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+}
+
+func TestMessageVerifier_Rejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(msg *protocol.Message)
+		wantErr error
+	}{
+		{"unsigned", func(msg *protocol.Message) { msg.Signature = "" }, ErrMissingSignature},
+		{"wrong key", func(msg *protocol.Message) { SignMessage("sig_other", msg) }, ErrInvalidSignature},
+		{"signed with server key", func(msg *protocol.Message) { SignMessage(testSecret, msg) }, ErrInvalidSignature},
+		{"tampered type", func(msg *protocol.Message) { msg.Type = protocol.TypeStopContainer }, ErrInvalidSignature},
+		{"tampered payload", func(msg *protocol.Message) {
+			msg.Payload = json.RawMessage(`{"container_id":"other","action":"remove"}`)
+		}, ErrInvalidSignature},
+		{"tampered id", func(msg *protocol.Message) { msg.ID = "other" }, ErrInvalidSignature},
+		{"extended deadline", func(msg *protocol.Message) { msg.SetTTL(time.Hour) }, ErrInvalidSignature},
+		{"dropped deadline", func(msg *protocol.Message) { msg.ExpiresAt = nil }, ErrInvalidSignature},
+		{"tampered version", func(msg *protocol.Message) { msg.Version = "1.0" }, ErrInvalidSignature},
+		{"tampered encryption", func(msg *protocol.Message) { msg.Encryption = "x25519-aes256gcm" }, ErrInvalidSignature},
+		{"stale", func(msg *protocol.Message) {
+			msg.Timestamp = time.Now().Add(-10 * time.Minute)
+			SignMessage(testSigningKey, msg)
+		}, ErrInvalidTimestamp},
+		{"from the future", func(msg *protocol.Message) {
+			msg.Timestamp = time.Now().Add(10 * time.Minute)
+			SignMessage(testSigningKey, msg)
+		}, ErrInvalidTimestamp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := newSignedCommand(t)
+			tt.mutate(msg)
+
+			if err := NewMessageVerifier(testSigningKey, 0).Verify(msg); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMessageVerifier_RejectsReusedID(t *testing.T) {
+	verifier := NewMessageVerifier(testSigningKey, time.Minute)
+	msg := newSignedCommand(t)
+
+	if err := verifier.Verify(msg); err != nil {
+		t.Fatalf("first Verify() error = %v", err)
+	}
+	if err := verifier.Verify(msg); !errors.Is(err, ErrReplay) {
+		t.Errorf("second Verify() error = %v, want ErrReplay", err)
+	}
+
+	// A forged command with a fresh ID doesn't get through either
+	forged := newSignedCommand(t)
+	forged.Signature = "00"
+	if err := verifier.Verify(forged); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("forged Verify() error = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestMessageVerifier_MaxAge(t *testing.T) {
+	msg := protocol.NewMessage(protocol.TypePing, nil)
+	msg.Timestamp = time.Now().Add(-2 * time.Minute)
+	SignMessage(testSigningKey, msg)
+
+	if err := NewMessageVerifier(testSigningKey, time.Minute).Verify(msg); !errors.Is(err, ErrInvalidTimestamp) {
+		t.Errorf("Verify() error = %v, want ErrInvalidTimestamp", err)
+	}
+	if err := NewMessageVerifier(testSigningKey, 5*time.Minute).Verify(msg); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
+
+func TestGenerateSigningKey(t *testing.T) {
+	key, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatalf("GenerateSigningKey() error = %v", err)
+	}
+	if !strings.HasPrefix(key, SigningKeyPrefix) || len(key) != len(SigningKeyPrefix)+64 {
+		t.Errorf("Unexpected signing key %q", key)
+	}
+
+	other, _ := GenerateSigningKey()
+	if other == key {
+		t.Error("Signing keys must be random")
+	}
+}
+
+func TestVerifyEncryptionKey(t *testing.T) {
+	key := protocol.EncryptionKey{ID: "key-1", PublicKey: []byte("public key bytes")}
+	if err := VerifyEncryptionKey(testSigningKey, key); !errors.Is(err, ErrMissingSignature) {
+		t.Errorf("unsigned VerifyEncryptionKey() error = %v, want ErrMissingSignature", err)
+	}
+
+	SignEncryptionKey(testSigningKey, &key)
+	if err := VerifyEncryptionKey(testSigningKey, key); err != nil {
+		t.Fatalf("VerifyEncryptionKey() error = %v", err)
+	}
+	if err := VerifyEncryptionKey("sig_other", key); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("wrong key VerifyEncryptionKey() error = %v, want ErrInvalidSignature", err)
+	}
+
+	// The signature doesn't transfer to another public key
+	key.PublicKey = []byte("attacker key bytes")
+	if err := VerifyEncryptionKey(testSigningKey, key); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("swapped key VerifyEncryptionKey() error = %v, want ErrInvalidSignature", err)
+	}
+}
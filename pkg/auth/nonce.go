@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// NonceCache remembers used nonces for ttl to reject replayed requests
+type NonceCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewNonceCache creates a nonce cache
+func NewNonceCache(ttl time.Duration) *NonceCache {
+	return &NonceCache{
+		ttl:  ttl,
+		seen: make(map[string]time.Time),
+	}
+}
+
+// Use marks nonce as used; returns false if it was already used within ttl
+func (c *NonceCache) Use(nonce string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Sweep expired entries at most once per ttl to keep Use cheap
+	if now.Sub(c.lastSweep) >= c.ttl {
+		for n, expires := range c.seen {
+			if now.After(expires) {
+				delete(c.seen, n)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	if expires, ok := c.seen[nonce]; ok && !now.After(expires) {
+		return false
+	}
+
+	c.seen[nonce] = now.Add(c.ttl)
+	return true
+}
+
+// Len returns number of remembered nonces
+func (c *NonceCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.seen)
+}
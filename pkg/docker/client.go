@@ -39,7 +39,7 @@ func (c *Client) GetContainers(ctx context.Context) (*protocol.ContainersPayload
 	c.logger.Debug("Getting Docker containers information")
 
 	// Check if Docker is available
-	if err := c.checkDockerAvailable(); err != nil {
+	if err := c.checkDockerAvailable(ctx); err != nil {
 		return nil, fmt.Errorf("Docker not available: %v", err)
 	}
 
@@ -66,8 +66,8 @@ func (c *Client) GetContainers(ctx context.Context) (*protocol.ContainersPayload
 }
 
 // checkDockerAvailable checks if Docker is available and accessible
-func (c *Client) checkDockerAvailable() error {
-	cmd := exec.Command("docker", "version", "--format", "json")
+func (c *Client) checkDockerAvailable(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "docker", "version", "--format", "json")
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("docker command failed: %v", err)
 	}
@@ -132,7 +132,7 @@ func TestCheckDockerAvailable(t *testing.T) {
 
 	// This test will only pass if Docker is available on the system
 	// In CI environment, Docker should be available
-	err := client.checkDockerAvailable()
+	err := client.checkDockerAvailable(context.Background())
 
 	// We don't assert here because Docker might not be available in all test environments
 	// Just ensure the method doesn't panic
@@ -152,7 +152,7 @@ func TestGetContainers_Integration(t *testing.T) {
 	ctx := context.Background()
 
 	// This test requires Docker to be available
-	err := client.checkDockerAvailable()
+	err := client.checkDockerAvailable(context.Background())
 	if err != nil {
 		t.Skipf("Docker not available, skipping integration test: %v", err)
 	}
@@ -15,6 +15,9 @@ func (c *Client) StartContainer(ctx context.Context, containerID string) (*proto
 
 	// Check Docker availability first
 	if err := c.CheckDockerAvailability(ctx); err != nil {
+		if ctxErr := interrupted(ctx, "start"); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return &protocol.ContainerActionResponse{
 			ContainerID: containerID,
 			Action:      "start",
@@ -34,6 +37,9 @@ func (c *Client) StartContainer(ctx context.Context, containerID string) (*proto
 	}
 
 	if err != nil {
+		if ctxErr := interrupted(ctx, "start"); ctxErr != nil {
+			return nil, ctxErr
+		}
 		c.logger.WithError(err).Error("Failed to start container")
 		response.Message = fmt.Sprintf("Failed to start container: %v", err)
 		return response, nil
@@ -54,6 +60,9 @@ func (c *Client) StopContainer(ctx context.Context, containerID string) (*protoc
 
 	// Check Docker availability first
 	if err := c.CheckDockerAvailability(ctx); err != nil {
+		if ctxErr := interrupted(ctx, "stop"); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return &protocol.ContainerActionResponse{
 			ContainerID: containerID,
 			Action:      "stop",
@@ -73,6 +82,9 @@ func (c *Client) StopContainer(ctx context.Context, containerID string) (*protoc
 	}
 
 	if err != nil {
+		if ctxErr := interrupted(ctx, "stop"); ctxErr != nil {
+			return nil, ctxErr
+		}
 		c.logger.WithError(err).Error("Failed to stop container")
 		response.Message = fmt.Sprintf("Failed to stop container: %v", err)
 		return response, nil
@@ -93,6 +105,9 @@ func (c *Client) RestartContainer(ctx context.Context, containerID string) (*pro
 
 	// Check Docker availability first
 	if err := c.CheckDockerAvailability(ctx); err != nil {
+		if ctxErr := interrupted(ctx, "restart"); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return &protocol.ContainerActionResponse{
 			ContainerID: containerID,
 			Action:      "restart",
@@ -112,6 +127,9 @@ func (c *Client) RestartContainer(ctx context.Context, containerID string) (*pro
 	}
 
 	if err != nil {
+		if ctxErr := interrupted(ctx, "restart"); ctxErr != nil {
+			return nil, ctxErr
+		}
 		c.logger.WithError(err).Error("Failed to restart container")
 		response.Message = fmt.Sprintf("Failed to restart container: %v", err)
 		return response, nil
@@ -132,6 +150,9 @@ func (c *Client) RemoveContainer(ctx context.Context, containerID string) (*prot
 
 	// Check Docker availability first
 	if err := c.CheckDockerAvailability(ctx); err != nil {
+		if ctxErr := interrupted(ctx, "remove"); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return &protocol.ContainerActionResponse{
 			ContainerID: containerID,
 			Action:      "remove",
@@ -152,6 +173,9 @@ func (c *Client) RemoveContainer(ctx context.Context, containerID string) (*prot
 	}
 
 	if err != nil {
+		if ctxErr := interrupted(ctx, "remove"); ctxErr != nil {
+			return nil, ctxErr
+		}
 		c.logger.WithError(err).Error("Failed to remove container")
 		response.Message = fmt.Sprintf("Failed to remove container: %v", err)
 		return response, nil
@@ -168,6 +192,9 @@ func (c *Client) CreateContainer(ctx context.Context, payload *protocol.CreateCo
 
 	// Check Docker availability first
 	if err := c.CheckDockerAvailability(ctx); err != nil {
+		if ctxErr := interrupted(ctx, "create"); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return &protocol.ContainerActionResponse{
 			ContainerName: payload.Name,
 			Action:        "create",
@@ -209,6 +236,10 @@ func (c *Client) CreateContainer(ctx context.Context, payload *protocol.CreateCo
 	}
 
 	if err != nil {
+		// Image pull or start was interrupted by the command deadline or cancel_command
+		if ctxErr := interrupted(ctx, "create"); ctxErr != nil {
+			return nil, ctxErr
+		}
 		c.logger.WithError(err).Error("Failed to create container")
 		outputStr := string(output)
 
@@ -243,3 +274,11 @@ func (c *Client) getContainerState(ctx context.Context, containerID string) (str
 	}
 	return strings.TrimSpace(string(output)), nil
 }
+
+// interrupted returns the context error when a docker command was killed because ctx is done
+func interrupted(ctx context.Context, action string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("docker %s interrupted: %w", action, err)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+// Package e2e implements end-to-end encryption of message payloads between bot and agent.
+//
+// The agent owns a static X25519 key pair and advertises the public key with its
+// capabilities. For every command the bot generates an ephemeral X25519 key, derives an
+// AES-256-GCM key from the shared secret with HKDF-SHA256 and seals the payload. The agent
+// derives the same secret from its private key and seals the response with a second key
+// derived from it, so the relay (Redis, HTTP proxy) only sees message metadata.
+package e2e
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Algorithm identifies the envelope format in protocol.Message.Encryption
+const Algorithm = "x25519-hkdf-sha256-aes256gcm"
+
+// HKDF info strings, one key per direction
+const (
+	commandInfo  = "servereye-e2e-v1 command"
+	responseInfo = "servereye-e2e-v1 response"
+)
+
+// Errors
+var (
+	ErrUnknownKey = errors.New("unknown encryption key")
+	ErrDecrypt    = errors.New("failed to decrypt payload")
+)
+
+// Envelope is the encrypted form of a payload
+type Envelope struct {
+	KeyID        string `json:"kid"`           // recipient key ID, empty for responses
+	EphemeralKey []byte `json:"epk,omitempty"` // bot ephemeral public key, commands only
+	Nonce        []byte `json:"nonce"`
+	Ciphertext   []byte `json:"ct"`
+}
+
+// KeyPair is an agent's static X25519 key pair
+type KeyPair struct {
+	ID        string
+	Private   *ecdh.PrivateKey
+	CreatedAt time.Time
+}
+
+// GenerateKeyPair creates a new random key pair
+func GenerateKeyPair() (*KeyPair, error) {
+	private, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return newKeyPair(private, time.Now()), nil
+}
+
+// ParseKeyPair restores a key pair from raw private key bytes
+func ParseKeyPair(private []byte, createdAt time.Time) (*KeyPair, error) {
+	key, err := ecdh.X25519().NewPrivateKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	return newKeyPair(key, createdAt), nil
+}
+
+func newKeyPair(private *ecdh.PrivateKey, createdAt time.Time) *KeyPair {
+	return &KeyPair{
+		ID:        KeyID(private.PublicKey().Bytes()),
+		Private:   private,
+		CreatedAt: createdAt,
+	}
+}
+
+// PublicKey returns raw public key bytes
+func (k *KeyPair) PublicKey() []byte {
+	return k.Private.PublicKey().Bytes()
+}
+
+// KeyID returns a short public identifier of a public key
+func KeyID(publicKey []byte) string {
+	sum := sha256.Sum256(publicKey)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Session holds the secret shared by one command and its response
+type Session struct {
+	secret []byte
+	salt   []byte
+}
+
+// Seal encrypts a command payload for the recipient public key.
+// The returned session opens the response to this command.
+func Seal(recipientKey []byte, keyID string, plaintext, aad []byte) (*Envelope, *Session, error) {
+	recipient, err := ecdh.X25519().NewPublicKey(recipientKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid recipient key: %w", err)
+	}
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+
+	secret, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session := newSession(secret, ephemeral.PublicKey().Bytes(), recipientKey)
+	env, err := session.seal(commandInfo, plaintext, aad)
+	if err != nil {
+		return nil, nil, err
+	}
+	env.KeyID = keyID
+	env.EphemeralKey = ephemeral.PublicKey().Bytes()
+
+	return env, session, nil
+}
+
+// Open decrypts a command payload with the recipient private key.
+// The returned session seals the response to this command.
+func Open(key *KeyPair, env *Envelope, aad []byte) ([]byte, *Session, error) {
+	if env.KeyID != key.ID {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnknownKey, env.KeyID)
+	}
+
+	ephemeral, err := ecdh.X25519().NewPublicKey(env.EphemeralKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid ephemeral key", ErrDecrypt)
+	}
+
+	secret, err := key.Private.ECDH(ephemeral)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrDecrypt, err)
+	}
+
+	session := newSession(secret, env.EphemeralKey, key.PublicKey())
+	plaintext, err := session.open(commandInfo, env, aad)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return plaintext, session, nil
+}
+
+// SealResponse encrypts the response payload
+func (s *Session) SealResponse(plaintext, aad []byte) (*Envelope, error) {
+	return s.seal(responseInfo, plaintext, aad)
+}
+
+// OpenResponse decrypts the response payload
+func (s *Session) OpenResponse(env *Envelope, aad []byte) ([]byte, error) {
+	return s.open(responseInfo, env, aad)
+}
+
+// newSession binds the shared secret to both public keys
+func newSession(secret, ephemeralKey, recipientKey []byte) *Session {
+	salt := make([]byte, 0, len(ephemeralKey)+len(recipientKey))
+	salt = append(salt, ephemeralKey...)
+	salt = append(salt, recipientKey...)
+	return &Session{secret: secret, salt: salt}
+}
+
+// aead derives the AES-256-GCM cipher for one direction
+func (s *Session) aead(info string) (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, s.secret, s.salt, info, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s *Session) seal(info string, plaintext, aad []byte) (*Envelope, error) {
+	aead, err := s.aead(info)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return &Envelope{
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, aad),
+	}, nil
+}
+
+func (s *Session) open(info string, env *Envelope, aad []byte) ([]byte, error) {
+	aead, err := s.aead(info)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(env.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid nonce", ErrDecrypt)
+	}
+
+	plaintext, err := aead.Open(nil, env.Nonce, env.Ciphertext, aad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
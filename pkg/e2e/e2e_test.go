@@ -0,0 +1,144 @@
+package e2e
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/servereye/servereye/pkg/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealOpen(t *testing.T) {
+	agentKey, err := GenerateKeyPair()
+	require.NoError(t, err)
+
+	env, botSession, err := Seal(agentKey.PublicKey(), agentKey.ID, []byte("secret command"), []byte("aad"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(env.Ciphertext), "secret")
+
+	plaintext, agentSession, err := Open(agentKey, env, []byte("aad"))
+	require.NoError(t, err)
+	assert.Equal(t, "secret command", string(plaintext))
+
+	// Response goes back under the same session with its own key
+	respEnv, err := agentSession.SealResponse([]byte("secret response"), []byte("aad"))
+	require.NoError(t, err)
+	resp, err := botSession.OpenResponse(respEnv, []byte("aad"))
+	require.NoError(t, err)
+	assert.Equal(t, "secret response", string(resp))
+
+	_, err = botSession.OpenResponse(env, []byte("aad"))
+	assert.ErrorIs(t, err, ErrDecrypt, "command envelope must not open as a response")
+}
+
+func TestOpen_Rejects(t *testing.T) {
+	agentKey, err := GenerateKeyPair()
+	require.NoError(t, err)
+	otherKey, err := GenerateKeyPair()
+	require.NoError(t, err)
+
+	env, _, err := Seal(agentKey.PublicKey(), agentKey.ID, []byte("payload"), []byte("aad"))
+	require.NoError(t, err)
+
+	_, _, err = Open(otherKey, env, []byte("aad"))
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	_, _, err = Open(agentKey, env, []byte("other aad"))
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	env.Ciphertext[0] ^= 0xff
+	_, _, err = Open(agentKey, env, []byte("aad"))
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestParseKeyPair(t *testing.T) {
+	key, err := GenerateKeyPair()
+	require.NoError(t, err)
+
+	parsed, err := ParseKeyPair(key.Private.Bytes(), key.CreatedAt)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, parsed.ID)
+	assert.Equal(t, key.PublicKey(), parsed.PublicKey())
+
+	_, err = ParseKeyPair([]byte("short"), key.CreatedAt)
+	assert.Error(t, err)
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	agentKey, err := GenerateKeyPair()
+	require.NoError(t, err)
+	keyFor := func(id string) (*KeyPair, bool) { return agentKey, id == agentKey.ID }
+
+	command := protocol.NewMessage(protocol.TypeCreateContainer, protocol.CreateContainerPayload{
+		Image:       "postgres",
+		Environment: map[string]string{"POSTGRES_PASSWORD": "hunter2"},
+	})
+	session, err := EncryptCommand(command, protocol.EncryptionKey{ID: agentKey.ID, PublicKey: agentKey.PublicKey()})
+	require.NoError(t, err)
+
+	// What the relay sees
+	data, err := command.ToJSON()
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "hunter2")
+	assert.Contains(t, string(data), string(protocol.TypeCreateContainer))
+	_, err = protocol.DecodeAs[protocol.CreateContainerPayload](command)
+	assert.ErrorIs(t, err, protocol.ErrPayloadEncrypted)
+
+	received, err := protocol.FromJSON(data)
+	require.NoError(t, err)
+	agentSession, err := DecryptCommand(received, keyFor)
+	require.NoError(t, err)
+	payload, err := protocol.DecodeAs[protocol.CreateContainerPayload](received)
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", payload.Environment["POSTGRES_PASSWORD"])
+
+	response := protocol.NewMessage(protocol.TypeProcessesResponse, protocol.ProcessesPayload{
+		Processes: []protocol.ProcessInfo{{PID: 1, Name: "secret-daemon"}},
+	})
+	response.ID = received.ID
+	require.NoError(t, agentSession.EncryptResponse(response))
+	respData, err := response.ToJSON()
+	require.NoError(t, err)
+	assert.NotContains(t, string(respData), "secret-daemon")
+
+	// Relay moving the ciphertext to another message is detected
+	moved, err := protocol.FromJSON(respData)
+	require.NoError(t, err)
+	moved.ID = "other"
+	assert.ErrorIs(t, session.DecryptResponse(moved), ErrDecrypt)
+
+	parsed, err := protocol.FromJSON(respData)
+	require.NoError(t, err)
+	require.NoError(t, session.DecryptResponse(parsed))
+	procs, err := protocol.DecodeAs[protocol.ProcessesPayload](parsed)
+	require.NoError(t, err)
+	assert.Equal(t, "secret-daemon", procs.Processes[0].Name)
+}
+
+func TestMessageWithoutPayload(t *testing.T) {
+	agentKey, err := GenerateKeyPair()
+	require.NoError(t, err)
+
+	command := protocol.NewMessage(protocol.TypeGetProcesses, nil)
+	_, err = EncryptCommand(command, protocol.EncryptionKey{ID: agentKey.ID, PublicKey: agentKey.PublicKey()})
+	require.NoError(t, err)
+
+	_, err = DecryptCommand(command, func(id string) (*KeyPair, bool) { return agentKey, true })
+	require.NoError(t, err)
+	assert.Nil(t, command.Payload)
+	assert.Empty(t, command.Encryption)
+}
+
+func TestDecryptCommand_UnknownKey(t *testing.T) {
+	agentKey, err := GenerateKeyPair()
+	require.NoError(t, err)
+
+	command := protocol.NewMessage(protocol.TypeGetProcesses, nil)
+	_, err = EncryptCommand(command, protocol.EncryptionKey{ID: agentKey.ID, PublicKey: agentKey.PublicKey()})
+	require.NoError(t, err)
+
+	_, err = DecryptCommand(command, func(string) (*KeyPair, bool) { return nil, false })
+	assert.True(t, errors.Is(err, ErrUnknownKey) && strings.Contains(err.Error(), agentKey.ID), err)
+}
@@ -0,0 +1,118 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+// messageAAD binds the ciphertext to the message it was sealed for
+func messageAAD(msg *protocol.Message) []byte {
+	return []byte(msg.ID + "\n" + string(msg.Type))
+}
+
+// EncryptCommand replaces the command payload with an envelope for the agent key.
+// The returned session decrypts the response with DecryptResponse.
+func EncryptCommand(msg *protocol.Message, key protocol.EncryptionKey) (*Session, error) {
+	env, session, err := Seal(key.PublicKey, key.ID, plaintextPayload(msg), messageAAD(msg))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setEnvelope(msg, env); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// DecryptCommand restores the command payload with the agent key pair.
+// The returned session encrypts the response with EncryptResponse.
+func DecryptCommand(msg *protocol.Message, keyFor func(keyID string) (*KeyPair, bool)) (*Session, error) {
+	env, err := envelope(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := keyFor(env.KeyID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, env.KeyID)
+	}
+
+	plaintext, session, err := Open(key, env, messageAAD(msg))
+	if err != nil {
+		return nil, err
+	}
+
+	setPlaintext(msg, plaintext)
+	return session, nil
+}
+
+// EncryptResponse replaces the response payload with an envelope for the command's session
+func (s *Session) EncryptResponse(msg *protocol.Message) error {
+	env, err := s.SealResponse(plaintextPayload(msg), messageAAD(msg))
+	if err != nil {
+		return err
+	}
+	return setEnvelope(msg, env)
+}
+
+// DecryptResponse restores the response payload. Plaintext responses are left as is:
+// the agent may reject a command before it could decrypt it.
+func (s *Session) DecryptResponse(msg *protocol.Message) error {
+	if msg.Encryption == "" {
+		return nil
+	}
+
+	env, err := envelope(msg)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := s.OpenResponse(env, messageAAD(msg))
+	if err != nil {
+		return err
+	}
+
+	setPlaintext(msg, plaintext)
+	return nil
+}
+
+// plaintextPayload returns the payload to seal, "null" for messages without payload
+func plaintextPayload(msg *protocol.Message) []byte {
+	if len(msg.Payload) == 0 {
+		return []byte("null")
+	}
+	return msg.Payload
+}
+
+func setEnvelope(msg *protocol.Message, env *Envelope) error {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	msg.Payload = data
+	msg.Encryption = Algorithm
+	return nil
+}
+
+func setPlaintext(msg *protocol.Message, plaintext []byte) {
+	msg.Payload = plaintext
+	if string(plaintext) == "null" {
+		msg.Payload = nil
+	}
+	msg.Encryption = ""
+}
+
+// envelope parses the encrypted payload of msg
+func envelope(msg *protocol.Message) (*Envelope, error) {
+	if msg.Encryption != Algorithm {
+		return nil, fmt.Errorf("%w: unsupported encryption %q", ErrDecrypt, msg.Encryption)
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(msg.Payload, &env); err != nil {
+		return nil, fmt.Errorf("%w: invalid envelope", ErrDecrypt)
+	}
+	return &env, nil
+}
@@ -193,6 +193,7 @@ func (p *Producer) PublishBatch(ctx context.Context, metrics []*publisher.Metric
 				{Key: "server_id", Value: []byte(metric.ServerID)},
 				{Key: "server_key", Value: []byte(metric.ServerKey)},
 				{Key: "metric_type", Value: []byte(metric.Type)},
+				{Key: "version", Value: []byte(metric.Version)},
 			},
 		})
 	}
@@ -0,0 +1,226 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Metric is a single value produced by a Collector
+type Metric struct {
+	Name  string
+	Value interface{}
+	Tags  map[string]string
+}
+
+// Collector gathers a group of metrics from one source
+type Collector interface {
+	// Name identifies the collector in configuration and logs
+	Name() string
+	// Interval is how often the collector should run
+	Interval() time.Duration
+	// Collect reads the current values, it should return once ctx is done
+	Collect(ctx context.Context) ([]Metric, error)
+}
+
+// collectorFunc adapts a collect function to Collector
+type collectorFunc struct {
+	name     string
+	interval time.Duration
+	collect  func(ctx context.Context) ([]Metric, error)
+}
+
+// NewCollector creates a Collector from a collect function
+func NewCollector(name string, interval time.Duration, collect func(ctx context.Context) ([]Metric, error)) Collector {
+	return &collectorFunc{name: name, interval: interval, collect: collect}
+}
+
+func (c *collectorFunc) Name() string            { return c.name }
+func (c *collectorFunc) Interval() time.Duration { return c.interval }
+
+func (c *collectorFunc) Collect(ctx context.Context) ([]Metric, error) {
+	return c.collect(ctx)
+}
+
+// defaultCollectorInterval is used for collectors reporting a non-positive interval
+const defaultCollectorInterval = 30 * time.Second
+
+// errStillRunning is returned when the previous run of a collector hasn't returned yet
+var errStillRunning = errors.New("previous run still in progress")
+
+// registeredCollector is a collector with its registry settings
+type registeredCollector struct {
+	collector Collector
+	timeout   time.Duration
+	busy      atomic.Bool // Collect is running, including runs abandoned after the timeout
+}
+
+// interval returns how often the collector runs
+func (rc *registeredCollector) interval() time.Duration {
+	if interval := rc.collector.Interval(); interval > 0 {
+		return interval
+	}
+	return defaultCollectorInterval
+}
+
+// Registry holds the enabled collectors and runs them concurrently
+type Registry struct {
+	logger     *logrus.Logger
+	collectors []*registeredCollector
+	emitMu     sync.Mutex // emit is never called concurrently
+}
+
+// NewRegistry creates an empty collector registry
+func NewRegistry(logger *logrus.Logger) *Registry {
+	return &Registry{logger: logger}
+}
+
+// Register adds a collector. Its results are dropped if Collect takes longer than timeout,
+// zero timeout means only the caller's context limits it.
+func (r *Registry) Register(c Collector, timeout time.Duration) {
+	r.collectors = append(r.collectors, &registeredCollector{collector: c, timeout: timeout})
+}
+
+// Names returns names of the registered collectors in registration order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.collectors))
+	for _, rc := range r.collectors {
+		names = append(names, rc.collector.Name())
+	}
+	return names
+}
+
+// Collect runs every collector once concurrently and passes each result to emit as soon as it's ready,
+// so a slow source doesn't delay the rest. emit is never called concurrently.
+// Failed and timed out collectors are logged and skipped. Collect returns when all are done.
+func (r *Registry) Collect(ctx context.Context, emit func(collector string, metrics []Metric)) {
+	var wg sync.WaitGroup
+	for _, rc := range r.collectors {
+		wg.Add(1)
+		go func(rc *registeredCollector) {
+			defer wg.Done()
+			r.collectOne(ctx, rc, emit)
+		}(rc)
+	}
+	wg.Wait()
+}
+
+// Run runs every collector at its own interval until ctx is done.
+// The first run of each collector is delayed by a random duration up to startJitter
+// (at most its interval), so agents started together don't publish in lockstep.
+// A tick is skipped while the previous run of the collector is still in progress.
+func (r *Registry) Run(ctx context.Context, startJitter time.Duration, emit func(collector string, metrics []Metric)) {
+	var wg sync.WaitGroup
+	for _, rc := range r.collectors {
+		wg.Add(1)
+		go func(rc *registeredCollector) {
+			defer wg.Done()
+			r.schedule(ctx, rc, startJitter, emit)
+		}(rc)
+	}
+	wg.Wait()
+}
+
+// schedule runs one collector periodically until ctx is done
+func (r *Registry) schedule(ctx context.Context, rc *registeredCollector, startJitter time.Duration, emit func(string, []Metric)) {
+	interval := rc.interval()
+
+	if delay := startDelay(startJitter, interval); delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.collectOne(ctx, rc, emit)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// startDelay returns a random delay below min(startJitter, interval)
+func startDelay(startJitter, interval time.Duration) time.Duration {
+	limit := min(startJitter, interval)
+	if limit <= 0 {
+		return 0
+	}
+	return rand.N(limit)
+}
+
+// collectOne runs a collector and emits its metrics, errors are logged
+func (r *Registry) collectOne(ctx context.Context, rc *registeredCollector, emit func(string, []Metric)) {
+	name := rc.collector.Name()
+	start := time.Now()
+
+	collected, err := run(ctx, rc)
+	switch {
+	case errors.Is(err, errStillRunning):
+		r.logger.WithField("collector", name).Warn("Collector is still running, skipping this run")
+		return
+	case errors.Is(err, context.DeadlineExceeded):
+		r.logger.WithField("collector", name).Warn("Collector timed out")
+		return
+	case err != nil:
+		// Source unavailable on this host (no sensors, no Docker): not worth a warning every cycle
+		r.logger.WithError(err).WithField("collector", name).Debug("Collector failed")
+		return
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"collector": name,
+		"metrics":   len(collected),
+		"duration":  time.Since(start),
+	}).Debug("Collector finished")
+
+	r.emitMu.Lock()
+	defer r.emitMu.Unlock()
+	emit(name, collected)
+}
+
+// run calls the collector and gives up when its timeout expires.
+// Collectors reading files ignore ctx, so the call is abandoned rather than interrupted;
+// the collector stays busy until it actually returns.
+func run(ctx context.Context, rc *registeredCollector) ([]Metric, error) {
+	if !rc.busy.CompareAndSwap(false, true) {
+		return nil, errStillRunning
+	}
+
+	if rc.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, rc.timeout)
+		defer cancel()
+	}
+
+	type result struct {
+		metrics []Metric
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer rc.busy.Store(false)
+		collected, err := rc.collector.Collect(ctx)
+		done <- result{metrics: collected, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.metrics, res.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("collector %s: %w", rc.collector.Name(), ctx.Err())
+	}
+}
@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func quietLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	return logger
+}
+
+func staticCollector(name string, delay time.Duration, err error) Collector {
+	return NewCollector(name, time.Minute, func(ctx context.Context) ([]Metric, error) {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []Metric{{Name: name + "_value", Value: 1.0}}, nil
+	})
+}
+
+func TestRegistry_SlowCollectorDoesNotDelayOthers(t *testing.T) {
+	registry := NewRegistry(quietLogger())
+	registry.Register(staticCollector("slow", 300*time.Millisecond, nil), time.Second)
+	registry.Register(staticCollector("fast", 0, nil), time.Second)
+
+	start := time.Now()
+	emitted := make(map[string]time.Duration)
+	registry.Collect(context.Background(), func(collector string, collected []Metric) {
+		emitted[collector] = time.Since(start)
+		if len(collected) != 1 || collected[0].Name != collector+"_value" {
+			t.Errorf("Unexpected metrics from %s: %+v", collector, collected)
+		}
+	})
+
+	if len(emitted) != 2 {
+		t.Fatalf("Expected both collectors to emit, got %v", emitted)
+	}
+	if emitted["fast"] >= 300*time.Millisecond {
+		t.Errorf("Fast collector waited for the slow one: %v", emitted["fast"])
+	}
+}
+
+func TestRegistry_TimeoutAndErrorsSkipped(t *testing.T) {
+	registry := NewRegistry(quietLogger())
+	registry.Register(staticCollector("hung", time.Minute, nil), 50*time.Millisecond)
+	registry.Register(staticCollector("broken", 0, errors.New("no sensors")), time.Second)
+	registry.Register(staticCollector("ok", 0, nil), 0)
+
+	var mu sync.Mutex
+	var emitted []string
+	done := make(chan struct{})
+	go func() {
+		registry.Collect(context.Background(), func(collector string, _ []Metric) {
+			mu.Lock()
+			emitted = append(emitted, collector)
+			mu.Unlock()
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Collect did not return after the collector timeout")
+	}
+	if len(emitted) != 1 || emitted[0] != "ok" {
+		t.Errorf("Expected only the ok collector to emit, got %v", emitted)
+	}
+}
+
+func TestRegistry_Names(t *testing.T) {
+	registry := NewRegistry(quietLogger())
+	registry.Register(NewMemoryCollector(NewSystemMonitor(quietLogger()), time.Minute), 0)
+	registry.Register(NewDockerCollector(nil, time.Minute), 0)
+
+	names := registry.Names()
+	if len(names) != 2 || names[0] != CollectorMemory || names[1] != CollectorDocker {
+		t.Errorf("Names() = %v", names)
+	}
+}
+
+func TestRegistry_RunPerCollectorInterval(t *testing.T) {
+	var mu sync.Mutex
+	runs := make(map[string]int)
+	counting := func(name string, interval time.Duration) Collector {
+		return NewCollector(name, interval, func(ctx context.Context) ([]Metric, error) {
+			mu.Lock()
+			runs[name]++
+			mu.Unlock()
+			return nil, nil
+		})
+	}
+
+	registry := NewRegistry(quietLogger())
+	registry.Register(counting("fast", 20*time.Millisecond), 0)
+	registry.Register(counting("slow", time.Hour), 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	registry.Run(ctx, 0, func(string, []Metric) {})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if runs["fast"] < 4 {
+		t.Errorf("Fast collector ran %d times", runs["fast"])
+	}
+	if runs["slow"] != 1 {
+		t.Errorf("Slow collector ran %d times, want 1 (first run only)", runs["slow"])
+	}
+}
+
+func TestRegistry_SkipsWhileStillRunning(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	calls := 0
+	hung := NewCollector("hung", time.Minute, func(ctx context.Context) ([]Metric, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		<-release // ignores ctx like the file readers
+		return nil, nil
+	})
+
+	registry := NewRegistry(quietLogger())
+	registry.Register(hung, 20*time.Millisecond)
+
+	// The first run is abandoned after the timeout but keeps going
+	registry.Collect(context.Background(), func(string, []Metric) {})
+	registry.Collect(context.Background(), func(string, []Metric) {})
+
+	mu.Lock()
+	if calls != 1 {
+		t.Errorf("Collector started %d times while the first run was in progress", calls)
+	}
+	mu.Unlock()
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for registry.collectors[0].busy.Load() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	emitted := false
+	registry.Collect(context.Background(), func(string, []Metric) { emitted = true })
+	if !emitted {
+		t.Error("Collector must run again once the previous run returned")
+	}
+}
+
+func TestStartDelay(t *testing.T) {
+	if d := startDelay(0, time.Minute); d != 0 {
+		t.Errorf("startDelay() = %v with jitter disabled", d)
+	}
+	for i := 0; i < 100; i++ {
+		if d := startDelay(time.Hour, time.Second); d < 0 || d >= time.Second {
+			t.Fatalf("startDelay() = %v, must stay below the interval", d)
+		}
+		if d := startDelay(10*time.Millisecond, time.Minute); d >= 10*time.Millisecond {
+			t.Fatalf("startDelay() = %v, must stay below the jitter", d)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+// Names of the built-in collectors
+const (
+	CollectorCPUTemperature = "cpu_temperature"
+	CollectorCPUUsage       = "cpu_usage"
+	CollectorMemory         = "memory"
+	CollectorDisk           = "disk"
+	CollectorNetwork        = "network"
+	CollectorDocker         = "docker"
+)
+
+const bytesInGB = 1024 * 1024 * 1024
+
+// ContainerSource lists Docker containers, implemented by docker.Client
+type ContainerSource interface {
+	GetContainers(ctx context.Context) (*protocol.ContainersPayload, error)
+}
+
+// unitMetric creates a metric tagged with its unit
+func unitMetric(name string, value float64, unit string) Metric {
+	return Metric{Name: name, Value: value, Tags: map[string]string{"unit": unit}}
+}
+
+// NewCPUTemperatureCollector reports the CPU temperature
+func NewCPUTemperatureCollector(cpu *CPUMetrics, interval time.Duration) Collector {
+	return NewCollector(CollectorCPUTemperature, interval, func(ctx context.Context) ([]Metric, error) {
+		temp, err := cpu.GetTemperature()
+		if err != nil {
+			return nil, err
+		}
+		return []Metric{unitMetric("cpu_temperature", temp, "°C")}, nil
+	})
+}
+
+// NewCPUUsageMetricsCollector reports total and per-core CPU utilization
+func NewCPUUsageMetricsCollector(usage *CPUUsageCollector, interval time.Duration) Collector {
+	return NewCollector(CollectorCPUUsage, interval, func(ctx context.Context) ([]Metric, error) {
+		payload, err := usage.GetUsage()
+		if err != nil {
+			return nil, err
+		}
+
+		result := []Metric{
+			unitMetric("cpu_usage", payload.Total.Usage, "%"),
+			unitMetric("cpu_user", payload.Total.User, "%"),
+			unitMetric("cpu_system", payload.Total.System, "%"),
+			unitMetric("cpu_iowait", payload.Total.IOWait, "%"),
+			unitMetric("cpu_steal", payload.Total.Steal, "%"),
+		}
+		for _, core := range payload.Cores {
+			result = append(result, Metric{
+				Name:  "cpu_core_usage",
+				Value: core.Usage,
+				Tags:  map[string]string{"core": core.Core, "unit": "%"},
+			})
+		}
+		return result, nil
+	})
+}
+
+// NewMemoryCollector reports memory usage
+func NewMemoryCollector(monitor *SystemMonitor, interval time.Duration) Collector {
+	return NewCollector(CollectorMemory, interval, func(ctx context.Context) ([]Metric, error) {
+		memInfo, err := monitor.GetMemoryInfo()
+		if err != nil {
+			return nil, err
+		}
+		return []Metric{
+			unitMetric("memory_usage", memInfo.UsedPercent, "%"),
+			unitMetric("memory_total", float64(memInfo.Total)/bytesInGB, "GB"),
+			unitMetric("memory_used", float64(memInfo.Used)/bytesInGB, "GB"),
+			unitMetric("memory_available", float64(memInfo.Available)/bytesInGB, "GB"),
+		}, nil
+	})
+}
+
+// NewDiskCollector reports usage of every mounted filesystem
+func NewDiskCollector(monitor *SystemMonitor, interval time.Duration) Collector {
+	return NewCollector(CollectorDisk, interval, func(ctx context.Context) ([]Metric, error) {
+		diskInfo, err := monitor.GetDiskInfo()
+		if err != nil {
+			return nil, err
+		}
+
+		result := make([]Metric, 0, len(diskInfo.Disks))
+		for _, disk := range diskInfo.Disks {
+			result = append(result, Metric{
+				Name:  "disk_usage",
+				Value: disk.UsedPercent,
+				Tags:  map[string]string{"path": disk.Path},
+			})
+		}
+		return result, nil
+	})
+}
+
+// NewNetworkCollector reports network speed and per-interface traffic
+func NewNetworkCollector(monitor *SystemMonitor, interval time.Duration) Collector {
+	return NewCollector(CollectorNetwork, interval, func(ctx context.Context) ([]Metric, error) {
+		networkInfo, err := monitor.GetNetworkInfo()
+		if err != nil {
+			return nil, err
+		}
+
+		result := []Metric{
+			unitMetric("network_download_speed", networkInfo.DownloadSpeed, "Mbps"),
+			unitMetric("network_upload_speed", networkInfo.UploadSpeed, "Mbps"),
+			unitMetric("network_total_download", float64(networkInfo.TotalDownload), "GB"),
+			unitMetric("network_total_upload", float64(networkInfo.TotalUpload), "GB"),
+		}
+		for _, iface := range networkInfo.Interfaces {
+			result = append(result,
+				Metric{
+					Name:  "network_bytes_sent",
+					Value: float64(iface.BytesSent) / bytesInGB,
+					Tags:  map[string]string{"interface": iface.Name},
+				},
+				Metric{
+					Name:  "network_bytes_recv",
+					Value: float64(iface.BytesRecv) / bytesInGB,
+					Tags:  map[string]string{"interface": iface.Name},
+				},
+			)
+		}
+		return result, nil
+	})
+}
+
+// NewDockerCollector reports the list of Docker containers
+func NewDockerCollector(source ContainerSource, interval time.Duration) Collector {
+	return NewCollector(CollectorDocker, interval, func(ctx context.Context) ([]Metric, error) {
+		containers, err := source.GetContainers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []Metric{{Name: "containers", Value: containers}}, nil
+	})
+}
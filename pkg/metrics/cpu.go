@@ -9,21 +9,28 @@ import (
 )
 
 // CPUMetrics provides methods for collecting CPU metrics
-type CPUMetrics struct{}
+type CPUMetrics struct {
+	paths HostPaths
+}
 
 // NewCPUMetrics creates a new CPUMetrics instance
 func NewCPUMetrics() *CPUMetrics {
-	return &CPUMetrics{}
+	return NewCPUMetricsWithPaths(DefaultHostPaths())
+}
+
+// NewCPUMetricsWithPaths creates a CPUMetrics instance reading sensors from the host sysfs at paths
+func NewCPUMetricsWithPaths(paths HostPaths) *CPUMetrics {
+	return &CPUMetrics{paths: paths}
 }
 
 // GetTemperature retrieves CPU temperature in Celsius
 func (c *CPUMetrics) GetTemperature() (float64, error) {
 	// Try different CPU temperature sources
 	sources := []string{
-		"/sys/class/thermal/thermal_zone0/temp",
-		"/sys/class/hwmon/hwmon0/temp1_input",
-		"/sys/class/hwmon/hwmon1/temp1_input",
-		"/sys/class/hwmon/hwmon2/temp1_input",
+		c.paths.sys("class/thermal/thermal_zone0/temp"),
+		c.paths.sys("class/hwmon/hwmon0/temp1_input"),
+		c.paths.sys("class/hwmon/hwmon1/temp1_input"),
+		c.paths.sys("class/hwmon/hwmon2/temp1_input"),
 	}
 
 	for _, source := range sources {
@@ -74,8 +81,8 @@ func (c *CPUMetrics) readTemperatureFromFile(filepath string) (float64, error) {
 func (c *CPUMetrics) getTemperatureFromCoretemp() (float64, error) {
 	// Look for coretemp sensors
 	coretempPaths := []string{
-		"/sys/devices/platform/coretemp.0/hwmon/hwmon*/temp1_input",
-		"/sys/devices/platform/coretemp.0/temp1_input",
+		c.paths.sys("devices/platform/coretemp.0/hwmon/hwmon*/temp1_input"),
+		c.paths.sys("devices/platform/coretemp.0/temp1_input"),
 	}
 
 	for _, pattern := range coretempPaths {
@@ -94,9 +101,9 @@ func (c *CPUMetrics) getTemperatureFromCoretemp() (float64, error) {
 // GetSensorInfo returns information about available sensors
 func (c *CPUMetrics) GetSensorInfo() string {
 	sources := []string{
-		"/sys/class/thermal/thermal_zone0/temp",
-		"/sys/class/hwmon/hwmon0/temp1_input",
-		"/sys/class/hwmon/hwmon1/temp1_input",
+		c.paths.sys("class/thermal/thermal_zone0/temp"),
+		c.paths.sys("class/hwmon/hwmon0/temp1_input"),
+		c.paths.sys("class/hwmon/hwmon1/temp1_input"),
 	}
 
 	for _, source := range sources {
@@ -0,0 +1,214 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+// defaultSampleInterval is used when no previous /proc/stat sample exists
+const defaultSampleInterval = 500 * time.Millisecond
+
+// cpuTimes holds cumulative jiffies for one line of /proc/stat
+type cpuTimes struct {
+	user    uint64
+	nice    uint64
+	system  uint64
+	idle    uint64
+	iowait  uint64
+	irq     uint64
+	softirq uint64
+	steal   uint64
+}
+
+// total returns the sum of all tracked jiffies
+func (t cpuTimes) total() uint64 {
+	return t.user + t.nice + t.system + t.idle + t.iowait + t.irq + t.softirq + t.steal
+}
+
+// cpuSample is a snapshot of /proc/stat taken at a point in time
+type cpuSample struct {
+	total cpuTimes
+	cores map[string]cpuTimes
+	order []string
+	taken time.Time
+}
+
+// CPUUsageCollector calculates CPU utilization from /proc/stat deltas
+type CPUUsageCollector struct {
+	statPath       string
+	sampleInterval time.Duration
+
+	mu   sync.Mutex
+	prev *cpuSample
+}
+
+// NewCPUUsageCollector creates a new CPU usage collector
+func NewCPUUsageCollector() *CPUUsageCollector {
+	return NewCPUUsageCollectorWithPaths(DefaultHostPaths())
+}
+
+// NewCPUUsageCollectorWithPaths creates a CPU usage collector reading the host procfs at paths
+func NewCPUUsageCollectorWithPaths(paths HostPaths) *CPUUsageCollector {
+	return &CPUUsageCollector{
+		statPath:       paths.proc("stat"),
+		sampleInterval: defaultSampleInterval,
+	}
+}
+
+// GetUsage returns CPU utilization since the previous call.
+// On the first call two samples are taken sampleInterval apart.
+func (c *CPUUsageCollector) GetUsage() (*protocol.CPUUsagePayload, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prev := c.prev
+	if prev == nil {
+		first, err := c.readSample()
+		if err != nil {
+			return nil, err
+		}
+		prev = first
+		time.Sleep(c.sampleInterval)
+	}
+
+	current, err := c.readSample()
+	if err != nil {
+		return nil, err
+	}
+	c.prev = current
+
+	payload := &protocol.CPUUsagePayload{
+		Total:    calculateUsage("cpu", prev.total, current.total),
+		Cores:    make([]protocol.CPUCoreUsage, 0, len(current.order)),
+		Interval: current.taken.Sub(prev.taken).Seconds(),
+	}
+
+	for _, name := range current.order {
+		before, ok := prev.cores[name]
+		if !ok {
+			// Core came online between samples, nothing to compare with
+			continue
+		}
+		payload.Cores = append(payload.Cores, calculateUsage(name, before, current.cores[name]))
+	}
+
+	return payload, nil
+}
+
+// readSample reads and parses the current /proc/stat contents
+func (c *CPUUsageCollector) readSample() (*cpuSample, error) {
+	file, err := os.Open(c.statPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", c.statPath, err)
+	}
+	defer file.Close()
+
+	sample := &cpuSample{
+		cores: make(map[string]cpuTimes),
+		taken: time.Now(),
+	}
+	foundTotal := false
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "cpu") {
+			continue
+		}
+
+		name, times, err := parseCPUStatLine(line)
+		if err != nil {
+			return nil, err
+		}
+
+		if name == "cpu" {
+			sample.total = times
+			foundTotal = true
+			continue
+		}
+
+		sample.cores[name] = times
+		sample.order = append(sample.order, name)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", c.statPath, err)
+	}
+
+	if !foundTotal {
+		return nil, fmt.Errorf("aggregate cpu line not found in %s", c.statPath)
+	}
+
+	return sample, nil
+}
+
+// parseCPUStatLine parses a single "cpuN ..." line from /proc/stat
+func parseCPUStatLine(line string) (string, cpuTimes, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 5 {
+		return "", cpuTimes{}, fmt.Errorf("invalid cpu line: %q", line)
+	}
+
+	// Older kernels don't report all columns, missing ones stay zero
+	values := make([]uint64, 8)
+	for i := 1; i < len(fields) && i <= len(values); i++ {
+		v, err := strconv.ParseUint(fields[i], 10, 64)
+		if err != nil {
+			return "", cpuTimes{}, fmt.Errorf("failed to parse cpu field %q: %w", fields[i], err)
+		}
+		values[i-1] = v
+	}
+
+	return fields[0], cpuTimes{
+		user:    values[0],
+		nice:    values[1],
+		system:  values[2],
+		idle:    values[3],
+		iowait:  values[4],
+		irq:     values[5],
+		softirq: values[6],
+		steal:   values[7],
+	}, nil
+}
+
+// calculateUsage converts two cumulative samples into percentages
+func calculateUsage(name string, before, after cpuTimes) protocol.CPUCoreUsage {
+	usage := protocol.CPUCoreUsage{Core: name}
+
+	totalDelta := float64(delta(before.total(), after.total()))
+	if totalDelta == 0 {
+		usage.Idle = 100
+		return usage
+	}
+
+	percent := func(b, a uint64) float64 {
+		return float64(delta(b, a)) / totalDelta * 100
+	}
+
+	usage.User = percent(before.user+before.nice, after.user+after.nice)
+	usage.System = percent(before.system+before.irq+before.softirq, after.system+after.irq+after.softirq)
+	usage.IOWait = percent(before.iowait, after.iowait)
+	usage.Steal = percent(before.steal, after.steal)
+	usage.Idle = percent(before.idle, after.idle)
+	usage.Usage = 100 - usage.Idle - usage.IOWait
+	if usage.Usage < 0 {
+		usage.Usage = 0
+	}
+
+	return usage
+}
+
+// delta returns a-b guarding against counter resets
+func delta(b, a uint64) uint64 {
+	if a < b {
+		return 0
+	}
+	return a - b
+}
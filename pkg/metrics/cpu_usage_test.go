@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeStatFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create mock stat file: %v", err)
+	}
+}
+
+func TestCPUUsageCollector_GetUsage(t *testing.T) {
+	statFile := filepath.Join(t.TempDir(), "stat")
+
+	collector := NewCPUUsageCollector()
+	collector.statPath = statFile
+	collector.sampleInterval = 0
+
+	writeStatFile(t, statFile, `cpu  100 0 100 800 0 0 0 0 0 0
+cpu0 50 0 50 400 0 0 0 0 0 0
+cpu1 50 0 50 400 0 0 0 0 0 0
+intr 12345
+`)
+
+	// Prime the collector with the first sample
+	if _, err := collector.GetUsage(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// cpu0 fully busy, cpu1 idle with some iowait
+	writeStatFile(t, statFile, `cpu  200 0 200 900 100 0 0 0 0 0
+cpu0 150 0 150 400 0 0 0 0 0 0
+cpu1 50 0 50 500 100 0 0 0 0 0
+intr 23456
+`)
+
+	usage, err := collector.GetUsage()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(usage.Cores) != 2 {
+		t.Fatalf("Expected 2 cores, got %d", len(usage.Cores))
+	}
+
+	assertPercent(t, "total usage", usage.Total.Usage, 50)
+	assertPercent(t, "total iowait", usage.Total.IOWait, 25)
+	assertPercent(t, "total idle", usage.Total.Idle, 25)
+	assertPercent(t, "cpu0 usage", usage.Cores[0].Usage, 100)
+	assertPercent(t, "cpu1 usage", usage.Cores[1].Usage, 0)
+	assertPercent(t, "cpu1 iowait", usage.Cores[1].IOWait, 50)
+
+	if usage.Cores[0].Core != "cpu0" {
+		t.Errorf("Expected core name cpu0, got %s", usage.Cores[0].Core)
+	}
+}
+
+func TestCPUUsageCollector_MissingFile(t *testing.T) {
+	collector := NewCPUUsageCollector()
+	collector.statPath = filepath.Join(t.TempDir(), "missing")
+
+	if _, err := collector.GetUsage(); err == nil {
+		t.Error("Expected error for missing stat file, got nil")
+	}
+}
+
+func TestCPUUsageCollector_NoAggregateLine(t *testing.T) {
+	statFile := filepath.Join(t.TempDir(), "stat")
+	writeStatFile(t, statFile, "cpu0 1 2 3 4 5 6 7 8\n")
+
+	collector := NewCPUUsageCollector()
+	collector.statPath = statFile
+
+	if _, err := collector.GetUsage(); err == nil {
+		t.Error("Expected error when aggregate cpu line is missing, got nil")
+	}
+}
+
+func TestParseCPUStatLine(t *testing.T) {
+	name, times, err := parseCPUStatLine("cpu3 10 20 30 40 50 60 70 80 0 0")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if name != "cpu3" {
+		t.Errorf("Expected name cpu3, got %s", name)
+	}
+	if times.user != 10 || times.idle != 40 || times.steal != 80 {
+		t.Errorf("Unexpected parsed values: %+v", times)
+	}
+	if times.total() != 360 {
+		t.Errorf("Expected total 360, got %d", times.total())
+	}
+
+	if _, _, err := parseCPUStatLine("cpu 1 2"); err == nil {
+		t.Error("Expected error for short line, got nil")
+	}
+	if _, _, err := parseCPUStatLine("cpu 1 2 x 4"); err == nil {
+		t.Error("Expected error for invalid value, got nil")
+	}
+}
+
+func TestCalculateUsage_CounterReset(t *testing.T) {
+	before := cpuTimes{user: 100, idle: 100}
+	after := cpuTimes{user: 10, idle: 10}
+
+	usage := calculateUsage("cpu", before, after)
+	if usage.Idle != 100 || usage.Usage != 0 {
+		t.Errorf("Expected idle CPU after counter reset, got %+v", usage)
+	}
+}
+
+func assertPercent(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 0.01 {
+		t.Errorf("Expected %s %.2f%%, got %.2f%%", name, want, got)
+	}
+}
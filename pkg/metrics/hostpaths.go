@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"path/filepath"
+)
+
+// Default locations of the monitored host filesystems
+const (
+	DefaultHostProc = "/proc"
+	DefaultHostSys  = "/sys"
+	DefaultHostRoot = "/"
+)
+
+// HostPaths locates the host filesystems read by collectors.
+// An agent running in a container sees the host through read-only mounts, e.g. /host/proc.
+type HostPaths struct {
+	Proc string // procfs, /proc by default
+	Sys  string // sysfs, /sys by default
+	Root string // host root filesystem: mount points, /etc
+}
+
+// DefaultHostPaths returns paths of a host monitored from its own mount namespace
+func DefaultHostPaths() HostPaths {
+	return HostPaths{Proc: DefaultHostProc, Sys: DefaultHostSys, Root: DefaultHostRoot}
+}
+
+// NewHostPaths fills empty paths: Proc and Sys default to proc and sys under root
+func NewHostPaths(proc, sys, root string) HostPaths {
+	if root == "" {
+		root = DefaultHostRoot
+	}
+	if proc == "" {
+		proc = filepath.Join(root, "proc")
+	}
+	if sys == "" {
+		sys = filepath.Join(root, "sys")
+	}
+	return HostPaths{Proc: proc, Sys: sys, Root: root}
+}
+
+// proc returns a path inside procfs
+func (p HostPaths) proc(elem ...string) string {
+	return filepath.Join(append([]string{p.Proc}, elem...)...)
+}
+
+// sys returns a path inside sysfs
+func (p HostPaths) sys(elem ...string) string {
+	return filepath.Join(append([]string{p.Sys}, elem...)...)
+}
+
+// host returns an absolute host path, such as a mount point, under the host root
+func (p HostPaths) host(path string) string {
+	return filepath.Join(p.Root, path)
+}
+
+// ownProc reports whether Proc is the agent's own procfs
+func (p HostPaths) ownProc() bool {
+	return filepath.Clean(p.Proc) == DefaultHostProc
+}
+
+// hostProcess returns a file under /proc/<pid> of a process in the host namespaces:
+// the agent itself for its own procfs, host init for a host procfs mounted into a container,
+// where "self" doesn't resolve
+func (p HostPaths) hostProcess(elem ...string) string {
+	pid := "1"
+	if p.ownProc() {
+		pid = "self"
+	}
+	return p.proc(append([]string{pid}, elem...)...)
+}
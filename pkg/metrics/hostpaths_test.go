@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"testing"
+)
+
+func TestNewHostPaths(t *testing.T) {
+	tests := []struct {
+		name            string
+		proc, sys, root string
+		want            HostPaths
+	}{
+		{
+			name: "defaults",
+			want: DefaultHostPaths(),
+		},
+		{
+			name: "root only",
+			root: "/host",
+			want: HostPaths{Proc: "/host/proc", Sys: "/host/sys", Root: "/host"},
+		},
+		{
+			name: "separate mounts",
+			proc: "/host/proc",
+			sys:  "/host/sys",
+			root: "/host/root",
+			want: HostPaths{Proc: "/host/proc", Sys: "/host/sys", Root: "/host/root"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewHostPaths(tt.proc, tt.sys, tt.root); got != tt.want {
+				t.Errorf("NewHostPaths() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostPaths_HostProcess(t *testing.T) {
+	if got := DefaultHostPaths().hostProcess("mounts"); got != "/proc/self/mounts" {
+		t.Errorf("Own procfs: got %s, want /proc/self/mounts", got)
+	}
+
+	// "self" doesn't resolve in a host procfs mounted into a container
+	if got := NewHostPaths("/host/proc", "", "").hostProcess("net", "dev"); got != "/host/proc/1/net/dev" {
+		t.Errorf("Host procfs: got %s, want /host/proc/1/net/dev", got)
+	}
+
+	if got := NewHostPaths("", "", "/host").host("/etc/passwd"); got != "/host/etc/passwd" {
+		t.Errorf("Host path: got %s, want /host/etc/passwd", got)
+	}
+}
+
+func TestCPUMetrics_HostSys(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, "sys/class/hwmon/hwmon1/temp1_input", "52000\n")
+
+	cpu := NewCPUMetricsWithPaths(NewHostPaths("", "", root))
+
+	temp, err := cpu.GetTemperature()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if temp != 52 {
+		t.Errorf("Expected temperature 52.0, got %.1f", temp)
+	}
+}
+
+func TestCPUUsageCollector_HostProc(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, "proc/stat", "cpu  100 0 100 800 0 0 0 0 0 0\ncpu0 100 0 100 800 0 0 0 0 0 0\n")
+
+	collector := NewCPUUsageCollectorWithPaths(NewHostPaths("", "", root))
+	collector.sampleInterval = 0
+
+	usage, err := collector.GetUsage()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(usage.Cores) != 1 {
+		t.Errorf("Expected 1 core from the host /proc/stat, got %d", len(usage.Cores))
+	}
+}
+
+func TestSystemMonitor_GetSystemInfo_HostRoot(t *testing.T) {
+	monitor, root := newFixtureMonitor(t)
+	writeFixture(t, root, "etc/hostname", "web-01\n")
+	writeFixture(t, root, "etc/os-release", "PRETTY_NAME=\"Debian GNU/Linux 12 (bookworm)\"\nVERSION_ID=\"12\"\n")
+	writeFixture(t, root, "proc/sys/kernel/osrelease", "6.1.0-18-amd64\n")
+	writeFixture(t, root, "proc/meminfo", "MemTotal:        2048000 kB\n")
+	writeFixture(t, root, "proc/stat", "cpu  1 2 3 4\nbtime 1700000000\n")
+	writeFixture(t, root, "proc/uptime", "3600.00 7000.00\n")
+	writeFixture(t, root, "sys/class/dmi/id/sys_vendor", "QEMU\n")
+
+	info, err := monitor.GetSystemInfo()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if info.Hostname != "web-01" {
+		t.Errorf("Expected host name from /etc/hostname, got %q", info.Hostname)
+	}
+	if info.Distro != "Debian GNU/Linux 12 (bookworm)" || info.DistroVersion != "12" {
+		t.Errorf("Unexpected distro: %q %q", info.Distro, info.DistroVersion)
+	}
+	if info.Kernel != "6.1.0-18-amd64" {
+		t.Errorf("Unexpected kernel: %q", info.Kernel)
+	}
+	if info.TotalMemory != 2048000*1024 || info.BootTime != 1700000000 {
+		t.Errorf("Unexpected memory or boot time: %d, %d", info.TotalMemory, info.BootTime)
+	}
+	// The agent's own container markers must not leak into the host view
+	if info.Virtualization != "kvm" {
+		t.Errorf("Expected kvm, got %s", info.Virtualization)
+	}
+}
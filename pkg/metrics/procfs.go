@@ -0,0 +1,258 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+// clockTicks is USER_HZ, the unit of CPU times in /proc/<pid>/stat (100 on every Linux platform)
+const clockTicks = 100
+
+// ignoredFilesystems are pseudo and in-memory filesystems hidden from disk usage like `df -x`
+var ignoredFilesystems = map[string]bool{
+	"tmpfs": true, "devtmpfs": true, "squashfs": true, "ramfs": true,
+	"proc": true, "sysfs": true, "devpts": true, "mqueue": true, "cgroup": true, "cgroup2": true,
+	"debugfs": true, "tracefs": true, "securityfs": true, "pstore": true, "bpf": true,
+	"autofs": true, "configfs": true, "fusectl": true, "hugetlbfs": true, "binfmt_misc": true,
+	"nsfs": true, "rpc_pipefs": true, "efivarfs": true,
+}
+
+// readUptimeSeconds reads seconds since boot from /proc/uptime
+func readUptimeSeconds(path string) (float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) < 1 {
+		return 0, fmt.Errorf("invalid uptime format")
+	}
+
+	uptime, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse uptime: %w", err)
+	}
+	return uptime, nil
+}
+
+// mountEntry is one line of a mount table
+type mountEntry struct {
+	source     string
+	mountPoint string
+	fsType     string
+}
+
+// readMounts parses a mount table in /proc/<pid>/mounts format
+func readMounts(path string) ([]mountEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var mounts []mountEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		mounts = append(mounts, mountEntry{
+			source:     unescapeMountField(fields[0]),
+			mountPoint: unescapeMountField(fields[1]),
+			fsType:     fields[2],
+		})
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMountField decodes octal escapes such as \040 for a space
+func unescapeMountField(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+3 < len(field) {
+			if code, err := strconv.ParseUint(field[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(code))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(field[i])
+	}
+	return b.String()
+}
+
+// procStat holds the fields of /proc/<pid>/stat used for process listing
+type procStat struct {
+	comm      string
+	state     string
+	cpuTicks  uint64 // utime + stime
+	startTick uint64 // start time after boot
+	rssPages  uint64
+}
+
+// parseProcStat parses /proc/<pid>/stat. The command name may contain spaces and
+// parentheses, so fields are counted from the last ')'.
+func parseProcStat(data []byte) (*procStat, error) {
+	open := bytes.IndexByte(data, '(')
+	end := bytes.LastIndexByte(data, ')')
+	if open < 0 || end < open {
+		return nil, fmt.Errorf("invalid stat format")
+	}
+
+	// Fields after the command start with state (field 3 in proc(5))
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("invalid stat format: %d fields", len(fields))
+	}
+
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	start, _ := strconv.ParseUint(fields[19], 10, 64)
+	rss, _ := strconv.ParseInt(fields[21], 10, 64)
+
+	stat := &procStat{
+		comm:      string(data[open+1 : end]),
+		state:     fields[0],
+		cpuTicks:  utime + stime,
+		startTick: start,
+	}
+	if rss > 0 {
+		stat.rssPages = uint64(rss)
+	}
+	return stat, nil
+}
+
+// readProcessUID returns the real UID from /proc/<pid>/status
+func readProcessUID(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == "Uid:" {
+			return fields[1], nil
+		}
+	}
+	return "", fmt.Errorf("uid not found in %s", path)
+}
+
+// readProcessName returns argv[0] like ps, or [comm] for kernel threads
+func readProcessName(cmdlinePath, comm string) string {
+	data, err := os.ReadFile(cmdlinePath)
+	if err == nil {
+		if arg, _, _ := bytes.Cut(data, []byte{0}); len(arg) > 0 {
+			return string(arg)
+		}
+	}
+	return "[" + comm + "]"
+}
+
+// readUsernames maps UIDs to user names from an /etc/passwd file
+func readUsernames(path string) map[string]string {
+	names := make(map[string]string)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return names
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) >= 3 {
+			names[fields[2]] = fields[0]
+		}
+	}
+	return names
+}
+
+// listProcesses reads every /proc/<pid> and returns processes sorted by CPU, then memory usage
+func (s *SystemMonitor) listProcesses() ([]protocol.ProcessInfo, error) {
+	entries, err := os.ReadDir(s.paths.proc())
+	if err != nil {
+		return nil, err
+	}
+
+	uptime, err := readUptimeSeconds(s.paths.proc("uptime"))
+	if err != nil {
+		return nil, err
+	}
+	memTotal, _ := readMemTotal(s.paths.proc("meminfo"))
+	bootTime, _ := readBootTime(s.paths.proc("stat"))
+	usernames := readUsernames(s.paths.host("/etc/passwd"))
+	pageSize := uint64(os.Getpagesize())
+
+	var processes []protocol.ProcessInfo
+	for _, entry := range entries {
+		pid, err := strconv.ParseInt(entry.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+
+		// The process may exit while we read it
+		data, err := os.ReadFile(s.paths.proc(entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		stat, err := parseProcStat(data)
+		if err != nil {
+			continue
+		}
+
+		// %CPU like ps: CPU time over the process lifetime
+		var cpuPercent float64
+		if elapsed := uptime - float64(stat.startTick)/clockTicks; elapsed > 0 {
+			cpuPercent = float64(stat.cpuTicks) / clockTicks / elapsed * 100
+		}
+
+		rss := stat.rssPages * pageSize
+		var memPercent float32
+		if memTotal > 0 {
+			memPercent = float32(float64(rss) / float64(memTotal) * 100)
+		}
+
+		username := ""
+		if uid, err := readProcessUID(s.paths.proc(entry.Name(), "status")); err == nil {
+			username = uid
+			if name, ok := usernames[uid]; ok {
+				username = name
+			}
+		}
+
+		var createTime int64
+		if bootTime > 0 {
+			createTime = int64(bootTime + stat.startTick/clockTicks)
+		}
+
+		processes = append(processes, protocol.ProcessInfo{
+			PID:           int32(pid),
+			Name:          readProcessName(s.paths.proc(entry.Name(), "cmdline"), stat.comm),
+			CPUPercent:    cpuPercent,
+			MemoryMB:      rss / 1024 / 1024,
+			MemoryPercent: memPercent,
+			Status:        stat.state,
+			Username:      username,
+			CreateTime:    createTime,
+		})
+	}
+
+	sort.SliceStable(processes, func(i, j int) bool {
+		if processes[i].CPUPercent != processes[j].CPUPercent {
+			return processes[i].CPUPercent > processes[j].CPUPercent
+		}
+		return processes[i].MemoryPercent > processes[j].MemoryPercent
+	})
+	return processes, nil
+}
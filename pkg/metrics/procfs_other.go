@@ -0,0 +1,10 @@
+//go:build !unix
+
+package metrics
+
+import "errors"
+
+// statfsUsage is unavailable without statfs, disks are not reported on this platform
+func statfsUsage(path string) (total, used, free uint64, usedPercent float64, err error) {
+	return 0, 0, 0, 0, errors.New("statfs is not supported on this platform")
+}
@@ -0,0 +1,203 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// writeFixture creates a file under root, creating parent directories
+func writeFixture(t *testing.T, root, name, content string) {
+	t.Helper()
+	path := filepath.Join(root, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create fixture dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create fixture %s: %v", name, err)
+	}
+}
+
+// newFixtureMonitor creates a SystemMonitor reading from a temporary root
+func newFixtureMonitor(t *testing.T) (*SystemMonitor, string) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	root := t.TempDir()
+	return NewSystemMonitorWithPaths(logger, NewHostPaths("", "", root)), root
+}
+
+func TestGetMemoryInfo_Fixture(t *testing.T) {
+	monitor, root := newFixtureMonitor(t)
+	writeFixture(t, root, "proc/meminfo", `MemTotal:        8000000 kB
+MemFree:         1000000 kB
+MemAvailable:    6000000 kB
+Buffers:          200000 kB
+Cached:          3000000 kB
+SwapTotal:             0 kB
+`)
+
+	memInfo, err := monitor.GetMemoryInfo()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if memInfo.Total != 8000000*1024 {
+		t.Errorf("Total = %d, want %d", memInfo.Total, 8000000*1024)
+	}
+	if memInfo.Used != 2000000*1024 {
+		t.Errorf("Used = %d, want %d", memInfo.Used, 2000000*1024)
+	}
+	if memInfo.Cached != 3000000*1024 || memInfo.Buffers != 200000*1024 || memInfo.Free != 1000000*1024 {
+		t.Errorf("Unexpected cached/buffers/free: %+v", memInfo)
+	}
+	assertPercent(t, "used percent", memInfo.UsedPercent, 25)
+}
+
+func TestGetUptime_Fixture(t *testing.T) {
+	monitor, root := newFixtureMonitor(t)
+	writeFixture(t, root, "proc/uptime", "90061.42 170000.00\n")
+
+	uptime, err := monitor.GetUptime()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if uptime.Uptime != 90061 {
+		t.Errorf("Uptime = %d, want 90061", uptime.Uptime)
+	}
+	if uptime.Formatted != "1 days, 1 hours, 1 minutes" {
+		t.Errorf("Unexpected formatted uptime: %q", uptime.Formatted)
+	}
+}
+
+func TestGetNetworkInfo_Fixture(t *testing.T) {
+	monitor, root := newFixtureMonitor(t)
+	writeFixture(t, root, "proc/1/net/dev", `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:  123456     100    0    0    0     0          0         0   123456     100    0    0    0     0       0          0
+  eth0: 2147483648  2000    1    2    0     0          0         0 1073741824  1000    3    4    0     0       0          0
+`)
+
+	networkInfo, err := monitor.GetNetworkInfo()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(networkInfo.Interfaces) != 1 {
+		t.Fatalf("Expected loopback to be skipped, got %d interfaces", len(networkInfo.Interfaces))
+	}
+
+	eth0 := networkInfo.Interfaces[0]
+	if eth0.Name != "eth0" || eth0.BytesRecv != 2147483648 || eth0.BytesSent != 1073741824 {
+		t.Errorf("Unexpected interface stats: %+v", eth0)
+	}
+	if eth0.ErrorsIn != 1 || eth0.DropIn != 2 || eth0.ErrorsOut != 3 || eth0.DropOut != 4 {
+		t.Errorf("Unexpected error counters: %+v", eth0)
+	}
+	if networkInfo.TotalDownload != 2 || networkInfo.TotalUpload != 1 {
+		t.Errorf("Unexpected totals: download %d GB, upload %d GB", networkInfo.TotalDownload, networkInfo.TotalUpload)
+	}
+}
+
+func TestUnescapeMountField(t *testing.T) {
+	tests := map[string]string{
+		"/":                  "/",
+		`/mnt/my\040data`:    "/mnt/my data",
+		`/mnt/tab\011name`:   "/mnt/tab\tname",
+		`/mnt/back\134slash`: `/mnt/back\slash`,
+		`/mnt/broken\04`:     `/mnt/broken\04`,
+	}
+
+	for input, want := range tests {
+		if got := unescapeMountField(input); got != want {
+			t.Errorf("unescapeMountField(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestParseProcStat(t *testing.T) {
+	stat, err := parseProcStat([]byte("42 (my (odd) app) S 1 42 42 0 -1 4194560 100 0 0 0 250 50 0 0 20 0 1 0 1000 10000000 512 18446744073709551615 0 0 0 0 0 0 0 0 0 0 0 0 17 0 0 0 0 0 0\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if stat.comm != "my (odd) app" {
+		t.Errorf("comm = %q, want %q", stat.comm, "my (odd) app")
+	}
+	if stat.state != "S" || stat.cpuTicks != 300 || stat.startTick != 1000 || stat.rssPages != 512 {
+		t.Errorf("Unexpected stat: %+v", stat)
+	}
+
+	if _, err := parseProcStat([]byte("42 (short) S 1")); err == nil {
+		t.Error("Expected error for truncated stat")
+	}
+}
+
+func TestGetTopProcesses_Fixture(t *testing.T) {
+	monitor, root := newFixtureMonitor(t)
+	pageSize := os.Getpagesize()
+
+	writeFixture(t, root, "proc/uptime", "1010.00 2000.00\n")
+	writeFixture(t, root, "proc/stat", "cpu  1 2 3 4\nbtime 1700000000\n")
+	writeFixture(t, root, "proc/meminfo", "MemTotal:        1048576 kB\n")
+	writeFixture(t, root, "etc/passwd", "root:x:0:0:root:/root:/bin/bash\nwww:x:33:33::/var/www:/usr/sbin/nologin\n")
+
+	// pid 1: 10s of CPU over 1000s lifetime (1%), 256 pages
+	writeFixture(t, root, "proc/1/stat", "1 (init) S 0 1 1 0 -1 0 0 0 0 0 600 400 0 0 20 0 1 0 1000 0 256 0 0\n")
+	writeFixture(t, root, "proc/1/status", "Name:\tinit\nUid:\t0\t0\t0\t0\n")
+	writeFixture(t, root, "proc/1/cmdline", "/sbin/init\x00splash\x00")
+
+	// pid 200: 50s of CPU over 100s lifetime (50%), unknown uid
+	writeFixture(t, root, "proc/200/stat", "200 (worker) R 1 200 200 0 -1 0 0 0 0 0 4000 1000 0 0 20 0 1 0 91000 0 1024 0 0\n")
+	writeFixture(t, root, "proc/200/status", "Name:\tworker\nUid:\t1234\t1234\t1234\t1234\n")
+	writeFixture(t, root, "proc/200/cmdline", "/usr/bin/worker\x00--fast\x00")
+
+	// pid 300: kernel thread without cmdline
+	writeFixture(t, root, "proc/300/stat", "300 (kworker/0:1) I 2 0 0 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0 500 0 0 0 0\n")
+	writeFixture(t, root, "proc/300/status", "Name:\tkworker/0:1\nUid:\t33\t33\t33\t33\n")
+	writeFixture(t, root, "proc/300/cmdline", "")
+
+	// Not a process directory
+	writeFixture(t, root, "proc/sys/kernel/osrelease", "6.1.0\n")
+
+	payload, err := monitor.GetTopProcesses(10)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if payload.Total != 3 {
+		t.Fatalf("Expected 3 processes, got %d", payload.Total)
+	}
+
+	worker := payload.Processes[0]
+	if worker.PID != 200 || worker.Name != "/usr/bin/worker" || worker.Username != "1234" || worker.Status != "R" {
+		t.Errorf("Unexpected top process: %+v", worker)
+	}
+	assertPercent(t, "worker cpu", worker.CPUPercent, 50)
+	assertPercent(t, "worker memory", float64(worker.MemoryPercent), float64(1024*pageSize)/float64(1048576*1024)*100)
+	if worker.CreateTime != 1700000000+910 {
+		t.Errorf("CreateTime = %d, want %d", worker.CreateTime, 1700000000+910)
+	}
+
+	initProc := payload.Processes[1]
+	if initProc.PID != 1 || initProc.Name != "/sbin/init" || initProc.Username != "root" {
+		t.Errorf("Unexpected second process: %+v", initProc)
+	}
+	assertPercent(t, "init cpu", initProc.CPUPercent, 1)
+
+	kworker := payload.Processes[2]
+	if kworker.Name != "[kworker/0:1]" || kworker.Username != "www" {
+		t.Errorf("Unexpected kernel thread: %+v", kworker)
+	}
+
+	limited, err := monitor.GetTopProcesses(1)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if limited.Total != 1 || limited.Processes[0].PID != 200 {
+		t.Errorf("Expected only the top process, got %+v", limited.Processes)
+	}
+}
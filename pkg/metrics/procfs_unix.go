@@ -0,0 +1,23 @@
+//go:build unix
+
+package metrics
+
+import "syscall"
+
+// statfsUsage returns exact usage of the filesystem mounted at path.
+// Used and UsedPercent match df: reserved blocks count neither as used nor as free.
+func statfsUsage(path string) (total, used, free uint64, usedPercent float64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	blockSize := uint64(st.Bsize)
+	total = uint64(st.Blocks) * blockSize
+	used = (uint64(st.Blocks) - uint64(st.Bfree)) * blockSize
+	free = uint64(st.Bavail) * blockSize
+	if used+free > 0 {
+		usedPercent = float64(used) / float64(used+free) * 100
+	}
+	return total, used, free, usedPercent, nil
+}
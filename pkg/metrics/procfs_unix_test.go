@@ -0,0 +1,55 @@
+//go:build unix
+
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestGetDiskInfo_Fixture(t *testing.T) {
+	monitor, root := newFixtureMonitor(t)
+	if err := os.MkdirAll(filepath.Join(root, "mnt", "my data"), 0755); err != nil {
+		t.Fatalf("Failed to create mount point: %v", err)
+	}
+	writeFixture(t, root, "proc/1/mounts", `/dev/sda1 / ext4 rw,relatime 0 0
+proc /proc proc rw,relatime 0 0
+tmpfs /run tmpfs rw,nosuid 0 0
+/dev/sda1 /bind ext4 rw,relatime 0 0
+/dev/sdb1 /mnt/my\040data ext4 rw,relatime 0 0
+/dev/sdc1 /missing ext4 rw,relatime 0 0
+`)
+
+	diskInfo, err := monitor.GetDiskInfo()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(diskInfo.Disks) != 2 {
+		t.Fatalf("Expected 2 disks, got %d: %+v", len(diskInfo.Disks), diskInfo.Disks)
+	}
+	if diskInfo.Disks[0].Path != "/" || diskInfo.Disks[0].Filesystem != "/dev/sda1" {
+		t.Errorf("Unexpected first disk: %+v", diskInfo.Disks[0])
+	}
+	if diskInfo.Disks[1].Path != "/mnt/my data" || diskInfo.Disks[1].Filesystem != "/dev/sdb1" {
+		t.Errorf("Unexpected second disk: %+v", diskInfo.Disks[1])
+	}
+
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(root, &st); err != nil {
+		t.Fatalf("Statfs failed: %v", err)
+	}
+
+	disk := diskInfo.Disks[0]
+	if want := uint64(st.Blocks) * uint64(st.Bsize); disk.Total != want {
+		t.Errorf("Total = %d, want exact %d bytes", disk.Total, want)
+	}
+	if disk.Used+disk.Free > disk.Total {
+		t.Errorf("Used %d + Free %d exceeds Total %d", disk.Used, disk.Free, disk.Total)
+	}
+	if disk.UsedPercent < 0 || disk.UsedPercent > 100 {
+		t.Errorf("UsedPercent out of range: %f", disk.UsedPercent)
+	}
+}
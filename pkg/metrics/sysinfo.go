@@ -0,0 +1,280 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/servereye/servereye/pkg/protocol"
+	"github.com/sirupsen/logrus"
+)
+
+// Files used to build static system information, relative to the host root, procfs or sysfs
+const (
+	osReleasePath    = "/etc/os-release"
+	hostnamePath     = "/etc/hostname"
+	dockerEnvPath    = "/.dockerenv"
+	containerEnvPath = "/run/.containerenv"
+	kernelPath       = "sys/kernel/osrelease"
+	cpuInfoPath      = "cpuinfo"
+	memInfoPath      = "meminfo"
+	procStatPath     = "stat"
+	initCgroupPath   = "1/cgroup"
+	dmiProductPath   = "class/dmi/id/product_name"
+	dmiVendorPath    = "class/dmi/id/sys_vendor"
+)
+
+// GetSystemInfo retrieves static host information (hostname, kernel, distro, hardware)
+func (s *SystemMonitor) GetSystemInfo() (*protocol.SystemInfoPayload, error) {
+	s.logger.Debug("Getting system information")
+
+	hostname, err := s.hostname()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to get hostname")
+		return nil, fmt.Errorf("failed to get hostname: %w", err)
+	}
+
+	info := &protocol.SystemInfoPayload{
+		Hostname: hostname,
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		CPUCount: runtime.NumCPU(),
+	}
+
+	if kernel, err := os.ReadFile(s.paths.proc(kernelPath)); err == nil {
+		info.Kernel = strings.TrimSpace(string(kernel))
+	}
+
+	if release, err := parseOSRelease(s.paths.host(osReleasePath)); err == nil {
+		info.Distro = release["PRETTY_NAME"]
+		if info.Distro == "" {
+			info.Distro = release["NAME"]
+		}
+		info.DistroVersion = release["VERSION_ID"]
+	} else {
+		s.logger.WithError(err).Debug("Failed to read os-release")
+	}
+
+	if model, count, err := readCPUModel(s.paths.proc(cpuInfoPath)); err == nil {
+		info.CPUModel = model
+		if count > 0 {
+			info.CPUCount = count
+		}
+	}
+
+	if total, err := readMemTotal(s.paths.proc(memInfoPath)); err == nil {
+		info.TotalMemory = total
+	}
+
+	if bootTime, err := readBootTime(s.paths.proc(procStatPath)); err == nil {
+		info.BootTime = bootTime
+	}
+
+	if uptime, err := s.GetUptime(); err == nil {
+		info.Uptime = uptime.Formatted
+	}
+
+	info.Virtualization = detectVirtualization(virtualizationPaths{
+		dockerEnv:    s.paths.host(dockerEnvPath),
+		containerEnv: s.paths.host(containerEnvPath),
+		initCgroup:   s.paths.proc(initCgroupPath),
+		cpuInfo:      s.paths.proc(cpuInfoPath),
+		dmiProduct:   s.paths.sys(dmiProductPath),
+		dmiVendor:    s.paths.sys(dmiVendorPath),
+	})
+
+	s.logger.WithFields(logrus.Fields{
+		"hostname":       info.Hostname,
+		"distro":         info.Distro,
+		"kernel":         info.Kernel,
+		"virtualization": info.Virtualization,
+	}).Debug("System info retrieved")
+
+	return info, nil
+}
+
+// hostname returns the host name. In a container with the host root mounted
+// the kernel reports the container's name, so /etc/hostname of the host is preferred.
+func (s *SystemMonitor) hostname() (string, error) {
+	if filepath.Clean(s.paths.Root) != DefaultHostRoot {
+		if data, err := os.ReadFile(s.paths.host(hostnamePath)); err == nil {
+			if name := strings.TrimSpace(string(data)); name != "" {
+				return name, nil
+			}
+		}
+	}
+	return os.Hostname()
+}
+
+// parseOSRelease parses KEY=value pairs from an os-release file
+func parseOSRelease(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+
+		values[key] = strings.Trim(value, `"'`)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return values, nil
+}
+
+// readCPUModel returns the CPU model name and number of logical processors
+func readCPUModel(path string) (string, int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var model string
+	count := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+
+		switch strings.TrimSpace(key) {
+		case "processor":
+			count++
+		case "model name", "Model", "cpu model":
+			if model == "" {
+				model = strings.TrimSpace(value)
+			}
+		}
+	}
+
+	return model, count, nil
+}
+
+// readMemTotal returns MemTotal from /proc/meminfo in bytes
+func readMemTotal(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse MemTotal: %w", err)
+		}
+		return value * 1024, nil
+	}
+
+	return 0, fmt.Errorf("MemTotal not found in %s", path)
+}
+
+// readBootTime returns the btime value (unix seconds) from /proc/stat
+func readBootTime(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "btime" {
+			continue
+		}
+
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse btime: %w", err)
+		}
+		return value, nil
+	}
+
+	return 0, fmt.Errorf("btime not found in %s", path)
+}
+
+// virtualizationPaths groups files inspected by detectVirtualization
+type virtualizationPaths struct {
+	dockerEnv    string
+	containerEnv string
+	initCgroup   string
+	cpuInfo      string
+	dmiProduct   string
+	dmiVendor    string
+}
+
+// detectVirtualization guesses container or hypervisor type, "none" for bare metal
+func detectVirtualization(paths virtualizationPaths) string {
+	// Containers first: a container may run inside a VM
+	if _, err := os.Stat(paths.dockerEnv); err == nil {
+		return "docker"
+	}
+	if _, err := os.Stat(paths.containerEnv); err == nil {
+		return "podman"
+	}
+	if data, err := os.ReadFile(paths.initCgroup); err == nil {
+		cgroup := string(data)
+		switch {
+		case strings.Contains(cgroup, "docker"):
+			return "docker"
+		case strings.Contains(cgroup, "kubepods"):
+			return "kubernetes"
+		case strings.Contains(cgroup, "lxc"):
+			return "lxc"
+		}
+	}
+
+	var dmi string
+	for _, path := range []string{paths.dmiProduct, paths.dmiVendor} {
+		if data, err := os.ReadFile(path); err == nil {
+			dmi += strings.ToLower(strings.TrimSpace(string(data))) + " "
+		}
+	}
+
+	switch {
+	case strings.Contains(dmi, "kvm"), strings.Contains(dmi, "qemu"):
+		return "kvm"
+	case strings.Contains(dmi, "vmware"):
+		return "vmware"
+	case strings.Contains(dmi, "virtualbox"):
+		return "virtualbox"
+	case strings.Contains(dmi, "xen"):
+		return "xen"
+	case strings.Contains(dmi, "microsoft"):
+		return "hyperv"
+	case strings.Contains(dmi, "amazon"):
+		return "aws"
+	case strings.Contains(dmi, "google"):
+		return "gce"
+	}
+
+	if data, err := os.ReadFile(paths.cpuInfo); err == nil {
+		if strings.Contains(string(data), " hypervisor") {
+			return "vm"
+		}
+	}
+
+	return "none"
+}
@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseOSRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "os-release")
+	content := `# comment
+NAME="Ubuntu"
+VERSION_ID="22.04"
+PRETTY_NAME="Ubuntu 22.04.3 LTS"
+ID=ubuntu
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create mock os-release: %v", err)
+	}
+
+	release, err := parseOSRelease(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if release["PRETTY_NAME"] != "Ubuntu 22.04.3 LTS" {
+		t.Errorf("Unexpected PRETTY_NAME: %q", release["PRETTY_NAME"])
+	}
+	if release["VERSION_ID"] != "22.04" {
+		t.Errorf("Unexpected VERSION_ID: %q", release["VERSION_ID"])
+	}
+	if release["ID"] != "ubuntu" {
+		t.Errorf("Unexpected ID: %q", release["ID"])
+	}
+}
+
+func TestReadCPUModel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cpuinfo")
+	content := `processor	: 0
+model name	: Intel(R) Xeon(R) CPU E5-2680 v4 @ 2.40GHz
+flags		: fpu vme hypervisor
+
+processor	: 1
+model name	: Intel(R) Xeon(R) CPU E5-2680 v4 @ 2.40GHz
+flags		: fpu vme hypervisor
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create mock cpuinfo: %v", err)
+	}
+
+	model, count, err := readCPUModel(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if model != "Intel(R) Xeon(R) CPU E5-2680 v4 @ 2.40GHz" {
+		t.Errorf("Unexpected model: %q", model)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 processors, got %d", count)
+	}
+}
+
+func TestReadMemTotalAndBootTime(t *testing.T) {
+	dir := t.TempDir()
+	memPath := filepath.Join(dir, "meminfo")
+	statPath := filepath.Join(dir, "stat")
+
+	if err := os.WriteFile(memPath, []byte("MemTotal:       16384000 kB\nMemFree: 1 kB\n"), 0644); err != nil {
+		t.Fatalf("Failed to create mock meminfo: %v", err)
+	}
+	if err := os.WriteFile(statPath, []byte("cpu  1 2 3 4\nbtime 1700000000\n"), 0644); err != nil {
+		t.Fatalf("Failed to create mock stat: %v", err)
+	}
+
+	total, err := readMemTotal(memPath)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if total != 16384000*1024 {
+		t.Errorf("Unexpected MemTotal: %d", total)
+	}
+
+	bootTime, err := readBootTime(statPath)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if bootTime != 1700000000 {
+		t.Errorf("Unexpected btime: %d", bootTime)
+	}
+
+	if _, err := readBootTime(memPath); err == nil {
+		t.Error("Expected error when btime is missing, got nil")
+	}
+}
+
+func TestDetectVirtualization(t *testing.T) {
+	dir := t.TempDir()
+	paths := virtualizationPaths{
+		dockerEnv:    filepath.Join(dir, ".dockerenv"),
+		containerEnv: filepath.Join(dir, ".containerenv"),
+		initCgroup:   filepath.Join(dir, "cgroup"),
+		cpuInfo:      filepath.Join(dir, "cpuinfo"),
+		dmiProduct:   filepath.Join(dir, "product_name"),
+		dmiVendor:    filepath.Join(dir, "sys_vendor"),
+	}
+
+	if got := detectVirtualization(paths); got != "none" {
+		t.Errorf("Expected none, got %s", got)
+	}
+
+	if err := os.WriteFile(paths.dmiVendor, []byte("QEMU\n"), 0644); err != nil {
+		t.Fatalf("Failed to create mock sys_vendor: %v", err)
+	}
+	if got := detectVirtualization(paths); got != "kvm" {
+		t.Errorf("Expected kvm, got %s", got)
+	}
+
+	if err := os.WriteFile(paths.dockerEnv, nil, 0644); err != nil {
+		t.Fatalf("Failed to create mock .dockerenv: %v", err)
+	}
+	if got := detectVirtualization(paths); got != "docker" {
+		t.Errorf("Expected docker, got %s", got)
+	}
+}
+
+func TestSystemMonitor_GetSystemInfo(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	monitor := NewSystemMonitor(logger)
+
+	info, err := monitor.GetSystemInfo()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if info.Hostname == "" {
+		t.Error("Hostname is empty")
+	}
+	if info.Arch == "" || info.OS == "" {
+		t.Error("OS/Arch not set")
+	}
+	if info.CPUCount <= 0 {
+		t.Errorf("Unexpected CPU count: %d", info.CPUCount)
+	}
+	if info.Virtualization == "" {
+		t.Error("Virtualization is empty")
+	}
+}
@@ -2,7 +2,7 @@ package metrics
 
 import (
 	"fmt"
-	"os/exec"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -14,6 +14,7 @@ import (
 // SystemMonitor provides system monitoring capabilities
 type SystemMonitor struct {
 	logger *logrus.Logger
+	paths  HostPaths
 	// Previous network stats for speed calculation
 	prevNetStats map[string]*networkStats
 	prevNetTime  time.Time
@@ -27,8 +28,14 @@ type networkStats struct {
 
 // NewSystemMonitor creates a new system monitor
 func NewSystemMonitor(logger *logrus.Logger) *SystemMonitor {
+	return NewSystemMonitorWithPaths(logger, DefaultHostPaths())
+}
+
+// NewSystemMonitorWithPaths creates a system monitor reading the host filesystems at paths
+func NewSystemMonitorWithPaths(logger *logrus.Logger, paths HostPaths) *SystemMonitor {
 	return &SystemMonitor{
 		logger:       logger,
+		paths:        paths,
 		prevNetStats: make(map[string]*networkStats),
 		prevNetTime:  time.Now(),
 	}
@@ -39,8 +46,7 @@ func (s *SystemMonitor) GetMemoryInfo() (*protocol.MemoryInfo, error) {
 	s.logger.Debug("Getting memory information")
 
 	// Read /proc/meminfo for detailed memory stats
-	cmd := exec.Command("cat", "/proc/meminfo")
-	output, err := cmd.Output()
+	output, err := os.ReadFile(s.paths.proc("meminfo"))
 	if err != nil {
 		s.logger.WithError(err).Error("Failed to read /proc/meminfo")
 		return nil, fmt.Errorf("failed to get memory info: %w", err)
@@ -105,52 +111,44 @@ func (s *SystemMonitor) GetMemoryInfo() (*protocol.MemoryInfo, error) {
 func (s *SystemMonitor) GetDiskInfo() (*protocol.DiskInfoPayload, error) {
 	s.logger.Debug("Getting disk information")
 
-	// Use df command to get disk usage
-	cmd := exec.Command("df", "-h", "-x", "tmpfs", "-x", "devtmpfs", "-x", "squashfs")
-	output, err := cmd.Output()
+	mountsPath := s.paths.hostProcess("mounts")
+	mounts, err := readMounts(mountsPath)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to execute df command")
+		s.logger.WithError(err).Errorf("Failed to read %s", mountsPath)
 		return nil, fmt.Errorf("failed to get disk info: %w", err)
 	}
 
-	lines := strings.Split(string(output), "\n")
 	var disks []protocol.DiskInfo
+	seen := make(map[string]bool)
 
-	// Skip header line
-	for i, line := range lines {
-		if i == 0 || strings.TrimSpace(line) == "" {
+	for _, mount := range mounts {
+		if ignoredFilesystems[mount.fsType] {
 			continue
 		}
 
-		fields := strings.Fields(line)
-		if len(fields) < 6 {
+		// The same device may be mounted several times (bind mounts), report it once like df
+		if strings.HasPrefix(mount.source, "/dev/") && seen[mount.source] {
 			continue
 		}
 
-		filesystem := fields[0]
-		totalStr := fields[1]
-		usedStr := fields[2]
-		availStr := fields[3]
-		usedPercentStr := strings.TrimSuffix(fields[4], "%")
-		mountPoint := fields[5]
-
-		// Parse sizes (df -h gives human readable format)
-		total := s.parseHumanSize(totalStr)
-		used := s.parseHumanSize(usedStr)
-		free := s.parseHumanSize(availStr)
-
-		usedPercent, _ := strconv.ParseFloat(usedPercentStr, 64)
+		total, used, free, usedPercent, err := statfsUsage(s.paths.host(mount.mountPoint))
+		if err != nil {
+			s.logger.WithError(err).WithField("mount_point", mount.mountPoint).Debug("Failed to stat filesystem")
+			continue
+		}
+		if total == 0 {
+			continue
+		}
+		seen[mount.source] = true
 
-		diskInfo := protocol.DiskInfo{
-			Path:        mountPoint,
+		disks = append(disks, protocol.DiskInfo{
+			Path:        mount.mountPoint,
 			Total:       total,
 			Used:        used,
 			Free:        free,
 			UsedPercent: usedPercent,
-			Filesystem:  filesystem,
-		}
-
-		disks = append(disks, diskInfo)
+			Filesystem:  mount.source,
+		})
 	}
 
 	payload := &protocol.DiskInfoPayload{
@@ -165,24 +163,12 @@ func (s *SystemMonitor) GetDiskInfo() (*protocol.DiskInfoPayload, error) {
 func (s *SystemMonitor) GetUptime() (*protocol.UptimeInfo, error) {
 	s.logger.Debug("Getting uptime information")
 
-	// Read /proc/uptime
-	cmd := exec.Command("cat", "/proc/uptime")
-	output, err := cmd.Output()
+	uptimeFloat, err := readUptimeSeconds(s.paths.proc("uptime"))
 	if err != nil {
 		s.logger.WithError(err).Error("Failed to read /proc/uptime")
 		return nil, fmt.Errorf("failed to get uptime: %w", err)
 	}
 
-	fields := strings.Fields(strings.TrimSpace(string(output)))
-	if len(fields) < 1 {
-		return nil, fmt.Errorf("invalid uptime format")
-	}
-
-	uptimeFloat, err := strconv.ParseFloat(fields[0], 64)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse uptime: %w", err)
-	}
-
 	uptimeSeconds := uint64(uptimeFloat)
 	now := time.Now().Unix()
 	if now < 0 {
@@ -226,50 +212,14 @@ func (s *SystemMonitor) GetTopProcesses(limit int) (*protocol.ProcessesPayload,
 		limit = 10
 	}
 
-	// Use ps command to get process information
-	cmd := exec.Command("ps", "aux", "--sort=-pcpu,-pmem")
-	output, err := cmd.Output()
+	processes, err := s.listProcesses()
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to execute ps command")
+		s.logger.WithError(err).Error("Failed to list processes")
 		return nil, fmt.Errorf("failed to get processes: %w", err)
 	}
 
-	lines := strings.Split(string(output), "\n")
-	var processes []protocol.ProcessInfo
-
-	// Skip header line and process up to limit
-	count := 0
-	for i, line := range lines {
-		if i == 0 || strings.TrimSpace(line) == "" || count >= limit {
-			continue
-		}
-
-		fields := strings.Fields(line)
-		if len(fields) < 11 {
-			continue
-		}
-
-		pid, _ := strconv.ParseInt(fields[1], 10, 32)
-		cpuPercent, _ := strconv.ParseFloat(fields[2], 64)
-		memPercent, _ := strconv.ParseFloat(fields[3], 32)
-
-		// Memory in KB, convert to MB
-		memKB, _ := strconv.ParseUint(fields[5], 10, 64)
-		memMB := memKB / 1024
-
-		processInfo := protocol.ProcessInfo{
-			PID:           int32(pid),
-			Name:          fields[10], // Command name
-			CPUPercent:    cpuPercent,
-			MemoryMB:      memMB,
-			MemoryPercent: float32(memPercent),
-			Status:        fields[7],
-			Username:      fields[0],
-			CreateTime:    0, // Would need additional parsing
-		}
-
-		processes = append(processes, processInfo)
-		count++
+	if len(processes) > limit {
+		processes = processes[:limit]
 	}
 
 	payload := &protocol.ProcessesPayload{
@@ -286,8 +236,7 @@ func (s *SystemMonitor) GetNetworkInfo() (*protocol.NetworkInfo, error) {
 	s.logger.Debug("Getting network information")
 
 	// Read /proc/net/dev
-	cmd := exec.Command("cat", "/proc/net/dev")
-	output, err := cmd.Output()
+	output, err := os.ReadFile(s.paths.hostProcess("net", "dev"))
 	if err != nil {
 		s.logger.WithError(err).Error("Failed to read /proc/net/dev")
 		return nil, fmt.Errorf("failed to get network info: %w", err)
@@ -397,33 +346,3 @@ func (s *SystemMonitor) GetNetworkInfo() (*protocol.NetworkInfo, error) {
 
 	return networkInfo, nil
 }
-
-// parseHumanSize converts human readable size (like 1.5G, 512M) to bytes
-func (s *SystemMonitor) parseHumanSize(sizeStr string) uint64 {
-	if len(sizeStr) == 0 {
-		return 0
-	}
-
-	// Get the last character (unit)
-	unit := sizeStr[len(sizeStr)-1:]
-	valueStr := sizeStr[:len(sizeStr)-1]
-
-	value, err := strconv.ParseFloat(valueStr, 64)
-	if err != nil {
-		return 0
-	}
-
-	switch strings.ToUpper(unit) {
-	case "K":
-		return uint64(value * 1024)
-	case "M":
-		return uint64(value * 1024 * 1024)
-	case "G":
-		return uint64(value * 1024 * 1024 * 1024)
-	case "T":
-		return uint64(value * 1024 * 1024 * 1024 * 1024)
-	default:
-		// Assume it's already in bytes
-		return uint64(value)
-	}
-}
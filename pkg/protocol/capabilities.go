@@ -0,0 +1,132 @@
+package protocol
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	// ProtocolVersion is the protocol version stamped on every message
+	ProtocolVersion = "1.2"
+	// LegacyProtocolVersion is assumed for agents that don't advertise capabilities
+	LegacyProtocolVersion = "1.0"
+)
+
+// legacyCommands are the commands every 1.0 agent handles.
+// Commands added later must be advertised in Capabilities instead.
+var legacyCommands = []MessageType{
+	TypeGetCPUTemp, TypeGetContainers,
+	TypeStartContainer, TypeStopContainer, TypeRestartContainer, TypeRemoveContainer,
+	TypeCreateContainer, TypeGetMemoryInfo, TypeGetDiskInfo, TypeGetUptime,
+	TypeGetProcesses, TypeGetNetworkInfo, TypeUpdateAgent, TypePing,
+}
+
+// Capabilities is what an agent advertises on registration and with every heartbeat
+type Capabilities struct {
+	ProtocolVersion string        `json:"protocol_version"`
+	Commands        []MessageType `json:"commands"`
+	// EncryptionKey is set when the agent accepts end-to-end encrypted payloads
+	EncryptionKey *EncryptionKey `json:"encryption_key,omitempty"`
+}
+
+// EncryptionKey is the public X25519 key an agent receives encrypted payloads with
+type EncryptionKey struct {
+	ID        string `json:"id"`
+	PublicKey []byte `json:"public_key"`
+	// Signature - HMAC ключом подписи сервера (auth.SignEncryptionKey), без него бот не примет смену ключа
+	Signature string `json:"signature,omitempty"`
+}
+
+// NewCapabilities returns capabilities of the current protocol version with the given commands
+func NewCapabilities(commands ...MessageType) Capabilities {
+	return Capabilities{
+		ProtocolVersion: ProtocolVersion,
+		Commands:        commands,
+	}
+}
+
+// LegacyCapabilities describes an agent that predates capability advertisement
+func LegacyCapabilities() Capabilities {
+	return Capabilities{
+		ProtocolVersion: LegacyProtocolVersion,
+		Commands:        append([]MessageType(nil), legacyCommands...),
+	}
+}
+
+// IsLegacyCommand reports whether every agent, including 1.0 ones, handles msgType
+func IsLegacyCommand(msgType MessageType) bool {
+	for _, t := range legacyCommands {
+		if t == msgType {
+			return true
+		}
+	}
+	return false
+}
+
+// Supports reports whether the agent handles msgType
+func (c Capabilities) Supports(msgType MessageType) bool {
+	for _, t := range c.Commands {
+		if t == msgType {
+			return true
+		}
+	}
+	return false
+}
+
+// Equal reports whether both sides advertise the same version and command set
+func (c Capabilities) Equal(other Capabilities) bool {
+	if c.ProtocolVersion != other.ProtocolVersion || len(c.Commands) != len(other.Commands) {
+		return false
+	}
+	if c.encryptionKeyID() != other.encryptionKeyID() {
+		return false
+	}
+	for _, t := range other.Commands {
+		if !c.Supports(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// encryptionKeyID returns ID of the advertised encryption key, "" if there is none
+func (c Capabilities) encryptionKeyID() string {
+	if c.EncryptionKey == nil {
+		return ""
+	}
+	return c.EncryptionKey.ID
+}
+
+// IsCompatible reports whether a peer speaking version can talk to us.
+// Versions are compatible while the major part matches; an empty version is a 1.0 peer.
+func IsCompatible(version string) bool {
+	if version == "" {
+		version = LegacyProtocolVersion
+	}
+	return majorVersion(version) == majorVersion(ProtocolVersion)
+}
+
+// versionAtLeast reports whether version is required or newer, an empty version is a 1.0 peer
+func versionAtLeast(version, required string) bool {
+	if version == "" {
+		version = LegacyProtocolVersion
+	}
+	if majorVersion(version) != majorVersion(required) {
+		return majorVersion(version) > majorVersion(required)
+	}
+	return minorVersion(version) >= minorVersion(required)
+}
+
+// majorVersion returns the major part of a "major.minor" version
+func majorVersion(version string) int {
+	major, _, _ := strings.Cut(strings.TrimSpace(version), ".")
+	n, _ := strconv.Atoi(major)
+	return n
+}
+
+// minorVersion returns the minor part of a "major.minor" version
+func minorVersion(version string) int {
+	_, minor, _ := strings.Cut(strings.TrimSpace(version), ".")
+	n, _ := strconv.Atoi(minor)
+	return n
+}
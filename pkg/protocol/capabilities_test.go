@@ -0,0 +1,78 @@
+package protocol
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapabilities_Supports(t *testing.T) {
+	caps := NewCapabilities(TypePing, TypeGetUptime)
+
+	assert.Equal(t, ProtocolVersion, caps.ProtocolVersion)
+	assert.True(t, caps.Supports(TypePing))
+	assert.False(t, caps.Supports(TypeUpdateAgent))
+}
+
+func TestLegacyCapabilities(t *testing.T) {
+	caps := LegacyCapabilities()
+
+	assert.Equal(t, LegacyProtocolVersion, caps.ProtocolVersion)
+	for _, msgType := range []MessageType{TypeGetCPUTemp, TypeStartContainer, TypeUpdateAgent, TypePing} {
+		assert.True(t, caps.Supports(msgType), msgType)
+		assert.True(t, IsLegacyCommand(msgType), msgType)
+	}
+	assert.False(t, IsLegacyCommand("cancel_command"))
+
+	// Copy must not alias the package list
+	caps.Commands[0] = "changed"
+	assert.True(t, LegacyCapabilities().Supports(TypeGetCPUTemp))
+}
+
+func TestLegacyCommands_MatchLegacyAgent(t *testing.T) {
+	// Exactly what the 1.0 agent dispatches, newer commands are never assumed
+	assert.ElementsMatch(t, []MessageType{
+		TypeGetCPUTemp, TypeGetContainers,
+		TypeStartContainer, TypeStopContainer, TypeRestartContainer, TypeRemoveContainer,
+		TypeCreateContainer, TypeGetMemoryInfo, TypeGetDiskInfo, TypeGetUptime,
+		TypeGetProcesses, TypeGetNetworkInfo, TypeUpdateAgent, TypePing,
+	}, LegacyCapabilities().Commands)
+
+	assert.False(t, IsLegacyCommand(TypeGetCPUUsage))
+	assert.False(t, IsLegacyCommand(TypeGetSystemInfo))
+}
+
+func TestCapabilities_Equal(t *testing.T) {
+	a := NewCapabilities(TypePing, TypeGetUptime)
+
+	assert.True(t, a.Equal(NewCapabilities(TypeGetUptime, TypePing)))
+	assert.False(t, a.Equal(NewCapabilities(TypePing)))
+	assert.False(t, a.Equal(Capabilities{ProtocolVersion: "1.0", Commands: a.Commands}))
+}
+
+func TestCapabilities_JSON(t *testing.T) {
+	data, err := json.Marshal(NewCapabilities(TypePing))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"protocol_version":"`+ProtocolVersion+`","commands":["ping"]}`, string(data))
+}
+
+func TestIsCompatible(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"", true},
+		{"1.0", true},
+		{ProtocolVersion, true},
+		{"1.9", true},
+		{"2.0", false},
+		{"0.1", false},
+		{"garbage", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, IsCompatible(tt.version), tt.version)
+	}
+}
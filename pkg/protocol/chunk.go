@@ -0,0 +1,108 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+const (
+	// ChunkedResponsesVersion is the first protocol version whose bots reassemble multi-part responses
+	ChunkedResponsesVersion = "1.2"
+	// DefaultChunkSize is the largest part of a serialized response sent in one stream entry.
+	// It keeps entries well below the agent HTTP request limit.
+	DefaultChunkSize = 256 * 1024
+)
+
+// Chunk errors
+var (
+	ErrChunkOutOfOrder  = errors.New("response chunk out of order")
+	ErrResponseTooLarge = errors.New("response exceeds size limit")
+)
+
+// Chunk is one part of a multi-part response
+type Chunk struct {
+	Seq       int  // position of the part, starting at 0
+	Final     bool // the last part of the response
+	TotalSize int  // size of the whole serialized response in bytes
+	Data      []byte
+}
+
+// SupportsChunkedResponses reports whether the sender of a command with version reassembles chunks
+func SupportsChunkedResponses(version string) bool {
+	return versionAtLeast(version, ChunkedResponsesVersion)
+}
+
+// SplitChunks splits a serialized response into parts of at most size bytes.
+// Parts end on UTF-8 boundaries, so every part is a valid string for JSON transports.
+func SplitChunks(data []byte, size int) []Chunk {
+	if size <= 0 || len(data) <= size {
+		return []Chunk{{Seq: 0, Final: true, TotalSize: len(data), Data: data}}
+	}
+
+	var chunks []Chunk
+	for start := 0; start < len(data); {
+		end := start + size
+		if end >= len(data) {
+			end = len(data)
+		} else {
+			// Don't cut a multi-byte character; tiny sizes may still have to
+			cut := end
+			for cut > start && !utf8.RuneStart(data[cut]) {
+				cut--
+			}
+			if cut > start {
+				end = cut
+			}
+		}
+
+		chunks = append(chunks, Chunk{
+			Seq:       len(chunks),
+			Final:     end == len(data),
+			TotalSize: len(data),
+			Data:      data[start:end],
+		})
+		start = end
+	}
+	return chunks
+}
+
+// ChunkAssembler reassembles one response from its parts
+type ChunkAssembler struct {
+	maxSize int
+	data    []byte
+	next    int
+}
+
+// NewChunkAssembler creates an assembler rejecting responses larger than maxSize bytes (no limit if zero)
+func NewChunkAssembler(maxSize int) *ChunkAssembler {
+	return &ChunkAssembler{maxSize: maxSize}
+}
+
+// Add appends the next part. It returns the whole response once the final part arrived.
+// A part with Seq 0 starts over: the agent resends the whole response after a failed delivery.
+func (a *ChunkAssembler) Add(chunk Chunk) ([]byte, bool, error) {
+	if chunk.Seq == 0 {
+		a.data, a.next = nil, 0
+	}
+	if chunk.Seq != a.next {
+		return nil, false, fmt.Errorf("%w: got %d, want %d", ErrChunkOutOfOrder, chunk.Seq, a.next)
+	}
+	if a.maxSize > 0 && (chunk.TotalSize > a.maxSize || len(a.data)+len(chunk.Data) > a.maxSize) {
+		return nil, false, fmt.Errorf("%w: %d bytes, limit %d", ErrResponseTooLarge, chunk.TotalSize, a.maxSize)
+	}
+
+	if a.data == nil && chunk.TotalSize > 0 {
+		a.data = make([]byte, 0, chunk.TotalSize)
+	}
+	a.data = append(a.data, chunk.Data...)
+	a.next++
+
+	if !chunk.Final {
+		return nil, false, nil
+	}
+	if len(a.data) != chunk.TotalSize {
+		return nil, false, fmt.Errorf("response size %d doesn't match announced %d", len(a.data), chunk.TotalSize)
+	}
+	return a.data, true, nil
+}
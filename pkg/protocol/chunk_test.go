@@ -0,0 +1,77 @@
+package protocol
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitChunks_RoundTrip(t *testing.T) {
+	data := []byte(strings.Repeat("процесс nginx ", 1000))
+
+	chunks := SplitChunks(data, 1001)
+	require.Greater(t, len(chunks), 1)
+
+	assembler := NewChunkAssembler(0)
+	for i, chunk := range chunks {
+		assert.Equal(t, i, chunk.Seq)
+		assert.Equal(t, len(data), chunk.TotalSize)
+		assert.LessOrEqual(t, len(chunk.Data), 1001)
+		assert.True(t, utf8.Valid(chunk.Data), "chunk %d cuts a character", i)
+
+		result, done, err := assembler.Add(chunk)
+		require.NoError(t, err)
+		assert.Equal(t, i == len(chunks)-1, done)
+		if done {
+			assert.True(t, bytes.Equal(data, result))
+		}
+	}
+}
+
+func TestSplitChunks_Small(t *testing.T) {
+	chunks := SplitChunks([]byte(`{"id":"1"}`), DefaultChunkSize)
+
+	require.Len(t, chunks, 1)
+	assert.True(t, chunks[0].Final)
+	assert.Equal(t, 10, chunks[0].TotalSize)
+}
+
+func TestChunkAssembler_Errors(t *testing.T) {
+	chunks := SplitChunks([]byte(strings.Repeat("x", 100)), 30)
+
+	assembler := NewChunkAssembler(0)
+	_, _, err := assembler.Add(chunks[1])
+	assert.ErrorIs(t, err, ErrChunkOutOfOrder)
+
+	_, _, err = NewChunkAssembler(50).Add(chunks[0])
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+}
+
+func TestChunkAssembler_Restart(t *testing.T) {
+	data := []byte(strings.Repeat("y", 100))
+	chunks := SplitChunks(data, 30)
+
+	// Agent failed after two parts and resent the whole response
+	assembler := NewChunkAssembler(0)
+	for _, chunk := range append(chunks[:2:2], chunks...) {
+		result, done, err := assembler.Add(chunk)
+		require.NoError(t, err)
+		if done {
+			assert.Equal(t, data, result)
+			return
+		}
+	}
+	t.Fatal("response was not completed")
+}
+
+func TestSupportsChunkedResponses(t *testing.T) {
+	assert.True(t, SupportsChunkedResponses(ProtocolVersion))
+	assert.True(t, SupportsChunkedResponses("1.10"))
+	assert.True(t, SupportsChunkedResponses("2.0"))
+	assert.False(t, SupportsChunkedResponses("1.1"))
+	assert.False(t, SupportsChunkedResponses(""))
+}
@@ -13,6 +13,7 @@ type MessageType string
 const (
 	// Commands from bot to agent
 	TypeGetCPUTemp       MessageType = "get_cpu_temp"
+	TypeGetCPUUsage      MessageType = "get_cpu_usage"
 	TypeGetSystemInfo    MessageType = "get_system_info"
 	TypeGetContainers    MessageType = "get_containers"
 	TypeStartContainer   MessageType = "start_container"
@@ -27,9 +28,11 @@ const (
 	TypeGetNetworkInfo   MessageType = "get_network_info"
 	TypeUpdateAgent      MessageType = "update_agent"
 	TypePing             MessageType = "ping"
+	TypeCancelCommand    MessageType = "cancel_command"
 
 	// Responses from agent to bot
 	TypeCPUTempResponse         MessageType = "cpu_temp_response"
+	TypeCPUUsageResponse        MessageType = "cpu_usage_response"
 	TypeSystemInfoResponse      MessageType = "system_info_response"
 	TypeContainersResponse      MessageType = "containers_response"
 	TypeContainerActionResponse MessageType = "container_action_response"
@@ -40,27 +43,56 @@ const (
 	TypeNetworkInfoResponse     MessageType = "network_info_response"
 	TypeUpdateAgentResponse     MessageType = "update_agent_response"
 	TypePong                    MessageType = "pong"
+	TypeCancelCommandResponse   MessageType = "cancel_command_response"
 	TypeErrorResponse           MessageType = "error_response"
 )
 
 // Message represents a base protocol message
 type Message struct {
-	ID        string      `json:"id"`
-	Type      MessageType `json:"type"`
-	Timestamp time.Time   `json:"timestamp"`
-	Version   string      `json:"version"`
-	Payload   interface{} `json:"payload"`
+	ID        string          `json:"id"`
+	Type      MessageType     `json:"type"`
+	Timestamp time.Time       `json:"timestamp"`
+	Version   string          `json:"version"`
+	Payload   json.RawMessage `json:"payload"` // decoded with Decode/DecodeAs by the payload registry
+	// ExpiresAt - крайний срок выполнения команды, после него агент её не выполняет
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Signature - HMAC подпись команды ключом подписи сервера (auth.SignMessage)
+	Signature string `json:"signature,omitempty"`
+	// Encryption - алгоритм шифрования Payload (e2e.Algorithm), пусто для открытого payload
+	Encryption string `json:"encryption,omitempty"`
 }
 
-// NewMessage creates a new message
+// NewMessage creates a new message.
+// payload must be JSON-serializable, protocol payload types always are.
 func NewMessage(msgType MessageType, payload interface{}) *Message {
-	return &Message{
+	msg := &Message{
 		ID:        uuid.New().String(),
 		Type:      msgType,
 		Timestamp: time.Now(),
-		Version:   "1.0",
-		Payload:   payload,
+		Version:   ProtocolVersion,
 	}
+	_ = msg.SetPayload(payload)
+	return msg
+}
+
+// SetTTL sets the command deadline relative to the message timestamp
+func (m *Message) SetTTL(ttl time.Duration) {
+	expiresAt := m.Timestamp.Add(ttl)
+	m.ExpiresAt = &expiresAt
+}
+
+// Deadline returns the command deadline if one is set
+func (m *Message) Deadline() (time.Time, bool) {
+	if m.ExpiresAt == nil || m.ExpiresAt.IsZero() {
+		return time.Time{}, false
+	}
+	return *m.ExpiresAt, true
+}
+
+// IsExpired reports whether the command deadline has passed
+func (m *Message) IsExpired(now time.Time) bool {
+	deadline, ok := m.Deadline()
+	return ok && !now.Before(deadline)
 }
 
 // ToJSON serializes message to JSON
@@ -82,12 +114,39 @@ type CPUTempPayload struct {
 	Sensor      string  `json:"sensor"`
 }
 
+// CPUCoreUsage represents utilization of a single core (or all cores) in percent
+type CPUCoreUsage struct {
+	Core   string  `json:"core"`   // "cpu" for aggregate, "cpu0".."cpuN" for cores
+	Usage  float64 `json:"usage"`  // Busy time (everything except idle and iowait)
+	User   float64 `json:"user"`   // User + nice
+	System float64 `json:"system"` // System + irq + softirq
+	IOWait float64 `json:"iowait"`
+	Steal  float64 `json:"steal"`
+	Idle   float64 `json:"idle"`
+}
+
+// CPUUsagePayload represents CPU utilization data
+type CPUUsagePayload struct {
+	Total    CPUCoreUsage   `json:"total"`
+	Cores    []CPUCoreUsage `json:"cores"`
+	Interval float64        `json:"interval_seconds"` // Sampling window
+}
+
 // SystemInfoPayload represents system information data
 type SystemInfoPayload struct {
-	Hostname string `json:"hostname"`
-	OS       string `json:"os"`
-	Arch     string `json:"arch"`
-	Uptime   string `json:"uptime"`
+	Hostname       string `json:"hostname"`
+	OS             string `json:"os"`
+	Arch           string `json:"arch"`
+	Uptime         string `json:"uptime"`
+	Kernel         string `json:"kernel"`
+	Distro         string `json:"distro"`
+	DistroVersion  string `json:"distro_version"`
+	CPUModel       string `json:"cpu_model"`
+	CPUCount       int    `json:"cpu_count"`
+	TotalMemory    uint64 `json:"total_memory"`
+	Virtualization string `json:"virtualization"`
+	AgentVersion   string `json:"agent_version"`
+	BootTime       uint64 `json:"boot_time"`
 }
 
 // ErrorPayload represents error information
@@ -233,6 +292,17 @@ type UpdateAgentResponse struct {
 	RestartRequired bool   `json:"restart_required"`
 }
 
+// CancelCommandPayload asks the agent to stop a running command
+type CancelCommandPayload struct {
+	CommandID string `json:"command_id"`
+}
+
+// CancelCommandResponse represents cancellation result
+type CancelCommandResponse struct {
+	CommandID string `json:"command_id"`
+	Cancelled bool   `json:"cancelled"` // false if the command already finished or never ran
+}
+
 // Error codes
 const (
 	ErrorSensorNotFound    = "SENSOR_NOT_FOUND"
@@ -242,4 +312,15 @@ const (
 	ErrorContainerNotFound = "CONTAINER_NOT_FOUND"
 	ErrorContainerAction   = "CONTAINER_ACTION_FAILED"
 	ErrorDockerUnavailable = "DOCKER_UNAVAILABLE"
+	ErrorCommandExpired    = "COMMAND_EXPIRED"
+	// Агент не знает команду или версию протокола: нужно обновить агент
+	ErrorUnsupportedCommand = "UNSUPPORTED_COMMAND"
+	// Подпись команды отсутствует, неверна, устарела или ID уже использован
+	ErrorSignatureInvalid = "SIGNATURE_INVALID"
+	// Зашифрованный payload не удалось расшифровать
+	ErrorDecryptionFailed = "DECRYPTION_FAILED"
+	// Команда остановлена по cancel_command
+	ErrorCommandCancelled = "COMMAND_CANCELLED"
+	// Агент перезапустился во время выполнения команды, результат неизвестен
+	ErrorCommandInterrupted = "COMMAND_INTERRUPTED"
 )
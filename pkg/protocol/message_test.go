@@ -18,8 +18,8 @@ func TestNewMessage(t *testing.T) {
 
 	assert.NotEmpty(t, msg.ID)
 	assert.Equal(t, TypeGetCPUTemp, msg.Type)
-	assert.Equal(t, "1.0", msg.Version)
-	assert.Equal(t, payload, msg.Payload)
+	assert.Equal(t, ProtocolVersion, msg.Version)
+	assert.JSONEq(t, `{"test":"data"}`, string(msg.Payload))
 	assert.WithinDuration(t, time.Now(), msg.Timestamp, time.Second)
 }
 
@@ -42,7 +42,7 @@ func TestMessage_ToJSON(t *testing.T) {
 	require.NoError(t, err)
 
 	assert.Equal(t, string(TypeCPUTempResponse), result["type"])
-	assert.Equal(t, "1.0", result["version"])
+	assert.Equal(t, ProtocolVersion, result["version"])
 	assert.NotEmpty(t, result["id"])
 	assert.NotEmpty(t, result["timestamp"])
 	assert.NotNil(t, result["payload"])
@@ -178,6 +178,7 @@ func TestErrorCodes(t *testing.T) {
 		ErrorPermissionDenied,
 		ErrorCommandTimeout,
 		ErrorInvalidCommand,
+		ErrorCommandExpired,
 	}
 
 	for _, code := range errorCodes {
@@ -252,13 +253,43 @@ func TestMessage_JSONRoundTrip(t *testing.T) {
 			assert.Equal(t, original.Version, parsed.Version)
 			assert.WithinDuration(t, original.Timestamp, parsed.Timestamp, time.Second)
 
-			// Verify payload (this is a basic check since payload is interface{})
+			// Payload survives the round trip byte for byte
 			if tc.payload != nil {
-				assert.NotNil(t, parsed.Payload)
+				assert.JSONEq(t, string(original.Payload), string(parsed.Payload))
 			} else {
-				// Payload might be nil or an empty map depending on JSON unmarshaling
-				// This is acceptable for commands without payload
+				assert.True(t, isNullPayload(parsed.Payload))
 			}
 		})
 	}
 }
+
+func TestMessage_Expiry(t *testing.T) {
+	t.Run("no deadline by default", func(t *testing.T) {
+		msg := NewMessage(TypePing, nil)
+
+		_, ok := msg.Deadline()
+		assert.False(t, ok)
+		assert.False(t, msg.IsExpired(time.Now().Add(time.Hour)))
+
+		data, err := msg.ToJSON()
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "expires_at")
+	})
+
+	t.Run("ttl survives round trip", func(t *testing.T) {
+		msg := NewMessage(TypePing, nil)
+		msg.SetTTL(10 * time.Second)
+
+		data, err := msg.ToJSON()
+		require.NoError(t, err)
+
+		parsed, err := FromJSON(data)
+		require.NoError(t, err)
+
+		deadline, ok := parsed.Deadline()
+		require.True(t, ok)
+		assert.WithinDuration(t, msg.Timestamp.Add(10*time.Second), deadline, time.Millisecond)
+		assert.False(t, parsed.IsExpired(msg.Timestamp))
+		assert.True(t, parsed.IsExpired(msg.Timestamp.Add(10*time.Second)))
+	})
+}
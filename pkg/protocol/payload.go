@@ -0,0 +1,190 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	// ErrInvalidPayload is returned when a payload doesn't match its message type
+	ErrInvalidPayload = errors.New("invalid payload")
+	// ErrPayloadEncrypted is returned when decoding a payload that wasn't decrypted first
+	ErrPayloadEncrypted = errors.New("payload is encrypted")
+)
+
+// payloadSpec describes the Go type and required JSON fields of a message payload
+type payloadSpec struct {
+	typ      reflect.Type // nil for messages without payload
+	required []string
+}
+
+var (
+	registryMu      sync.RWMutex
+	payloadRegistry = map[MessageType]payloadSpec{}
+)
+
+func init() {
+	// Commands without payload
+	for _, t := range []MessageType{
+		TypeGetCPUTemp, TypeGetCPUUsage, TypeGetSystemInfo, TypeGetContainers,
+		TypeGetMemoryInfo, TypeGetDiskInfo, TypeGetUptime, TypeGetProcesses,
+		TypeGetNetworkInfo, TypePing,
+	} {
+		RegisterPayload(t, nil)
+	}
+
+	RegisterPayload(TypeStartContainer, ContainerActionPayload{}, "container_id")
+	RegisterPayload(TypeStopContainer, ContainerActionPayload{}, "container_id")
+	RegisterPayload(TypeRestartContainer, ContainerActionPayload{}, "container_id")
+	RegisterPayload(TypeRemoveContainer, ContainerActionPayload{}, "container_id")
+	RegisterPayload(TypeCreateContainer, CreateContainerPayload{}, "image")
+	RegisterPayload(TypeUpdateAgent, UpdateAgentPayload{})
+	RegisterPayload(TypeCancelCommand, CancelCommandPayload{}, "command_id")
+
+	RegisterPayload(TypeCPUTempResponse, CPUTempPayload{}, "temperature")
+	RegisterPayload(TypeCPUUsageResponse, CPUUsagePayload{}, "total")
+	RegisterPayload(TypeSystemInfoResponse, SystemInfoPayload{}, "hostname")
+	RegisterPayload(TypeContainersResponse, ContainersPayload{}, "containers")
+	RegisterPayload(TypeContainerActionResponse, ContainerActionResponse{}, "action", "success")
+	RegisterPayload(TypeMemoryInfoResponse, MemoryInfo{}, "total")
+	RegisterPayload(TypeDiskInfoResponse, DiskInfoPayload{}, "disks")
+	RegisterPayload(TypeUptimeResponse, UptimeInfo{}, "uptime")
+	RegisterPayload(TypeProcessesResponse, ProcessesPayload{}, "processes")
+	RegisterPayload(TypeNetworkInfoResponse, NetworkInfo{}, "interfaces")
+	RegisterPayload(TypeUpdateAgentResponse, UpdateAgentResponse{}, "success")
+	RegisterPayload(TypePong, PongPayload{}, "status")
+	RegisterPayload(TypeCancelCommandResponse, CancelCommandResponse{}, "command_id", "cancelled")
+	RegisterPayload(TypeErrorResponse, ErrorPayload{}, "error_code")
+}
+
+// RegisterPayload maps msgType to the Go type of sample (nil for no payload).
+// required lists JSON fields that must be present in the payload object.
+func RegisterPayload(msgType MessageType, sample interface{}, required ...string) {
+	spec := payloadSpec{required: required}
+	if sample != nil {
+		spec.typ = reflect.TypeOf(sample)
+		if spec.typ.Kind() == reflect.Pointer {
+			spec.typ = spec.typ.Elem()
+		}
+	}
+
+	registryMu.Lock()
+	payloadRegistry[msgType] = spec
+	registryMu.Unlock()
+}
+
+// lookupPayload returns the registered payload spec for msgType
+func lookupPayload(msgType MessageType) (payloadSpec, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	spec, ok := payloadRegistry[msgType]
+	return spec, ok
+}
+
+// NewPayload returns a pointer to a zero value of the payload type registered for msgType.
+// Returns false for unknown types and types without payload.
+func NewPayload(msgType MessageType) (interface{}, bool) {
+	spec, ok := lookupPayload(msgType)
+	if !ok || spec.typ == nil {
+		return nil, false
+	}
+	return reflect.New(spec.typ).Interface(), true
+}
+
+// SetPayload serializes payload into the message
+func (m *Message) SetPayload(payload interface{}) error {
+	if payload == nil {
+		m.Payload = nil
+		return nil
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to serialize %s payload: %w", m.Type, err)
+	}
+	m.Payload = data
+	return nil
+}
+
+// Validate checks that the payload has all fields required for the message type
+func (m *Message) Validate() error {
+	if m.Encryption != "" {
+		return fmt.Errorf("%w: %s", ErrPayloadEncrypted, m.Type)
+	}
+
+	spec, ok := lookupPayload(m.Type)
+	if !ok || len(spec.required) == 0 {
+		return nil
+	}
+
+	if isNullPayload(m.Payload) {
+		return fmt.Errorf("%w: %s: missing payload", ErrInvalidPayload, m.Type)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(m.Payload, &fields); err != nil {
+		return fmt.Errorf("%w: %s: payload is not an object", ErrInvalidPayload, m.Type)
+	}
+
+	for _, name := range spec.required {
+		if _, ok := fields[name]; !ok {
+			return fmt.Errorf("%w: %s: missing required field %q", ErrInvalidPayload, m.Type, name)
+		}
+	}
+
+	return nil
+}
+
+// Decode validates the payload and unmarshals it into target.
+// target must be a pointer to the type registered for the message type.
+func (m *Message) Decode(target interface{}) error {
+	if spec, ok := lookupPayload(m.Type); ok && spec.typ != nil {
+		if t := reflect.TypeOf(target); t == nil || t.Kind() != reflect.Pointer || t.Elem() != spec.typ {
+			return fmt.Errorf("%w: %s payload is %s, cannot decode into %T", ErrInvalidPayload, m.Type, spec.typ, target)
+		}
+	}
+
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	if isNullPayload(m.Payload) {
+		return nil
+	}
+
+	if err := json.Unmarshal(m.Payload, target); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidPayload, m.Type, err)
+	}
+	return nil
+}
+
+// DecodePayload decodes the payload into a new value of the registered type
+func (m *Message) DecodePayload() (interface{}, error) {
+	target, ok := NewPayload(m.Type)
+	if !ok {
+		return nil, fmt.Errorf("%w: no payload type registered for %s", ErrInvalidPayload, m.Type)
+	}
+	if err := m.Decode(target); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// DecodeAs decodes the payload of m into a new T
+func DecodeAs[T any](m *Message) (*T, error) {
+	var target T
+	if err := m.Decode(&target); err != nil {
+		return nil, err
+	}
+	return &target, nil
+}
+
+// isNullPayload reports whether raw JSON is absent or null
+func isNullPayload(data json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null"))
+}
@@ -0,0 +1,98 @@
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeAs(t *testing.T) {
+	original := NewMessage(TypeContainerActionResponse, ContainerActionResponse{
+		ContainerID: "abc123",
+		Action:      "restart",
+		Success:     true,
+		NewState:    "running",
+	})
+
+	data, err := original.ToJSON()
+	require.NoError(t, err)
+	parsed, err := FromJSON(data)
+	require.NoError(t, err)
+
+	resp, err := DecodeAs[ContainerActionResponse](parsed)
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", resp.ContainerID)
+	assert.True(t, resp.Success)
+	assert.Equal(t, "running", resp.NewState)
+}
+
+func TestDecodePayload_Registry(t *testing.T) {
+	msg := NewMessage(TypeCPUTempResponse, CPUTempPayload{Temperature: 42.5, Unit: "celsius"})
+
+	payload, err := msg.DecodePayload()
+	require.NoError(t, err)
+
+	temp, ok := payload.(*CPUTempPayload)
+	require.True(t, ok, "payload type %T", payload)
+	assert.Equal(t, 42.5, temp.Temperature)
+
+	_, err = NewMessage(MessageType("unknown"), nil).DecodePayload()
+	assert.ErrorIs(t, err, ErrInvalidPayload)
+}
+
+func TestDecode_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		msgType MessageType
+		payload string
+		wantErr string
+	}{
+		{"missing payload", TypeUpdateAgentResponse, "", "missing payload"},
+		{"null payload", TypeUpdateAgentResponse, "null", "missing payload"},
+		{"not an object", TypeUpdateAgentResponse, `"ok"`, "not an object"},
+		{"missing required field", TypeUpdateAgentResponse, `{"message":"done"}`, `missing required field "success"`},
+		{"wrong field type", TypeUpdateAgentResponse, `{"success":"yes"}`, "cannot unmarshal"},
+		{"valid", TypeUpdateAgentResponse, `{"success":true}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &Message{Type: tt.msgType, Payload: json.RawMessage(tt.payload)}
+
+			_, err := DecodeAs[UpdateAgentResponse](msg)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, ErrInvalidPayload))
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestDecode_WrongTargetType(t *testing.T) {
+	msg := NewMessage(TypeContainersResponse, ContainersPayload{Total: 0})
+
+	_, err := DecodeAs[ContainerActionResponse](msg)
+	assert.ErrorIs(t, err, ErrInvalidPayload)
+}
+
+func TestDecode_CommandWithoutPayload(t *testing.T) {
+	var target struct{}
+	assert.NoError(t, NewMessage(TypeGetContainers, nil).Decode(&target))
+}
+
+func TestDecode_CancelCommand(t *testing.T) {
+	msg := NewMessage(TypeCancelCommand, CancelCommandPayload{CommandID: "create-001"})
+	payload, err := DecodeAs[CancelCommandPayload](msg)
+	require.NoError(t, err)
+	assert.Equal(t, "create-001", payload.CommandID)
+
+	var target CancelCommandPayload
+	err = NewMessage(TypeCancelCommand, map[string]string{}).Decode(&target)
+	assert.ErrorIs(t, err, ErrInvalidPayload)
+}
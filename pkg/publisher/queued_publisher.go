@@ -0,0 +1,198 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultQueueLimit сколько метрик может ждать отправки в памяти
+	DefaultQueueLimit = 10000
+	// maxBatchSize сколько метрик уходит в одном PublishBatch
+	maxBatchSize = 500
+	// flushTimeout ограничивает одну отправку пакета
+	flushTimeout = 30 * time.Second
+	// closeTimeout ограничивает отправку остатка очереди в Close
+	closeTimeout = 10 * time.Second
+)
+
+// ErrPublisherClosed возвращается при отправке в закрытый publisher
+var ErrPublisherClosed = errors.New("publisher closed")
+
+// QueuedPublisher копит метрики в ограниченной очереди и отправляет их пакетами
+// через PublishBatch в фоне, чтобы сбор метрик не ждал брокер.
+// При переполнении отбрасываются самые старые метрики.
+type QueuedPublisher struct {
+	publisher    Publisher
+	logger       *logrus.Logger
+	limit        int
+	closeTimeout time.Duration
+
+	ctx    context.Context // отменяется, когда истекает время на Close
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	pending []*Metric
+	closed  bool
+	dropped int64
+
+	wake chan struct{} // сигнал фоновой отправке, закрывается в Close
+	done chan struct{} // закрывается, когда очередь отправлена после Close
+}
+
+// NewQueuedPublisher создает очередь перед publisher и запускает фоновую отправку.
+// limit <= 0 означает DefaultQueueLimit.
+func NewQueuedPublisher(pub Publisher, limit int, logger *logrus.Logger) *QueuedPublisher {
+	if logger == nil {
+		logger = logrus.New()
+	}
+	if limit <= 0 {
+		limit = DefaultQueueLimit
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &QueuedPublisher{
+		publisher:    pub,
+		logger:       logger,
+		limit:        limit,
+		closeTimeout: closeTimeout,
+		ctx:          ctx,
+		cancel:       cancel,
+		wake:         make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// Publish ставит метрику в очередь, не дожидаясь отправки
+func (q *QueuedPublisher) Publish(ctx context.Context, metric *Metric) error {
+	return q.PublishBatch(ctx, []*Metric{metric})
+}
+
+// PublishBatch ставит пакет метрик в очередь, не дожидаясь отправки
+func (q *QueuedPublisher) PublishBatch(ctx context.Context, metrics []*Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrPublisherClosed
+	}
+
+	q.pending = append(q.pending, metrics...)
+	if overflow := len(q.pending) - q.limit; overflow > 0 {
+		// Брокер не успевает: теряем самые старые метрики, а не блокируем сбор
+		q.pending = q.pending[overflow:]
+		q.dropped += int64(overflow)
+		q.logger.WithFields(logrus.Fields{
+			"dropped":       overflow,
+			"dropped_total": q.dropped,
+			"limit":         q.limit,
+		}).Warn("Metric queue is full, oldest metrics dropped")
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Close отправляет оставшиеся метрики не дольше closeTimeout и закрывает publisher.
+// Метрики, которые не успели или не смогли уйти, отбрасываются.
+func (q *QueuedPublisher) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	close(q.wake)
+	q.mu.Unlock()
+
+	timer := time.NewTimer(q.closeTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-q.done:
+	case <-timer.C:
+		// Брокер не отвечает: прерываем отправку, чтобы не задерживать остановку агента
+		q.cancel()
+		<-q.done
+	}
+	q.cancel()
+
+	if dropped := q.Pending(); dropped > 0 {
+		q.logger.WithFields(logrus.Fields{
+			"publisher": q.publisher.Name(),
+			"dropped":   dropped,
+		}).Warn("Metric queue closed, unsent metrics dropped")
+	}
+	return q.publisher.Close()
+}
+
+// Name возвращает имя publisher
+func (q *QueuedPublisher) Name() string {
+	return fmt.Sprintf("queued[%s]", q.publisher.Name())
+}
+
+// Pending возвращает число метрик, ожидающих отправки
+func (q *QueuedPublisher) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// run отправляет очередь при каждом сигнале и один раз после Close
+func (q *QueuedPublisher) run() {
+	defer close(q.done)
+
+	for range q.wake {
+		q.flush()
+	}
+	q.flush()
+}
+
+// flush отправляет накопившиеся метрики пакетами до maxBatchSize.
+// После первой ошибки остаток ждет следующего сигнала, чтобы не перебирать
+// всю очередь по flushTimeout на каждый пакет, пока брокер недоступен.
+func (q *QueuedPublisher) flush() {
+	for q.ctx.Err() == nil {
+		batch := q.take()
+		if len(batch) == 0 {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(q.ctx, flushTimeout)
+		err := q.publisher.PublishBatch(ctx, batch)
+		cancel()
+
+		if err != nil {
+			q.logger.WithError(err).WithFields(logrus.Fields{
+				"publisher": q.publisher.Name(),
+				"count":     len(batch),
+			}).Error("Failed to publish queued metrics")
+			return
+		}
+	}
+}
+
+// take забирает из очереди следующий пакет
+func (q *QueuedPublisher) take() []*Metric {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := min(len(q.pending), maxBatchSize)
+	batch := q.pending[:n:n]
+	q.pending = q.pending[n:]
+	return batch
+}
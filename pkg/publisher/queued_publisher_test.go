@@ -0,0 +1,200 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// blockingPublisher отправляет пакеты, только когда release открыт
+type blockingPublisher struct {
+	release chan struct{}
+
+	mu      sync.Mutex
+	batches [][]*Metric
+	closed  bool
+}
+
+func (p *blockingPublisher) Publish(ctx context.Context, metric *Metric) error {
+	return p.PublishBatch(ctx, []*Metric{metric})
+}
+
+func (p *blockingPublisher) PublishBatch(ctx context.Context, metrics []*Metric) error {
+	<-p.release
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.batches = append(p.batches, metrics)
+	return nil
+}
+
+func (p *blockingPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
+
+func (p *blockingPublisher) Name() string { return "blocking" }
+
+func (p *blockingPublisher) published() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var types []string
+	for _, batch := range p.batches {
+		for _, m := range batch {
+			types = append(types, m.Type)
+		}
+	}
+	return types
+}
+
+func quietLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	return logger
+}
+
+func metricsOf(types ...string) []*Metric {
+	result := make([]*Metric, 0, len(types))
+	for _, t := range types {
+		result = append(result, &Metric{Type: t})
+	}
+	return result
+}
+
+func TestQueuedPublisher_DoesNotBlockOnBroker(t *testing.T) {
+	inner := &blockingPublisher{release: make(chan struct{})}
+	queue := NewQueuedPublisher(inner, 100, quietLogger())
+
+	done := make(chan error, 1)
+	go func() { done <- queue.PublishBatch(context.Background(), metricsOf("cpu_usage", "memory_usage")) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("PublishBatch() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("PublishBatch blocked on a slow broker")
+	}
+
+	close(inner.release)
+	if err := queue.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := inner.published(); len(got) != 2 {
+		t.Errorf("Published %v, want both metrics", got)
+	}
+	if !inner.closed {
+		t.Error("Close must close the wrapped publisher")
+	}
+}
+
+func TestQueuedPublisher_DropsOldestWhenFull(t *testing.T) {
+	inner := &blockingPublisher{release: make(chan struct{})}
+	queue := NewQueuedPublisher(inner, 2, quietLogger())
+
+	// The flusher takes the first batch and blocks on the broker
+	queue.Publish(context.Background(), &Metric{Type: "first"})
+	deadline := time.Now().Add(time.Second)
+	for queue.Pending() != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	queue.PublishBatch(context.Background(), metricsOf("a", "b", "c"))
+	if n := queue.Pending(); n != 2 {
+		t.Fatalf("Pending() = %d, want limit 2", n)
+	}
+
+	close(inner.release)
+	queue.Close()
+
+	got := inner.published()
+	want := []string{"first", "b", "c"}
+	if len(got) != len(want) {
+		t.Fatalf("Published %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Published %v, want %v", got, want)
+		}
+	}
+}
+
+func TestQueuedPublisher_Closed(t *testing.T) {
+	inner := &blockingPublisher{release: make(chan struct{})}
+	close(inner.release)
+	queue := NewQueuedPublisher(inner, 0, quietLogger())
+	queue.Close()
+
+	if err := queue.Publish(context.Background(), &Metric{Type: "late"}); !errors.Is(err, ErrPublisherClosed) {
+		t.Errorf("Expected ErrPublisherClosed, got %v", err)
+	}
+	if err := queue.Close(); err != nil {
+		t.Errorf("Second Close() error = %v", err)
+	}
+}
+
+// unavailablePublisher имитирует недоступный брокер: отправка ждет отмены ctx
+type unavailablePublisher struct {
+	mu     sync.Mutex
+	calls  int
+	closed bool
+}
+
+func (p *unavailablePublisher) Publish(ctx context.Context, metric *Metric) error {
+	return p.PublishBatch(ctx, []*Metric{metric})
+}
+
+func (p *unavailablePublisher) PublishBatch(ctx context.Context, metrics []*Metric) error {
+	p.mu.Lock()
+	p.calls++
+	p.mu.Unlock()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (p *unavailablePublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
+
+func (p *unavailablePublisher) Name() string { return "unavailable" }
+
+func TestQueuedPublisher_CloseDeadline(t *testing.T) {
+	inner := &unavailablePublisher{}
+	queue := NewQueuedPublisher(inner, 0, quietLogger())
+	queue.closeTimeout = 50 * time.Millisecond
+
+	metrics := make([]*Metric, 0, 4*maxBatchSize)
+	for i := 0; i < 4*maxBatchSize; i++ {
+		metrics = append(metrics, &Metric{Type: "cpu_usage"})
+	}
+	queue.PublishBatch(context.Background(), metrics)
+
+	start := time.Now()
+	if err := queue.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Close took %v with an unavailable broker", elapsed)
+	}
+
+	inner.mu.Lock()
+	defer inner.mu.Unlock()
+	if inner.calls != 1 {
+		t.Errorf("Expected sending to stop after the first failed batch, got %d attempts", inner.calls)
+	}
+	if !inner.closed {
+		t.Error("Close must close the wrapped publisher")
+	}
+	if n := queue.Pending(); n != 3*maxBatchSize {
+		t.Errorf("Pending() = %d, want %d unsent metrics", n, 3*maxBatchSize)
+	}
+}
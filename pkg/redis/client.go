@@ -99,6 +99,26 @@ func (c *Client) Subscribe(ctx context.Context, channel string) (*Subscription,
 
 	c.logger.WithField("channel", channel).Info("Подписались на канал Redis")
 
+	return c.newSubscription(ctx, pubsub), nil
+}
+
+// PSubscribe подписывается на каналы по шаблону (например heartbeat:*)
+func (c *Client) PSubscribe(ctx context.Context, pattern string) (*Subscription, error) {
+	pubsub := c.rdb.PSubscribe(ctx, pattern)
+
+	// Проверяем подписку
+	_, err := pubsub.Receive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подписки на шаблон %s: %v", pattern, err)
+	}
+
+	c.logger.WithField("pattern", pattern).Info("Подписались на шаблон каналов Redis")
+
+	return c.newSubscription(ctx, pubsub), nil
+}
+
+// newSubscription читает сообщения pubsub в канал подписки до отмены ctx
+func (c *Client) newSubscription(ctx context.Context, pubsub *redis.PubSub) *Subscription {
 	// Создаем объект подписки
 	subscription := &Subscription{
 		pubsub:  pubsub,
@@ -142,7 +162,7 @@ func (c *Client) Subscribe(ctx context.Context, channel string) (*Subscription,
 		}
 	}()
 
-	return subscription, nil
+	return subscription
 }
 
 // Close закрывает соединение с Redis
@@ -9,20 +9,23 @@ import (
 	"sync"
 	"time"
 
+	"github.com/servereye/servereye/pkg/auth"
 	"github.com/sirupsen/logrus"
 )
 
 // HTTPClient implements Redis operations over HTTP
 type HTTPClient struct {
 	baseURL    string
+	secretKey  string
 	httpClient *http.Client
 	logger     *logrus.Logger
 }
 
 // HTTPConfig configuration for HTTP Redis client
 type HTTPConfig struct {
-	BaseURL string
-	Timeout time.Duration
+	BaseURL   string
+	SecretKey string // used to sign requests
+	Timeout   time.Duration
 }
 
 // NewHTTPClient creates a new HTTP Redis client
@@ -32,7 +35,8 @@ func NewHTTPClient(config HTTPConfig, logger *logrus.Logger) (*HTTPClient, error
 	}
 
 	return &HTTPClient{
-		baseURL: config.BaseURL,
+		baseURL:   config.BaseURL,
+		secretKey: config.SecretKey,
 		httpClient: &http.Client{
 			Timeout: config.Timeout,
 		},
@@ -101,6 +105,9 @@ func (c *HTTPClient) Publish(ctx context.Context, channel string, message []byte
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
+	if err := auth.SignRequest(httpReq, c.secretKey, jsonData); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -194,6 +201,9 @@ func (c *HTTPClient) pollForMessage(ctx context.Context, subscription *HTTPSubsc
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
+	if err := auth.SignRequest(httpReq, c.secretKey, jsonData); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
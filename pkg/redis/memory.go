@@ -0,0 +1,131 @@
+package redis
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MemoryClient реализует Pub/Sub в памяти процесса, без Redis.
+// Как и в Redis, сообщения без подписчиков теряются.
+type MemoryClient struct {
+	logger *logrus.Logger
+
+	mu          sync.RWMutex
+	subscribers map[string]map[*MemorySubscription]struct{}
+}
+
+// MemorySubscription подписка на канал MemoryClient
+type MemorySubscription struct {
+	client    *MemoryClient
+	channel   string
+	msgChan   chan []byte
+	mu        sync.Mutex // защищает msgChan от отправки после закрытия
+	closed    bool
+	closeOnce sync.Once
+}
+
+// NewMemoryClient создает Pub/Sub клиент в памяти
+func NewMemoryClient(logger *logrus.Logger) *MemoryClient {
+	return &MemoryClient{
+		logger:      logger,
+		subscribers: make(map[string]map[*MemorySubscription]struct{}),
+	}
+}
+
+// Publish доставляет сообщение всем текущим подписчикам канала
+func (c *MemoryClient) Publish(ctx context.Context, channel string, message []byte) error {
+	c.mu.RLock()
+	subs := make([]*MemorySubscription, 0, len(c.subscribers[channel]))
+	for sub := range c.subscribers[channel] {
+		subs = append(subs, sub)
+	}
+	c.mu.RUnlock()
+
+	for _, sub := range subs {
+		// Копия, чтобы подписчики не делили один буфер
+		payload := append([]byte(nil), message...)
+		if !sub.deliver(payload) {
+			c.logger.WithField("channel", channel).Warn("Буфер подписки переполнен, сообщение отброшено")
+		}
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"channel":     channel,
+		"size":        len(message),
+		"subscribers": len(subs),
+	}).Debug("Сообщение отправлено в memory Pub/Sub")
+
+	return nil
+}
+
+// Subscribe подписывается на канал; подписка закрывается при отмене ctx
+func (c *MemoryClient) Subscribe(ctx context.Context, channel string) (*MemorySubscription, error) {
+	sub := &MemorySubscription{
+		client:  c,
+		channel: channel,
+		msgChan: make(chan []byte, 100),
+	}
+
+	c.mu.Lock()
+	if c.subscribers[channel] == nil {
+		c.subscribers[channel] = make(map[*MemorySubscription]struct{})
+	}
+	c.subscribers[channel][sub] = struct{}{}
+	c.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		sub.Close()
+	}()
+
+	c.logger.WithField("channel", channel).Debug("Подписались на канал memory Pub/Sub")
+
+	return sub, nil
+}
+
+// Close закрывает клиент; подписки закрываются вместе с их контекстом
+func (c *MemoryClient) Close() error {
+	return nil
+}
+
+// deliver кладет сообщение в буфер без блокировки
+func (s *MemorySubscription) deliver(message []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return true
+	}
+
+	select {
+	case s.msgChan <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// Channel returns the message channel
+func (s *MemorySubscription) Channel() <-chan []byte {
+	return s.msgChan
+}
+
+// Close отписывается от канала и закрывает канал сообщений
+func (s *MemorySubscription) Close() error {
+	s.closeOnce.Do(func() {
+		s.client.mu.Lock()
+		delete(s.client.subscribers[s.channel], s)
+		if len(s.client.subscribers[s.channel]) == 0 {
+			delete(s.client.subscribers, s.channel)
+		}
+		s.client.mu.Unlock()
+
+		s.mu.Lock()
+		s.closed = true
+		close(s.msgChan)
+		s.mu.Unlock()
+	})
+	return nil
+}
@@ -0,0 +1,64 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMemoryClient_PublishSubscribe(t *testing.T) {
+	client := NewMemoryClient(logrus.New())
+	ctx := context.Background()
+
+	first, _ := client.Subscribe(ctx, "cmd:srv_1")
+	second, _ := client.Subscribe(ctx, "cmd:srv_1")
+	other, _ := client.Subscribe(ctx, "cmd:srv_2")
+
+	if err := client.Publish(ctx, "cmd:srv_1", []byte("hello")); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	for _, sub := range []*MemorySubscription{first, second} {
+		select {
+		case msg := <-sub.Channel():
+			if string(msg) != "hello" {
+				t.Errorf("Got %q, want hello", msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Subscriber did not receive message")
+		}
+	}
+
+	select {
+	case msg := <-other.Channel():
+		t.Errorf("Unexpected message on other channel: %q", msg)
+	default:
+	}
+}
+
+func TestMemoryClient_ContextClosesSubscription(t *testing.T) {
+	client := NewMemoryClient(logrus.New())
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sub, _ := client.Subscribe(ctx, "resp:srv_1")
+	cancel()
+
+	select {
+	case _, ok := <-sub.Channel():
+		if ok {
+			t.Fatal("Expected closed channel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Subscription was not closed")
+	}
+
+	// Публикация после отписки не должна паниковать
+	if err := client.Publish(context.Background(), "resp:srv_1", []byte("late")); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if err := sub.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+}
@@ -3,6 +3,8 @@ package streams
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/servereye/servereye/pkg/protocol"
@@ -19,16 +21,44 @@ type AgentAdapter struct {
 	serverKey     string
 	consumerGroup string
 	consumerName  string
+
+	startID           string
+	maxRetries        int
+	visibilityTimeout time.Duration
+
+	// inFlight holds IDs of queued and running messages, recovery must not claim them
+	inFlight sync.Map
 }
 
 // NewAgentAdapter creates a new agent adapter
 func NewAgentAdapter(client StreamClient, serverKey, consumerGroup, consumerName string, logger *logrus.Logger) *AgentAdapter {
+	defaults := DefaultConfig()
 	return &AgentAdapter{
-		client:        client,
-		logger:        logger,
-		serverKey:     serverKey,
-		consumerGroup: consumerGroup,
-		consumerName:  consumerName,
+		client:            client,
+		logger:            logger,
+		serverKey:         serverKey,
+		consumerGroup:     consumerGroup,
+		consumerName:      consumerName,
+		startID:           "$",
+		maxRetries:        defaults.MaxRetries,
+		visibilityTimeout: defaults.VisibilityTimeout,
+	}
+}
+
+// SetRetryPolicy overrides redelivery settings, zero values keep defaults
+func (a *AgentAdapter) SetRetryPolicy(maxRetries int, visibilityTimeout time.Duration) {
+	if maxRetries > 0 {
+		a.maxRetries = maxRetries
+	}
+	if visibilityTimeout > 0 {
+		a.visibilityTimeout = visibilityTimeout
+	}
+}
+
+// SetStartID sets the stream ID a newly created consumer group starts from ("$" by default)
+func (a *AgentAdapter) SetStartID(startID string) {
+	if startID != "" {
+		a.startID = startID
 	}
 }
 
@@ -37,7 +67,7 @@ func (a *AgentAdapter) Initialize(ctx context.Context) error {
 	cmdStream := fmt.Sprintf("stream:cmd:%s", a.serverKey)
 
 	// Create consumer group
-	err := a.client.CreateConsumerGroup(ctx, cmdStream, a.consumerGroup)
+	err := a.client.CreateConsumerGroup(ctx, cmdStream, a.consumerGroup, a.startID)
 	if err != nil {
 		return fmt.Errorf("failed to create consumer group: %w", err)
 	}
@@ -51,13 +81,26 @@ func (a *AgentAdapter) Initialize(ctx context.Context) error {
 	return nil
 }
 
-// ProcessCommands starts processing commands from the stream
+// ProcessCommands starts processing commands from the stream.
+// Commands run one at a time on a CommandQueue while the stream keeps being read.
 func (a *AgentAdapter) ProcessCommands(ctx context.Context, handler CommandHandler) error {
 	cmdStream := fmt.Sprintf("stream:cmd:%s", a.serverKey)
 	respStream := fmt.Sprintf("stream:resp:%s", a.serverKey)
 
 	a.logger.WithField("stream", cmdStream).Info("Starting command processing")
 
+	queue := NewCommandQueue(ctx)
+	defer queue.Close()
+	dispatch := func(messages []StreamMessage) {
+		for _, msg := range messages {
+			a.submit(ctx, queue, msg, handler, cmdStream, respStream)
+		}
+	}
+
+	// Reclaim at least twice per visibility timeout so stuck messages wait at most ~1.5x of it
+	reclaimEvery := a.visibilityTimeout / 2
+	lastReclaim := time.Now()
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -65,6 +108,11 @@ func (a *AgentAdapter) ProcessCommands(ctx context.Context, handler CommandHandl
 			return ctx.Err()
 
 		default:
+			if time.Since(lastReclaim) >= reclaimEvery {
+				a.recoverPending(ctx, dispatch)
+				lastReclaim = time.Now()
+			}
+
 			// Read messages from consumer group
 			messages, err := a.client.ReadGroupMessages(
 				ctx,
@@ -80,20 +128,221 @@ func (a *AgentAdapter) ProcessCommands(ctx context.Context, handler CommandHandl
 				continue
 			}
 
-			// Process each message
-			for _, msg := range messages {
-				if err := a.processMessage(ctx, msg, handler, respStream); err != nil {
-					a.logger.WithError(err).Error("Failed to process message")
-					// Don't ACK - message will be redelivered
-					continue
-				}
-
-				// ACK message after successful processing
-				if err := a.client.AckMessage(ctx, cmdStream, a.consumerGroup, msg.ID); err != nil {
-					a.logger.WithError(err).Error("Failed to ACK message")
-				}
+			dispatch(messages)
+		}
+	}
+}
+
+// Deliver reads commands through the consumer group and passes each one to deliver.
+// It is meant for consumers that answer asynchronously, such as the bot pushing commands
+// into an agent tunnel: delivered messages stay pending and in flight until Ack, stuck
+// ones are reclaimed and dead-lettered like in ProcessCommands. Returns the deliver error.
+func (a *AgentAdapter) Deliver(ctx context.Context, block time.Duration, deliver func(StreamMessage) error) error {
+	cmdStream := fmt.Sprintf("stream:cmd:%s", a.serverKey)
+
+	var deliverErr error
+	send := func(messages []StreamMessage) {
+		for _, msg := range messages {
+			if deliverErr != nil {
+				return
+			}
+			a.inFlight.Store(msg.ID, struct{}{})
+			if err := deliver(msg); err != nil {
+				a.inFlight.Delete(msg.ID)
+				deliverErr = err
+			}
+		}
+	}
+
+	// Messages pushed by this consumer over a previous connection were lost with it
+	a.reclaim(ctx, 0, a.consumerName, send)
+
+	reclaimEvery := a.visibilityTimeout / 2
+	lastReclaim := time.Now()
+
+	for ctx.Err() == nil && deliverErr == nil {
+		if time.Since(lastReclaim) >= reclaimEvery {
+			a.recoverPending(ctx, send)
+			lastReclaim = time.Now()
+		}
+
+		messages, err := a.client.ReadGroupMessages(ctx, cmdStream, a.consumerGroup, a.consumerName, 10, block)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
 			}
+			a.logger.WithError(err).Error("Failed to read messages")
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		send(messages)
+	}
+
+	if deliverErr != nil {
+		return deliverErr
+	}
+	return ctx.Err()
+}
+
+// Ack acknowledges a message passed to Deliver once its response is written
+func (a *AgentAdapter) Ack(ctx context.Context, messageID string) error {
+	cmdStream := fmt.Sprintf("stream:cmd:%s", a.serverKey)
+
+	a.inFlight.Delete(messageID)
+	return a.client.AckMessage(ctx, cmdStream, a.consumerGroup, messageID)
+}
+
+// submit queues a message, it stays in flight until handled
+func (a *AgentAdapter) submit(ctx context.Context, queue *CommandQueue, msg StreamMessage, handler CommandHandler, cmdStream, respStream string) {
+	a.inFlight.Store(msg.ID, struct{}{})
+
+	queued := queue.Submit(protocol.MessageType(msg.Values["type"]), func() {
+		defer a.inFlight.Delete(msg.ID)
+		a.handleMessages(ctx, []StreamMessage{msg}, handler, cmdStream, respStream)
+	})
+	if !queued {
+		// Not ACKed: recovered after the visibility timeout
+		a.inFlight.Delete(msg.ID)
+	}
+}
+
+// handleMessages processes messages and ACKs successfully handled ones
+func (a *AgentAdapter) handleMessages(ctx context.Context, messages []StreamMessage, handler CommandHandler, cmdStream, respStream string) {
+	for _, msg := range messages {
+		if err := a.processMessage(ctx, msg, handler, respStream); err != nil {
+			a.logger.WithFields(logrus.Fields{
+				"message_id": msg.ID,
+				"retries":    msg.Retries,
+			}).WithError(err).Error("Failed to process message")
+			// Don't ACK - message will be reclaimed after visibility timeout
+			continue
+		}
+
+		// ACK message after successful processing
+		if err := a.client.AckMessage(ctx, cmdStream, a.consumerGroup, msg.ID); err != nil {
+			a.logger.WithError(err).Error("Failed to ACK message")
+		}
+	}
+}
+
+// RecoverPending reclaims messages stuck longer than the visibility timeout.
+// Messages delivered maxRetries times are moved to the dead-letter stream instead.
+func (a *AgentAdapter) RecoverPending(ctx context.Context, handler CommandHandler) {
+	cmdStream := fmt.Sprintf("stream:cmd:%s", a.serverKey)
+	respStream := fmt.Sprintf("stream:resp:%s", a.serverKey)
+
+	a.recoverPending(ctx, func(messages []StreamMessage) {
+		a.handleMessages(ctx, messages, handler, cmdStream, respStream)
+	})
+}
+
+// recoverPending claims stuck messages and passes them to handle
+func (a *AgentAdapter) recoverPending(ctx context.Context, handle func([]StreamMessage)) {
+	a.reclaim(ctx, a.visibilityTimeout, "", handle)
+}
+
+// reclaim claims messages idle for minIdle, only of consumer if set, and passes them to handle
+func (a *AgentAdapter) reclaim(ctx context.Context, minIdle time.Duration, consumer string, handle func([]StreamMessage)) {
+	cmdStream := fmt.Sprintf("stream:cmd:%s", a.serverKey)
+
+	pending, err := a.client.PendingMessages(ctx, cmdStream, a.consumerGroup, minIdle, 100)
+	if err != nil {
+		a.logger.WithError(err).Error("Failed to list pending messages")
+		return
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	var retryIDs, deadIDs []string
+	retries := make(map[string]PendingMessage, len(pending))
+	for _, p := range pending {
+		if _, running := a.inFlight.Load(p.ID); running {
+			// Long command of this consumer, not a stuck one
+			continue
+		}
+		if consumer != "" && p.Consumer != consumer {
+			continue
+		}
+		retries[p.ID] = p
+		if p.RetryCount >= int64(a.maxRetries) {
+			deadIDs = append(deadIDs, p.ID)
+		} else {
+			retryIDs = append(retryIDs, p.ID)
+		}
+	}
+
+	if len(deadIDs) > 0 {
+		a.deadLetter(ctx, cmdStream, minIdle, deadIDs, retries)
+	}
+
+	if len(retryIDs) == 0 {
+		return
+	}
+
+	claimed, err := a.client.ClaimMessages(ctx, cmdStream, a.consumerGroup, a.consumerName, minIdle, retryIDs)
+	if err != nil {
+		a.logger.WithError(err).Error("Failed to claim pending messages")
+		return
+	}
+
+	for i := range claimed {
+		// XCLAIM counts as another delivery
+		claimed[i].Retries = int(retries[claimed[i].ID].RetryCount) + 1
+	}
+
+	a.logger.WithFields(logrus.Fields{
+		"stream":  cmdStream,
+		"claimed": len(claimed),
+	}).Info("Reclaimed pending commands")
+
+	handle(claimed)
+}
+
+// deadLetter moves exhausted messages to the dead-letter stream and ACKs them
+func (a *AgentAdapter) deadLetter(ctx context.Context, cmdStream string, minIdle time.Duration, ids []string, pending map[string]PendingMessage) {
+	dlqStream := DeadLetterStream(a.serverKey)
+
+	// Claim to get message contents, minIdle guards against racing with another consumer
+	claimed, err := a.client.ClaimMessages(ctx, cmdStream, a.consumerGroup, a.consumerName, minIdle, ids)
+	if err != nil {
+		a.logger.WithError(err).Error("Failed to claim messages for dead-lettering")
+		return
+	}
+
+	for _, msg := range claimed {
+		info := pending[msg.ID]
+
+		values := make(map[string]string, len(msg.Values)+5)
+		for k, v := range msg.Values {
+			values[k] = v
+		}
+		values["original_id"] = msg.ID
+		values["consumer"] = info.Consumer
+		values["retries"] = strconv.FormatInt(info.RetryCount, 10)
+		values["reason"] = "max retries exceeded"
+		values["dead_lettered_at"] = time.Now().Format(time.RFC3339)
+
+		if _, err := a.client.AddMessage(ctx, dlqStream, values); err != nil {
+			// Leave it pending, next recovery round will try again
+			a.logger.WithError(err).Error("Failed to add message to dead-letter stream")
+			continue
+		}
+
+		if err := a.client.AckMessage(ctx, cmdStream, a.consumerGroup, msg.ID); err != nil {
+			a.logger.WithError(err).Error("Failed to ACK dead-lettered message")
 		}
+
+		a.logger.WithFields(logrus.Fields{
+			"message_id": msg.ID,
+			"command_id": msg.Values["id"],
+			"retries":    info.RetryCount,
+			"stream":     dlqStream,
+		}).Warn("Command moved to dead-letter stream")
 	}
 }
 
@@ -114,14 +363,15 @@ func (a *AgentAdapter) processMessage(ctx context.Context, msg StreamMessage, ha
 		"message_id":   msg.ID,
 	}).Info("Processing command")
 
-	// Execute handler
+	// Execute handler, nil response means nothing to reply (e.g. duplicate command)
 	response := handler(ctx, command)
 	if response == nil {
-		return fmt.Errorf("handler returned nil response")
+		a.logger.WithField("command_id", command.ID).Debug("Handler returned no response")
+		return nil
 	}
 
 	// Send response
-	if err := a.SendResponse(ctx, response, command.ID); err != nil {
+	if err := a.SendResponse(ctx, response, command); err != nil {
 		return fmt.Errorf("failed to send response: %w", err)
 	}
 
@@ -134,50 +384,44 @@ func (a *AgentAdapter) processMessage(ctx context.Context, msg StreamMessage, ha
 	return nil
 }
 
-// SendResponse sends a response to the response stream
-func (a *AgentAdapter) SendResponse(ctx context.Context, response *protocol.Message, commandID string) error {
+// SendResponse sends the response to command to the response stream.
+// Large responses are split into several entries if the bot reassembles them.
+func (a *AgentAdapter) SendResponse(ctx context.Context, response *protocol.Message, command *protocol.Message) error {
 	respStream := fmt.Sprintf("stream:resp:%s", a.serverKey)
 
-	responseJSON, err := response.ToJSON()
+	entries, err := ResponseEntries(response, command.ID, ResponseChunkSize(command))
 	if err != nil {
-		return fmt.Errorf("failed to serialize response: %w", err)
-	}
-
-	values := map[string]string{
-		"type":       string(response.Type),
-		"id":         response.ID,
-		"command_id": commandID, // Link response to original command
-		"payload":    string(responseJSON),
-		"timestamp":  time.Now().Format(time.RFC3339),
+		return err
 	}
 
-	_, err = a.client.AddMessage(ctx, respStream, values)
-	if err != nil {
-		return fmt.Errorf("failed to add response to stream: %w", err)
+	for _, values := range entries {
+		if _, err := a.client.AddMessage(ctx, respStream, values); err != nil {
+			return fmt.Errorf("failed to add response to stream: %w", err)
+		}
 	}
 
 	a.logger.WithFields(logrus.Fields{
-		"command_id":    commandID,
+		"command_id":    command.ID,
 		"response_type": response.Type,
 		"stream":        respStream,
+		"entries":       len(entries),
 	}).Debug("Response sent to stream")
 
 	return nil
 }
 
 // GetPendingMessages returns messages that were delivered but not ACKed
-func (a *AgentAdapter) GetPendingMessages(ctx context.Context) ([]StreamMessage, error) {
+func (a *AgentAdapter) GetPendingMessages(ctx context.Context) ([]PendingMessage, error) {
 	cmdStream := fmt.Sprintf("stream:cmd:%s", a.serverKey)
 
-	// XPENDING shows pending messages in consumer group
-	pending, err := a.client.(*Client).client.XPending(ctx, cmdStream, a.consumerGroup).Result()
+	pending, err := a.client.PendingMessages(ctx, cmdStream, a.consumerGroup, 0, 100)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pending messages: %w", err)
 	}
 
 	a.logger.WithFields(logrus.Fields{
-		"count": pending.Count,
+		"count": len(pending),
 	}).Debug("Pending messages info")
 
-	return nil, nil // Simplified for now
+	return pending, nil
 }
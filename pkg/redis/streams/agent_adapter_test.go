@@ -0,0 +1,203 @@
+package streams
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeStreamClient records stream operations for adapter tests
+type fakeStreamClient struct {
+	mu       sync.Mutex
+	messages map[string]StreamMessage // by ID, command stream only
+	pending  []PendingMessage
+	added    map[string][]map[string]string
+	acked    []string
+	claimed  []string
+}
+
+func newFakeStreamClient() *fakeStreamClient {
+	return &fakeStreamClient{
+		messages: make(map[string]StreamMessage),
+		added:    make(map[string][]map[string]string),
+	}
+}
+
+func (f *fakeStreamClient) AddMessage(ctx context.Context, stream string, values map[string]string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.added[stream] = append(f.added[stream], values)
+	return "0-1", nil
+}
+
+func (f *fakeStreamClient) ReadMessages(ctx context.Context, stream, lastID string, count int64, block time.Duration) ([]StreamMessage, error) {
+	return nil, nil
+}
+
+func (f *fakeStreamClient) CreateConsumerGroup(ctx context.Context, stream, group, startID string) error {
+	return nil
+}
+
+func (f *fakeStreamClient) ReadGroupMessages(ctx context.Context, stream, group, consumer string, count int64, block time.Duration) ([]StreamMessage, error) {
+	return nil, nil
+}
+
+func (f *fakeStreamClient) AckMessage(ctx context.Context, stream, group, messageID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.acked = append(f.acked, messageID)
+	return nil
+}
+
+func (f *fakeStreamClient) PendingMessages(ctx context.Context, stream, group string, minIdle time.Duration, count int64) ([]PendingMessage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var result []PendingMessage
+	for _, p := range f.pending {
+		if p.Idle >= minIdle {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeStreamClient) ClaimMessages(ctx context.Context, stream, group, consumer string, minIdle time.Duration, ids []string) ([]StreamMessage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var result []StreamMessage
+	for _, id := range ids {
+		if msg, ok := f.messages[id]; ok {
+			f.claimed = append(f.claimed, id)
+			result = append(result, msg)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeStreamClient) TrimStream(ctx context.Context, stream string, maxLen int64) error {
+	return nil
+}
+
+func (f *fakeStreamClient) GetStreamLength(ctx context.Context, stream string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return int64(len(f.added[stream])), nil
+}
+
+func (f *fakeStreamClient) RangeMessages(ctx context.Context, stream, start, end string, count int64) ([]StreamMessage, error) {
+	return nil, nil
+}
+
+func (f *fakeStreamClient) LastMessageID(ctx context.Context, stream string) (string, error) {
+	return "0", nil
+}
+
+func (f *fakeStreamClient) Ping(ctx context.Context) error {
+	return nil
+}
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func commandEntry(t *testing.T, streamID, commandID string) StreamMessage {
+	t.Helper()
+	cmd := protocol.NewMessage(protocol.TypePing, nil)
+	cmd.ID = commandID
+	data, err := cmd.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+	return StreamMessage{
+		ID: streamID,
+		Values: map[string]string{
+			"type":    string(cmd.Type),
+			"id":      cmd.ID,
+			"payload": string(data),
+		},
+	}
+}
+
+func TestAgentAdapter_RecoverPending(t *testing.T) {
+	client := newFakeStreamClient()
+	client.messages["1-0"] = commandEntry(t, "1-0", "cmd-retry")
+	client.messages["2-0"] = commandEntry(t, "2-0", "cmd-dead")
+	client.pending = []PendingMessage{
+		{ID: "1-0", Consumer: "agent", Idle: 2 * time.Minute, RetryCount: 1},
+		{ID: "2-0", Consumer: "agent", Idle: 2 * time.Minute, RetryCount: 3},
+		{ID: "3-0", Consumer: "agent", Idle: time.Second, RetryCount: 1}, // still in flight
+	}
+
+	adapter := NewAgentAdapter(client, "srv_test", "group", "agent", testLogger())
+	adapter.SetRetryPolicy(3, time.Minute)
+
+	var handled []string
+	handler := func(ctx context.Context, cmd *protocol.Message) *protocol.Message {
+		handled = append(handled, cmd.ID)
+		return protocol.NewMessage(protocol.TypePong, nil)
+	}
+
+	adapter.RecoverPending(context.Background(), handler)
+
+	if len(handled) != 1 || handled[0] != "cmd-retry" {
+		t.Errorf("Expected only cmd-retry to be re-executed, got %v", handled)
+	}
+
+	dlq := client.added[DeadLetterStream("srv_test")]
+	if len(dlq) != 1 {
+		t.Fatalf("Expected 1 dead-lettered message, got %d", len(dlq))
+	}
+	if dlq[0]["original_id"] != "2-0" || dlq[0]["id"] != "cmd-dead" || dlq[0]["retries"] != "3" {
+		t.Errorf("Unexpected dead-letter entry: %v", dlq[0])
+	}
+
+	if len(client.added["stream:resp:srv_test"]) != 1 {
+		t.Errorf("Expected response for reclaimed command, got %d", len(client.added["stream:resp:srv_test"]))
+	}
+
+	acked := map[string]bool{}
+	for _, id := range client.acked {
+		acked[id] = true
+	}
+	if !acked["1-0"] || !acked["2-0"] || acked["3-0"] {
+		t.Errorf("Unexpected ACKs: %v", client.acked)
+	}
+}
+
+func TestAgentAdapter_NilResponseIsAcked(t *testing.T) {
+	client := newFakeStreamClient()
+	adapter := NewAgentAdapter(client, "srv_test", "group", "agent", testLogger())
+
+	handler := func(ctx context.Context, cmd *protocol.Message) *protocol.Message {
+		return nil
+	}
+
+	msg := commandEntry(t, "5-0", "cmd-dup")
+	adapter.handleMessages(context.Background(), []StreamMessage{msg}, handler, "stream:cmd:srv_test", "stream:resp:srv_test")
+
+	if len(client.acked) != 1 || client.acked[0] != "5-0" {
+		t.Errorf("Expected duplicate command to be ACKed, got %v", client.acked)
+	}
+	if len(client.added["stream:resp:srv_test"]) != 0 {
+		t.Error("No response expected for nil handler result")
+	}
+}
+
+func TestAgentAdapter_InvalidPayloadNotAcked(t *testing.T) {
+	client := newFakeStreamClient()
+	adapter := NewAgentAdapter(client, "srv_test", "group", "agent", testLogger())
+
+	msg := StreamMessage{ID: "6-0", Values: map[string]string{"payload": "{broken"}}
+	adapter.handleMessages(context.Background(), []StreamMessage{msg}, nil, "stream:cmd:srv_test", "stream:resp:srv_test")
+
+	if len(client.acked) != 0 {
+		t.Errorf("Invalid message must stay pending for dead-lettering, got ACKs %v", client.acked)
+	}
+}
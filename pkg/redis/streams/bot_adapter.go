@@ -3,23 +3,59 @@ package streams
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/servereye/servereye/pkg/protocol"
 	"github.com/sirupsen/logrus"
 )
 
-// BotAdapter provides high-level API for bot to send commands and receive responses
+const (
+	// responseReadBlock is how long a response reader blocks in a single XREAD
+	responseReadBlock = 1 * time.Second
+	// responseReaderIdle is how long a reader without waiters stays alive
+	responseReaderIdle = 30 * time.Second
+	// DefaultMaxResponseSize limits a response reassembled from chunks
+	DefaultMaxResponseSize = 16 << 20
+)
+
+// BotAdapter provides high-level API for bot to send commands and receive responses.
+// Responses are read by one blocking reader per server and dispatched to waiters by command_id.
 type BotAdapter struct {
 	client StreamClient
 	logger *logrus.Logger
+
+	maxResponseSize int
+
+	mu      sync.Mutex
+	readers map[string]*responseReader // by server key
+	closed  bool
+}
+
+// responseReader tails stream:resp:<key> and fans out responses to waiting callers
+type responseReader struct {
+	stream  string
+	waiters map[string]chan responseResult      // by command ID
+	partial map[string]*protocol.ChunkAssembler // multi-part responses being received, by command ID
+	idleAt  time.Time                           // when the last waiter left
+	ready   chan struct{}                       // closed once the tail ID is resolved
+	err     error                               // tail resolution error, valid after ready
+	cancel  context.CancelFunc
+}
+
+// responseResult is a response or the reason it couldn't be received
+type responseResult struct {
+	response *protocol.Message
+	err      error
 }
 
 // NewBotAdapter creates a new bot adapter
 func NewBotAdapter(client StreamClient, logger *logrus.Logger) *BotAdapter {
 	return &BotAdapter{
-		client: client,
-		logger: logger,
+		client:          client,
+		logger:          logger,
+		maxResponseSize: DefaultMaxResponseSize,
+		readers:         make(map[string]*responseReader),
 	}
 }
 
@@ -27,7 +63,6 @@ func NewBotAdapter(client StreamClient, logger *logrus.Logger) *BotAdapter {
 func (a *BotAdapter) SendCommand(ctx context.Context, serverKey string, command *protocol.Message, timeout time.Duration) (*protocol.Message, error) {
 	// Stream names
 	cmdStream := fmt.Sprintf("stream:cmd:%s", serverKey)
-	respStream := fmt.Sprintf("stream:resp:%s", serverKey)
 
 	// Serialize command
 	commandJSON, err := command.ToJSON()
@@ -35,6 +70,22 @@ func (a *BotAdapter) SendCommand(ctx context.Context, serverKey string, command
 		return nil, fmt.Errorf("failed to serialize command: %w", err)
 	}
 
+	// Register waiter before XADD so a fast response can't be missed
+	reader, respCh, err := a.addWaiter(serverKey, command.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer a.removeWaiter(serverKey, reader, command.ID)
+
+	select {
+	case <-reader.ready:
+		if reader.err != nil {
+			return nil, fmt.Errorf("failed to start response reader: %w", reader.err)
+		}
+	case <-ctx.Done():
+		return nil, fmt.Errorf("timeout waiting for response")
+	}
+
 	// Add command to stream
 	values := map[string]string{
 		"type":       string(command.Type),
@@ -56,57 +107,204 @@ func (a *BotAdapter) SendCommand(ctx context.Context, serverKey string, command
 	}).Info("Command sent to stream")
 
 	// Wait for response
-	return a.waitForResponse(ctx, respStream, command.ID, timeout)
-}
-
-// waitForResponse waits for a specific response by command ID
-func (a *BotAdapter) waitForResponse(ctx context.Context, respStream, commandID string, timeout time.Duration) (*protocol.Message, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	lastID := "0" // Start from beginning
-	ticker := time.NewTicker(100 * time.Millisecond)
-	defer ticker.Stop()
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("timeout waiting for response")
+	case result := <-respCh:
+		return result.response, result.err
+	}
+}
+
+// Close stops all response readers
+func (a *BotAdapter) Close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.closed = true
+	for key, reader := range a.readers {
+		reader.cancel()
+		delete(a.readers, key)
+	}
+}
+
+// addWaiter registers a waiter for commandID, starting the server reader if needed
+func (a *BotAdapter) addWaiter(serverKey, commandID string) (*responseReader, chan responseResult, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return nil, nil, fmt.Errorf("bot adapter is closed")
+	}
+
+	reader, ok := a.readers[serverKey]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		reader = &responseReader{
+			stream:  fmt.Sprintf("stream:resp:%s", serverKey),
+			waiters: make(map[string]chan responseResult),
+			partial: make(map[string]*protocol.ChunkAssembler),
+			ready:   make(chan struct{}),
+			cancel:  cancel,
+		}
+		a.readers[serverKey] = reader
+		go a.runReader(ctx, serverKey, reader)
+	}
+
+	// Buffered so the reader never blocks on a caller that already gave up
+	ch := make(chan responseResult, 1)
+	reader.waiters[commandID] = ch
+	return reader, ch, nil
+}
+
+// removeWaiter unregisters a waiter, the reader stops after responseReaderIdle without waiters
+func (a *BotAdapter) removeWaiter(serverKey string, reader *responseReader, commandID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(reader.waiters, commandID)
+	delete(reader.partial, commandID)
+	if len(reader.waiters) == 0 {
+		reader.idleAt = time.Now()
+	}
+}
+
+// runReader tails the response stream starting at its current end
+func (a *BotAdapter) runReader(ctx context.Context, serverKey string, reader *responseReader) {
+	defer reader.cancel()
+
+	lastID, err := a.client.LastMessageID(ctx, reader.stream)
+	if err != nil {
+		a.mu.Lock()
+		reader.err = err
+		if a.readers[serverKey] == reader {
+			delete(a.readers, serverKey)
+		}
+		a.mu.Unlock()
+		close(reader.ready)
+		return
+	}
+	close(reader.ready)
+
+	a.logger.WithFields(logrus.Fields{
+		"stream":  reader.stream,
+		"last_id": lastID,
+	}).Debug("Response reader started")
 
 	for {
-		select {
-		case <-ctx.Done():
-			return nil, fmt.Errorf("timeout waiting for response")
-
-		case <-ticker.C:
-			// Read messages from response stream
-			messages, err := a.client.ReadMessages(ctx, respStream, lastID, 10, 500*time.Millisecond)
-			if err != nil {
-				a.logger.WithError(err).Error("Failed to read response stream")
-				continue
-			}
+		if a.stopIdleReader(serverKey, reader) {
+			a.logger.WithField("stream", reader.stream).Debug("Response reader stopped")
+			return
+		}
 
-			for _, msg := range messages {
-				lastID = msg.ID
-
-				// Check if this response is for our command
-				if msg.Values["command_id"] != commandID {
-					continue
-				}
-
-				// Parse response
-				payloadJSON := msg.Values["payload"]
-				response, err := protocol.FromJSON([]byte(payloadJSON))
-				if err != nil {
-					a.logger.WithError(err).Error("Failed to parse response")
-					continue
-				}
-
-				a.logger.WithFields(logrus.Fields{
-					"command_id":    commandID,
-					"response_type": response.Type,
-					"message_id":    msg.ID,
-				}).Info("Response received from stream")
-
-				return response, nil
+		messages, err := a.client.ReadMessages(ctx, reader.stream, lastID, 100, responseReadBlock)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			a.logger.WithError(err).Error("Failed to read response stream")
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
 			}
+			continue
+		}
+
+		for _, msg := range messages {
+			lastID = msg.ID
+			a.dispatch(reader, msg)
+		}
+	}
+}
+
+// stopIdleReader removes the reader once it had no waiters for responseReaderIdle
+func (a *BotAdapter) stopIdleReader(serverKey string, reader *responseReader) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.readers[serverKey] != reader {
+		// Adapter closed
+		return true
+	}
+	if len(reader.waiters) > 0 || reader.idleAt.IsZero() || time.Since(reader.idleAt) < responseReaderIdle {
+		return false
+	}
+
+	delete(a.readers, serverKey)
+	return true
+}
+
+// dispatch delivers a response to the caller waiting for its command_id.
+// Parts of a multi-part response are collected until the final one.
+func (a *BotAdapter) dispatch(reader *responseReader, msg StreamMessage) {
+	commandID := msg.Values["command_id"]
+	payload := []byte(msg.Values["payload"])
+
+	a.mu.Lock()
+	ch, ok := reader.waiters[commandID]
+	if !ok {
+		// Nobody waits: caller timed out or response belongs to another bot instance
+		a.mu.Unlock()
+		return
+	}
+
+	chunk, chunked, err := parseChunk(msg.Values)
+	if chunked && err == nil {
+		assembler, started := reader.partial[commandID]
+		if !started {
+			assembler = protocol.NewChunkAssembler(a.maxResponseSize)
+			reader.partial[commandID] = assembler
 		}
+
+		var complete bool
+		payload, complete, err = assembler.Add(chunk)
+		if err == nil && !complete {
+			a.mu.Unlock()
+			return
+		}
+	}
+
+	delete(reader.waiters, commandID)
+	delete(reader.partial, commandID)
+	if len(reader.waiters) == 0 {
+		reader.idleAt = time.Now()
+	}
+	a.mu.Unlock()
+
+	if err != nil {
+		a.logger.WithError(err).WithField("command_id", commandID).Error("Failed to reassemble response")
+		ch <- responseResult{err: fmt.Errorf("failed to receive response: %w", err)}
+		return
+	}
+
+	response, err := protocol.FromJSON(payload)
+	if err != nil {
+		a.logger.WithError(err).WithField("command_id", commandID).Error("Failed to parse response")
+		ch <- responseResult{err: fmt.Errorf("failed to parse response: %w", err)}
+		return
+	}
+
+	a.logger.WithFields(logrus.Fields{
+		"command_id":    commandID,
+		"response_type": response.Type,
+		"message_id":    msg.ID,
+	}).Info("Response received from stream")
+
+	ch <- responseResult{response: response}
+}
+
+// waiterCount returns the number of callers waiting on serverKey responses
+func (a *BotAdapter) waiterCount(serverKey string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if reader, ok := a.readers[serverKey]; ok {
+		return len(reader.waiters)
 	}
+	return 0
 }
 
 // GetStreamStats returns statistics about command/response streams
@@ -129,3 +327,23 @@ func (a *BotAdapter) GetStreamStats(ctx context.Context, serverKey string) (map[
 		"responses": respLen,
 	}, nil
 }
+
+// GetDeadLetters returns up to count oldest dead-lettered commands and the DLQ length
+func (a *BotAdapter) GetDeadLetters(ctx context.Context, serverKey string, count int64) ([]StreamMessage, int64, error) {
+	dlqStream := DeadLetterStream(serverKey)
+
+	total, err := a.client.GetStreamLength(ctx, dlqStream)
+	if err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return nil, 0, nil
+	}
+
+	messages, err := a.client.RangeMessages(ctx, dlqStream, "-", "+", count)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return messages, total, nil
+}
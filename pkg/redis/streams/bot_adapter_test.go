@@ -0,0 +1,273 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+// memoryResponseStreams is a minimal in-memory stream store with an echoing agent
+type memoryResponseStreams struct {
+	fakeStreamClient
+
+	mu       sync.Mutex
+	streams  map[string][]StreamMessage
+	seq      int
+	silent   bool     // agent never replies
+	response []byte   // payload of the agent reply, pong without payload if nil
+	rawReply string   // if set, the agent replies with this entry payload as is
+	firstIDs []string // lastID of the first read of each reader
+	reads    int
+}
+
+func newMemoryResponseStreams() *memoryResponseStreams {
+	return &memoryResponseStreams{streams: make(map[string][]StreamMessage)}
+}
+
+func (m *memoryResponseStreams) add(stream string, values map[string]string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seq++
+	id := fmt.Sprintf("%d-0", m.seq)
+	m.streams[stream] = append(m.streams[stream], StreamMessage{ID: id, Values: values, Stream: stream})
+	return id
+}
+
+func (m *memoryResponseStreams) AddMessage(ctx context.Context, stream string, values map[string]string) (string, error) {
+	id := m.add(stream, values)
+
+	if strings.HasPrefix(stream, "stream:cmd:") && !m.silent {
+		serverKey := strings.TrimPrefix(stream, "stream:cmd:")
+		commandID := values["id"]
+		command, _ := protocol.FromJSON([]byte(values["payload"]))
+		go func() {
+			if m.rawReply != "" {
+				m.add("stream:resp:"+serverKey, map[string]string{"command_id": commandID, "payload": m.rawReply})
+				return
+			}
+			resp := protocol.NewMessage(protocol.TypePong, nil)
+			resp.ID = commandID
+			resp.Payload = m.response
+			entries, _ := ResponseEntries(resp, commandID, ResponseChunkSize(command))
+			for _, entry := range entries {
+				m.add("stream:resp:"+serverKey, entry)
+			}
+		}()
+	}
+	return id, nil
+}
+
+func (m *memoryResponseStreams) ReadMessages(ctx context.Context, stream, lastID string, count int64, block time.Duration) ([]StreamMessage, error) {
+	m.mu.Lock()
+	m.reads++
+	if m.reads == 1 {
+		m.firstIDs = append(m.firstIDs, lastID)
+	}
+	m.mu.Unlock()
+
+	after := streamSeq(lastID)
+	deadline := time.Now().Add(block)
+	for {
+		m.mu.Lock()
+		var result []StreamMessage
+		for _, msg := range m.streams[stream] {
+			if streamSeq(msg.ID) > after {
+				result = append(result, msg)
+			}
+		}
+		m.mu.Unlock()
+
+		if len(result) > 0 || time.Now().After(deadline) {
+			return result, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+func (m *memoryResponseStreams) LastMessageID(ctx context.Context, stream string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	messages := m.streams[stream]
+	if len(messages) == 0 {
+		return "0", nil
+	}
+	return messages[len(messages)-1].ID, nil
+}
+
+func streamSeq(id string) int {
+	n, _ := strconv.Atoi(strings.SplitN(id, "-", 2)[0])
+	return n
+}
+
+func TestBotAdapter_ConcurrentCommandsShareReader(t *testing.T) {
+	client := newMemoryResponseStreams()
+	// Old responses must not be rescanned
+	client.add("stream:resp:srv_test", map[string]string{"command_id": "old"})
+	client.add("stream:resp:srv_test", map[string]string{"command_id": "old"})
+
+	adapter := NewBotAdapter(client, testLogger())
+	defer adapter.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := protocol.NewMessage(protocol.TypePing, nil)
+			resp, err := adapter.SendCommand(context.Background(), "srv_test", cmd, 2*time.Second)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if resp.ID != cmd.ID {
+				errs <- fmt.Errorf("got response %s for command %s", resp.ID, cmd.ID)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if len(client.firstIDs) != 1 || client.firstIDs[0] != "2-0" {
+		t.Errorf("Expected a single reader starting at stream tail 2-0, got %v", client.firstIDs)
+	}
+}
+
+func TestBotAdapter_TimeoutRemovesWaiter(t *testing.T) {
+	client := newMemoryResponseStreams()
+	client.silent = true
+
+	adapter := NewBotAdapter(client, testLogger())
+	defer adapter.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := adapter.SendCommand(ctx, "srv_test", protocol.NewMessage(protocol.TypePing, nil), time.Minute)
+		done <- err
+	}()
+
+	// Wait until the waiter is registered, then cancel the caller
+	for i := 0; i < 100 && adapter.waiterCount("srv_test") == 0; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Expected error after cancellation")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SendCommand did not return after context cancellation")
+	}
+
+	if n := adapter.waiterCount("srv_test"); n != 0 {
+		t.Errorf("Expected waiter cleanup, %d left", n)
+	}
+}
+
+func TestBotAdapter_ClosedAdapter(t *testing.T) {
+	adapter := NewBotAdapter(newMemoryResponseStreams(), testLogger())
+	adapter.Close()
+
+	if _, err := adapter.SendCommand(context.Background(), "srv_test", protocol.NewMessage(protocol.TypePing, nil), time.Second); err == nil {
+		t.Error("Expected error from closed adapter")
+	}
+}
+
+func TestBotAdapter_ChunkedResponse(t *testing.T) {
+	client := newMemoryResponseStreams()
+	client.response = []byte(`{"output":"` + strings.Repeat("a", 2*protocol.DefaultChunkSize) + `"}`)
+
+	adapter := NewBotAdapter(client, testLogger())
+	defer adapter.Close()
+
+	resp, err := adapter.SendCommand(context.Background(), "srv_test", protocol.NewMessage(protocol.TypePing, nil), 2*time.Second)
+	if err != nil {
+		t.Fatalf("SendCommand() error = %v", err)
+	}
+	if string(resp.Payload) != string(client.response) {
+		t.Errorf("Reassembled payload differs: got %d bytes, want %d", len(resp.Payload), len(client.response))
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if n := len(client.streams["stream:resp:srv_test"]); n != 3 {
+		t.Errorf("Expected response in 3 entries, got %d", n)
+	}
+}
+
+func TestBotAdapter_ChunkedResponseTooLarge(t *testing.T) {
+	client := newMemoryResponseStreams()
+	client.response = []byte(`{"output":"` + strings.Repeat("a", 2*protocol.DefaultChunkSize) + `"}`)
+
+	adapter := NewBotAdapter(client, testLogger())
+	adapter.maxResponseSize = protocol.DefaultChunkSize
+	defer adapter.Close()
+
+	_, err := adapter.SendCommand(context.Background(), "srv_test", protocol.NewMessage(protocol.TypePing, nil), 2*time.Second)
+	if !errors.Is(err, protocol.ErrResponseTooLarge) {
+		t.Fatalf("Expected ErrResponseTooLarge, got %v", err)
+	}
+	if n := adapter.waiterCount("srv_test"); n != 0 {
+		t.Errorf("Expected waiter cleanup, %d left", n)
+	}
+}
+
+func TestBotAdapter_MalformedResponse(t *testing.T) {
+	client := newMemoryResponseStreams()
+	client.rawReply = "{not json"
+
+	adapter := NewBotAdapter(client, testLogger())
+	defer adapter.Close()
+
+	start := time.Now()
+	_, err := adapter.SendCommand(context.Background(), "srv_test", protocol.NewMessage(protocol.TypePing, nil), 2*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "failed to parse response") {
+		t.Fatalf("Expected parse error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Parse error reported after %v, caller should not wait for the timeout", elapsed)
+	}
+	if n := adapter.waiterCount("srv_test"); n != 0 {
+		t.Errorf("Expected waiter cleanup, %d left", n)
+	}
+}
+
+func TestResponseEntries_LegacyCommand(t *testing.T) {
+	command := protocol.NewMessage(protocol.TypePing, nil)
+	command.Version = "1.1"
+
+	resp := protocol.NewMessage(protocol.TypePong, nil)
+	resp.Payload = []byte(`"` + strings.Repeat("b", 2*protocol.DefaultChunkSize) + `"`)
+
+	entries, err := ResponseEntries(resp, command.ID, ResponseChunkSize(command))
+	if err != nil {
+		t.Fatalf("ResponseEntries() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Old bot can't reassemble chunks, got %d entries", len(entries))
+	}
+	if _, chunked := entries[0][ChunkSeqField]; chunked {
+		t.Error("Single entry must not carry chunk fields")
+	}
+}
@@ -0,0 +1,87 @@
+package streams
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+// Stream entry fields of multi-part responses
+const (
+	ChunkSeqField   = "chunk_seq"
+	ChunkFinalField = "chunk_final"
+	TotalSizeField  = "total_size"
+)
+
+// ResponseEntries returns the response stream entries carrying response to commandID.
+// The serialized response is split into parts of chunkSize bytes, chunkSize <= 0 sends it whole.
+func ResponseEntries(response *protocol.Message, commandID string, chunkSize int) ([]map[string]string, error) {
+	data, err := response.ToJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize response: %w", err)
+	}
+
+	timestamp := time.Now().Format(time.RFC3339)
+	entry := func(payload []byte) map[string]string {
+		return map[string]string{
+			"type":       string(response.Type),
+			"id":         response.ID,
+			"command_id": commandID, // Link response to original command
+			"payload":    string(payload),
+			"timestamp":  timestamp,
+		}
+	}
+
+	if chunkSize <= 0 || len(data) <= chunkSize {
+		return []map[string]string{entry(data)}, nil
+	}
+
+	chunks := protocol.SplitChunks(data, chunkSize)
+	entries := make([]map[string]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		values := entry(chunk.Data)
+		values[ChunkSeqField] = strconv.Itoa(chunk.Seq)
+		values[ChunkFinalField] = strconv.FormatBool(chunk.Final)
+		values[TotalSizeField] = strconv.Itoa(chunk.TotalSize)
+		entries = append(entries, values)
+	}
+	return entries, nil
+}
+
+// ResponseChunkSize returns the chunk size for responses to command, 0 if its sender can't reassemble them
+func ResponseChunkSize(command *protocol.Message) int {
+	if !protocol.SupportsChunkedResponses(command.Version) {
+		return 0
+	}
+	return protocol.DefaultChunkSize
+}
+
+// parseChunk reads chunk fields of a response entry, false for single-entry responses
+func parseChunk(values map[string]string) (protocol.Chunk, bool, error) {
+	seqValue, ok := values[ChunkSeqField]
+	if !ok {
+		return protocol.Chunk{}, false, nil
+	}
+
+	seq, err := strconv.Atoi(seqValue)
+	if err != nil {
+		return protocol.Chunk{}, true, fmt.Errorf("invalid %s: %q", ChunkSeqField, seqValue)
+	}
+	final, err := strconv.ParseBool(values[ChunkFinalField])
+	if err != nil {
+		return protocol.Chunk{}, true, fmt.Errorf("invalid %s: %q", ChunkFinalField, values[ChunkFinalField])
+	}
+	total, err := strconv.Atoi(values[TotalSizeField])
+	if err != nil {
+		return protocol.Chunk{}, true, fmt.Errorf("invalid %s: %q", TotalSizeField, values[TotalSizeField])
+	}
+
+	return protocol.Chunk{
+		Seq:       seq,
+		Final:     final,
+		TotalSize: total,
+		Data:      []byte(values["payload"]),
+	}, true, nil
+}
@@ -86,7 +86,8 @@ func (c *Client) AddMessage(ctx context.Context, stream string, values map[strin
 	return id, nil
 }
 
-// ReadMessages reads messages from a stream (simple read, no consumer group)
+// ReadMessages reads messages from a stream (simple read, no consumer group).
+// block <= 0 returns immediately instead of blocking forever.
 func (c *Client) ReadMessages(ctx context.Context, stream string, lastID string, count int64, block time.Duration) ([]StreamMessage, error) {
 	if lastID == "" {
 		lastID = "0" // Start from beginning
@@ -99,7 +100,7 @@ func (c *Client) ReadMessages(ctx context.Context, stream string, lastID string,
 	args := &redis.XReadArgs{
 		Streams: []string{stream, lastID},
 		Count:   count,
-		Block:   block,
+		Block:   blockArg(block),
 	}
 
 	streams, err := c.client.XRead(ctx, args).Result()
@@ -132,11 +133,15 @@ func (c *Client) ReadMessages(ctx context.Context, stream string, lastID string,
 	return messages, nil
 }
 
-// CreateConsumerGroup creates a consumer group for a stream
-func (c *Client) CreateConsumerGroup(ctx context.Context, stream, group string) error {
-	// Create group starting from the beginning ("0")
+// CreateConsumerGroup creates a consumer group for a stream.
+// startID "0" delivers the whole stream, "$" only new messages; empty means "0".
+func (c *Client) CreateConsumerGroup(ctx context.Context, stream, group, startID string) error {
+	if startID == "" {
+		startID = "0"
+	}
+
 	// Use MKSTREAM to create the stream if it doesn't exist
-	err := c.client.XGroupCreateMkStream(ctx, stream, group, "0").Err()
+	err := c.client.XGroupCreateMkStream(ctx, stream, group, startID).Err()
 	if err != nil {
 		// Ignore error if group already exists
 		if err.Error() == "BUSYGROUP Consumer Group name already exists" {
@@ -168,7 +173,7 @@ func (c *Client) ReadGroupMessages(ctx context.Context, stream, group, consumer
 		Consumer: consumer,
 		Streams:  []string{stream, ">"}, // ">" means only new messages
 		Count:    count,
-		Block:    block,
+		Block:    blockArg(block),
 	}
 
 	streams, err := c.client.XReadGroup(ctx, args).Result()
@@ -229,6 +234,71 @@ func (c *Client) AckMessage(ctx context.Context, stream, group, messageID string
 	return nil
 }
 
+// PendingMessages returns delivered but unacknowledged messages idle for at least minIdle
+func (c *Client) PendingMessages(ctx context.Context, stream, group string, minIdle time.Duration, count int64) ([]PendingMessage, error) {
+	if count <= 0 {
+		count = c.config.BatchSize
+	}
+
+	// XPENDING stream group IDLE ms - + count
+	pending, err := c.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  group,
+		Idle:   minIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  count,
+	}).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("XPENDING failed: %w", err)
+	}
+
+	result := make([]PendingMessage, 0, len(pending))
+	for _, p := range pending {
+		result = append(result, PendingMessage{
+			ID:         p.ID,
+			Consumer:   p.Consumer,
+			Idle:       p.Idle,
+			RetryCount: p.RetryCount,
+		})
+	}
+
+	return result, nil
+}
+
+// ClaimMessages transfers ownership of pending messages idle for at least minIdle to consumer
+func (c *Client) ClaimMessages(ctx context.Context, stream, group, consumer string, minIdle time.Duration, ids []string) ([]StreamMessage, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	claimed, err := c.client.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   stream,
+		Group:    group,
+		Consumer: consumer,
+		MinIdle:  minIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("XCLAIM failed: %w", err)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"stream":   stream,
+		"group":    group,
+		"consumer": consumer,
+		"count":    len(claimed),
+	}).Debug("Pending messages claimed")
+
+	return convertMessages(stream, claimed), nil
+}
+
 // TrimStream trims a stream to a maximum length
 func (c *Client) TrimStream(ctx context.Context, stream string, maxLen int64) error {
 	// Use approximate trimming (~) for better performance
@@ -254,6 +324,70 @@ func (c *Client) GetStreamLength(ctx context.Context, stream string) (int64, err
 	return length, nil
 }
 
+// RangeMessages returns messages between start and end IDs (XRANGE), "-" and "+" mean the whole stream
+func (c *Client) RangeMessages(ctx context.Context, stream, start, end string, count int64) ([]StreamMessage, error) {
+	if start == "" {
+		start = "-"
+	}
+	if end == "" {
+		end = "+"
+	}
+
+	var (
+		messages []redis.XMessage
+		err      error
+	)
+	if count > 0 {
+		messages, err = c.client.XRangeN(ctx, stream, start, end, count).Result()
+	} else {
+		messages, err = c.client.XRange(ctx, stream, start, end).Result()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("XRANGE failed: %w", err)
+	}
+
+	return convertMessages(stream, messages), nil
+}
+
+// LastMessageID returns the ID of the newest message, "0" for an empty stream
+func (c *Client) LastMessageID(ctx context.Context, stream string) (string, error) {
+	messages, err := c.client.XRevRangeN(ctx, stream, "+", "-", 1).Result()
+	if err != nil {
+		return "", fmt.Errorf("XREVRANGE failed: %w", err)
+	}
+	if len(messages) == 0 {
+		return "0", nil
+	}
+	return messages[0].ID, nil
+}
+
+// blockArg maps non-positive block to -1 so go-redis omits BLOCK (BLOCK 0 waits forever)
+func blockArg(block time.Duration) time.Duration {
+	if block <= 0 {
+		return -1
+	}
+	return block
+}
+
+// convertMessages converts go-redis messages to StreamMessage
+func convertMessages(stream string, messages []redis.XMessage) []StreamMessage {
+	result := make([]StreamMessage, 0, len(messages))
+	for _, msg := range messages {
+		values := make(map[string]string)
+		for k, v := range msg.Values {
+			if str, ok := v.(string); ok {
+				values[k] = str
+			}
+		}
+		result = append(result, StreamMessage{
+			ID:     msg.ID,
+			Values: values,
+			Stream: stream,
+		})
+	}
+	return result
+}
+
 // Ping checks if Redis connection is alive
 func (c *Client) Ping(ctx context.Context) error {
 	return c.client.Ping(ctx).Err()
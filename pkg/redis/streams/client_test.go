@@ -0,0 +1,40 @@
+package streams_test
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/servereye/servereye/pkg/redis/streams"
+	"github.com/servereye/servereye/pkg/redis/streams/streamtest"
+	"github.com/sirupsen/logrus"
+)
+
+// testRedisAddr returns the Redis address for integration tests or skips the test
+func testRedisAddr(t *testing.T) string {
+	addr := os.Getenv("SERVEREYE_TEST_REDIS")
+	if addr == "" {
+		t.Skip("Requires Redis server, set SERVEREYE_TEST_REDIS=host:port")
+	}
+	return addr
+}
+
+func TestClientConformance(t *testing.T) {
+	addr := testRedisAddr(t)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	config := streams.DefaultConfig()
+	config.Addr = addr
+	client, err := streams.NewClient(config, logger)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	streamtest.Run(t, func(t *testing.T) (streams.StreamClient, string) {
+		return client, "stream:test:" + uuid.NewString()
+	})
+}
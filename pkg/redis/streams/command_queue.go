@@ -0,0 +1,71 @@
+package streams
+
+import (
+	"context"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+// commandQueueSize is how many commands may wait while one is running
+const commandQueueSize = 100
+
+// CommandQueue runs commands one at a time in arrival order on its own goroutine,
+// so the reader keeps receiving while a long command runs.
+// Cancel commands bypass the queue: they have to reach the command they stop.
+type CommandQueue struct {
+	ctx  context.Context
+	jobs chan func()
+	done chan struct{} // closed when the worker exits
+}
+
+// NewCommandQueue starts a queue that stops when ctx is done, queued jobs are dropped then
+func NewCommandQueue(ctx context.Context) *CommandQueue {
+	q := &CommandQueue{
+		ctx:  ctx,
+		jobs: make(chan func(), commandQueueSize),
+		done: make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// Submit runs job right away for cancel commands and queues it otherwise.
+// Blocks while the queue is full; returns false if the queue stopped.
+// Must not be called after Close.
+func (q *CommandQueue) Submit(msgType protocol.MessageType, job func()) bool {
+	if msgType == protocol.TypeCancelCommand {
+		job()
+		return true
+	}
+
+	select {
+	case q.jobs <- job:
+		return true
+	case <-q.ctx.Done():
+		return false
+	}
+}
+
+// Close stops accepting jobs and waits for the worker to exit. With a live ctx the queued
+// jobs run first; once ctx is done only the running job finishes and the queued ones are
+// dropped: they aren't acknowledged, so they are delivered again.
+func (q *CommandQueue) Close() {
+	close(q.jobs)
+	<-q.done
+}
+
+func (q *CommandQueue) run() {
+	defer close(q.done)
+
+	for {
+		select {
+		case job, ok := <-q.jobs:
+			if !ok || q.ctx.Err() != nil {
+				return
+			}
+			job()
+		case <-q.ctx.Done():
+			return
+		}
+	}
+}
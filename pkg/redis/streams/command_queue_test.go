@@ -0,0 +1,110 @@
+package streams
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+func TestCommandQueue_RunsInOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	queue := NewCommandQueue(ctx)
+
+	done := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		i := i
+		queue.Submit(protocol.TypePing, func() { done <- i })
+	}
+
+	for want := 0; want < 3; want++ {
+		select {
+		case got := <-done:
+			if got != want {
+				t.Fatalf("Job %d ran before job %d", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Queued job did not run")
+		}
+	}
+}
+
+func TestCommandQueue_CancelBypassesBusyWorker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	queue := NewCommandQueue(ctx)
+
+	release := make(chan struct{})
+	queue.Submit(protocol.TypeCreateContainer, func() { <-release })
+	defer close(release)
+
+	ran := false
+	queue.Submit(protocol.TypeCancelCommand, func() { ran = true })
+	if !ran {
+		t.Error("Cancel command must run while another command is running")
+	}
+}
+
+func TestCommandQueue_Stopped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	queue := NewCommandQueue(ctx)
+
+	release := make(chan struct{})
+	defer close(release)
+	queue.Submit(protocol.TypePing, func() { <-release })
+	for i := 0; i < commandQueueSize; i++ {
+		queue.Submit(protocol.TypePing, func() {})
+	}
+	cancel()
+
+	if queue.Submit(protocol.TypePing, func() {}) {
+		t.Error("Submit to a stopped full queue must return false")
+	}
+}
+
+func TestCommandQueue_CloseRunsQueuedJobs(t *testing.T) {
+	queue := NewCommandQueue(context.Background())
+
+	finished := 0
+	for i := 0; i < 3; i++ {
+		queue.Submit(protocol.TypePing, func() {
+			time.Sleep(10 * time.Millisecond)
+			finished++
+		})
+	}
+	queue.Close()
+
+	if finished != 3 {
+		t.Errorf("Close returned with %d of 3 jobs finished", finished)
+	}
+}
+
+func TestCommandQueue_CloseAfterCancelDropsQueuedJobs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	queue := NewCommandQueue(ctx)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	runningDone := false
+	queue.Submit(protocol.TypePing, func() {
+		close(started)
+		<-release
+		runningDone = true
+	})
+	<-started
+
+	queuedRan := false
+	queue.Submit(protocol.TypePing, func() { queuedRan = true })
+	cancel()
+	close(release)
+	queue.Close()
+
+	if !runningDone {
+		t.Error("Close must wait for the running job")
+	}
+	if queuedRan {
+		t.Error("Queued job must be dropped once ctx is done")
+	}
+}
@@ -4,24 +4,47 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/servereye/servereye/pkg/auth"
 	"github.com/sirupsen/logrus"
 )
 
+// ErrUnsupported is returned when the bot API has no such streams endpoint (older bot)
+var ErrUnsupported = errors.New("streams API not supported by server")
+
 // HTTPStreamClient implements StreamClient over HTTP
 type HTTPStreamClient struct {
 	baseURL    string
+	secretKey  string
 	httpClient *http.Client
 	logger     *logrus.Logger
 }
 
-// NewHTTPStreamClient creates HTTP-based streams client
-func NewHTTPStreamClient(baseURL string, logger *logrus.Logger) *HTTPStreamClient {
+// httpMessage is the JSON shape of a stream entry returned by the bot API
+type httpMessage struct {
+	ID     string
+	Values map[string]interface{}
+}
+
+// httpStreamsResponse is the XREAD/XREADGROUP reply shape
+type httpStreamsResponse struct {
+	Streams []struct {
+		Stream   string
+		Messages []httpMessage
+	}
+}
+
+// NewHTTPStreamClient creates HTTP-based streams client, requests are signed with secretKey
+func NewHTTPStreamClient(baseURL, secretKey string, logger *logrus.Logger) *HTTPStreamClient {
 	return &HTTPStreamClient{
-		baseURL: baseURL,
+		baseURL:   baseURL,
+		secretKey: secretKey,
 		httpClient: &http.Client{
 			Timeout: 35 * time.Second, // Longer for blocking reads
 		},
@@ -56,44 +79,27 @@ func (c *HTTPStreamClient) ReadMessages(ctx context.Context, stream string, last
 		"block_ms": block.Milliseconds(),
 	}
 
-	var resp struct {
-		Streams []struct {
-			Stream   string
-			Messages []struct {
-				ID     string
-				Values map[string]interface{}
-			}
-		}
-	}
-
+	var resp httpStreamsResponse
 	if err := c.doRequest(ctx, "/api/streams/xread", req, &resp); err != nil {
 		return nil, err
 	}
 
-	var messages []StreamMessage
-	for _, s := range resp.Streams {
-		for _, msg := range s.Messages {
-			values := make(map[string]string)
-			for k, v := range msg.Values {
-				if str, ok := v.(string); ok {
-					values[k] = str
-				}
-			}
-			messages = append(messages, StreamMessage{
-				ID:     msg.ID,
-				Values: values,
-				Stream: s.Stream,
-			})
-		}
+	return resp.messages(), nil
+}
+
+// CreateConsumerGroup creates consumer group via HTTP
+func (c *HTTPStreamClient) CreateConsumerGroup(ctx context.Context, stream, group, startID string) error {
+	req := map[string]interface{}{
+		"stream":   stream,
+		"group":    group,
+		"start_id": startID,
 	}
 
-	return messages, nil
-}
+	var resp struct {
+		Success bool `json:"success"`
+	}
 
-// CreateConsumerGroup creates group via HTTP (not implemented in HTTP API yet)
-func (c *HTTPStreamClient) CreateConsumerGroup(ctx context.Context, stream, group string) error {
-	c.logger.Warn("CreateConsumerGroup not implemented in HTTP API")
-	return nil
+	return c.doRequest(ctx, "/api/streams/xgroup", req, &resp)
 }
 
 // ReadGroupMessages reads from consumer group via HTTP
@@ -106,38 +112,12 @@ func (c *HTTPStreamClient) ReadGroupMessages(ctx context.Context, stream, group,
 		"block_ms": block.Milliseconds(),
 	}
 
-	var resp struct {
-		Streams []struct {
-			Stream   string
-			Messages []struct {
-				ID     string
-				Values map[string]interface{}
-			}
-		}
-	}
-
+	var resp httpStreamsResponse
 	if err := c.doRequest(ctx, "/api/streams/xreadgroup", req, &resp); err != nil {
 		return nil, err
 	}
 
-	var messages []StreamMessage
-	for _, s := range resp.Streams {
-		for _, msg := range s.Messages {
-			values := make(map[string]string)
-			for k, v := range msg.Values {
-				if str, ok := v.(string); ok {
-					values[k] = str
-				}
-			}
-			messages = append(messages, StreamMessage{
-				ID:     msg.ID,
-				Values: values,
-				Stream: s.Stream,
-			})
-		}
-	}
-
-	return messages, nil
+	return resp.messages(), nil
 }
 
 // AckMessage acknowledges message via HTTP
@@ -155,19 +135,141 @@ func (c *HTTPStreamClient) AckMessage(ctx context.Context, stream, group, messag
 	return c.doRequest(ctx, "/api/streams/xack", req, &resp)
 }
 
-// TrimStream trims stream (not implemented)
+// PendingMessages lists unacknowledged messages via HTTP
+func (c *HTTPStreamClient) PendingMessages(ctx context.Context, stream, group string, minIdle time.Duration, count int64) ([]PendingMessage, error) {
+	req := map[string]interface{}{
+		"stream":  stream,
+		"group":   group,
+		"idle_ms": minIdle.Milliseconds(),
+		"count":   count,
+	}
+
+	var resp struct {
+		Pending []struct {
+			ID         string `json:"id"`
+			Consumer   string `json:"consumer"`
+			IdleMs     int64  `json:"idle_ms"`
+			RetryCount int64  `json:"retry_count"`
+		} `json:"pending"`
+	}
+
+	if err := c.doRequest(ctx, "/api/streams/xpending", req, &resp); err != nil {
+		return nil, err
+	}
+
+	pending := make([]PendingMessage, 0, len(resp.Pending))
+	for _, p := range resp.Pending {
+		pending = append(pending, PendingMessage{
+			ID:         p.ID,
+			Consumer:   p.Consumer,
+			Idle:       time.Duration(p.IdleMs) * time.Millisecond,
+			RetryCount: p.RetryCount,
+		})
+	}
+
+	return pending, nil
+}
+
+// ClaimMessages claims pending messages via HTTP
+func (c *HTTPStreamClient) ClaimMessages(ctx context.Context, stream, group, consumer string, minIdle time.Duration, ids []string) ([]StreamMessage, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	req := map[string]interface{}{
+		"stream":      stream,
+		"group":       group,
+		"consumer":    consumer,
+		"min_idle_ms": minIdle.Milliseconds(),
+		"ids":         ids,
+	}
+
+	var resp struct {
+		Messages []httpMessage
+	}
+
+	if err := c.doRequest(ctx, "/api/streams/xclaim", req, &resp); err != nil {
+		return nil, err
+	}
+
+	return convertHTTPMessages(stream, resp.Messages), nil
+}
+
+// TrimStream trims stream via HTTP
 func (c *HTTPStreamClient) TrimStream(ctx context.Context, stream string, maxLen int64) error {
-	return nil
+	req := map[string]interface{}{
+		"stream":  stream,
+		"max_len": maxLen,
+	}
+
+	var resp struct {
+		Success bool `json:"success"`
+	}
+
+	return c.doRequest(ctx, "/api/streams/xtrim", req, &resp)
 }
 
-// GetStreamLength gets stream length (not implemented)
+// GetStreamLength gets stream length via HTTP
 func (c *HTTPStreamClient) GetStreamLength(ctx context.Context, stream string) (int64, error) {
-	return 0, nil
+	req := map[string]interface{}{
+		"stream": stream,
+	}
+
+	var resp struct {
+		Length int64 `json:"length"`
+	}
+
+	if err := c.doRequest(ctx, "/api/streams/xlen", req, &resp); err != nil {
+		return 0, err
+	}
+
+	return resp.Length, nil
 }
 
-// Ping checks connection
+// RangeMessages reads stream range via HTTP
+func (c *HTTPStreamClient) RangeMessages(ctx context.Context, stream, start, end string, count int64) ([]StreamMessage, error) {
+	req := map[string]interface{}{
+		"stream": stream,
+		"start":  start,
+		"end":    end,
+		"count":  count,
+	}
+
+	var resp struct {
+		Messages []httpMessage
+	}
+
+	if err := c.doRequest(ctx, "/api/streams/xrange", req, &resp); err != nil {
+		return nil, err
+	}
+
+	return convertHTTPMessages(stream, resp.Messages), nil
+}
+
+// LastMessageID returns the newest message ID via HTTP
+func (c *HTTPStreamClient) LastMessageID(ctx context.Context, stream string) (string, error) {
+	req := map[string]interface{}{
+		"stream": stream,
+	}
+
+	var resp struct {
+		ID string `json:"id"`
+	}
+
+	if err := c.doRequest(ctx, "/api/streams/xlast", req, &resp); err != nil {
+		return "", err
+	}
+
+	return resp.ID, nil
+}
+
+// Ping checks that the bot API and its Redis are reachable and credentials are accepted
 func (c *HTTPStreamClient) Ping(ctx context.Context) error {
-	return nil
+	var resp struct {
+		Success bool `json:"success"`
+	}
+
+	return c.doRequest(ctx, "/api/streams/ping", map[string]interface{}{}, &resp)
 }
 
 // doRequest performs HTTP request
@@ -183,6 +285,9 @@ func (c *HTTPStreamClient) doRequest(ctx context.Context, endpoint string, reqBo
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	if err := auth.SignRequest(req, c.secretKey, data); err != nil {
+		return err
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -190,7 +295,15 @@ func (c *HTTPStreamClient) doRequest(ctx context.Context, endpoint string, reqBo
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrUnsupported, endpoint)
+	}
+
 	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if msg := strings.TrimSpace(string(body)); msg != "" {
+			return fmt.Errorf("HTTP error: %d: %s", resp.StatusCode, msg)
+		}
 		return fmt.Errorf("HTTP error: %d", resp.StatusCode)
 	}
 
@@ -202,3 +315,31 @@ func (c *HTTPStreamClient) doRequest(ctx context.Context, endpoint string, reqBo
 
 	return nil
 }
+
+// messages flattens XREAD reply into StreamMessage list
+func (r httpStreamsResponse) messages() []StreamMessage {
+	var messages []StreamMessage
+	for _, s := range r.Streams {
+		messages = append(messages, convertHTTPMessages(s.Stream, s.Messages)...)
+	}
+	return messages
+}
+
+// convertHTTPMessages converts JSON stream entries to StreamMessage
+func convertHTTPMessages(stream string, messages []httpMessage) []StreamMessage {
+	result := make([]StreamMessage, 0, len(messages))
+	for _, msg := range messages {
+		values := make(map[string]string)
+		for k, v := range msg.Values {
+			if str, ok := v.(string); ok {
+				values[k] = str
+			}
+		}
+		result = append(result, StreamMessage{
+			ID:     msg.ID,
+			Values: values,
+			Stream: stream,
+		})
+	}
+	return result
+}
@@ -13,6 +13,19 @@ type StreamMessage struct {
 	Retries int               // Number of delivery attempts
 }
 
+// PendingMessage describes a message delivered to a consumer but not yet acknowledged
+type PendingMessage struct {
+	ID         string        // Redis stream message ID
+	Consumer   string        // Consumer that currently owns the message
+	Idle       time.Duration // Time since the last delivery
+	RetryCount int64         // Number of times the message was delivered
+}
+
+// DeadLetterStream returns the dead-letter stream name for a server key
+func DeadLetterStream(serverKey string) string {
+	return "stream:dlq:" + serverKey
+}
+
 // StreamClient defines the interface for Redis Streams operations
 type StreamClient interface {
 	// Producer operations
@@ -22,13 +35,19 @@ type StreamClient interface {
 	ReadMessages(ctx context.Context, stream string, lastID string, count int64, block time.Duration) ([]StreamMessage, error)
 
 	// Consumer Group operations
-	CreateConsumerGroup(ctx context.Context, stream, group string) error
+	CreateConsumerGroup(ctx context.Context, stream, group, startID string) error
 	ReadGroupMessages(ctx context.Context, stream, group, consumer string, count int64, block time.Duration) ([]StreamMessage, error)
 	AckMessage(ctx context.Context, stream, group, messageID string) error
 
+	// Recovery of unacknowledged messages
+	PendingMessages(ctx context.Context, stream, group string, minIdle time.Duration, count int64) ([]PendingMessage, error)
+	ClaimMessages(ctx context.Context, stream, group, consumer string, minIdle time.Duration, ids []string) ([]StreamMessage, error)
+
 	// Stream management
 	TrimStream(ctx context.Context, stream string, maxLen int64) error
 	GetStreamLength(ctx context.Context, stream string) (int64, error)
+	RangeMessages(ctx context.Context, stream, start, end string, count int64) ([]StreamMessage, error)
+	LastMessageID(ctx context.Context, stream string) (string, error)
 
 	// Health check
 	Ping(ctx context.Context) error
@@ -42,10 +61,11 @@ type Config struct {
 	DB       int
 
 	// Stream settings
-	MaxRetries      int           // Maximum delivery attempts
-	BlockDuration   time.Duration // How long to block waiting for messages
-	BatchSize       int64         // Number of messages to read at once
-	StreamMaxLength int64         // Maximum stream length (for trimming)
+	MaxRetries        int           // Maximum delivery attempts before dead-lettering
+	VisibilityTimeout time.Duration // Idle time after which a pending message is reclaimed
+	BlockDuration     time.Duration // How long to block waiting for messages
+	BatchSize         int64         // Number of messages to read at once
+	StreamMaxLength   int64         // Maximum stream length (for trimming)
 
 	// Consumer group settings
 	ConsumerGroup string // Consumer group name
@@ -55,11 +75,12 @@ type Config struct {
 // DefaultConfig returns sensible defaults
 func DefaultConfig() *Config {
 	return &Config{
-		MaxRetries:      3,
-		BlockDuration:   5 * time.Second,
-		BatchSize:       10,
-		StreamMaxLength: 1000,
-		ConsumerGroup:   "servereye-consumers",
-		ConsumerName:    "consumer-1",
+		MaxRetries:        3,
+		VisibilityTimeout: 60 * time.Second,
+		BlockDuration:     5 * time.Second,
+		BatchSize:         10,
+		StreamMaxLength:   1000,
+		ConsumerGroup:     "servereye-consumers",
+		ConsumerName:      "consumer-1",
 	}
 }
@@ -0,0 +1,481 @@
+package streams
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryClient implements StreamClient in process memory.
+// Useful for single-instance deployments without Redis and for tests.
+type MemoryClient struct {
+	config *Config
+
+	mu      sync.Mutex
+	streams map[string]*memoryStream
+	lastID  streamID
+	notify  chan struct{} // closed on every XADD to wake blocked readers
+}
+
+// memoryStream is a single stream with its consumer groups
+type memoryStream struct {
+	entries []memoryEntry
+	groups  map[string]*memoryGroup
+}
+
+type memoryEntry struct {
+	id     streamID
+	values map[string]string
+}
+
+// memoryGroup tracks delivery state of a consumer group
+type memoryGroup struct {
+	lastDelivered streamID
+	pending       map[streamID]*memoryPending
+}
+
+type memoryPending struct {
+	consumer    string
+	deliveredAt time.Time
+	deliveries  int64
+}
+
+// streamID is a parsed "<ms>-<seq>" stream entry ID
+type streamID struct {
+	ms  uint64
+	seq uint64
+}
+
+var maxStreamID = streamID{ms: ^uint64(0), seq: ^uint64(0)}
+
+func (id streamID) String() string {
+	return fmt.Sprintf("%d-%d", id.ms, id.seq)
+}
+
+func (id streamID) less(other streamID) bool {
+	return id.ms < other.ms || (id.ms == other.ms && id.seq < other.seq)
+}
+
+// parseStreamID parses "ms-seq" or "ms"; a missing seq means 0 (or max for range ends)
+func parseStreamID(s string, missingSeq uint64) (streamID, error) {
+	msPart, seqPart, hasSeq := strings.Cut(s, "-")
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return streamID{}, fmt.Errorf("invalid stream ID %q", s)
+	}
+	if !hasSeq {
+		return streamID{ms: ms, seq: missingSeq}, nil
+	}
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return streamID{}, fmt.Errorf("invalid stream ID %q", s)
+	}
+	return streamID{ms: ms, seq: seq}, nil
+}
+
+// NewMemoryClient creates an in-memory Streams client
+func NewMemoryClient(config *Config) *MemoryClient {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	return &MemoryClient{
+		config:  config,
+		streams: make(map[string]*memoryStream),
+		notify:  make(chan struct{}),
+	}
+}
+
+// stream returns the named stream, creating it when create is set
+func (c *MemoryClient) stream(name string, create bool) *memoryStream {
+	s, ok := c.streams[name]
+	if !ok && create {
+		s = &memoryStream{groups: make(map[string]*memoryGroup)}
+		c.streams[name] = s
+	}
+	return s
+}
+
+// tail returns the ID of the newest entry
+func (s *memoryStream) tail() streamID {
+	if s == nil || len(s.entries) == 0 {
+		return streamID{}
+	}
+	return s.entries[len(s.entries)-1].id
+}
+
+// after returns up to count entries with ID greater than id
+func (s *memoryStream) after(id streamID, count int64) []memoryEntry {
+	if s == nil {
+		return nil
+	}
+
+	i := sort.Search(len(s.entries), func(i int) bool { return id.less(s.entries[i].id) })
+	end := len(s.entries)
+	if count > 0 && int64(end-i) > count {
+		end = i + int(count)
+	}
+	return s.entries[i:end]
+}
+
+// find returns the entry with the given ID
+func (s *memoryStream) find(id streamID) (memoryEntry, bool) {
+	i := sort.Search(len(s.entries), func(i int) bool { return !s.entries[i].id.less(id) })
+	if i < len(s.entries) && s.entries[i].id == id {
+		return s.entries[i], true
+	}
+	return memoryEntry{}, false
+}
+
+// AddMessage adds a message to a stream
+func (c *MemoryClient) AddMessage(ctx context.Context, stream string, values map[string]string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// IDs are monotonic even if the clock goes backwards
+	id := streamID{ms: uint64(time.Now().UnixMilli())}
+	if !c.lastID.less(id) {
+		id = streamID{ms: c.lastID.ms, seq: c.lastID.seq + 1}
+	}
+	c.lastID = id
+
+	copied := make(map[string]string, len(values))
+	for k, v := range values {
+		copied[k] = v
+	}
+
+	s := c.stream(stream, true)
+	s.entries = append(s.entries, memoryEntry{id: id, values: copied})
+	if c.config.StreamMaxLength > 0 {
+		s.trim(c.config.StreamMaxLength)
+	}
+
+	close(c.notify)
+	c.notify = make(chan struct{})
+
+	return id.String(), nil
+}
+
+// trim keeps only the newest maxLen entries
+func (s *memoryStream) trim(maxLen int64) {
+	if excess := int64(len(s.entries)) - maxLen; excess > 0 {
+		s.entries = append([]memoryEntry(nil), s.entries[excess:]...)
+	}
+}
+
+// waitForMessages runs read until it returns messages, block expires or ctx is done.
+// read is called with c.mu held.
+func (c *MemoryClient) waitForMessages(ctx context.Context, block time.Duration, read func() ([]StreamMessage, error)) ([]StreamMessage, error) {
+	var deadline <-chan time.Time
+	if block > 0 {
+		timer := time.NewTimer(block)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		c.mu.Lock()
+		messages, err := read()
+		notify := c.notify
+		c.mu.Unlock()
+
+		if err != nil || len(messages) > 0 || deadline == nil {
+			return messages, err
+		}
+
+		select {
+		case <-notify:
+		case <-deadline:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// ReadMessages reads messages after lastID, blocking up to block when none are available
+func (c *MemoryClient) ReadMessages(ctx context.Context, stream string, lastID string, count int64, block time.Duration) ([]StreamMessage, error) {
+	if count <= 0 {
+		count = c.config.BatchSize
+	}
+
+	c.mu.Lock()
+	var from streamID
+	var err error
+	switch lastID {
+	case "", "0":
+	case "$":
+		from = c.stream(stream, false).tail()
+	default:
+		from, err = parseStreamID(lastID, 0)
+	}
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.waitForMessages(ctx, block, func() ([]StreamMessage, error) {
+		return toStreamMessages(stream, c.stream(stream, false).after(from, count)), nil
+	})
+}
+
+// CreateConsumerGroup creates a consumer group, existing groups are left untouched
+func (c *MemoryClient) CreateConsumerGroup(ctx context.Context, stream, group, startID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.stream(stream, true)
+	if _, ok := s.groups[group]; ok {
+		return nil
+	}
+
+	var start streamID
+	switch startID {
+	case "", "0":
+	case "$":
+		start = s.tail()
+	default:
+		var err error
+		if start, err = parseStreamID(startID, 0); err != nil {
+			return err
+		}
+	}
+
+	s.groups[group] = &memoryGroup{
+		lastDelivered: start,
+		pending:       make(map[streamID]*memoryPending),
+	}
+	return nil
+}
+
+// group returns a consumer group or a NOGROUP error
+func (c *MemoryClient) group(stream, group string) (*memoryStream, *memoryGroup, error) {
+	s := c.stream(stream, false)
+	if s == nil || s.groups[group] == nil {
+		return nil, nil, fmt.Errorf("NOGROUP no such key '%s' or consumer group '%s'", stream, group)
+	}
+	return s, s.groups[group], nil
+}
+
+// ReadGroupMessages delivers new messages to consumer and marks them pending
+func (c *MemoryClient) ReadGroupMessages(ctx context.Context, stream, group, consumer string, count int64, block time.Duration) ([]StreamMessage, error) {
+	if count <= 0 {
+		count = c.config.BatchSize
+	}
+
+	return c.waitForMessages(ctx, block, func() ([]StreamMessage, error) {
+		s, g, err := c.group(stream, group)
+		if err != nil {
+			return nil, err
+		}
+
+		entries := s.after(g.lastDelivered, count)
+		now := time.Now()
+		for _, e := range entries {
+			g.lastDelivered = e.id
+			g.pending[e.id] = &memoryPending{consumer: consumer, deliveredAt: now, deliveries: 1}
+		}
+		return toStreamMessages(stream, entries), nil
+	})
+}
+
+// AckMessage removes a message from the group's pending list
+func (c *MemoryClient) AckMessage(ctx context.Context, stream, group, messageID string) error {
+	id, err := parseStreamID(messageID, 0)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, g, err := c.group(stream, group)
+	if err != nil {
+		return err
+	}
+	delete(g.pending, id)
+	return nil
+}
+
+// PendingMessages returns pending messages idle for at least minIdle, oldest first
+func (c *MemoryClient) PendingMessages(ctx context.Context, stream, group string, minIdle time.Duration, count int64) ([]PendingMessage, error) {
+	if count <= 0 {
+		count = c.config.BatchSize
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, g, err := c.group(stream, group)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]streamID, 0, len(g.pending))
+	for id := range g.pending {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].less(ids[j]) })
+
+	now := time.Now()
+	var result []PendingMessage
+	for _, id := range ids {
+		p := g.pending[id]
+		idle := now.Sub(p.deliveredAt)
+		if idle < minIdle {
+			continue
+		}
+		result = append(result, PendingMessage{
+			ID:         id.String(),
+			Consumer:   p.consumer,
+			Idle:       idle,
+			RetryCount: p.deliveries,
+		})
+		if int64(len(result)) == count {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// ClaimMessages transfers idle pending messages to consumer
+func (c *MemoryClient) ClaimMessages(ctx context.Context, stream, group, consumer string, minIdle time.Duration, ids []string) ([]StreamMessage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, g, err := c.group(stream, group)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var claimed []memoryEntry
+	for _, raw := range ids {
+		id, err := parseStreamID(raw, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		p, ok := g.pending[id]
+		if !ok || now.Sub(p.deliveredAt) < minIdle {
+			continue
+		}
+
+		entry, ok := s.find(id)
+		if !ok {
+			// Entry was trimmed, like Redis 7 drop it from the pending list
+			delete(g.pending, id)
+			continue
+		}
+
+		p.consumer = consumer
+		p.deliveredAt = now
+		p.deliveries++
+		claimed = append(claimed, entry)
+	}
+
+	return toStreamMessages(stream, claimed), nil
+}
+
+// TrimStream trims a stream to exactly maxLen newest messages
+func (c *MemoryClient) TrimStream(ctx context.Context, stream string, maxLen int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if s := c.stream(stream, false); s != nil {
+		s.trim(maxLen)
+	}
+	return nil
+}
+
+// GetStreamLength returns the number of messages in a stream
+func (c *MemoryClient) GetStreamLength(ctx context.Context, stream string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if s := c.stream(stream, false); s != nil {
+		return int64(len(s.entries)), nil
+	}
+	return 0, nil
+}
+
+// RangeMessages returns messages between start and end IDs inclusive
+func (c *MemoryClient) RangeMessages(ctx context.Context, stream, start, end string, count int64) ([]StreamMessage, error) {
+	from, to := streamID{}, maxStreamID
+	var err error
+	if start != "" && start != "-" {
+		if from, err = parseStreamID(start, 0); err != nil {
+			return nil, err
+		}
+	}
+	if end != "" && end != "+" {
+		if to, err = parseStreamID(end, ^uint64(0)); err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.stream(stream, false)
+	if s == nil {
+		return nil, nil
+	}
+
+	var entries []memoryEntry
+	for _, e := range s.entries {
+		if e.id.less(from) || to.less(e.id) {
+			continue
+		}
+		entries = append(entries, e)
+		if count > 0 && int64(len(entries)) == count {
+			break
+		}
+	}
+
+	return toStreamMessages(stream, entries), nil
+}
+
+// LastMessageID returns the ID of the newest message, "0" for an empty stream
+func (c *MemoryClient) LastMessageID(ctx context.Context, stream string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.stream(stream, false)
+	if s == nil || len(s.entries) == 0 {
+		return "0", nil
+	}
+	return s.tail().String(), nil
+}
+
+// Ping always succeeds for the in-memory client
+func (c *MemoryClient) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close releases nothing, present for symmetry with Client
+func (c *MemoryClient) Close() error {
+	return nil
+}
+
+// toStreamMessages copies entries so callers can't mutate stored values
+func toStreamMessages(stream string, entries []memoryEntry) []StreamMessage {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	result := make([]StreamMessage, 0, len(entries))
+	for _, e := range entries {
+		values := make(map[string]string, len(e.values))
+		for k, v := range e.values {
+			values[k] = v
+		}
+		result = append(result, StreamMessage{ID: e.id.String(), Values: values, Stream: stream})
+	}
+	return result
+}
@@ -0,0 +1,61 @@
+package streams_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/servereye/servereye/pkg/redis/streams"
+	"github.com/servereye/servereye/pkg/redis/streams/streamtest"
+)
+
+func TestMemoryClientConformance(t *testing.T) {
+	streamtest.Run(t, func(t *testing.T) (streams.StreamClient, string) {
+		return streams.NewMemoryClient(nil), "stream:cmd:test"
+	})
+}
+
+func TestMemoryClient_MaxLength(t *testing.T) {
+	client := streams.NewMemoryClient(&streams.Config{StreamMaxLength: 3, BatchSize: 10})
+	ctx := context.Background()
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		id, err := client.AddMessage(ctx, "stream:resp:test", map[string]string{"n": fmt.Sprint(i)})
+		if err != nil {
+			t.Fatalf("AddMessage() error = %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	messages, err := client.ReadMessages(ctx, "stream:resp:test", "0", 10, 0)
+	if err != nil {
+		t.Fatalf("ReadMessages() error = %v", err)
+	}
+	if len(messages) != 3 || messages[0].ID != ids[2] {
+		t.Errorf("Expected 3 newest messages starting at %s, got %v", ids[2], messages)
+	}
+}
+
+func TestMemoryClient_BlockingReadCanceled(t *testing.T) {
+	client := streams.NewMemoryClient(nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.ReadMessages(ctx, "stream:cmd:test", "$", 1, 10*time.Second)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context deadline error, got %v", err)
+	}
+}
+
+func TestMemoryClient_ReadGroupWithoutGroup(t *testing.T) {
+	client := streams.NewMemoryClient(nil)
+
+	_, err := client.ReadGroupMessages(context.Background(), "stream:cmd:test", "missing", "c1", 1, 0)
+	if err == nil || !strings.HasPrefix(err.Error(), "NOGROUP") {
+		t.Errorf("Expected NOGROUP error, got %v", err)
+	}
+}
@@ -0,0 +1,341 @@
+// Package streamtest provides a conformance suite for streams.StreamClient implementations.
+package streamtest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/servereye/servereye/pkg/redis/streams"
+)
+
+// Factory returns a client and the name of an empty stream the client may use
+type Factory func(t *testing.T) (streams.StreamClient, string)
+
+// Run checks that a StreamClient behaves like Redis Streams.
+// Every subtest gets its own client and stream from newClient.
+func Run(t *testing.T, newClient Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, client streams.StreamClient, stream string)
+	}{
+		{"AddAndRead", testAddAndRead},
+		{"NonBlockingEmptyRead", testNonBlockingEmptyRead},
+		{"BlockingRead", testBlockingRead},
+		{"LengthRangeAndLastID", testLengthRangeAndLastID},
+		{"Trim", testTrim},
+		{"ConsumerGroup", testConsumerGroup},
+		{"GroupStartsAtEnd", testGroupStartsAtEnd},
+		{"PendingAndClaim", testPendingAndClaim},
+		{"Ping", testPing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, stream := newClient(t)
+			tt.fn(t, client, stream)
+		})
+	}
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func addMessages(t *testing.T, client streams.StreamClient, stream string, n int) []string {
+	t.Helper()
+	ids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		id, err := client.AddMessage(testContext(t), stream, map[string]string{"n": fmt.Sprint(i)})
+		if err != nil {
+			t.Fatalf("AddMessage() error = %v", err)
+		}
+		if id == "" {
+			t.Fatal("AddMessage() returned empty ID")
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func testAddAndRead(t *testing.T, client streams.StreamClient, stream string) {
+	ids := addMessages(t, client, stream, 3)
+
+	messages, err := client.ReadMessages(testContext(t), stream, "0", 10, 0)
+	if err != nil {
+		t.Fatalf("ReadMessages() error = %v", err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("ReadMessages() returned %d messages, want 3", len(messages))
+	}
+	for i, msg := range messages {
+		if msg.ID != ids[i] {
+			t.Errorf("message %d ID = %s, want %s", i, msg.ID, ids[i])
+		}
+		if msg.Values["n"] != fmt.Sprint(i) {
+			t.Errorf("message %d value = %q, want %q", i, msg.Values["n"], fmt.Sprint(i))
+		}
+		if msg.Stream != stream {
+			t.Errorf("message %d stream = %q, want %q", i, msg.Stream, stream)
+		}
+	}
+
+	messages, err = client.ReadMessages(testContext(t), stream, ids[1], 10, 0)
+	if err != nil {
+		t.Fatalf("ReadMessages() after ID error = %v", err)
+	}
+	if len(messages) != 1 || messages[0].ID != ids[2] {
+		t.Errorf("ReadMessages() after %s = %v, want only %s", ids[1], messages, ids[2])
+	}
+
+	messages, err = client.ReadMessages(testContext(t), stream, "0", 2, 0)
+	if err != nil {
+		t.Fatalf("ReadMessages() with count error = %v", err)
+	}
+	if len(messages) != 2 {
+		t.Errorf("ReadMessages() with count 2 returned %d messages", len(messages))
+	}
+}
+
+func testNonBlockingEmptyRead(t *testing.T, client streams.StreamClient, stream string) {
+	start := time.Now()
+	messages, err := client.ReadMessages(testContext(t), stream, "0", 10, 0)
+	if err != nil {
+		t.Fatalf("ReadMessages() error = %v", err)
+	}
+	if len(messages) != 0 {
+		t.Errorf("ReadMessages() on empty stream returned %d messages", len(messages))
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("ReadMessages() with block 0 took %v, must not block", elapsed)
+	}
+}
+
+func testBlockingRead(t *testing.T, client streams.StreamClient, stream string) {
+	lastID, err := client.LastMessageID(testContext(t), stream)
+	if err != nil {
+		t.Fatalf("LastMessageID() error = %v", err)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		client.AddMessage(context.Background(), stream, map[string]string{"late": "yes"})
+	}()
+
+	messages, err := client.ReadMessages(testContext(t), stream, lastID, 10, 3*time.Second)
+	if err != nil {
+		t.Fatalf("ReadMessages() error = %v", err)
+	}
+	if len(messages) != 1 || messages[0].Values["late"] != "yes" {
+		t.Errorf("blocking ReadMessages() = %v, want the late message", messages)
+	}
+}
+
+func testLengthRangeAndLastID(t *testing.T, client streams.StreamClient, stream string) {
+	ctx := testContext(t)
+
+	length, err := client.GetStreamLength(ctx, stream)
+	if err != nil {
+		t.Fatalf("GetStreamLength() error = %v", err)
+	}
+	if length != 0 {
+		t.Errorf("GetStreamLength() of empty stream = %d", length)
+	}
+
+	ids := addMessages(t, client, stream, 3)
+
+	length, err = client.GetStreamLength(ctx, stream)
+	if err != nil {
+		t.Fatalf("GetStreamLength() error = %v", err)
+	}
+	if length != 3 {
+		t.Errorf("GetStreamLength() = %d, want 3", length)
+	}
+
+	lastID, err := client.LastMessageID(ctx, stream)
+	if err != nil {
+		t.Fatalf("LastMessageID() error = %v", err)
+	}
+	if lastID != ids[2] {
+		t.Errorf("LastMessageID() = %s, want %s", lastID, ids[2])
+	}
+
+	messages, err := client.RangeMessages(ctx, stream, "-", "+", 2)
+	if err != nil {
+		t.Fatalf("RangeMessages() error = %v", err)
+	}
+	if len(messages) != 2 || messages[0].ID != ids[0] || messages[1].ID != ids[1] {
+		t.Errorf("RangeMessages(count 2) = %v, want first two messages", messages)
+	}
+
+	messages, err = client.RangeMessages(ctx, stream, ids[1], "+", 0)
+	if err != nil {
+		t.Fatalf("RangeMessages() from ID error = %v", err)
+	}
+	if len(messages) != 2 || messages[0].ID != ids[1] {
+		t.Errorf("RangeMessages(from %s) = %v, want messages from it inclusive", ids[1], messages)
+	}
+}
+
+func testTrim(t *testing.T, client streams.StreamClient, stream string) {
+	addMessages(t, client, stream, 5)
+
+	if err := client.TrimStream(testContext(t), stream, 2); err != nil {
+		t.Fatalf("TrimStream() error = %v", err)
+	}
+
+	// Trimming may be approximate, but never below maxLen
+	length, err := client.GetStreamLength(testContext(t), stream)
+	if err != nil {
+		t.Fatalf("GetStreamLength() error = %v", err)
+	}
+	if length < 2 || length > 5 {
+		t.Errorf("GetStreamLength() after trim = %d, want 2..5", length)
+	}
+}
+
+func testConsumerGroup(t *testing.T, client streams.StreamClient, stream string) {
+	ctx := testContext(t)
+
+	if err := client.CreateConsumerGroup(ctx, stream, "group", "0"); err != nil {
+		t.Fatalf("CreateConsumerGroup() error = %v", err)
+	}
+	// Creating an existing group is not an error
+	if err := client.CreateConsumerGroup(ctx, stream, "group", "0"); err != nil {
+		t.Fatalf("CreateConsumerGroup() for existing group error = %v", err)
+	}
+
+	ids := addMessages(t, client, stream, 2)
+
+	messages, err := client.ReadGroupMessages(ctx, stream, "group", "c1", 10, 0)
+	if err != nil {
+		t.Fatalf("ReadGroupMessages() error = %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("ReadGroupMessages() returned %d messages, want 2", len(messages))
+	}
+
+	// Delivered messages are not delivered again to the group
+	messages, err = client.ReadGroupMessages(ctx, stream, "group", "c2", 10, 0)
+	if err != nil {
+		t.Fatalf("second ReadGroupMessages() error = %v", err)
+	}
+	if len(messages) != 0 {
+		t.Errorf("second ReadGroupMessages() returned %d messages, want 0", len(messages))
+	}
+
+	pending, err := client.PendingMessages(ctx, stream, "group", 0, 10)
+	if err != nil {
+		t.Fatalf("PendingMessages() error = %v", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("PendingMessages() returned %d entries, want 2", len(pending))
+	}
+	if pending[0].ID != ids[0] || pending[0].Consumer != "c1" || pending[0].RetryCount != 1 {
+		t.Errorf("PendingMessages()[0] = %+v, want %s owned by c1 delivered once", pending[0], ids[0])
+	}
+
+	if err := client.AckMessage(ctx, stream, "group", ids[0]); err != nil {
+		t.Fatalf("AckMessage() error = %v", err)
+	}
+
+	pending, err = client.PendingMessages(ctx, stream, "group", 0, 10)
+	if err != nil {
+		t.Fatalf("PendingMessages() after ACK error = %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != ids[1] {
+		t.Errorf("PendingMessages() after ACK = %+v, want only %s", pending, ids[1])
+	}
+}
+
+func testGroupStartsAtEnd(t *testing.T, client streams.StreamClient, stream string) {
+	ctx := testContext(t)
+	addMessages(t, client, stream, 1)
+
+	if err := client.CreateConsumerGroup(ctx, stream, "tail", "$"); err != nil {
+		t.Fatalf("CreateConsumerGroup() error = %v", err)
+	}
+
+	messages, err := client.ReadGroupMessages(ctx, stream, "tail", "c1", 10, 0)
+	if err != nil {
+		t.Fatalf("ReadGroupMessages() error = %v", err)
+	}
+	if len(messages) != 0 {
+		t.Errorf("group created at $ returned %d old messages", len(messages))
+	}
+
+	ids := addMessages(t, client, stream, 1)
+	messages, err = client.ReadGroupMessages(ctx, stream, "tail", "c1", 10, 0)
+	if err != nil {
+		t.Fatalf("ReadGroupMessages() error = %v", err)
+	}
+	if len(messages) != 1 || messages[0].ID != ids[0] {
+		t.Errorf("ReadGroupMessages() = %v, want new message %s", messages, ids[0])
+	}
+}
+
+func testPendingAndClaim(t *testing.T, client streams.StreamClient, stream string) {
+	ctx := testContext(t)
+
+	if err := client.CreateConsumerGroup(ctx, stream, "group", "0"); err != nil {
+		t.Fatalf("CreateConsumerGroup() error = %v", err)
+	}
+	ids := addMessages(t, client, stream, 1)
+
+	if _, err := client.ReadGroupMessages(ctx, stream, "group", "c1", 10, 0); err != nil {
+		t.Fatalf("ReadGroupMessages() error = %v", err)
+	}
+
+	// Not idle long enough yet
+	pending, err := client.PendingMessages(ctx, stream, "group", time.Hour, 10)
+	if err != nil {
+		t.Fatalf("PendingMessages() error = %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("PendingMessages(idle 1h) returned %d entries, want 0", len(pending))
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	pending, err = client.PendingMessages(ctx, stream, "group", 50*time.Millisecond, 10)
+	if err != nil {
+		t.Fatalf("PendingMessages() error = %v", err)
+	}
+	if len(pending) != 1 || pending[0].Idle < 50*time.Millisecond {
+		t.Fatalf("PendingMessages(idle 50ms) = %+v, want one idle entry", pending)
+	}
+
+	claimed, err := client.ClaimMessages(ctx, stream, "group", "c2", 50*time.Millisecond, ids)
+	if err != nil {
+		t.Fatalf("ClaimMessages() error = %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != ids[0] || claimed[0].Values["n"] != "0" {
+		t.Fatalf("ClaimMessages() = %v, want %s with values", claimed, ids[0])
+	}
+
+	pending, err = client.PendingMessages(ctx, stream, "group", 0, 10)
+	if err != nil {
+		t.Fatalf("PendingMessages() after claim error = %v", err)
+	}
+	if len(pending) != 1 || pending[0].Consumer != "c2" || pending[0].RetryCount != 2 {
+		t.Errorf("PendingMessages() after claim = %+v, want owned by c2 delivered twice", pending)
+	}
+
+	// Freshly claimed message is not idle, so it can't be claimed again right away
+	claimed, err = client.ClaimMessages(ctx, stream, "group", "c3", time.Hour, ids)
+	if err != nil {
+		t.Fatalf("second ClaimMessages() error = %v", err)
+	}
+	if len(claimed) != 0 {
+		t.Errorf("second ClaimMessages() claimed %d messages, want 0", len(claimed))
+	}
+}
+
+func testPing(t *testing.T, client streams.StreamClient, stream string) {
+	if err := client.Ping(testContext(t)); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+}
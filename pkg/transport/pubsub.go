@@ -0,0 +1,94 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+	"github.com/servereye/servereye/pkg/redis"
+	"github.com/sirupsen/logrus"
+)
+
+// pingChannel is used to probe Pub/Sub connectivity, nobody subscribes to it
+const pingChannel = "transport:ping"
+
+// PubSubTransport delivers commands over cmd:<key> / resp:<key>:<id> Pub/Sub channels
+type PubSubTransport struct {
+	client PubSubClient
+	logger *logrus.Logger
+}
+
+// NewPubSub creates Pub/Sub transport
+func NewPubSub(client PubSubClient, logger *logrus.Logger) *PubSubTransport {
+	return &PubSubTransport{client: client, logger: logger}
+}
+
+// Name returns transport name
+func (t *PubSubTransport) Name() string {
+	return PubSub
+}
+
+// SendCommand publishes command and waits for the response on its unique channel
+func (t *PubSubTransport) SendCommand(ctx context.Context, serverKey string, command *protocol.Message, timeout time.Duration) (*protocol.Message, error) {
+	data, err := command.ToJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Subscribe before publishing so a fast response can't be missed
+	respChannel := fmt.Sprintf("resp:%s:%s", serverKey, command.ID)
+	sub, err := t.client.Subscribe(ctx, respChannel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+	defer sub.Close()
+
+	if err := t.client.Publish(ctx, redis.GetCommandChannel(serverKey), data); err != nil {
+		return nil, fmt.Errorf("failed to publish: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timeout waiting for response")
+		case respData, ok := <-sub.Channel():
+			if !ok {
+				return nil, fmt.Errorf("response subscription closed")
+			}
+
+			resp, err := protocol.FromJSON(respData)
+			if err != nil {
+				t.logger.WithError(err).Debug("Skipping invalid response")
+				continue
+			}
+			if resp.ID != command.ID {
+				continue
+			}
+			return resp, nil
+		}
+	}
+}
+
+// Subscribe subscribes to a Pub/Sub channel
+func (t *PubSubTransport) Subscribe(ctx context.Context, channel string) (Subscription, error) {
+	return t.client.Subscribe(ctx, channel)
+}
+
+// Publish publishes an event to a Pub/Sub channel
+func (t *PubSubTransport) Publish(ctx context.Context, channel string, data []byte) error {
+	return t.client.Publish(ctx, channel, data)
+}
+
+// Ping publishes to a probe channel to check the connection
+func (t *PubSubTransport) Ping(ctx context.Context) error {
+	return t.client.Publish(ctx, pingChannel, []byte("ping"))
+}
+
+// Close closes the underlying client
+func (t *PubSubTransport) Close() error {
+	return t.client.Close()
+}
@@ -0,0 +1,248 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+	"github.com/sirupsen/logrus"
+)
+
+// Stats counts command deliveries of one transport
+type Stats struct {
+	Sent      int64 // commands answered through this transport
+	Failed    int64 // attempts that returned an error
+	Fallbacks int64 // commands answered here after a preferred transport failed
+}
+
+// Observer is notified about every delivery attempt
+type Observer func(transport string, duration time.Duration, err error)
+
+// Router picks a transport per server and falls back to the next one on failure.
+// It is the only place where transport fallback happens.
+type Router struct {
+	logger     *logrus.Logger
+	transports []Transport // in preference order
+
+	mu        sync.RWMutex
+	available map[string]bool   // result of Negotiate, nil means all
+	servers   map[string]string // explicit per-server transport
+	stats     map[string]*Stats
+	observer  Observer
+}
+
+// NewRouter creates router over transports listed in preference order
+func NewRouter(logger *logrus.Logger, transports ...Transport) *Router {
+	stats := make(map[string]*Stats, len(transports))
+	for _, t := range transports {
+		stats[t.Name()] = &Stats{}
+	}
+
+	return &Router{
+		logger:     logger,
+		transports: transports,
+		servers:    make(map[string]string),
+		stats:      stats,
+	}
+}
+
+// Name returns transport name
+func (r *Router) Name() string {
+	return "router"
+}
+
+// Negotiate probes every transport and keeps only reachable ones as fallback candidates.
+// If none responds all of them stay eligible, the backend may come up later.
+func (r *Router) Negotiate(ctx context.Context) []string {
+	available := make(map[string]bool)
+	var names []string
+
+	for _, t := range r.transports {
+		if err := t.Ping(ctx); err != nil {
+			r.logger.WithError(err).WithField("transport", t.Name()).Warn("Transport unavailable")
+			continue
+		}
+		available[t.Name()] = true
+		names = append(names, t.Name())
+	}
+
+	if len(names) == 0 {
+		r.logger.Warn("No transport responded, keeping all of them")
+		available = nil
+	}
+
+	r.mu.Lock()
+	r.available = available
+	r.mu.Unlock()
+
+	r.logger.WithField("transports", names).Info("Transport negotiation finished")
+	return names
+}
+
+// SetServerTransport pins a server to one transport, commands for it never fall back
+func (r *Router) SetServerTransport(serverKey, name string) error {
+	if r.find(name) == nil {
+		return fmt.Errorf("unknown transport %q", name)
+	}
+
+	r.mu.Lock()
+	r.servers[serverKey] = name
+	r.mu.Unlock()
+	return nil
+}
+
+// SetObserver sets a callback for delivery metrics
+func (r *Router) SetObserver(observer Observer) {
+	r.mu.Lock()
+	r.observer = observer
+	r.mu.Unlock()
+}
+
+// Stats returns a snapshot of delivery counters per transport
+func (r *Router) Stats() map[string]Stats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make(map[string]Stats, len(r.stats))
+	for name, s := range r.stats {
+		result[name] = *s
+	}
+	return result
+}
+
+// find returns a transport by name
+func (r *Router) find(name string) Transport {
+	for _, t := range r.transports {
+		if t.Name() == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// candidates returns transports to try for serverKey, in order
+func (r *Router) candidates(serverKey string) []Transport {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if name, ok := r.servers[serverKey]; ok {
+		return []Transport{r.find(name)}
+	}
+
+	result := make([]Transport, 0, len(r.transports))
+	for _, t := range r.transports {
+		if r.available == nil || r.available[t.Name()] {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// record updates counters and notifies the observer
+func (r *Router) record(name string, duration time.Duration, err error, fallback bool) {
+	r.mu.Lock()
+	s := r.stats[name]
+	switch {
+	case err != nil:
+		s.Failed++
+	case fallback:
+		s.Sent++
+		s.Fallbacks++
+	default:
+		s.Sent++
+	}
+	observer := r.observer
+	r.mu.Unlock()
+
+	if observer != nil {
+		observer(name, duration, err)
+	}
+}
+
+// SendCommand delivers command through the first transport that answers
+func (r *Router) SendCommand(ctx context.Context, serverKey string, command *protocol.Message, timeout time.Duration) (*protocol.Message, error) {
+	var lastErr error
+
+	for i, t := range r.candidates(serverKey) {
+		start := time.Now()
+		resp, err := t.SendCommand(ctx, serverKey, command, timeout)
+		r.record(t.Name(), time.Since(start), err, i > 0)
+		if err == nil {
+			return resp, nil
+		}
+
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"transport":  t.Name(),
+			"command_id": command.ID,
+		}).Warn("Command delivery failed")
+		lastErr = err
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if lastErr == nil {
+		return nil, ErrNoTransport
+	}
+	return nil, lastErr
+}
+
+// Subscribe subscribes through the first transport that accepts the subscription
+func (r *Router) Subscribe(ctx context.Context, channel string) (Subscription, error) {
+	var errs []error
+	for _, t := range r.candidates("") {
+		sub, err := t.Subscribe(ctx, channel)
+		if err == nil {
+			return sub, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", t.Name(), err))
+	}
+	return nil, r.joinErrors(errs)
+}
+
+// Publish publishes through the first transport that accepts the event
+func (r *Router) Publish(ctx context.Context, channel string, data []byte) error {
+	var errs []error
+	for _, t := range r.candidates("") {
+		err := t.Publish(ctx, channel, data)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", t.Name(), err))
+	}
+	return r.joinErrors(errs)
+}
+
+// Ping succeeds if any transport is reachable
+func (r *Router) Ping(ctx context.Context) error {
+	var errs []error
+	for _, t := range r.transports {
+		err := t.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", t.Name(), err))
+	}
+	return r.joinErrors(errs)
+}
+
+// Close closes all transports
+func (r *Router) Close() error {
+	var errs []error
+	for _, t := range r.transports {
+		errs = append(errs, t.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// joinErrors returns ErrNoTransport when nothing was tried
+func (r *Router) joinErrors(errs []error) error {
+	if len(errs) == 0 {
+		return ErrNoTransport
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,126 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeTransport answers commands or fails with err
+type fakeTransport struct {
+	name    string
+	err     error
+	pingErr error
+	calls   int
+}
+
+func (f *fakeTransport) Name() string { return f.name }
+
+func (f *fakeTransport) SendCommand(ctx context.Context, serverKey string, command *protocol.Message, timeout time.Duration) (*protocol.Message, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	resp := protocol.NewMessage(protocol.TypePong, nil)
+	resp.ID = command.ID
+	return resp, nil
+}
+
+func (f *fakeTransport) Subscribe(ctx context.Context, channel string) (Subscription, error) {
+	return nil, f.err
+}
+
+func (f *fakeTransport) Publish(ctx context.Context, channel string, data []byte) error {
+	return f.err
+}
+
+func (f *fakeTransport) Ping(ctx context.Context) error { return f.pingErr }
+
+func (f *fakeTransport) Close() error { return nil }
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func TestRouter_FallbackRecordsStats(t *testing.T) {
+	primary := &fakeTransport{name: Streams, err: errors.New("timeout")}
+	fallback := &fakeTransport{name: PubSub}
+	router := NewRouter(testLogger(), primary, fallback)
+
+	var observed []string
+	router.SetObserver(func(name string, d time.Duration, err error) {
+		observed = append(observed, name)
+	})
+
+	cmd := protocol.NewMessage(protocol.TypePing, nil)
+	resp, err := router.SendCommand(context.Background(), "srv_1", cmd, time.Second)
+	if err != nil {
+		t.Fatalf("SendCommand() error = %v", err)
+	}
+	if resp.ID != cmd.ID {
+		t.Errorf("Response ID = %s, want %s", resp.ID, cmd.ID)
+	}
+
+	stats := router.Stats()
+	if stats[Streams].Failed != 1 || stats[PubSub].Sent != 1 || stats[PubSub].Fallbacks != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if len(observed) != 2 || observed[0] != Streams || observed[1] != PubSub {
+		t.Errorf("Observer saw %v", observed)
+	}
+}
+
+func TestRouter_PinnedServerDoesNotFallBack(t *testing.T) {
+	primary := &fakeTransport{name: Streams}
+	fallback := &fakeTransport{name: PubSub, err: errors.New("down")}
+	router := NewRouter(testLogger(), primary, fallback)
+
+	if err := router.SetServerTransport("srv_legacy", PubSub); err != nil {
+		t.Fatalf("SetServerTransport() error = %v", err)
+	}
+	if err := router.SetServerTransport("srv_x", "carrier-pigeon"); err == nil {
+		t.Error("Expected error for unknown transport")
+	}
+
+	_, err := router.SendCommand(context.Background(), "srv_legacy", protocol.NewMessage(protocol.TypePing, nil), time.Second)
+	if err == nil {
+		t.Fatal("Expected pinned transport error")
+	}
+	if primary.calls != 0 {
+		t.Errorf("Pinned server fell back to %s", Streams)
+	}
+}
+
+func TestRouter_NegotiateSkipsUnavailable(t *testing.T) {
+	primary := &fakeTransport{name: Streams, pingErr: errors.New("unknown command")}
+	fallback := &fakeTransport{name: PubSub}
+	router := NewRouter(testLogger(), primary, fallback)
+
+	names := router.Negotiate(context.Background())
+	if len(names) != 1 || names[0] != PubSub {
+		t.Fatalf("Negotiate() = %v, want [%s]", names, PubSub)
+	}
+
+	if _, err := router.SendCommand(context.Background(), "srv_1", protocol.NewMessage(protocol.TypePing, nil), time.Second); err != nil {
+		t.Fatalf("SendCommand() error = %v", err)
+	}
+	if primary.calls != 0 {
+		t.Error("Unavailable transport was used")
+	}
+}
+
+func TestRouter_NoTransports(t *testing.T) {
+	router := NewRouter(testLogger())
+
+	_, err := router.SendCommand(context.Background(), "srv_1", protocol.NewMessage(protocol.TypePing, nil), time.Second)
+	if !errors.Is(err, ErrNoTransport) {
+		t.Errorf("Expected ErrNoTransport, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+	"github.com/servereye/servereye/pkg/redis/streams"
+	"github.com/sirupsen/logrus"
+)
+
+// StreamsTransport delivers commands over stream:cmd / stream:resp and events as stream entries
+type StreamsTransport struct {
+	client  streams.StreamClient
+	adapter *streams.BotAdapter
+	logger  *logrus.Logger
+}
+
+// NewStreams creates Streams transport over any StreamClient (Redis, in-memory or HTTP)
+func NewStreams(client streams.StreamClient, logger *logrus.Logger) *StreamsTransport {
+	return &StreamsTransport{
+		client:  client,
+		adapter: streams.NewBotAdapter(client, logger),
+		logger:  logger,
+	}
+}
+
+// Name returns transport name
+func (t *StreamsTransport) Name() string {
+	return Streams
+}
+
+// Adapter returns the shared response dispatcher, e.g. for DLQ and stream stats
+func (t *StreamsTransport) Adapter() *streams.BotAdapter {
+	return t.adapter
+}
+
+// SendCommand adds command to the agent stream and waits for the response
+func (t *StreamsTransport) SendCommand(ctx context.Context, serverKey string, command *protocol.Message, timeout time.Duration) (*protocol.Message, error) {
+	return t.adapter.SendCommand(ctx, serverKey, command, timeout)
+}
+
+// Subscribe delivers payloads of entries added to stream channel after the call
+func (t *StreamsTransport) Subscribe(ctx context.Context, channel string) (Subscription, error) {
+	lastID, err := t.client.LastMessageID(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	sub := &streamSubscription{
+		msgChan: make(chan []byte, 100),
+		cancel:  cancel,
+	}
+
+	go func() {
+		defer close(sub.msgChan)
+
+		for ctx.Err() == nil {
+			messages, err := t.client.ReadMessages(ctx, channel, lastID, 100, time.Second)
+			if err != nil {
+				if ctx.Err() == nil {
+					t.logger.WithError(err).WithField("stream", channel).Warn("Stream subscription read failed")
+					time.Sleep(time.Second)
+				}
+				continue
+			}
+
+			for _, msg := range messages {
+				lastID = msg.ID
+				select {
+				case sub.msgChan <- []byte(msg.Values["payload"]):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return sub, nil
+}
+
+// Publish adds an event entry to stream channel
+func (t *StreamsTransport) Publish(ctx context.Context, channel string, data []byte) error {
+	_, err := t.client.AddMessage(ctx, channel, map[string]string{
+		"payload":   string(data),
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+	return err
+}
+
+// Ping checks the Streams backend
+func (t *StreamsTransport) Ping(ctx context.Context) error {
+	return t.client.Ping(ctx)
+}
+
+// Close stops response readers and closes the client
+func (t *StreamsTransport) Close() error {
+	t.adapter.Close()
+	if closer, ok := t.client.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// streamSubscription is a Subscription fed by a stream reader goroutine
+type streamSubscription struct {
+	msgChan   chan []byte
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+}
+
+func (s *streamSubscription) Channel() <-chan []byte {
+	return s.msgChan
+}
+
+func (s *streamSubscription) Close() error {
+	s.closeOnce.Do(s.cancel)
+	return nil
+}
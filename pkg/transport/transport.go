@@ -0,0 +1,81 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+// Transport names
+const (
+	Streams = "streams"
+	PubSub  = "pubsub"
+	Tunnel  = "tunnel"
+)
+
+// ErrNoTransport is returned when no transport can deliver a command
+var ErrNoTransport = errors.New("no transport available")
+
+// Transport delivers commands to agents and events between bot and agents
+type Transport interface {
+	// Name returns the transport name used in logs, metrics and configuration
+	Name() string
+	// SendCommand delivers a command to the agent and waits for its response
+	SendCommand(ctx context.Context, serverKey string, command *protocol.Message, timeout time.Duration) (*protocol.Message, error)
+	// Subscribe receives events published on channel until ctx is done or the subscription is closed
+	Subscribe(ctx context.Context, channel string) (Subscription, error)
+	// Publish sends an event to channel
+	Publish(ctx context.Context, channel string, data []byte) error
+	// Ping checks that the transport is usable, used for negotiation at startup
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// Subscription is a stream of raw messages
+type Subscription interface {
+	Channel() <-chan []byte
+	Close() error
+}
+
+// PubSubClient is a Pub/Sub capable client (Redis, in-memory or HTTP)
+type PubSubClient interface {
+	Subscribe(ctx context.Context, channel string) (Subscription, error)
+	Publish(ctx context.Context, channel string, message []byte) error
+	Close() error
+}
+
+// pubSubSource is a Pub/Sub client returning its own subscription type
+type pubSubSource[S Subscription] interface {
+	Subscribe(ctx context.Context, channel string) (S, error)
+	Publish(ctx context.Context, channel string, message []byte) error
+	Close() error
+}
+
+// wrappedPubSub adapts pubSubSource to PubSubClient
+type wrappedPubSub[S Subscription] struct {
+	client pubSubSource[S]
+}
+
+// WrapPubSub adapts redis.Client, redis.MemoryClient or redis.HTTPClient to PubSubClient
+func WrapPubSub[S Subscription](client pubSubSource[S]) PubSubClient {
+	return &wrappedPubSub[S]{client: client}
+}
+
+func (w *wrappedPubSub[S]) Subscribe(ctx context.Context, channel string) (Subscription, error) {
+	sub, err := w.client.Subscribe(ctx, channel)
+	if err != nil {
+		// Avoid returning a typed nil pointer inside the interface
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (w *wrappedPubSub[S]) Publish(ctx context.Context, channel string, message []byte) error {
+	return w.client.Publish(ctx, channel, message)
+}
+
+func (w *wrappedPubSub[S]) Close() error {
+	return w.client.Close()
+}
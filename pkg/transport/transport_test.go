@@ -0,0 +1,102 @@
+package transport
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+	"github.com/servereye/servereye/pkg/redis"
+	"github.com/servereye/servereye/pkg/redis/streams"
+)
+
+// answerPing replies to commands published on the agent's Pub/Sub channel
+func answerPing(t *testing.T, client PubSubClient, serverKey string) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	sub, err := client.Subscribe(ctx, redis.GetCommandChannel(serverKey))
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	go func() {
+		for data := range sub.Channel() {
+			cmd, err := protocol.FromJSON(data)
+			if err != nil {
+				continue
+			}
+			resp := protocol.NewMessage(protocol.TypePong, nil)
+			resp.ID = cmd.ID
+			payload, _ := resp.ToJSON()
+			client.Publish(ctx, "resp:"+serverKey+":"+cmd.ID, payload)
+		}
+	}()
+}
+
+func TestPubSubTransport_SendCommand(t *testing.T) {
+	client := WrapPubSub(redis.NewMemoryClient(testLogger()))
+	answerPing(t, client, "srv_1")
+
+	tr := NewPubSub(client, testLogger())
+	if err := tr.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+
+	cmd := protocol.NewMessage(protocol.TypePing, nil)
+	resp, err := tr.SendCommand(context.Background(), "srv_1", cmd, time.Second)
+	if err != nil {
+		t.Fatalf("SendCommand() error = %v", err)
+	}
+	if resp.Type != protocol.TypePong || resp.ID != cmd.ID {
+		t.Errorf("Got %s/%s, want %s/%s", resp.Type, resp.ID, protocol.TypePong, cmd.ID)
+	}
+}
+
+func TestPubSubTransport_Timeout(t *testing.T) {
+	tr := NewPubSub(WrapPubSub(redis.NewMemoryClient(testLogger())), testLogger())
+
+	_, err := tr.SendCommand(context.Background(), "srv_silent", protocol.NewMessage(protocol.TypePing, nil), 50*time.Millisecond)
+	if err == nil {
+		t.Fatal("Expected timeout error")
+	}
+}
+
+func TestStreamsTransport_PublishSubscribe(t *testing.T) {
+	tr := NewStreams(streams.NewMemoryClient(nil), testLogger())
+	defer tr.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Entries added before Subscribe are not delivered
+	if err := tr.Publish(ctx, "stream:events:test", []byte("old")); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	sub, err := tr.Subscribe(ctx, "stream:events:test")
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer sub.Close()
+
+	if err := tr.Publish(ctx, "stream:events:test", []byte("new")); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	select {
+	case data := <-sub.Channel():
+		if string(data) != "new" {
+			t.Errorf("Got %q, want new", data)
+		}
+	case <-ctx.Done():
+		t.Fatal("Timeout waiting for event")
+	}
+
+	sub.Close()
+	for range sub.Channel() {
+		// drain until the reader goroutine closes the channel
+	}
+}
@@ -0,0 +1,179 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+// Agent tunnel: a persistent HTTP/1.1 Upgrade connection between agent and bot.
+// After "101 Switching Protocols" both sides exchange newline-delimited JSON frames.
+const (
+	TunnelProtocol = "servereye-tunnel/1"
+	TunnelPath     = "/api/tunnel"
+)
+
+// Tunnel frame types
+const (
+	FrameHello     = "hello"     // agent -> bot, ID is the last processed command entry
+	FrameCommand   = "command"   // bot -> agent, command stream entry
+	FrameResponse  = "response"  // agent -> bot, response stream entry
+	FrameEvent     = "event"     // agent -> bot, Pub/Sub event
+	FrameHeartbeat = "heartbeat" // agent -> bot
+	FramePing      = "ping"
+	FramePong      = "pong"
+	FrameError     = "error" // bot -> agent, the connection is closed after it
+)
+
+// CapabilitiesField is the heartbeat frame value with JSON-encoded protocol.Capabilities
+const CapabilitiesField = "capabilities"
+
+// ErrTunnelClosed is returned when writing to a closed tunnel
+var ErrTunnelClosed = errors.New("tunnel closed")
+
+// TunnelFrame is one message on the tunnel
+type TunnelFrame struct {
+	Type    string            `json:"type"`
+	ID      string            `json:"id,omitempty"`
+	Values  map[string]string `json:"values,omitempty"`
+	Channel string            `json:"channel,omitempty"`
+	Data    string            `json:"data,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// Capabilities returns the capabilities advertised in a heartbeat frame
+func (f TunnelFrame) Capabilities() (*protocol.Capabilities, bool) {
+	data, ok := f.Values[CapabilitiesField]
+	if !ok {
+		return nil, false
+	}
+
+	var caps protocol.Capabilities
+	if err := json.Unmarshal([]byte(data), &caps); err != nil {
+		return nil, false
+	}
+	return &caps, true
+}
+
+// TunnelConn is a framed tunnel connection, Send is safe for concurrent use
+type TunnelConn struct {
+	rwc io.ReadWriteCloser
+	dec *json.Decoder
+
+	writeMu sync.Mutex
+	enc     *json.Encoder
+
+	lastRead  atomic.Int64 // unix nanoseconds of the last received frame
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewTunnelConn wraps an upgraded connection.
+// r reads from rwc, it may be a buffered reader that already holds part of the stream.
+func NewTunnelConn(r io.Reader, rwc io.ReadWriteCloser) *TunnelConn {
+	if r == nil {
+		r = bufio.NewReader(rwc)
+	}
+
+	c := &TunnelConn{
+		rwc:  rwc,
+		dec:  json.NewDecoder(r),
+		enc:  json.NewEncoder(rwc),
+		done: make(chan struct{}),
+	}
+	c.lastRead.Store(time.Now().UnixNano())
+	return c
+}
+
+// Send writes a frame
+func (c *TunnelConn) Send(frame TunnelFrame) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	select {
+	case <-c.done:
+		return ErrTunnelClosed
+	default:
+	}
+
+	if err := c.enc.Encode(frame); err != nil {
+		c.Close()
+		return fmt.Errorf("tunnel write failed: %w", err)
+	}
+	return nil
+}
+
+// Receive returns the next frame, answering pings on the way
+func (c *TunnelConn) Receive() (TunnelFrame, error) {
+	for {
+		var frame TunnelFrame
+		if err := c.dec.Decode(&frame); err != nil {
+			c.Close()
+			return TunnelFrame{}, err
+		}
+		c.lastRead.Store(time.Now().UnixNano())
+
+		switch frame.Type {
+		case FramePing:
+			if err := c.Send(TunnelFrame{Type: FramePong}); err != nil {
+				return TunnelFrame{}, err
+			}
+		case FramePong:
+		default:
+			return frame, nil
+		}
+	}
+}
+
+// KeepAlive pings the peer every interval and closes the tunnel
+// when nothing was received for three intervals. Returns when the tunnel closes.
+func (c *TunnelConn) KeepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if time.Since(time.Unix(0, c.lastRead.Load())) > 3*interval {
+				c.Close()
+				return
+			}
+			// A write stuck on a dead peer must not block the watchdog, Close unblocks it
+			go c.Send(TunnelFrame{Type: FramePing})
+		}
+	}
+}
+
+// Done is closed when the tunnel is closed
+func (c *TunnelConn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close closes the underlying connection
+func (c *TunnelConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		err = c.rwc.Close()
+	})
+	return err
+}
+
+// closeOnCancel closes the tunnel when ctx is done
+func (c *TunnelConn) closeOnCancel(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		c.Close()
+	case <-c.done:
+	}
+}
@@ -0,0 +1,274 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/servereye/servereye/pkg/auth"
+	"github.com/servereye/servereye/pkg/protocol"
+	"github.com/servereye/servereye/pkg/redis/streams"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	tunnelKeepAlive  = 30 * time.Second
+	tunnelMinBackoff = time.Second
+	tunnelMaxBackoff = 30 * time.Second
+)
+
+var (
+	// ErrTunnelUnsupported is returned when the bot has no tunnel endpoint (older bot)
+	ErrTunnelUnsupported = errors.New("tunnel not supported by server")
+	// ErrTunnelDisconnected is returned when sending while the tunnel is down
+	ErrTunnelDisconnected = errors.New("tunnel not connected")
+)
+
+// HTTPConfig configuration of the bot HTTP API connection
+type HTTPConfig struct {
+	BaseURL   string
+	SecretKey string // used to sign requests
+	Timeout   time.Duration
+}
+
+// TunnelClient keeps a persistent tunnel to the bot: commands are pushed to the agent,
+// responses, events and heartbeats go back over the same connection
+type TunnelClient struct {
+	config     HTTPConfig
+	logger     *logrus.Logger
+	httpClient *http.Client
+
+	mu   sync.RWMutex
+	conn *TunnelConn
+}
+
+// NewTunnelClient creates tunnel client, call Run to connect
+func NewTunnelClient(config HTTPConfig, logger *logrus.Logger) *TunnelClient {
+	return &TunnelClient{
+		config: config,
+		logger: logger,
+		// No client timeout: the upgraded connection lives until either side closes it
+		httpClient: &http.Client{},
+	}
+}
+
+// Probe checks that the bot serves the tunnel, returns ErrTunnelUnsupported if not
+func (c *TunnelClient) Probe(ctx context.Context) error {
+	if c.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.Timeout)
+		defer cancel()
+	}
+
+	req, err := c.newRequest(ctx)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Without Upgrade header the bot answers 426 once the request is authenticated
+	switch resp.StatusCode {
+	case http.StatusUpgradeRequired:
+		return nil
+	case http.StatusNotFound:
+		return ErrTunnelUnsupported
+	default:
+		return statusError(resp)
+	}
+}
+
+// Run connects and serves the tunnel until ctx is done, reconnecting with backoff.
+// resumeID returns the last processed command entry, handle is called for every command.
+func (c *TunnelClient) Run(ctx context.Context, resumeID func() string, handle func(streams.StreamMessage)) error {
+	backoff := tunnelMinBackoff
+
+	for {
+		conn, err := c.connect(ctx, resumeID())
+		if err == nil {
+			c.logger.Info("Tunnel connected")
+			connected := time.Now()
+			err = c.serve(ctx, conn, handle)
+
+			// Only a connection that stayed up resets backoff, a flapping bot is not hammered
+			if time.Since(connected) > tunnelMaxBackoff {
+				backoff = tunnelMinBackoff
+			}
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, ErrTunnelUnsupported) {
+			return err
+		}
+
+		// Jitter so agents don't reconnect in lockstep after a bot restart
+		wait := backoff + rand.N(backoff/2)
+		c.logger.WithError(err).WithField("retry_in", wait).Warn("Tunnel connection lost")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, tunnelMaxBackoff)
+	}
+}
+
+// connect upgrades a signed request and sends hello with the resume point
+func (c *TunnelClient) connect(ctx context.Context, lastID string) (*TunnelConn, error) {
+	req, err := c.newRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", TunnelProtocol)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusSwitchingProtocols:
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrTunnelUnsupported
+	default:
+		defer resp.Body.Close()
+		return nil, statusError(resp)
+	}
+
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		resp.Body.Close()
+		return nil, fmt.Errorf("upgraded connection is not writable")
+	}
+
+	conn := NewTunnelConn(nil, rwc)
+	if err := conn.Send(TunnelFrame{Type: FrameHello, ID: lastID}); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// serve reads commands until the tunnel breaks
+func (c *TunnelClient) serve(ctx context.Context, conn *TunnelConn, handle func(streams.StreamMessage)) error {
+	c.setConn(conn)
+	defer c.setConn(nil)
+	defer conn.Close()
+
+	go conn.closeOnCancel(ctx)
+	go conn.KeepAlive(tunnelKeepAlive)
+
+	cmdStream := "stream:cmd:" + c.config.SecretKey
+	for {
+		frame, err := conn.Receive()
+		if err != nil {
+			return err
+		}
+
+		switch frame.Type {
+		case FrameCommand:
+			handle(streams.StreamMessage{ID: frame.ID, Values: frame.Values, Stream: cmdStream})
+		case FrameError:
+			return fmt.Errorf("bot closed tunnel: %s", frame.Error)
+		default:
+			c.logger.WithField("type", frame.Type).Debug("Ignoring unknown tunnel frame")
+		}
+	}
+}
+
+func (c *TunnelClient) setConn(conn *TunnelConn) {
+	c.mu.Lock()
+	c.conn = conn
+	c.mu.Unlock()
+}
+
+// send writes a frame to the current connection
+func (c *TunnelClient) send(frame TunnelFrame) error {
+	c.mu.RLock()
+	conn := c.conn
+	c.mu.RUnlock()
+
+	if conn == nil {
+		return ErrTunnelDisconnected
+	}
+	return conn.Send(frame)
+}
+
+// Connected reports whether the tunnel is up
+func (c *TunnelClient) Connected() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.conn != nil
+}
+
+// SendResponse adds a response entry to the agent response stream
+func (c *TunnelClient) SendResponse(ctx context.Context, values map[string]string) error {
+	return c.send(TunnelFrame{Type: FrameResponse, Values: values})
+}
+
+// Heartbeat reports the agent alive together with its capabilities
+func (c *TunnelClient) Heartbeat(caps protocol.Capabilities) error {
+	data, err := json.Marshal(caps)
+	if err != nil {
+		return err
+	}
+	return c.send(TunnelFrame{Type: FrameHeartbeat, Values: map[string]string{CapabilitiesField: string(data)}})
+}
+
+// Publish sends a Pub/Sub event through the bot
+func (c *TunnelClient) Publish(ctx context.Context, channel string, data []byte) error {
+	return c.send(TunnelFrame{Type: FrameEvent, Channel: channel, Data: string(data)})
+}
+
+// Subscribe is not available over the tunnel, commands are pushed by the bot
+func (c *TunnelClient) Subscribe(ctx context.Context, channel string) (Subscription, error) {
+	return nil, fmt.Errorf("subscribe is not supported over tunnel")
+}
+
+// Close closes the current connection, Run reconnects until its ctx is done
+func (c *TunnelClient) Close() error {
+	c.mu.RLock()
+	conn := c.conn
+	c.mu.RUnlock()
+
+	if conn != nil {
+		return conn.Close()
+	}
+	return nil
+}
+
+// newRequest creates a signed GET request to the tunnel endpoint
+func (c *TunnelClient) newRequest(ctx context.Context) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.config.BaseURL+TunnelPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := auth.SignRequest(req, c.config.SecretKey, nil); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// statusError builds an error from an unexpected HTTP response
+func statusError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if msg := strings.TrimSpace(string(body)); msg != "" {
+		return fmt.Errorf("HTTP error: %d: %s", resp.StatusCode, msg)
+	}
+	return fmt.Errorf("HTTP error: %d", resp.StatusCode)
+}
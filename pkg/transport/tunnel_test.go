@@ -0,0 +1,87 @@
+package transport
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/servereye/servereye/pkg/protocol"
+)
+
+func newTunnelPair(t *testing.T) (*TunnelConn, *TunnelConn) {
+	t.Helper()
+
+	a, b := net.Pipe()
+	left, right := NewTunnelConn(nil, a), NewTunnelConn(nil, b)
+	t.Cleanup(func() {
+		left.Close()
+		right.Close()
+	})
+	return left, right
+}
+
+func TestTunnelConn_SendReceive(t *testing.T) {
+	left, right := newTunnelPair(t)
+
+	go left.Send(TunnelFrame{Type: FrameCommand, ID: "1-0", Values: map[string]string{"payload": "{}"}})
+
+	frame, err := right.Receive()
+	if err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	}
+	if frame.Type != FrameCommand || frame.ID != "1-0" || frame.Values["payload"] != "{}" {
+		t.Errorf("Receive() = %+v", frame)
+	}
+}
+
+func TestTunnelConn_AnswersPing(t *testing.T) {
+	left, right := newTunnelPair(t)
+
+	// right answers pings while waiting for a data frame
+	go right.Receive()
+	go left.Send(TunnelFrame{Type: FramePing})
+
+	// Read left side raw: Receive would swallow the pong
+	var frame TunnelFrame
+	if err := left.dec.Decode(&frame); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if frame.Type != FramePong {
+		t.Errorf("Frame type = %q, want %q", frame.Type, FramePong)
+	}
+}
+
+func TestTunnelConn_KeepAliveClosesSilentPeer(t *testing.T) {
+	left, _ := newTunnelPair(t)
+
+	// Peer never reads, so pings are never answered
+	left.lastRead.Store(time.Now().Add(-time.Hour).UnixNano())
+
+	go left.KeepAlive(10 * time.Millisecond)
+
+	select {
+	case <-left.Done():
+	case <-time.After(time.Second):
+		t.Fatal("KeepAlive did not close silent tunnel")
+	}
+
+	if err := left.Send(TunnelFrame{Type: FramePing}); err != ErrTunnelClosed {
+		t.Errorf("Send() after close error = %v, want ErrTunnelClosed", err)
+	}
+}
+
+func TestTunnelFrame_Capabilities(t *testing.T) {
+	caps := protocol.NewCapabilities(protocol.TypePing)
+	data, _ := json.Marshal(caps)
+
+	frame := TunnelFrame{Type: FrameHeartbeat, Values: map[string]string{CapabilitiesField: string(data)}}
+	got, ok := frame.Capabilities()
+	if !ok || got.ProtocolVersion != protocol.ProtocolVersion || !got.Supports(protocol.TypePing) {
+		t.Errorf("Capabilities() = %+v, %v", got, ok)
+	}
+
+	if _, ok := (TunnelFrame{Type: FrameHeartbeat}).Capabilities(); ok {
+		t.Error("heartbeat without capabilities should report none")
+	}
+}
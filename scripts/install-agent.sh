@@ -8,6 +8,7 @@ set -e
 AGENT_USER="servereye"
 AGENT_DIR="/opt/servereye"
 CONFIG_DIR="/etc/servereye"
+STATE_DIR="/var/lib/servereye"
 LOG_DIR="/var/log/servereye"
 SERVICE_FILE="/etc/systemd/system/servereye-agent.service"
 AGENT_URL="https://github.com/godofphonk/ServerEye/releases/latest/download/servereye-agent-linux-amd64"
@@ -66,8 +67,8 @@ fi
 
 # Create directories
 echo "[*] Creating directories..."
-mkdir -p "$AGENT_DIR" "$CONFIG_DIR" "$LOG_DIR"
-chown "$AGENT_USER:$AGENT_USER" "$AGENT_DIR" "$LOG_DIR"
+mkdir -p "$AGENT_DIR" "$CONFIG_DIR" "$LOG_DIR" "$STATE_DIR"
+chown "$AGENT_USER:$AGENT_USER" "$AGENT_DIR" "$LOG_DIR" "$STATE_DIR"
 chmod 755 "$CONFIG_DIR"
 
 # Check version if updating
@@ -254,7 +255,7 @@ NoNewPrivileges=true
 PrivateTmp=true
 ProtectSystem=strict
 ProtectHome=true
-ReadWritePaths=/var/log/servereye /etc/servereye
+ReadWritePaths=/var/log/servereye /etc/servereye /var/lib/servereye
 
 [Install]
 WantedBy=multi-user.target